@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Headers an authenticating proxy in front of the API sets to the caller's user name and groups by default, as for
+// the API server's request header authentication
+const (
+	defaultAccessUserHeader  = "X-Remote-User"
+	defaultAccessGroupHeader = "X-Remote-Group"
+)
+
+// Key the caller of a request is stored under in its gin.Context
+const accessCallerKey = "accessCaller"
+
+// AccessRule is an action a caller must be allowed to take, as checked with a SubjectAccessReview
+type AccessRule struct {
+	Verb      string `json:"verb"`
+	Group     string `json:"group"`
+	Resource  string `json:"resource"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"` // Replaced by the pod's namespace in the namespaces rule
+}
+
+// AccessConfig maps what callers see to what RBAC allows them to do. Pools without a rule are visible to everyone.
+type AccessConfig struct {
+	Pools       map[string]AccessRule `json:"pools"`       // Rule for seeing each pool's nodes - "*" applies to pools without their own
+	Namespaces  *AccessRule           `json:"namespaces"`  // Rule for seeing the pods in a namespace, list pods by default
+	ClusterWide *AccessRule           `json:"clusterWide"` // Rule for using endpoints that can't be filtered, list nodes by default
+}
+
+// Rules used when the config file doesn't set them
+var (
+	defaultNamespacesRule  = AccessRule{Verb: "list", Resource: "pods"}
+	defaultClusterWideRule = AccessRule{Verb: "list", Resource: "nodes"}
+)
+
+// Caller is the identity of whoever made a request
+type Caller struct {
+	User   string
+	Groups []string
+}
+
+// accessDecision is a cached SubjectAccessReview result
+type accessDecision struct {
+	allowed bool
+	expires time.Time
+}
+
+// AccessReviewer filters what each caller sees by their RBAC permissions, checked with SubjectAccessReviews against
+// the rules in the config file. Decisions are cached, since a single response can need one for every pool and
+// namespace.
+type AccessReviewer struct {
+	client      kubernetes.Interface
+	userHeader  string
+	groupHeader string
+	ttl         time.Duration // How long decisions are cached for
+	mutex       sync.Mutex
+	decisions   map[string]accessDecision
+	filtered    map[string]bool // Full paths of the routes that filter their responses
+}
+
+// newAccessReviewer returns an AccessReviewer that reads callers from the given headers and reviews their access
+// through client, caching decisions for ttl.
+func newAccessReviewer(client kubernetes.Interface, userHeader, groupHeader string, ttl time.Duration) *AccessReviewer {
+	return &AccessReviewer{
+		client:      client,
+		userHeader:  userHeader,
+		groupHeader: groupHeader,
+		ttl:         ttl,
+		decisions:   make(map[string]accessDecision),
+		filtered:    make(map[string]bool),
+	}
+}
+
+// getCaller returns the caller of a request from its headers, or false if it has no user. Groups can be given in
+// several headers or separated by commas.
+func (r *AccessReviewer) getCaller(c *gin.Context) (Caller, bool) {
+	caller := Caller{User: c.GetHeader(r.userHeader), Groups: make([]string, 0)}
+
+	for _, value := range c.Request.Header.Values(r.groupHeader) {
+		for _, group := range strings.Split(value, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				caller.Groups = append(caller.Groups, group)
+			}
+		}
+	}
+
+	return caller, caller.User != ""
+}
+
+// Allowed returns whether a caller may take the action of rule in namespace, or in the rule's own namespace if
+// namespace is empty.
+func (r *AccessReviewer) Allowed(caller Caller, rule AccessRule, namespace string) (bool, error) {
+	if namespace == "" {
+		namespace = rule.Namespace
+	}
+
+	key := strings.Join([]string{caller.User, strings.Join(caller.Groups, ","), rule.Verb, rule.Group, rule.Resource, rule.Name, namespace}, "\x00")
+
+	r.mutex.Lock()
+	decision, ok := r.decisions[key]
+	r.mutex.Unlock()
+
+	if ok && time.Now().Before(decision.expires) {
+		return decision.allowed, nil
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   caller.User,
+			Groups: caller.Groups,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      rule.Verb,
+				Group:     rule.Group,
+				Resource:  rule.Resource,
+				Name:      rule.Name,
+			},
+		},
+	}
+
+	result, err := r.client.AuthorizationV1().SubjectAccessReviews().Create(context.Background(), review, metav1.CreateOptions{})
+
+	if err != nil {
+		return false, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Forget expired decisions now and then so callers who have gone away don't use up memory
+	if len(r.decisions) > 10000 {
+		for key, decision := range r.decisions {
+			if time.Now().After(decision.expires) {
+				delete(r.decisions, key)
+			}
+		}
+	}
+
+	r.decisions[key] = accessDecision{allowed: result.Status.Allowed, expires: time.Now().Add(r.ttl)}
+
+	return result.Status.Allowed, nil
+}
+
+// filterSnapshot returns a copy of a snapshot with only the nodes in pools the caller may see, and the pods on
+// those nodes in namespaces the caller may see. Pending pods are kept if their namespace is visible.
+func (r *AccessReviewer) filterSnapshot(caller Caller, snapshot *Snapshot) (*Snapshot, error) {
+	config := currentConfig().Access
+
+	namespacesRule := defaultNamespacesRule
+	if config.Namespaces != nil {
+		namespacesRule = *config.Namespaces
+	}
+
+	filtered := *snapshot
+	filtered.Nodes = make([]NodeJson, 0, len(snapshot.Nodes))
+	filtered.Pods = make([]PodJson, 0, len(snapshot.Pods))
+
+	visiblePools := make(map[string]bool)
+	visibleNodes := make(map[string]bool)
+
+	for _, node := range snapshot.Nodes {
+		visible, ok := visiblePools[node.Pool]
+
+		if !ok {
+			rule, hasRule := config.Pools[node.Pool]
+			if !hasRule {
+				rule, hasRule = config.Pools["*"]
+			}
+
+			visible = true
+			if hasRule {
+				allowed, err := r.Allowed(caller, rule, "")
+
+				if err != nil {
+					return nil, err
+				}
+
+				visible = allowed
+			}
+
+			visiblePools[node.Pool] = visible
+		}
+
+		if visible {
+			filtered.Nodes = append(filtered.Nodes, node)
+			visibleNodes[node.Name] = true
+		}
+	}
+
+	visibleNamespaces := make(map[string]bool)
+
+	for _, pod := range snapshot.Pods {
+		if pod.Node != "" && !visibleNodes[pod.Node] {
+			continue
+		}
+
+		visible, ok := visibleNamespaces[pod.Namespace]
+
+		if !ok {
+			allowed, err := r.Allowed(caller, namespacesRule, pod.Namespace)
+
+			if err != nil {
+				return nil, err
+			}
+
+			visible = allowed
+			visibleNamespaces[pod.Namespace] = visible
+		}
+
+		if visible {
+			filtered.Pods = append(filtered.Pods, pod)
+		}
+	}
+
+	return &filtered, nil
+}
+
+// Middleware returns a HandlerFunc that rejects requests without a caller. Routes that don't filter their responses
+// also need the caller to pass the cluster-wide rule, so they don't reveal what the filtered routes hide.
+func (r *AccessReviewer) Middleware() gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		caller, ok := r.getCaller(c)
+
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, fmt.Sprintf("error: expected the caller's user name in the %v header", r.userHeader))
+			return
+		}
+
+		c.Set(accessCallerKey, caller)
+
+		if r.filtered[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		rule := defaultClusterWideRule
+		if clusterWide := currentConfig().Access.ClusterWide; clusterWide != nil {
+			rule = *clusterWide
+		}
+
+		allowed, err := r.Allowed(caller, rule, "")
+
+		if err != nil {
+			fmt.Println(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, "error reviewing access")
+			return
+		}
+
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, "error: "+caller.User+" isn't allowed to see the whole cluster")
+			return
+		}
+
+		c.Next()
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// route creates an endpoint at relativePath on routes that runs the handler newHandler creates for a Collector
+// serving only what the caller may see.
+func (r *AccessReviewer) route(routes *gin.RouterGroup, relativePath string, collector *Collector, newHandler func(*Collector) gin.HandlerFunc) {
+	r.filtered[path.Join(routes.BasePath(), relativePath)] = true
+
+	handler := func(c *gin.Context) {
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		filtered, err := r.filterSnapshot(c.MustGet(accessCallerKey).(Caller), snapshot)
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error reviewing access")
+			return
+		}
+
+		// Versions are shared with the collector, so long-polling clients still wait for the cluster to change
+		view := &Collector{imported: filtered, tracker: collector.tracker}
+		newHandler(view)(c)
+	}
+
+	routes.GET(relativePath, handler)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestAccessReviewer filters a snapshot for a caller who may see the cpu pool and the web namespace, checking what
+// each route shows them, that decisions are cached, and that unfiltered routes need cluster-wide access.
+func TestAccessReviewer(t *testing.T) {
+	defer runtimeConfig.Store(nil)
+
+	config := `{"access": {"pools": {"gpu": {"verb": "get", "resource": "pods", "namespace": "gpu-users"}}}}`
+	if err := applyRuntimeConfig([]byte(config), func(*RuntimeConfig) {}); err != nil {
+		t.Fatalf(`applyRuntimeConfig() = %v, want match for nil`, err)
+	}
+
+	// alice may list pods in web, and do nothing else
+	reviews := 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "alice" && attributes.Verb == "list" && attributes.Resource == "pods" && attributes.Namespace == "web"
+		reviews++
+
+		return true, review, nil
+	})
+
+	collector := &Collector{
+		imported: &Snapshot{
+			Nodes: []NodeJson{{Name: "cpu-1", Pool: "cpu"}, {Name: "gpu-1", Pool: "gpu"}},
+			Pods: []PodJson{
+				{Name: "frontend", Namespace: "web", Node: "cpu-1"},
+				{Name: "batch", Namespace: "jobs", Node: "cpu-1"},
+				{Name: "trainer", Namespace: "web", Node: "gpu-1"},
+				{Name: "pending", Namespace: "web"},
+			},
+		},
+		tracker: newNodeTracker(),
+	}
+
+	reviewer := newAccessReviewer(client, defaultAccessUserHeader, defaultAccessGroupHeader, time.Minute)
+	router := gin.New()
+	routes := router.Group("/capacity")
+	routes.Use(reviewer.Middleware())
+	reviewer.route(routes, "/nodes", collector, getNodesHandler)
+	reviewer.route(routes, "/pods", collector, getPodsHandler)
+	routes.GET("/gpus", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(target, user string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		if user != "" {
+			request.Header.Set(defaultAccessUserHeader, user)
+			request.Header.Add(defaultAccessGroupHeader, "developers, system:authenticated")
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	var nodes []NodeJson
+	json.Unmarshal(get("/capacity/nodes", "alice").Body.Bytes(), &nodes)
+
+	if len(nodes) != 1 || nodes[0].Name != "cpu-1" {
+		t.Fatalf(`GET /nodes = %v, want only cpu-1`, nodes)
+	}
+
+	var pods []PodJson
+	json.Unmarshal(get("/capacity/pods", "alice").Body.Bytes(), &pods)
+
+	if len(pods) != 2 || pods[0].Name != "frontend" || pods[1].Name != "pending" {
+		t.Fatalf(`GET /pods = %v, want frontend and pending`, pods)
+	}
+
+	// The gpu pool and the web and jobs namespaces were each reviewed once, and then cached
+	if reviews != 3 {
+		t.Fatalf(`reviews = %v, want match for %v`, reviews, 3)
+	}
+
+	if recorder := get("/capacity/gpus", "alice"); recorder.Code != http.StatusForbidden {
+		t.Fatalf(`GET /gpus = %v, want match for %v`, recorder.Code, http.StatusForbidden)
+	}
+
+	if recorder := get("/capacity/nodes", ""); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf(`GET /nodes without a user = %v, want match for %v`, recorder.Code, http.StatusUnauthorized)
+	}
+}
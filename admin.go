@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerAdminRoutes creates the operational endpoints, which change the server or expose its internals, on admin.
+func registerAdminRoutes(admin gin.IRoutes, collector *Collector) {
+	// Create endpoints at /admin/log-level to get and change the log level
+	admin.GET("/admin/log-level", getLogLevelHandler())
+	admin.PUT("/admin/log-level", getSetLogLevelHandler())
+
+	// Create an endpoint at /refresh that takes a new snapshot right away rather than waiting for caches to expire
+	admin.POST("/refresh", getRefreshHandler(collector))
+
+	// Create an endpoint at /debug/cache that returns how old the snapshots being served are
+	admin.GET("/debug/cache", getDebugCacheHandler(collector))
+
+	// Create endpoints at /debug/pprof for profiling the server with go tool pprof
+	admin.GET("/debug/pprof/*profile", getPprofHandler())
+	admin.POST("/debug/pprof/*profile", getPprofHandler())
+}
+
+// getPprofHandler returns a HandlerFunc to serve the profile named by the profile path parameter, or the index of
+// profiles if there is no name. Profiles are chosen by name rather than by pprof.Index, which expects to be served
+// at /debug/pprof/ and so doesn't work under a base path.
+func getPprofHandler() gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		switch name := strings.TrimPrefix(c.Param("profile"), "/"); name {
+		case "":
+			pprof.Index(c.Writer, c.Request)
+		case "cmdline":
+			pprof.Cmdline(c.Writer, c.Request)
+		case "profile":
+			pprof.Profile(c.Writer, c.Request)
+		case "symbol":
+			pprof.Symbol(c.Writer, c.Request)
+		case "trace":
+			pprof.Trace(c.Writer, c.Request)
+		default:
+			pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+		}
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestAdminRoutes requests the operational endpoints under a base path, checking that profiles can be fetched by
+// name and that the token is needed.
+func TestAdminRoutes(t *testing.T) {
+	router := gin.New()
+	registerAdminRoutes(router.Group("/capacity", requireToken("secret")), newCollector(fake.NewSimpleClientset()))
+
+	for _, test := range []struct {
+		target string
+		token  string
+		status int
+		want   string
+	}{
+		{"/capacity/debug/pprof/", "secret", http.StatusOK, "goroutine"},
+		{"/capacity/debug/pprof/goroutine?debug=1", "secret", http.StatusOK, "goroutine profile"},
+		{"/capacity/debug/cache", "secret", http.StatusOK, `"source": "live"`},
+		{"/capacity/debug/cache", "", http.StatusUnauthorized, ""},
+	} {
+		request := httptest.NewRequest(http.MethodGet, test.target, nil)
+		if test.token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.token)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status || !strings.Contains(recorder.Body.String(), test.want) {
+			t.Fatalf(`GET %v = %v, %q, want match for %v, %q`, test.target, recorder.Code, recorder.Body.String(), test.status, test.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertRule describes a condition on free resources that should fire an alert, such as
+// "cluster free GPU below 2" or "node free memory below 10 percent"
+type AlertRule struct {
+	Name     string  `json:"name"`
+	Scope    string  `json:"scope"`    // "cluster" to check the sum over all nodes, or "node" to check every node
+	Resource string  `json:"resource"` // One of cpu, memory, gpu, or ephemeral
+	Percent  bool    `json:"percent"`  // Compare free resources as a percentage of allocatable resources
+	Below    float64 `json:"below"`    // Fire when the free value is below this threshold
+}
+
+// Alert is a notification that a rule has started or stopped firing
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Status    string    `json:"status"` // "firing" or "resolved"
+	Node      string    `json:"node,omitempty"`
+	Resource  string    `json:"resource"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Percent   bool      `json:"percent"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt,omitempty"`
+}
+
+// Notifier sends alerts somewhere
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// WebhookNotifier sends each alert as a JSON POST request to a URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// newWebhookNotifier returns a WebhookNotifier that posts to url.
+func newWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify posts the alert to the webhook as JSON.
+func (w *WebhookNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(alert)
+
+	if err != nil {
+		return err
+	}
+
+	return postJson(w.client, w.url, body)
+}
+
+// postJson sends body to url as a JSON POST request, returning an error if the response status isn't 2xx.
+func postJson(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %v returned status %v", url, resp.Status)
+	}
+
+	return nil
+}
+
+// AlertManager evaluates alert rules against snapshots and notifies when alerts start or stop firing.
+// Alerts that are already firing are only sent once, until they resolve.
+type AlertManager struct {
+	rules     []AlertRule
+	notifiers []Notifier
+
+	mu     sync.Mutex
+	active map[string]*Alert
+}
+
+// newAlertManager returns an AlertManager that evaluates rules and sends alerts to notifiers.
+func newAlertManager(rules []AlertRule, notifiers []Notifier) *AlertManager {
+	return &AlertManager{
+		rules:     rules,
+		notifiers: notifiers,
+		active:    make(map[string]*Alert),
+	}
+}
+
+// SetRules replaces the rules that are evaluated. Alerts for rules that were removed resolve on the next evaluation.
+func (a *AlertManager) SetRules(rules []AlertRule) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rules = rules
+}
+
+// loadAlertRules reads a list of alert rules from the JSON file at path.
+func loadAlertRules(path string) ([]AlertRule, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []AlertRule
+
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	if err := validateAlertRules(rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// validateAlertRules returns an error if any rule has an unknown resource or scope. A misspelled resource would
+// always be 0 free and fire forever, and a misspelled scope would silently check the cluster.
+func validateAlertRules(rules []AlertRule) error {
+	for _, rule := range rules {
+		if !slices.Contains(resourceNames, rule.Resource) {
+			return fmt.Errorf("alert rule %q has invalid resource %q: expected one of %v", rule.Name, rule.Resource, strings.Join(resourceNames, ", "))
+		}
+
+		if rule.Scope != "node" && rule.Scope != "cluster" {
+			return fmt.Errorf("alert rule %q has invalid scope %q: expected node or cluster", rule.Name, rule.Scope)
+		}
+	}
+
+	return nil
+}
+
+// Evaluate checks every rule against the snapshot, sending firing alerts for rules that newly match
+// and resolved alerts for rules that matched before but no longer do.
+func (a *AlertManager) Evaluate(snapshot *Snapshot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Keys of every alert that is firing in this snapshot
+	firing := make(map[string]bool)
+
+	for _, rule := range a.rules {
+		for _, alert := range evaluateRule(rule, snapshot) {
+			key := alert.Rule + "/" + alert.Node
+			firing[key] = true
+
+			// Don't send the same alert again while it's still firing
+			if _, ok := a.active[key]; ok {
+				a.active[key].Value = alert.Value
+				continue
+			}
+
+			alert.Status = "firing"
+			alert.StartsAt = snapshot.Time
+			a.active[key] = &alert
+			a.notify(alert)
+		}
+	}
+
+	// Resolve any active alerts that didn't fire this time
+	for key, alert := range a.active {
+		if firing[key] {
+			continue
+		}
+
+		alert.Status = "resolved"
+		alert.EndsAt = snapshot.Time
+		a.notify(*alert)
+		delete(a.active, key)
+	}
+}
+
+// notify sends an alert to every notifier.
+func (a *AlertManager) notify(alert Alert) {
+	for _, notifier := range a.notifiers {
+		if err := notifier.Notify(alert); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// evaluateRule returns an alert for every node (or the cluster) matching the rule in the snapshot.
+func evaluateRule(rule AlertRule, snapshot *Snapshot) []Alert {
+	alerts := make([]Alert, 0)
+
+	check := func(node string, free, allocatable ResourcesJson) {
+		value := getResource(free, rule.Resource)
+
+		// Convert to a percentage of allocatable if needed - nodes without any of the resource can't fire
+		if rule.Percent {
+			total := getResource(allocatable, rule.Resource)
+			if total == 0 {
+				return
+			}
+			value = value / total * 100
+		}
+
+		if value < rule.Below {
+			alerts = append(alerts, Alert{
+				Rule:      rule.Name,
+				Node:      node,
+				Resource:  rule.Resource,
+				Value:     value,
+				Threshold: rule.Below,
+				Percent:   rule.Percent,
+			})
+		}
+	}
+
+	if rule.Scope == "node" {
+		for _, node := range snapshot.Nodes {
+			check(node.Name, node.Free, node.Allocatable)
+		}
+		return alerts
+	}
+
+	// Sum every node for cluster-wide rules
+	var free, allocatable ResourcesJson
+	for _, node := range snapshot.Nodes {
+		free = addResources(free, node.Free)
+		allocatable = addResources(allocatable, node.Allocatable)
+	}
+
+	check("", free, allocatable)
+
+	return alerts
+}
+
+// addResources returns the sum of each field of a and b.
+func addResources(a, b ResourcesJson) ResourcesJson {
+	return ResourcesJson{
+		Cpu:       a.Cpu + b.Cpu,
+		Memory:    a.Memory + b.Memory,
+		Gpu:       a.Gpu + b.Gpu,
+		Ephemeral: a.Ephemeral + b.Ephemeral,
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeNotifier records every alert it is sent
+type fakeNotifier struct {
+	alerts []Alert
+}
+
+func (f *fakeNotifier) Notify(alert Alert) error {
+	f.alerts = append(f.alerts, alert)
+	return nil
+}
+
+// TestAlertManager evaluates a cluster rule and a node percentage rule against a series of snapshots, checking
+// that alerts fire once while the condition holds and resolve once it stops.
+func TestAlertManager(t *testing.T) {
+	notifier := &fakeNotifier{}
+
+	alerts := newAlertManager([]AlertRule{
+		{Name: "low-gpu", Scope: "cluster", Resource: "gpu", Below: 2},
+		{Name: "low-memory", Scope: "node", Resource: "memory", Percent: true, Below: 10},
+	}, []Notifier{notifier})
+
+	snapshot := func(freeGpu int64, freeMemory int64) *Snapshot {
+		return &Snapshot{
+			Time: time.Now(),
+			Nodes: []NodeJson{
+				{Name: "node-1", Allocatable: ResourcesJson{Gpu: 4, Memory: 1000}, Free: ResourcesJson{Gpu: freeGpu, Memory: freeMemory}},
+				{Name: "node-2", Allocatable: ResourcesJson{Memory: 1000}, Free: ResourcesJson{Memory: 500}},
+			},
+		}
+	}
+
+	// One free GPU and 5% free memory on node-1 should fire both rules
+	alerts.Evaluate(snapshot(1, 50))
+
+	if len(notifier.alerts) != 2 {
+		t.Fatalf(`len(alerts) = %v, want match for %v`, len(notifier.alerts), 2)
+	}
+
+	for _, alert := range notifier.alerts {
+		if alert.Status != "firing" {
+			t.Fatalf(`alert.Status = %v, want match for %v`, alert.Status, "firing")
+		}
+		if alert.Rule == "low-memory" && alert.Node != "node-1" {
+			t.Fatalf(`alert.Node = %v, want match for %v`, alert.Node, "node-1")
+		}
+	}
+
+	// The same conditions again shouldn't send anything new
+	alerts.Evaluate(snapshot(0, 50))
+
+	if len(notifier.alerts) != 2 {
+		t.Fatalf(`len(alerts) = %v, want match for %v`, len(notifier.alerts), 2)
+	}
+
+	// Freeing up GPUs should resolve only the GPU alert
+	alerts.Evaluate(snapshot(3, 50))
+
+	if len(notifier.alerts) != 3 {
+		t.Fatalf(`len(alerts) = %v, want match for %v`, len(notifier.alerts), 3)
+	}
+
+	if last := notifier.alerts[2]; last.Rule != "low-gpu" || last.Status != "resolved" {
+		t.Fatalf(`alert = %v %v, want match for %v %v`, last.Rule, last.Status, "low-gpu", "resolved")
+	}
+}
+
+// TestValidateAlertRules checks that rules with an unknown resource or scope are rejected, since they would otherwise
+// fire on every snapshot or silently check the wrong thing.
+func TestValidateAlertRules(t *testing.T) {
+	valid := []AlertRule{
+		{Name: "low-gpu", Scope: "cluster", Resource: "gpu", Below: 2},
+		{Name: "low-memory", Scope: "node", Resource: "memory", Percent: true, Below: 10},
+	}
+
+	if err := validateAlertRules(valid); err != nil {
+		t.Fatalf(`validateAlertRules(valid) = %v, want match for %v`, err, nil)
+	}
+
+	for _, rule := range []AlertRule{
+		{Name: "typo", Scope: "cluster", Resource: "gpus", Below: 2},
+		{Name: "no-resource", Scope: "node", Below: 2},
+		{Name: "bad-scope", Scope: "nodes", Resource: "cpu", Below: 2},
+		{Name: "no-scope", Resource: "cpu", Below: 2},
+	} {
+		if err := validateAlertRules(append(valid, rule)); err == nil {
+			t.Fatalf(`validateAlertRules(%v) = %v, want match for %v`, rule.Name, err, "an error")
+		}
+	}
+}
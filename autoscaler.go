@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Namespace and name of the ConfigMap cluster-autoscaler writes its status to by default
+const defaultAutoscalerStatus = "kube-system/cluster-autoscaler-status"
+
+// AutoscalerNodeGroup contains the size and activity of a node group managed by cluster-autoscaler
+type AutoscalerNodeGroup struct {
+	Name          string     `json:"name"`
+	MinSize       int        `json:"minSize"`
+	MaxSize       int        `json:"maxSize"`
+	Current       int        `json:"current"`
+	Ready         int        `json:"ready"`
+	Target        int        `json:"target"`
+	Health        string     `json:"health"`
+	ScaleUp       string     `json:"scaleUp"`
+	ScaleDown     string     `json:"scaleDown"`
+	ScaleUpError  string     `json:"scaleUpError,omitempty"`
+	ScaleUpSince  *time.Time `json:"scaleUpSince,omitempty"`
+	CanScaleUp    bool       `json:"canScaleUp"`
+	CanScaleDown  bool       `json:"canScaleDown"`
+	BackoffReason string     `json:"backoffReason,omitempty"`
+}
+
+// AutoscalerStatus contains the state of cluster-autoscaler and of each node group, along with the pods that
+// can't be scheduled and so may be waiting for a scale-up
+type AutoscalerStatus struct {
+	Time        time.Time             `json:"time"`
+	Status      string                `json:"status"`
+	Health      string                `json:"health"`
+	ScaleUp     string                `json:"scaleUp"`
+	ScaleDown   string                `json:"scaleDown"`
+	NodeGroups  []AutoscalerNodeGroup `json:"nodeGroups"`
+	PendingPods []PodJson             `json:"pendingPods"`
+}
+
+// The parts of the YAML status written by cluster-autoscaler 1.30 and later that the API reports on
+type autoscalerStatusYaml struct {
+	Time             string `json:"time"`
+	AutoscalerStatus string `json:"autoscalerStatus"`
+	ClusterWide      struct {
+		Health    autoscalerConditionYaml `json:"health"`
+		ScaleUp   autoscalerConditionYaml `json:"scaleUp"`
+		ScaleDown autoscalerConditionYaml `json:"scaleDown"`
+	} `json:"clusterWide"`
+	NodeGroups []struct {
+		Name   string `json:"name"`
+		Health struct {
+			autoscalerConditionYaml
+			NodeCounts struct {
+				Registered struct {
+					Total int `json:"total"`
+					Ready int `json:"ready"`
+				} `json:"registered"`
+			} `json:"nodeCounts"`
+			CloudProviderTarget int `json:"cloudProviderTarget"`
+			MinSize             int `json:"minSize"`
+			MaxSize             int `json:"maxSize"`
+		} `json:"health"`
+		ScaleUp   autoscalerConditionYaml `json:"scaleUp"`
+		ScaleDown autoscalerConditionYaml `json:"scaleDown"`
+	} `json:"nodeGroups"`
+}
+
+// A single condition in the YAML status written by cluster-autoscaler
+type autoscalerConditionYaml struct {
+	Status             string `json:"status"`
+	LastTransitionTime string `json:"lastTransitionTime"`
+	BackoffInfo        struct {
+		ErrorCode    string `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	} `json:"backoffInfo"`
+}
+
+// AutoscalerStatus reads the status ConfigMap of cluster-autoscaler and lists the pods that can't be scheduled.
+// If the ConfigMap doesn't exist, a NotFound error is returned.
+func (c *Collector) AutoscalerStatus() (*AutoscalerStatus, error) {
+	namespace, name, _ := strings.Cut(c.autoscalerStatus, "/")
+
+	configMap, err := c.client.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := parseAutoscalerStatus(configMap.Data["status"])
+
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := c.UnschedulablePods()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pods {
+		status.PendingPods = append(status.PendingPods, getPodStructured(&pods[i]))
+	}
+
+	return status, nil
+}
+
+// UnschedulablePods returns every pending pod the scheduler has tried and failed to find a node for, and every pod
+// held back by scheduling gates, as both are demand for capacity. Pods nominated for a node aren't returned, since
+// their requests are already counted against that node.
+func (c *Collector) UnschedulablePods() ([]corev1.Pod, error) {
+	// Pods the scheduler couldn't place haven't been given a node yet
+	pods, err := c.listPods("status.phase=" + string(corev1.PodPending) + ",spec.nodeName=")
+
+	if err != nil {
+		return nil, err
+	}
+
+	unschedulable := make([]corev1.Pod, 0)
+	for i := range pods {
+		if getAccountingNode(&pods[i]) != "" {
+			continue
+		}
+
+		if isUnschedulable(&pods[i]) || len(pods[i].Spec.SchedulingGates) > 0 {
+			unschedulable = append(unschedulable, pods[i])
+		}
+	}
+
+	return unschedulable, nil
+}
+
+// parseAutoscalerStatus parses the YAML status written by cluster-autoscaler 1.30 and later. Earlier versions
+// write a plain text status, which isn't supported.
+func parseAutoscalerStatus(text string) (*AutoscalerStatus, error) {
+	if strings.HasPrefix(text, "Cluster-autoscaler status at") {
+		return nil, fmt.Errorf("cluster-autoscaler status is in the text format used before 1.30, which isn't supported")
+	}
+
+	var parsed autoscalerStatusYaml
+	if err := yaml.Unmarshal([]byte(text), &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing cluster-autoscaler status: %v", err)
+	}
+
+	status := AutoscalerStatus{
+		Status:      parsed.AutoscalerStatus,
+		Health:      parsed.ClusterWide.Health.Status,
+		ScaleUp:     parsed.ClusterWide.ScaleUp.Status,
+		ScaleDown:   parsed.ClusterWide.ScaleDown.Status,
+		NodeGroups:  make([]AutoscalerNodeGroup, 0, len(parsed.NodeGroups)),
+		PendingPods: make([]PodJson, 0),
+	}
+
+	// Leave the time as zero if it is missing rather than failing the whole status
+	status.Time, _ = parseAutoscalerTime(parsed.Time)
+
+	for _, group := range parsed.NodeGroups {
+		nodeGroup := AutoscalerNodeGroup{
+			Name:          group.Name,
+			MinSize:       group.Health.MinSize,
+			MaxSize:       group.Health.MaxSize,
+			Current:       group.Health.NodeCounts.Registered.Total,
+			Ready:         group.Health.NodeCounts.Registered.Ready,
+			Target:        group.Health.CloudProviderTarget,
+			Health:        group.Health.Status,
+			ScaleUp:       group.ScaleUp.Status,
+			ScaleDown:     group.ScaleDown.Status,
+			ScaleUpError:  group.ScaleUp.BackoffInfo.ErrorMessage,
+			BackoffReason: group.ScaleUp.BackoffInfo.ErrorCode,
+			CanScaleUp:    group.Health.CloudProviderTarget < group.Health.MaxSize,
+			CanScaleDown:  group.Health.CloudProviderTarget > group.Health.MinSize,
+		}
+
+		if since, err := parseAutoscalerTime(group.ScaleUp.LastTransitionTime); err == nil {
+			nodeGroup.ScaleUpSince = &since
+		}
+
+		status.NodeGroups = append(status.NodeGroups, nodeGroup)
+	}
+
+	return &status, nil
+}
+
+// parseAutoscalerTime parses a time in the status written by cluster-autoscaler, which is either in RFC 3339
+// format or in the format of time.Time.String.
+func parseAutoscalerTime(text string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", text)
+}
+
+// isUnschedulable returns whether the scheduler has tried and failed to find a node for a pod.
+func isUnschedulable(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return condition.Reason == corev1.PodReasonUnschedulable
+		}
+	}
+
+	return false
+}
+
+// getAutoscalerHandler returns a HandlerFunc to return the status of cluster-autoscaler given a Collector.
+func getAutoscalerHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		status, err := collector.AutoscalerStatus()
+
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, "error: cluster-autoscaler status not found")
+			return
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving cluster-autoscaler status")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, status)
+	}
+
+	return gin.HandlerFunc(handler)
+}
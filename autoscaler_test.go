@@ -0,0 +1,130 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestParseAutoscalerStatus parses a cluster-autoscaler status with a healthy node group and one backing off
+// after a failed scale-up, and checks that the text format used before 1.30 is rejected.
+func TestParseAutoscalerStatus(t *testing.T) {
+	text := `time: 2026-10-15 12:00:00.000000000 +0000 UTC
+autoscalerStatus: Running
+clusterWide:
+  health:
+    status: Healthy
+  scaleUp:
+    status: InProgress
+  scaleDown:
+    status: NoCandidates
+nodeGroups:
+- name: cpu
+  health:
+    status: Healthy
+    nodeCounts:
+      registered:
+        total: 3
+        ready: 3
+    cloudProviderTarget: 3
+    minSize: 1
+    maxSize: 3
+  scaleUp:
+    status: NoActivity
+  scaleDown:
+    status: NoCandidates
+- name: gpu
+  health:
+    status: Healthy
+    nodeCounts:
+      registered:
+        total: 1
+        ready: 1
+    cloudProviderTarget: 2
+    minSize: 0
+    maxSize: 4
+  scaleUp:
+    status: Backoff
+    backoffInfo:
+      errorCode: OutOfResource
+      errorMessage: insufficient capacity for p3.8xlarge
+    lastTransitionTime: "2026-10-15T11:50:00Z"
+  scaleDown:
+    status: NoCandidates
+`
+
+	status, err := parseAutoscalerStatus(text)
+
+	if err != nil {
+		t.Fatalf(`parseAutoscalerStatus() returned error %v`, err)
+	}
+
+	if !status.Time.Equal(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf(`status.Time = %v, want 2026-10-15T12:00:00Z`, status.Time)
+	}
+
+	if status.Status != "Running" || status.ScaleUp != "InProgress" || len(status.NodeGroups) != 2 {
+		t.Fatalf(`parseAutoscalerStatus() = %v, want running, scaling up, and 2 node groups`, status)
+	}
+
+	cpu, gpu := status.NodeGroups[0], status.NodeGroups[1]
+
+	switch {
+	case cpu.Current != 3 || cpu.MaxSize != 3 || cpu.CanScaleUp || !cpu.CanScaleDown:
+		t.Fatalf(`cpu = %v, want 3 of 3 nodes that can only scale down`, cpu)
+	case gpu.ScaleUp != "Backoff" || gpu.BackoffReason != "OutOfResource" || gpu.ScaleUpError != "insufficient capacity for p3.8xlarge":
+		t.Fatalf(`gpu = %v, want backoff after running out of resources`, gpu)
+	case gpu.ScaleUpSince == nil || !gpu.ScaleUpSince.Equal(time.Date(2026, 10, 15, 11, 50, 0, 0, time.UTC)):
+		t.Fatalf(`gpu.ScaleUpSince = %v, want 2026-10-15T11:50:00Z`, gpu.ScaleUpSince)
+	case gpu.Target != 2 || !gpu.CanScaleUp:
+		t.Fatalf(`gpu = %v, want target 2 that can scale up`, gpu)
+	}
+
+	if _, err := parseAutoscalerStatus("Cluster-autoscaler status at 2026-10-15 12:00:00 +0000 UTC:\nCluster-wide:\n"); err == nil {
+		t.Fatalf(`parseAutoscalerStatus() of text status returned no error`)
+	}
+}
+
+// TestUnschedulablePods lists the pending demand of the cluster, checking that pods held back by scheduling gates are
+// included and that pods nominated for a node are left out, as their requests are counted against it.
+func TestUnschedulablePods(t *testing.T) {
+	unschedulable := []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable}}
+
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "unschedulable", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending, Conditions: unschedulable},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "preempting", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending, Conditions: unschedulable, NominatedNodeName: "node-1"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "gated", Namespace: "default"},
+			Spec:       corev1.PodSpec{SchedulingGates: []corev1.PodSchedulingGate{{Name: "example.com/quota"}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+	)
+
+	pods, err := newCollector(client).UnschedulablePods()
+
+	if err != nil {
+		t.Fatalf(`UnschedulablePods() returned error %v`, err)
+	}
+
+	names := make(map[string]bool)
+	for _, pod := range pods {
+		names[pod.Name] = true
+	}
+
+	if len(names) != 2 || !names["unschedulable"] || !names["gated"] {
+		t.Fatalf(`UnschedulablePods() = %v, want unschedulable and gated`, names)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Kinds of capacity change
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeResized = "resized"
+	changeGpus    = "gpus" // The node's GPU count changed, whether or not anything else did
+)
+
+// NodeCapacity is the part of a node that capacity changes are detected in
+type NodeCapacity struct {
+	Pool        string        `json:"pool"`
+	Allocatable ResourcesJson `json:"allocatable"`
+	Capacity    ResourcesJson `json:"capacity"`
+}
+
+// CapacityChange is a change to the capacity of the cluster detected between two snapshots
+type CapacityChange struct {
+	Time   time.Time     `json:"time"`
+	Node   string        `json:"node"`
+	Pool   string        `json:"pool"`
+	Type   string        `json:"type"`
+	Before *NodeCapacity `json:"before,omitempty"`
+	After  *NodeCapacity `json:"after,omitempty"`
+}
+
+// getCapacityChanges compares the nodes of a snapshot taken at t with the last known capacity of each node,
+// returning the changes sorted by node name.
+func getCapacityChanges(previous map[string]NodeCapacity, nodes []NodeJson, t time.Time) []CapacityChange {
+	changes := make([]CapacityChange, 0)
+	seen := make(map[string]bool)
+
+	for _, node := range nodes {
+		seen[node.Name] = true
+		after := NodeCapacity{Pool: node.Pool, Allocatable: node.Allocatable, Capacity: node.Capacity}
+
+		before, ok := previous[node.Name]
+		change := CapacityChange{Time: t, Node: node.Name, Pool: node.Pool, After: &after}
+
+		switch {
+		case !ok:
+			change.Type = changeAdded
+		case before.Capacity.Gpu != after.Capacity.Gpu || before.Allocatable.Gpu != after.Allocatable.Gpu:
+			change.Type = changeGpus
+		case before.Capacity != after.Capacity || before.Allocatable != after.Allocatable:
+			change.Type = changeResized
+		default:
+			continue
+		}
+
+		if ok {
+			change.Before = &before
+		}
+
+		changes = append(changes, change)
+	}
+
+	for name, before := range previous {
+		if !seen[name] {
+			changes = append(changes, CapacityChange{Time: t, Node: name, Pool: before.Pool, Type: changeRemoved, Before: &before})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Node < changes[j].Node
+	})
+
+	return changes
+}
+
+// getNodeCapacities returns the capacity of each node, keyed by name.
+func getNodeCapacities(nodes []NodeJson) map[string]NodeCapacity {
+	capacities := make(map[string]NodeCapacity, len(nodes))
+
+	for _, node := range nodes {
+		capacities[node.Name] = NodeCapacity{Pool: node.Pool, Allocatable: node.Allocatable, Capacity: node.Capacity}
+	}
+
+	return capacities
+}
+
+// RecordChanges compares a snapshot with the last capacity of each node, appending any changes to the log and
+// saving the new capacities. The first snapshot recorded only saves the capacities, rather than logging every
+// node as added.
+func (h *HistoryStore) RecordChanges(snapshot *Snapshot) ([]CapacityChange, error) {
+	var changes []CapacityChange
+
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		capacities := tx.Bucket(capacityBucket)
+		previous := make(map[string]NodeCapacity)
+
+		err := capacities.ForEach(func(key, value []byte) error {
+			var capacity NodeCapacity
+
+			if err := json.Unmarshal(value, &capacity); err != nil {
+				return err
+			}
+
+			previous[string(key)] = capacity
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		first := len(previous) == 0
+		changes = getCapacityChanges(previous, snapshot.Nodes, snapshot.Time)
+
+		if first {
+			changes = changes[:0]
+		}
+
+		log := tx.Bucket(changesBucket)
+
+		for _, change := range changes {
+			value, err := json.Marshal(change)
+
+			if err != nil {
+				return err
+			}
+
+			// Several nodes can change in one snapshot, so the node name keeps the keys unique
+			key := append(timeKey(change.Time), change.Node...)
+
+			if err := log.Put(key, value); err != nil {
+				return err
+			}
+		}
+
+		// Replace the saved capacities with those of this snapshot, dropping removed nodes
+		for name := range previous {
+			if err := capacities.Delete([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		for _, node := range snapshot.Nodes {
+			value, err := json.Marshal(NodeCapacity{Pool: node.Pool, Allocatable: node.Allocatable, Capacity: node.Capacity})
+
+			if err != nil {
+				return err
+			}
+
+			if err := capacities.Put([]byte(node.Name), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return changes, err
+}
+
+// QueryChanges returns every capacity change logged at or after since, ordered by time.
+func (h *HistoryStore) QueryChanges(since time.Time) ([]CapacityChange, error) {
+	changes := make([]CapacityChange, 0)
+
+	err := h.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(changesBucket).Cursor()
+
+		for key, value := cursor.Seek(timeKey(since)); key != nil; key, value = cursor.Next() {
+			var change CapacityChange
+
+			if err := json.Unmarshal(value, &change); err != nil {
+				return err
+			}
+
+			changes = append(changes, change)
+		}
+
+		return nil
+	})
+
+	return changes, err
+}
+
+// getChangesHandler returns a HandlerFunc to return the capacity changes logged in a HistoryStore. How far back to
+// look is given by the since query parameter, either as a duration (default 24h) or an RFC 3339 time, and the
+// changes can be filtered with the node, pool, and type query parameters.
+func getChangesHandler(history *HistoryStore) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		since, err := parseSince(c.DefaultQuery("since", "24h"), time.Now())
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "error: since must be a positive duration such as 24h or a time such as 2024-08-01T00:00:00Z")
+			return
+		}
+
+		changes, err := history.QueryChanges(since)
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving capacity change information")
+			return
+		}
+
+		node, pool, changeType := c.Query("node"), c.Query("pool"), c.Query("type")
+		filtered := make([]CapacityChange, 0, len(changes))
+
+		for _, change := range changes {
+			if (node != "" && change.Node != node) || (pool != "" && change.Pool != pool) || (changeType != "" && change.Type != changeType) {
+				continue
+			}
+
+			filtered = append(filtered, change)
+		}
+
+		c.IndentedJSON(http.StatusOK, filtered)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// parseSince parses text as either a duration before now or an RFC 3339 time.
+func parseSince(text string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(text); err == nil {
+		if duration <= 0 {
+			return time.Time{}, fmt.Errorf("duration %v is not positive", duration)
+		}
+
+		return now.Add(-duration), nil
+	}
+
+	return time.Parse(time.RFC3339, text)
+}
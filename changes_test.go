@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestGetCapacityChanges detects a node being added, removed, resized, and losing GPUs, ignoring unchanged nodes.
+func TestGetCapacityChanges(t *testing.T) {
+	previous := map[string]NodeCapacity{
+		"gone":      {Pool: "cpu", Allocatable: ResourcesJson{Cpu: 4}},
+		"same":      {Pool: "cpu", Allocatable: ResourcesJson{Cpu: 4}},
+		"resized":   {Pool: "cpu", Allocatable: ResourcesJson{Cpu: 4}},
+		"lost-gpus": {Pool: "gpu", Allocatable: ResourcesJson{Cpu: 8, Gpu: 4}, Capacity: ResourcesJson{Gpu: 4}},
+	}
+
+	nodes := []NodeJson{
+		{Name: "same", Pool: "cpu", Allocatable: ResourcesJson{Cpu: 4}, Free: ResourcesJson{Cpu: 1}},
+		{Name: "resized", Pool: "cpu", Allocatable: ResourcesJson{Cpu: 8}},
+		{Name: "lost-gpus", Pool: "gpu", Allocatable: ResourcesJson{Cpu: 8, Gpu: 2}, Capacity: ResourcesJson{Gpu: 4}},
+		{Name: "new", Pool: "cpu", Allocatable: ResourcesJson{Cpu: 4}},
+	}
+
+	changes := getCapacityChanges(previous, nodes, time.Now())
+
+	want := map[string]string{"gone": changeRemoved, "resized": changeResized, "lost-gpus": changeGpus, "new": changeAdded}
+
+	if len(changes) != len(want) {
+		t.Fatalf(`changes = %v, want match for %v`, changes, want)
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Type != want[change.Node]:
+			t.Fatalf(`change.Type of %v = %v, want match for %v`, change.Node, change.Type, want[change.Node])
+		case (change.Before == nil) != (change.Type == changeAdded) || (change.After == nil) != (change.Type == changeRemoved):
+			t.Fatalf(`change of %v = %v, want before unless added and after unless removed`, change.Node, change)
+		}
+	}
+}
+
+// TestHistoryStoreRecordChanges records snapshots in a temporary database, checking that the first only saves the
+// capacities and that later changes are logged and can be queried.
+func TestHistoryStoreRecordChanges(t *testing.T) {
+	store, err := openHistoryStore(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatalf(`openHistoryStore() err = %v, want match for %v`, err, nil)
+	}
+
+	defer store.Close()
+
+	start := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	nodes := []NodeJson{{Name: "node-1"}, {Name: "node-2"}}
+
+	changes, err := store.RecordChanges(&Snapshot{Time: start, Nodes: nodes})
+
+	if err != nil || len(changes) != 0 {
+		t.Fatalf(`RecordChanges() = %v, %v, want no changes for the first snapshot`, changes, err)
+	}
+
+	_, err = store.RecordChanges(&Snapshot{Time: start.Add(time.Hour), Nodes: nodes[:1]})
+
+	if err != nil {
+		t.Fatalf(`RecordChanges() err = %v, want match for %v`, err, nil)
+	}
+
+	_, err = store.RecordChanges(&Snapshot{Time: start.Add(2 * time.Hour), Nodes: nodes})
+
+	if err != nil {
+		t.Fatalf(`RecordChanges() err = %v, want match for %v`, err, nil)
+	}
+
+	changes, err = store.QueryChanges(start)
+
+	switch {
+	case err != nil:
+		t.Fatalf(`QueryChanges() err = %v, want match for %v`, err, nil)
+	case len(changes) != 2 || changes[0].Type != changeRemoved || changes[1].Type != changeAdded || changes[1].Node != "node-2":
+		t.Fatalf(`QueryChanges() = %v, want node-2 removed then added`, changes)
+	}
+
+	changes, _ = store.QueryChanges(start.Add(90 * time.Minute))
+
+	if len(changes) != 1 {
+		t.Fatalf(`QueryChanges() = %v, want only the last change`, changes)
+	}
+}
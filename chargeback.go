@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChargebackEntry contains the resources requested by the pods of one group over a time range,
+// in resource-hours
+type ChargebackEntry struct {
+	Group          string  `json:"group"`
+	Source         string  `json:"source"` // "label" if grouped by the label value, or "namespace" if the pods didn't have the label
+	CpuHours       float64 `json:"cpuHours"`
+	MemoryGiBHours float64 `json:"memoryGiBHours"`
+	GpuHours       float64 `json:"gpuHours"`
+}
+
+// getChargeback sums the requests of every scheduled pod in a list of snapshots, grouped by the value of label.
+// Pods without the label are grouped by namespace instead. Each snapshot is weighted by the time until the next
+// snapshot, and the last snapshot by the time since the one before it.
+func getChargeback(snapshots []Snapshot, label string) []ChargebackEntry {
+	entries := make(map[string]*ChargebackEntry)
+
+	for i, snapshot := range snapshots {
+		// Work out how long this snapshot represents
+		var weight time.Duration
+		switch {
+		case i+1 < len(snapshots):
+			weight = snapshots[i+1].Time.Sub(snapshot.Time)
+		case i > 0:
+			weight = snapshot.Time.Sub(snapshots[i-1].Time)
+		}
+
+		hours := weight.Hours()
+
+		for _, pod := range snapshot.Pods {
+			// Pending pods aren't holding any capacity yet
+			if pod.Node == "" {
+				continue
+			}
+
+			group, source := pod.Labels[label], "label"
+			if group == "" {
+				group, source = pod.Namespace, "namespace"
+			}
+
+			key := source + "/" + group
+			if _, ok := entries[key]; !ok {
+				entries[key] = &ChargebackEntry{Group: group, Source: source}
+			}
+
+			entries[key].CpuHours += pod.Requests.Cpu * hours
+			entries[key].MemoryGiBHours += float64(pod.Requests.Memory) / (1 << 30) * hours
+			entries[key].GpuHours += float64(pod.Requests.Gpu) * hours
+		}
+	}
+
+	result := make([]ChargebackEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+
+	// Sort by source, then by group, so the response is stable
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Source != result[j].Source {
+			return result[i].Source < result[j].Source
+		}
+		return result[i].Group < result[j].Group
+	})
+
+	return result
+}
+
+// getChargebackHandler returns a HandlerFunc to return the resource-hours requested by each group of pods given
+// a HistoryStore. Pods are grouped by the label query parameter, over the range given by the from and to parameters.
+// Pods aren't rolled up like nodes, so they are gone after retention, and ranges starting before then are rejected
+// rather than returning partial totals.
+func getChargebackHandler(store *HistoryStore, retention time.Duration) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		label := c.Query("label")
+
+		if label == "" {
+			c.JSON(http.StatusBadRequest, "error: expected label parameter")
+			return
+		}
+
+		// Default to the last week, or as much of it as pods are kept for
+		from, to, ok := getTimeRange(c, min(7*24*time.Hour, retention))
+		if !ok {
+			return
+		}
+
+		// Compaction only deletes whole hours, so pods are kept from the start of the hour retention ago
+		if from.Before(time.Now().Add(-retention).Truncate(time.Hour)) {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("error: from must be within the last %v, since pods are only kept for HISTORY_RAW_RETENTION", retention))
+			return
+		}
+
+		snapshots, err := store.QueryPods(from, to)
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod history")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getChargeback(snapshots, label))
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestGetChargeback sums the requests of pods across three snapshots an hour apart, checking that pods are grouped
+// by label with a namespace fallback, that pending pods are ignored, and that each snapshot is weighted by time.
+func TestGetChargeback(t *testing.T) {
+	start := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	pods := []PodJson{
+		{Name: "train", Namespace: "ml", Node: "node-1", Labels: map[string]string{"team": "vision"}, Requests: ResourcesJson{Cpu: 2, Memory: 1 << 30, Gpu: 1}},
+		{Name: "web", Namespace: "web", Node: "node-2", Labels: map[string]string{}, Requests: ResourcesJson{Cpu: 0.5}},
+		{Name: "pending", Namespace: "ml", Labels: map[string]string{"team": "vision"}, Requests: ResourcesJson{Cpu: 100}},
+	}
+
+	snapshots := []Snapshot{
+		{Time: start, Pods: pods},
+		{Time: start.Add(time.Hour), Pods: pods},
+		{Time: start.Add(2 * time.Hour), Pods: pods[:1]},
+	}
+
+	entries := getChargeback(snapshots, "team")
+
+	if len(entries) != 2 {
+		t.Fatalf(`len(entries) = %v, want match for %v`, len(entries), 2)
+	}
+
+	// Every snapshot counts for an hour, so the labeled pod was running for 3 hours and the other for 2
+	want := []ChargebackEntry{
+		{Group: "vision", Source: "label", CpuHours: 6, MemoryGiBHours: 3, GpuHours: 3},
+		{Group: "web", Source: "namespace", CpuHours: 1},
+	}
+
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Fatalf(`entries[%v] = %v, want match for %v`, i, entries[i], want[i])
+		}
+	}
+}
+
+// TestChargebackRetention checks that ranges starting before pods are deleted are rejected rather than returning
+// partial totals, and that the default range is shortened to the retention.
+func TestChargebackRetention(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store, err := openHistoryStore(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatalf(`openHistoryStore() err = %v, want match for %v`, err, nil)
+	}
+
+	defer store.Close()
+
+	router := gin.New()
+	router.GET("/reports/chargeback", getChargebackHandler(store, 24*time.Hour))
+
+	for query, want := range map[string]int{
+		"label=team": http.StatusOK,
+		"label=team&from=" + time.Now().Add(-2*time.Hour).Format(time.RFC3339):    http.StatusOK,
+		"label=team&from=" + time.Now().Add(-7*24*time.Hour).Format(time.RFC3339): http.StatusBadRequest,
+		"label=team&to=" + time.Now().Add(-12*time.Hour).Format(time.RFC3339):     http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/reports/chargeback?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Fatalf(`GET /reports/chargeback?%v status = %v, want match for %v`, query, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// Most metrics CloudWatch accepts in one PutMetricData request
+const maxCloudWatchMetrics = 1000
+
+// cloudWatchClient is the part of the CloudWatch client the publisher uses
+type cloudWatchClient interface {
+	PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error)
+}
+
+// CloudWatchPublisher publishes the free and allocatable resources of each node and pool to CloudWatch
+type CloudWatchPublisher struct {
+	client    cloudWatchClient
+	namespace string
+	cluster   string // Added to every metric as a dimension if set
+}
+
+// newCloudWatchPublisher returns a CloudWatchPublisher that writes metrics under namespace in region. Requests go to
+// endpoint, or the regional CloudWatch endpoint if it's empty. Credentials and the rest of the AWS configuration are
+// loaded by the SDK's default chain, so environment variables, shared config profiles, IRSA web identity tokens, EKS
+// Pod Identity, and instance roles all work.
+func newCloudWatchPublisher(endpoint, region, namespace, cluster string) (*CloudWatchPublisher, error) {
+	awsConfig, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := cloudwatch.NewFromConfig(awsConfig, func(options *cloudwatch.Options) {
+		if endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+
+	return &CloudWatchPublisher{client: client, namespace: namespace, cluster: cluster}, nil
+}
+
+// Publish sends the metrics for a snapshot, in as many requests as needed.
+func (p *CloudWatchPublisher) Publish(snapshot *Snapshot) error {
+	data := getCloudWatchData(snapshot, p.cluster)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for start := 0; start < len(data); start += maxCloudWatchMetrics {
+		end := min(start+maxCloudWatchMetrics, len(data))
+
+		_, err := p.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(p.namespace),
+			MetricData: data[start:end],
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getCloudWatchData returns Free<Resource> and Allocatable<Resource> metrics for each node, with Node and Pool
+// dimensions, and the same metrics summed over each pool, with only a Pool dimension so alarms can be set on a pool
+// as a whole. A Cluster dimension is added to every metric if cluster is set. CPUs and GPUs are counted and memory
+// and storage are in bytes.
+func getCloudWatchData(snapshot *Snapshot, cluster string) []types.MetricDatum {
+	data := make([]types.MetricDatum, 0)
+
+	add := func(dimensions []types.Dimension, allocatable, free ResourcesJson) {
+		if cluster != "" {
+			dimensions = append([]types.Dimension{{Name: aws.String("Cluster"), Value: aws.String(cluster)}}, dimensions...)
+		}
+
+		for _, resource := range resourceNames {
+			name := strings.ToUpper(resource[:1]) + resource[1:]
+			unit := types.StandardUnitCount
+			if resource == "memory" || resource == "ephemeral" {
+				unit = types.StandardUnitBytes
+			}
+
+			data = append(data,
+				types.MetricDatum{MetricName: aws.String("Free" + name), Dimensions: dimensions, Unit: unit, Value: aws.Float64(getResource(free, resource)), Timestamp: aws.Time(snapshot.Time)},
+				types.MetricDatum{MetricName: aws.String("Allocatable" + name), Dimensions: dimensions, Unit: unit, Value: aws.Float64(getResource(allocatable, resource)), Timestamp: aws.Time(snapshot.Time)},
+			)
+		}
+	}
+
+	for _, pool := range getNodePools(snapshot.Nodes) {
+		add([]types.Dimension{{Name: aws.String("Pool"), Value: aws.String(pool.Name)}}, pool.Allocatable, pool.Free)
+	}
+
+	nodes := append([]NodeJson(nil), snapshot.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	for _, node := range nodes {
+		add([]types.Dimension{{Name: aws.String("Node"), Value: aws.String(node.Name)}, {Name: aws.String("Pool"), Value: aws.String(node.Pool)}}, node.Allocatable, node.Free)
+	}
+
+	return data
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+)
+
+// fakeCloudWatch records every PutMetricData request it is sent
+type fakeCloudWatch struct {
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (f *fakeCloudWatch) PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+	f.inputs = append(f.inputs, params)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+// TestCloudWatchPublisher publishes a snapshot to a fake CloudWatch client, checking the metrics and dimensions
+// for pools and nodes.
+func TestCloudWatchPublisher(t *testing.T) {
+	client := &fakeCloudWatch{}
+	publisher := &CloudWatchPublisher{client: client, namespace: "KubernetesCapacity", cluster: "nautilus"}
+
+	snapshot := &Snapshot{
+		Time: time.Unix(1714564800, 0),
+		Nodes: []NodeJson{
+			{Name: "node-1", Pool: "gpu", Allocatable: ResourcesJson{Gpu: 4}, Free: ResourcesJson{Gpu: 2}},
+			{Name: "node-2", Pool: "gpu", Allocatable: ResourcesJson{Gpu: 8}, Free: ResourcesJson{Gpu: 1}},
+		},
+	}
+
+	if err := publisher.Publish(snapshot); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	if len(client.inputs) != 1 {
+		t.Fatalf(`len(inputs) = %v, want match for %v`, len(client.inputs), 1)
+	}
+
+	// Pool metrics come first, so the fifth is the pool's free GPUs
+	data := client.inputs[0].MetricData
+
+	switch {
+	case aws.ToString(client.inputs[0].Namespace) != "KubernetesCapacity":
+		t.Fatalf(`Namespace = %v, want match for %v`, aws.ToString(client.inputs[0].Namespace), "KubernetesCapacity")
+	case len(data) != 24:
+		t.Fatalf(`len(MetricData) = %v, want match for %v`, len(data), 24)
+	case aws.ToString(data[4].MetricName) != "FreeGpu":
+		t.Fatalf(`MetricName = %v, want match for %v`, aws.ToString(data[4].MetricName), "FreeGpu")
+	case aws.ToFloat64(data[4].Value) != 3:
+		t.Fatalf(`Value = %v, want match for %v`, aws.ToFloat64(data[4].Value), 3)
+	case aws.ToString(data[4].Dimensions[0].Value) != "nautilus":
+		t.Fatalf(`Cluster = %v, want match for %v`, aws.ToString(data[4].Dimensions[0].Value), "nautilus")
+	case aws.ToString(data[4].Dimensions[1].Value) != "gpu":
+		t.Fatalf(`Pool = %v, want match for %v`, aws.ToString(data[4].Dimensions[1].Value), "gpu")
+	case aws.ToString(data[12].Dimensions[1].Value) != "node-1":
+		t.Fatalf(`Node = %v, want match for %v`, aws.ToString(data[12].Dimensions[1].Value), "node-1")
+	case aws.ToFloat64(data[12].Value) != 2:
+		t.Fatalf(`Value = %v, want match for %v`, aws.ToFloat64(data[12].Value), 2)
+	case aws.ToString(data[23].Dimensions[1].Value) != "node-2":
+		t.Fatalf(`Node = %v, want match for %v`, aws.ToString(data[23].Dimensions[1].Value), "node-2")
+	case !aws.ToTime(data[23].Timestamp).Equal(snapshot.Time):
+		t.Fatalf(`Timestamp = %v, want match for %v`, aws.ToTime(data[23].Timestamp), snapshot.Time)
+	}
+}
@@ -0,0 +1,356 @@
+// kubectl-resourceapi is a kubectl plugin that shows the free resources of the cluster's nodes from the resource
+// API, e.g. kubectl resourceapi nodes. The API is reached through the API server's service proxy using the current
+// kubeconfig, or directly with --server.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/client-go/kubernetes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth" // Register the OIDC, GCP, and Azure auth providers
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const usage = `Show the free resources of the cluster from the resource API.
+
+Usage:
+  kubectl resourceapi nodes                 List every node's free and allocatable resources
+  kubectl resourceapi summary               Show the total resources of the cluster
+  kubectl resourceapi fit SHAPE [SHAPE...]  Check whether pods of each shape fit in the cluster at once,
+                                            e.g. cpu=4,memory=16Gi,gpu=1,replicas=3
+
+Flags:
+`
+
+// Resources as returned by the API
+type Resources struct {
+	Cpu       float64 `json:"cpu"`
+	Memory    int64   `json:"memory"`
+	Gpu       int64   `json:"gpu"`
+	Ephemeral int64   `json:"ephemeral"`
+}
+
+// Node as returned by /nodes, with only the fields shown
+type Node struct {
+	Name         string    `json:"name"`
+	Pool         string    `json:"pool"`
+	CapacityType string    `json:"capacityType"`
+	Allocatable  Resources `json:"allocatable"`
+	Capacity     Resources `json:"capacity"`
+	Free         Resources `json:"free"`
+}
+
+// Summary as returned by /summary, with only the fields shown
+type Summary struct {
+	Nodes       int       `json:"nodes"`
+	Allocatable Resources `json:"allocatable"`
+	Capacity    Resources `json:"capacity"`
+	Free        Resources `json:"free"`
+}
+
+// FitShape is a shape in the body of POST /fit/batch
+type FitShape struct {
+	Name     string            `json:"name"`
+	Requests map[string]string `json:"requests"`
+	Replicas int               `json:"replicas"`
+}
+
+// FitResult as returned by POST /fit/batch
+type FitResult struct {
+	Fits   bool `json:"fits"`
+	Shapes []struct {
+		Name     string         `json:"name"`
+		Replicas int            `json:"replicas"`
+		Placed   int            `json:"placed"`
+		Fits     bool           `json:"fits"`
+		Nodes    map[string]int `json:"nodes"`
+	} `json:"shapes"`
+	Remaining Resources `json:"remaining"`
+}
+
+// Client calls the resource API, either directly or through the API server's service proxy
+type Client struct {
+	server    string                // Base URL of the API, if it is called directly
+	clientset *kubernetes.Clientset // Used to reach the API through the service proxy otherwise
+	namespace string
+	service   string // Name and port of the API's Service, e.g. humboldt-resource-api-svc:8080
+}
+
+// Do sends a request to path with an optional JSON body and returns the response body.
+func (c *Client) Do(method, path string, body []byte) ([]byte, error) {
+	if c.server != "" {
+		req, err := http.NewRequest(method, strings.TrimSuffix(c.server, "/")+path, bytes.NewReader(body))
+
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%v returned %v: %s", path, resp.Status, data)
+		}
+
+		return data, nil
+	}
+
+	request := c.clientset.CoreV1().RESTClient().Verb(method).
+		Namespace(c.namespace).
+		Resource("services").
+		Name(c.service).
+		SubResource("proxy").
+		Suffix(path)
+
+	if body != nil {
+		request = request.SetHeader("Content-Type", "application/json").Body(body)
+	}
+
+	return request.DoRaw(context.Background())
+}
+
+// newClient returns a Client for server if it is set, or else for the service in namespace of the cluster in the
+// current kubeconfig, using kubeContext if it is set. The kubeconfig's namespace is used if namespace is empty.
+func newClient(server, kubeContext, namespace, service string) (*Client, error) {
+	if server != "" {
+		return &Client{server: server}, nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+
+	config, err := clientConfig.ClientConfig()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{clientset: clientset, namespace: namespace, service: service}, nil
+}
+
+// parseArgs parses flags anywhere among args, as kubectl does, returning the arguments that aren't flags.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseShape parses a shape of the form cpu=4,memory=16Gi,gpu=1,replicas=3. Replicas default to 1.
+func parseShape(text string) (FitShape, error) {
+	shape := FitShape{Name: text, Requests: make(map[string]string), Replicas: 1}
+
+	for _, field := range strings.Split(text, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(field), "=")
+		if !found {
+			return shape, fmt.Errorf("invalid shape %q: expected resource=quantity", text)
+		}
+
+		if name != "replicas" {
+			shape.Requests[name] = value
+			continue
+		}
+
+		replicas, err := strconv.Atoi(value)
+		if err != nil || replicas < 1 {
+			return shape, fmt.Errorf("invalid shape %q: replicas must be a positive integer", text)
+		}
+
+		shape.Replicas = replicas
+	}
+
+	return shape, nil
+}
+
+// formatResources formats free and allocatable resources as free/allocatable, with memory in GiB.
+func formatResources(free, allocatable Resources) []string {
+	return []string{
+		fmt.Sprintf("%.1f/%.1f", free.Cpu, allocatable.Cpu),
+		fmt.Sprintf("%.1fGi/%.1fGi", float64(free.Memory)/(1<<30), float64(allocatable.Memory)/(1<<30)),
+		fmt.Sprintf("%d/%d", free.Gpu, allocatable.Gpu),
+	}
+}
+
+// printTable writes rows of tab-separated cells with aligned columns, as kubectl get does.
+func printTable(out io.Writer, header []string, rows [][]string) {
+	writer := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	writer.Flush()
+}
+
+// run runs the command given by args, writing its output to out.
+func run(client *Client, args []string, output string, out io.Writer) error {
+	var method, path string
+	var body []byte
+
+	switch args[0] {
+	case "nodes":
+		method, path = http.MethodGet, "/nodes"
+	case "summary":
+		method, path = http.MethodGet, "/summary"
+	case "fit":
+		if len(args) < 2 {
+			return fmt.Errorf("fit needs at least one shape, e.g. cpu=4,memory=16Gi,gpu=1")
+		}
+
+		shapes := make([]FitShape, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			shape, err := parseShape(arg)
+			if err != nil {
+				return err
+			}
+
+			shapes = append(shapes, shape)
+		}
+
+		method, path = http.MethodPost, "/fit/batch"
+		body, _ = json.Marshal(map[string][]FitShape{"shapes": shapes})
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	data, err := client.Do(method, path, body)
+
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		_, err := out.Write(data)
+		return err
+	}
+
+	switch args[0] {
+	case "nodes":
+		var nodes []Node
+		if err := json.Unmarshal(data, &nodes); err != nil {
+			return err
+		}
+
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+		rows := make([][]string, 0, len(nodes))
+		for _, node := range nodes {
+			rows = append(rows, append([]string{node.Name, node.Pool, node.CapacityType}, formatResources(node.Free, node.Allocatable)...))
+		}
+
+		printTable(out, []string{"NAME", "POOL", "CAPACITY-TYPE", "CPU", "MEMORY", "GPU"}, rows)
+	case "summary":
+		var summary Summary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return err
+		}
+
+		row := append([]string{strconv.Itoa(summary.Nodes)}, formatResources(summary.Free, summary.Allocatable)...)
+		printTable(out, []string{"NODES", "CPU", "MEMORY", "GPU"}, [][]string{row})
+	case "fit":
+		var result FitResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(result.Shapes))
+		for _, shape := range result.Shapes {
+			rows = append(rows, []string{shape.Name, strconv.Itoa(shape.Replicas), strconv.Itoa(shape.Placed), strconv.FormatBool(shape.Fits)})
+		}
+
+		printTable(out, []string{"SHAPE", "REPLICAS", "PLACED", "FITS"}, rows)
+		fmt.Fprintf(out, "\nAll shapes fit at once: %v\n", result.Fits)
+	}
+
+	return nil
+}
+
+func main() {
+	flags := flag.NewFlagSet("kubectl-resourceapi", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	server := flags.String("server", os.Getenv("RESOURCEAPI_URL"), "base URL of the resource API, to call it directly rather than through the API server")
+	kubeContext := flags.String("context", "", "name of the kubeconfig context to use")
+	namespace := flags.String("namespace", "", "namespace of the resource API's Service - defaults to the kubeconfig's namespace")
+	flags.StringVar(namespace, "n", "", "shorthand for --namespace")
+	service := flags.String("service", "humboldt-resource-api-svc:8080", "name and port of the resource API's Service")
+	output := flags.String("o", "table", "output format: table or json")
+
+	args, err := parseArgs(flags, os.Args[1:])
+
+	if err == flag.ErrHelp {
+		return
+	}
+
+	if err != nil {
+		os.Exit(2)
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	client, err := newClient(*server, *kubeContext, *namespace, *service)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := run(client, args, *output, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
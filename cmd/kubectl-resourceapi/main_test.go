@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseArgs checks that flags are parsed wherever they are among the arguments.
+func TestParseArgs(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	output := flags.String("o", "table", "")
+
+	args, err := parseArgs(flags, []string{"fit", "cpu=1", "-o", "json", "cpu=2"})
+
+	if err != nil || *output != "json" || strings.Join(args, " ") != "fit cpu=1 cpu=2" {
+		t.Fatalf(`parseArgs() = %v, %v, %v, want match for %v, %v, %v`, args, *output, err, "fit cpu=1 cpu=2", "json", nil)
+	}
+}
+
+// TestParseShape parses a shape with replicas and rejects one without quantities.
+func TestParseShape(t *testing.T) {
+	shape, err := parseShape("cpu=4,memory=16Gi,replicas=3")
+
+	switch {
+	case err != nil:
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	case shape.Replicas != 3 || shape.Requests["cpu"] != "4" || shape.Requests["memory"] != "16Gi":
+		t.Fatalf(`parseShape() = %v, want match for %v`, shape, "cpu 4, memory 16Gi, and 3 replicas")
+	}
+
+	if _, err := parseShape("cpu"); err == nil {
+		t.Fatalf(`err = %v, want error`, err)
+	}
+}
+
+// TestRunNodes lists nodes from a fake API, checking that they are shown sorted by name in a table.
+func TestRunNodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"node-b","pool":"gpu","free":{"gpu":2},"allocatable":{"gpu":4}},{"name":"node-a","pool":"general","free":{"cpu":1.5},"allocatable":{"cpu":8}}]`))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	if err := run(&Client{server: server.URL}, []string{"nodes"}, "table", &out); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+	switch {
+	case len(lines) != 3 || !strings.HasPrefix(lines[0], "NAME"):
+		t.Fatalf(`output = %q, want match for %v`, out.String(), "a header and two nodes")
+	case !strings.HasPrefix(lines[1], "node-a") || !strings.Contains(lines[1], "1.5/8.0"):
+		t.Fatalf(`lines[1] = %q, want match for %v`, lines[1], "node-a with 1.5/8.0 CPU")
+	case !strings.HasPrefix(lines[2], "node-b") || !strings.Contains(lines[2], "2/4"):
+		t.Fatalf(`lines[2] = %q, want match for %v`, lines[2], "node-b with 2/4 GPUs")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColumnPath selects a value from an item by its JSON field names, e.g. free.gpu, labels['kubernetes.io/hostname'],
+// or taints[0].key
+type ColumnPath struct {
+	Text  string        // The path as written, used as the column header
+	Steps []interface{} // Field names as strings and array indexes as ints, in order
+}
+
+// parseColumns parses a comma-separated list of column paths. A path is a field name followed by any number of
+// .field, ['field'] or ["field"], and [index] steps. Quoted field names can contain any character, including
+// dots and commas.
+func parseColumns(text string) ([]ColumnPath, error) {
+	columns := make([]ColumnPath, 0)
+	i := 0
+
+	for i < len(text) {
+		start := i
+		column := ColumnPath{}
+
+		name, next := parseColumnName(text, i)
+		if name == "" {
+			return nil, fmt.Errorf("expected a field name at position %v of %q", i+1, text)
+		}
+
+		column.Steps = append(column.Steps, name)
+		i = next
+
+		for i < len(text) && text[i] != ',' {
+			switch text[i] {
+			case '.':
+				name, next := parseColumnName(text, i+1)
+				if name == "" {
+					return nil, fmt.Errorf("expected a field name at position %v of %q", i+2, text)
+				}
+
+				column.Steps = append(column.Steps, name)
+				i = next
+			case '[':
+				step, next, err := parseColumnIndex(text, i+1)
+				if err != nil {
+					return nil, err
+				}
+
+				column.Steps = append(column.Steps, step)
+				i = next
+			default:
+				return nil, fmt.Errorf("unexpected %q at position %v of %q", text[i], i+1, text)
+			}
+		}
+
+		column.Text = strings.TrimSpace(text[start:i])
+		columns = append(columns, column)
+
+		// Skip the comma, and any spaces after it
+		i++
+		for i < len(text) && text[i] == ' ' {
+			i++
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("expected at least one column")
+	}
+
+	return columns, nil
+}
+
+// parseColumnName returns the unquoted field name starting at i, made of letters, digits, underscores, and
+// hyphens, and the position after it.
+func parseColumnName(text string, i int) (string, int) {
+	start := i
+
+	for i < len(text) {
+		char := text[i]
+		if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '_' || char == '-') {
+			break
+		}
+		i++
+	}
+
+	return text[start:i], i
+}
+
+// parseColumnIndex parses the inside of brackets starting at i, either a quoted field name or an array index, and
+// returns it along with the position after the closing bracket.
+func parseColumnIndex(text string, i int) (interface{}, int, error) {
+	if i < len(text) && (text[i] == '\'' || text[i] == '"') {
+		end := strings.IndexByte(text[i+1:], text[i])
+		if end < 0 || i+end+2 >= len(text) || text[i+end+2] != ']' {
+			return nil, 0, fmt.Errorf("unterminated quoted field name at position %v of %q", i+1, text)
+		}
+
+		return text[i+1 : i+end+1], i + end + 3, nil
+	}
+
+	end := strings.IndexByte(text[i:], ']')
+	if end < 0 {
+		return nil, 0, fmt.Errorf("unterminated index at position %v of %q", i, text)
+	}
+
+	index, err := strconv.Atoi(text[i : i+end])
+	if err != nil || index < 0 {
+		return nil, 0, fmt.Errorf("invalid index %q in %q: expected a number or a quoted field name", text[i:i+end], text)
+	}
+
+	return index, i + end + 1, nil
+}
+
+// getColumnValues converts an item to its JSON form, as it is returned by the API, for evaluating column paths on.
+func getColumnValues(item interface{}) (interface{}, error) {
+	data, err := json.Marshal(item)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep numbers as written so large integers such as memory aren't rounded
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var values interface{}
+	err = decoder.Decode(&values)
+
+	return values, err
+}
+
+// Evaluate returns the value the path selects from values, formatted as a cell. Paths that select nothing, such as
+// labels a node doesn't have, give an empty cell, and objects and arrays are given as JSON.
+func (p ColumnPath) Evaluate(values interface{}) string {
+	for _, step := range p.Steps {
+		switch step := step.(type) {
+		case string:
+			object, ok := values.(map[string]interface{})
+			if !ok {
+				return ""
+			}
+
+			values = object[step]
+		case int:
+			array, ok := values.([]interface{})
+			if !ok || step >= len(array) {
+				return ""
+			}
+
+			values = array[step]
+		}
+	}
+
+	switch value := values.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseColumns parses column paths with each kind of step, and paths that are invalid.
+func TestParseColumns(t *testing.T) {
+	columns, err := parseColumns("name, free.gpu,labels['node.kubernetes.io/instance-type'],taints[0][\"key\"]")
+	want := []ColumnPath{
+		{Text: "name", Steps: []interface{}{"name"}},
+		{Text: "free.gpu", Steps: []interface{}{"free", "gpu"}},
+		{Text: "labels['node.kubernetes.io/instance-type']", Steps: []interface{}{"labels", "node.kubernetes.io/instance-type"}},
+		{Text: "taints[0][\"key\"]", Steps: []interface{}{"taints", 0, "key"}},
+	}
+
+	if err != nil || !reflect.DeepEqual(columns, want) {
+		t.Fatalf(`parseColumns() = %v, %v, want match for %v, nil`, columns, err, want)
+	}
+
+	for _, text := range []string{"", "free.", "labels['a", "taints[-1]", "taints[x]", "name,,free", "name?"} {
+		if _, err := parseColumns(text); err == nil {
+			t.Fatalf(`parseColumns(%q) = nil error, want an error`, text)
+		}
+	}
+}
+
+// TestColumnPathEvaluate evaluates paths against a node, including ones that select nothing.
+func TestColumnPathEvaluate(t *testing.T) {
+	node := NodeJson{
+		Name:   "node-1",
+		Labels: map[string]string{"node.kubernetes.io/instance-type": "p4d.24xlarge"},
+		Free:   ResourcesJson{Memory: 68719476736, Gpu: 8},
+	}
+
+	values, err := getColumnValues(node)
+
+	if err != nil {
+		t.Fatalf(`getColumnValues() = %v, want match for nil`, err)
+	}
+
+	for text, want := range map[string]string{
+		"name": "node-1",
+		"labels['node.kubernetes.io/instance-type']": "p4d.24xlarge",
+		"free.memory":       "68719476736",
+		"free.gpu":          "8",
+		"labels['missing']": "",
+		"name[0]":           "",
+		"labels":            `{"node.kubernetes.io/instance-type":"p4d.24xlarge"}`,
+	} {
+		columns, err := parseColumns(text)
+
+		if err != nil {
+			t.Fatalf(`parseColumns(%q) = %v, want match for nil`, text, err)
+		}
+
+		if got := columns[0].Evaluate(values); got != want {
+			t.Fatalf(`Evaluate(%q) = %q, want match for %q`, text, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+)
+
+// compileComputedFields compiles the CEL expression of each computed field, keyed by field name. Expressions can use
+// the node they are evaluated for as the node variable.
+func compileComputedFields(fields map[string]string) (map[string]cel.Program, error) {
+	compiled := make(map[string]cel.Program, len(fields))
+
+	if len(fields) == 0 {
+		return compiled, nil
+	}
+
+	env, err := cel.NewEnv(cel.Variable("node", cel.MapType(cel.StringType, cel.DynType)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for name, text := range fields {
+		ast, issues := env.Compile(text)
+
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("computed field %q: %v", name, issues.Err())
+		}
+
+		program, err := env.Program(ast)
+
+		if err != nil {
+			return nil, fmt.Errorf("computed field %q: %v", name, err)
+		}
+
+		compiled[name] = program
+	}
+
+	return compiled, nil
+}
+
+// evalComputedField evaluates a computed field for a node's variables. Only numbers, strings, and booleans can be
+// added to a node, and numbers must be finite, such as when a resource the node has none of is divided by.
+func evalComputedField(program cel.Program, node map[string]interface{}) (interface{}, error) {
+	out, _, err := program.Eval(map[string]interface{}{"node": node})
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := out.Value().(type) {
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("result is not a finite number")
+		}
+
+		return value, nil
+	case int64, uint64, string, bool:
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("result is a %v, not a number, string, or boolean", out.Type())
+}
+
+// getNodeEnv returns the fields of the node variable computed field expressions can use for a node.
+func getNodeEnv(node NodeJson) map[string]interface{} {
+	resources := func(r ResourcesJson) map[string]interface{} {
+		return map[string]interface{}{
+			"cpu":       r.Cpu,
+			"memory":    float64(r.Memory),
+			"gpu":       float64(r.Gpu),
+			"ephemeral": float64(r.Ephemeral),
+		}
+	}
+
+	labels := node.Labels
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	env := map[string]interface{}{
+		"name":         node.Name,
+		"pool":         node.Pool,
+		"capacityType": node.CapacityType,
+		"labels":       labels,
+		"allocatable":  resources(node.Allocatable),
+		"capacity":     resources(node.Capacity),
+		"free":         resources(node.Free),
+	}
+
+	if node.HourlyCost != nil {
+		env["hourlyCost"] = *node.HourlyCost
+	}
+
+	return env
+}
+
+// setComputedFields evaluates each computed field for every node. A field is left out for any node it can't be
+// evaluated for, such as when it divides by a resource the node has none of.
+func setComputedFields(nodes []NodeJson, fields map[string]cel.Program) {
+	if len(fields) == 0 {
+		return
+	}
+
+	// Evaluate in a fixed order so debug logs are stable
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for i := range nodes {
+		env := getNodeEnv(nodes[i])
+
+		for _, name := range names {
+			value, err := evalComputedField(fields[name], env)
+
+			if err != nil {
+				debugf("computed field %v of node %v: %v", name, nodes[i].Name, err)
+				continue
+			}
+
+			if nodes[i].Computed == nil {
+				nodes[i].Computed = make(map[string]interface{})
+			}
+
+			nodes[i].Computed[name] = value
+		}
+	}
+}
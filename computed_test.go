@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestComputedFieldExpressions evaluates CEL expressions against a node, checking arithmetic, macros, and string
+// functions, and that expressions that don't compile, or can't be evaluated to a finite scalar, are reported.
+func TestComputedFieldExpressions(t *testing.T) {
+	cost := 2.5
+	node := getNodeEnv(NodeJson{
+		Name:        "node-1",
+		Labels:      map[string]string{"zone": "west"},
+		Allocatable: ResourcesJson{Cpu: 8},
+		Free:        ResourcesJson{Cpu: 2, Gpu: 3},
+		HourlyCost:  &cost,
+	})
+
+	tests := map[string]interface{}{
+		"node.free.cpu / node.allocatable.cpu * 0.5 + node.free.gpu":               3.125,
+		"node.free.gpu > 2.0 && node.labels['zone'] == 'west'":                     true,
+		"node.allocatable.gpu > 0.0 && node.free.gpu / node.allocatable.gpu > 0.5": false,
+		"has(node.hourlyCost) ? node.hourlyCost * 24.0 : 0.0":                      60.0,
+		"node.name.startsWith('node-') && !has(node.labels.region)":                true,
+		"node.labels.exists(key, key == 'zone')":                                   true,
+		"node.free.cpu >= 2.0 ? 'big' : 'small'":                                   "big",
+		"node.name + '-' + node.labels.zone":                                       "node-1-west",
+		"size(node.labels)":                                                        int64(1),
+	}
+
+	for text, want := range tests {
+		fields, err := compileComputedFields(map[string]string{"field": text})
+
+		if err != nil {
+			t.Fatalf(`compileComputedFields(%q) err = %v, want match for %v`, text, err, nil)
+		}
+
+		got, err := evalComputedField(fields["field"], node)
+
+		switch {
+		case err != nil:
+			t.Fatalf(`evalComputedField(%q) err = %v, want match for %v`, text, err, nil)
+		case got != want:
+			t.Fatalf(`evalComputedField(%q) = %v, want match for %v`, text, got, want)
+		}
+	}
+
+	for _, text := range []string{"", "node.free.cpu +", "(1", "free.cpu", "node ? 1 : 2"} {
+		if _, err := compileComputedFields(map[string]string{"field": text}); err == nil {
+			t.Fatalf(`compileComputedFields(%q) err = %v, want match for %v`, text, err, "an error")
+		}
+	}
+
+	for _, text := range []string{"node.free.gpu / node.allocatable.gpu", "node.missing", "node.labels.region", "node.free.cpu / 2", "node.labels"} {
+		fields, err := compileComputedFields(map[string]string{"field": text})
+
+		if err != nil {
+			t.Fatalf(`compileComputedFields(%q) err = %v, want match for %v`, text, err, nil)
+		}
+
+		if _, err := evalComputedField(fields["field"], node); err == nil {
+			t.Fatalf(`evalComputedField(%q) err = %v, want match for %v`, text, err, "an error")
+		}
+	}
+}
+
+// TestSetComputedFields computes a score for two nodes, checking that it is left out for the node it can't be
+// evaluated for.
+func TestSetComputedFields(t *testing.T) {
+	fields, err := compileComputedFields(map[string]string{"score": "node.free.cpu / node.allocatable.cpu * 0.5 + node.free.gpu / node.allocatable.gpu"})
+
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	nodes := []NodeJson{
+		{Name: "gpu-node", Allocatable: ResourcesJson{Cpu: 8, Gpu: 4}, Free: ResourcesJson{Cpu: 4, Gpu: 1}},
+		{Name: "cpu-node", Allocatable: ResourcesJson{Cpu: 8}, Free: ResourcesJson{Cpu: 8}},
+	}
+
+	setComputedFields(nodes, fields)
+
+	switch {
+	case nodes[0].Computed["score"] != 0.5:
+		t.Fatalf(`score = %v, want match for %v`, nodes[0].Computed["score"], 0.5)
+	case nodes[1].Computed != nil:
+		t.Fatalf(`computed = %v, want match for %v`, nodes[1].Computed, nil)
+	}
+
+	if _, err := compileComputedFields(map[string]string{"bad": "node.free.cpu +"}); err == nil {
+		t.Fatalf(`err = %v, want match for %v`, err, "an error")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/cel-go/cel"
+)
+
+// Headers listing the namespaces whose pods are left out of free resources, or are the only ones counted in them
+const (
+	freeExcludedHeader = "X-Free-Excluded-Namespaces"
+	freeIncludedHeader = "X-Free-Included-Namespaces"
+)
+
+// Prefixes of the extended resources counted as GPUs when no config file sets them
+var defaultGpuPrefixes = []string{"nvidia.com"}
+
+// RuntimeConfig is configuration that can be changed while the server is running by editing the config file,
+// usually a mounted ConfigMap
+type RuntimeConfig struct {
+	GpuPrefixes            []string              `json:"gpuPrefixes"`            // Prefixes of the extended resources counted as GPUs
+	FractionalGpus         []FractionalGpuScheme `json:"fractionalGpus"`         // GPU sharing schedulers whose resources are counted in fractions of GPUs
+	PoolLabel              string                `json:"poolLabel"`              // Overrides NODE_POOL_LABEL if set
+	ExcludedNamespaces     []string              `json:"excludedNamespaces"`     // Namespaces left out of pod listings and reports
+	AlertRules             []AlertRule           `json:"alertRules"`             // Replaces the rules in ALERT_RULES if set
+	ComputedFields         map[string]string     `json:"computedFields"`         // Expressions evaluated for every node, keyed by field name
+	ExcludedNodes          NodeExclusion         `json:"excludedNodes"`          // Nodes left out of every output and summary
+	FreeExcludedNamespaces []string              `json:"freeExcludedNamespaces"` // Namespaces whose pods don't use up free resources
+	FreeIncludedNamespaces []string              `json:"freeIncludedNamespaces"` // If set, the only namespaces whose pods use up free resources
+	Access                 AccessConfig          `json:"access"`                 // Maps what callers see to their RBAC permissions when ACCESS_REVIEW is enabled
+
+	computed      map[string]cel.Program // ComputedFields, compiled
+	excludedNodes *nodeExclusion         // ExcludedNodes, parsed - nil if no nodes are excluded
+}
+
+// The configuration in effect, which is swapped out whole when the config file changes
+var runtimeConfig atomic.Pointer[RuntimeConfig]
+
+// currentConfig returns the configuration in effect, or the defaults if no config file has been loaded.
+func currentConfig() *RuntimeConfig {
+	if config := runtimeConfig.Load(); config != nil {
+		return config
+	}
+
+	return &RuntimeConfig{GpuPrefixes: defaultGpuPrefixes, FractionalGpus: defaultFractionalGpuSchemes}
+}
+
+// parseRuntimeConfig parses the JSON contents of a config file, filling in defaults for anything it doesn't set.
+func parseRuntimeConfig(data []byte) (*RuntimeConfig, error) {
+	var config RuntimeConfig
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if len(config.GpuPrefixes) == 0 {
+		config.GpuPrefixes = defaultGpuPrefixes
+	}
+
+	if len(config.FractionalGpus) == 0 {
+		config.FractionalGpus = defaultFractionalGpuSchemes
+	}
+
+	computed, err := compileComputedFields(config.ComputedFields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config.computed = computed
+
+	excludedNodes, err := compileNodeExclusion(config.ExcludedNodes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config.excludedNodes = excludedNodes
+
+	if err := validateAlertRules(config.AlertRules); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// isGpuResource returns whether a resource name is one of the configured GPU resources. The memory and compute of
+// GPU sharing schemes are left out even if they have a GPU prefix, as they aren't counted in GPUs.
+func isGpuResource(name string) bool {
+	if isFractionalGpuResource(name) {
+		return false
+	}
+
+	for _, prefix := range currentConfig().GpuPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExcludedNamespace returns whether a namespace is configured to be left out of pod listings and reports.
+func isExcludedNamespace(namespace string) bool {
+	return slices.Contains(currentConfig().ExcludedNamespaces, namespace)
+}
+
+// isCountedNamespace returns whether the requests of pods in a namespace are subtracted from the free resources of
+// their nodes. Pods in namespaces that aren't counted, such as one for chaos testing whose requests are inflated on
+// purpose, are ignored when computing free resources.
+func isCountedNamespace(namespace string) bool {
+	config := currentConfig()
+
+	if len(config.FreeIncludedNamespaces) > 0 && !slices.Contains(config.FreeIncludedNamespaces, namespace) {
+		return false
+	}
+
+	return !slices.Contains(config.FreeExcludedNamespaces, namespace)
+}
+
+// freeAccountingHeaders returns a HandlerFunc that notes the namespaces left out of or exclusively counted in free
+// resources in the headers of every response, so consumers know the free resources they're given aren't the
+// cluster's actual ones.
+func freeAccountingHeaders() gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		config := currentConfig()
+
+		if len(config.FreeExcludedNamespaces) > 0 {
+			c.Header(freeExcludedHeader, strings.Join(config.FreeExcludedNamespaces, ","))
+		}
+
+		if len(config.FreeIncludedNamespaces) > 0 {
+			c.Header(freeIncludedHeader, strings.Join(config.FreeIncludedNamespaces, ","))
+		}
+
+		c.Next()
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// applyRuntimeConfig parses the contents of a config file and makes it the configuration in effect, passing it to
+// apply for anything that has to be updated by hand.
+func applyRuntimeConfig(data []byte, apply func(*RuntimeConfig)) error {
+	config, err := parseRuntimeConfig(data)
+
+	if err != nil {
+		return err
+	}
+
+	runtimeConfig.Store(config)
+	apply(config)
+
+	return nil
+}
+
+// watchRuntimeConfig rereads the config file at path every interval, applying it whenever its contents differ from
+// last. Polling the contents rather than watching for events works with ConfigMap volumes, which are updated by
+// swapping a symlink. A file that can't be read or parsed is logged and the previous configuration is kept. It
+// blocks forever, so it should be run in its own goroutine.
+func watchRuntimeConfig(path string, interval time.Duration, last []byte, apply func(*RuntimeConfig)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		if bytes.Equal(data, last) {
+			continue
+		}
+
+		// Only log a bad file once, rather than on every tick until it is fixed
+		last = data
+
+		if err := applyRuntimeConfig(data, apply); err != nil {
+			fmt.Printf("error reloading %v, keeping the previous config: %v\n", path, err)
+			continue
+		}
+
+		fmt.Printf("reloaded config from %v\n", path)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestApplyRuntimeConfig applies a config file setting GPU prefixes and excluded namespaces, and checks that
+// prefixes are defaulted when a file leaves them out.
+func TestApplyRuntimeConfig(t *testing.T) {
+	defer runtimeConfig.Store(nil)
+
+	var applied *RuntimeConfig
+	data := []byte(`{"gpuPrefixes": ["nvidia.com", "amd.com"], "poolLabel": "pool", "excludedNamespaces": ["kube-system"]}`)
+
+	if err := applyRuntimeConfig(data, func(config *RuntimeConfig) { applied = config }); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	switch {
+	case applied != currentConfig():
+		t.Fatalf(`applied = %v, want match for %v`, applied, currentConfig())
+	case !isGpuResource("amd.com/gpu"):
+		t.Fatalf(`isGpuResource("amd.com/gpu") = %v, want match for %v`, false, true)
+	case isGpuResource("example.com/fpga"):
+		t.Fatalf(`isGpuResource("example.com/fpga") = %v, want match for %v`, true, false)
+	case !isExcludedNamespace("kube-system"):
+		t.Fatalf(`isExcludedNamespace("kube-system") = %v, want match for %v`, false, true)
+	case isExcludedNamespace("default"):
+		t.Fatalf(`isExcludedNamespace("default") = %v, want match for %v`, true, false)
+	}
+
+	// A bad file keeps the previous config
+	if err := applyRuntimeConfig([]byte(`{"gpuPrefixes": "amd.com"}`), func(*RuntimeConfig) {}); err == nil {
+		t.Fatalf(`err = %v, want match for %v`, err, "an error")
+	}
+
+	if !isGpuResource("amd.com/gpu") {
+		t.Fatalf(`isGpuResource("amd.com/gpu") = %v, want match for %v`, false, true)
+	}
+
+	// So does one with a misspelled alert rule
+	if err := applyRuntimeConfig([]byte(`{"alertRules": [{"name": "low-gpu", "scope": "cluster", "resource": "gpus", "below": 2}]}`), func(*RuntimeConfig) {}); err == nil {
+		t.Fatalf(`err = %v, want match for %v`, err, "an error")
+	}
+
+	if err := applyRuntimeConfig([]byte(`{}`), func(*RuntimeConfig) {}); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	if isGpuResource("amd.com/gpu") || !isGpuResource("nvidia.com/gpu") {
+		t.Fatalf(`gpuPrefixes = %v, want match for %v`, currentConfig().GpuPrefixes, defaultGpuPrefixes)
+	}
+}
+
+// TestFreeAccountingNamespaces checks that pods in excluded namespaces, or outside the included ones, don't use up
+// the free resources of their nodes, and that the namespaces are noted in response headers.
+func TestFreeAccountingNamespaces(t *testing.T) {
+	defer runtimeConfig.Store(nil)
+
+	pod := func(namespace string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace, Namespace: namespace},
+			Spec: corev1.PodSpec{NodeName: "node-1", Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			}}},
+		}
+	}
+	pods := []corev1.Pod{pod("default"), pod("chaos"), pod("batch")}
+
+	for _, test := range []struct {
+		config string
+		free   float64
+		header string
+	}{
+		{`{}`, 5, ""},
+		{`{"freeExcludedNamespaces": ["chaos"]}`, 6, "chaos"},
+		{`{"freeIncludedNamespaces": ["default", "chaos"], "freeExcludedNamespaces": ["chaos"]}`, 7, "chaos"},
+	} {
+		if err := applyRuntimeConfig([]byte(test.config), func(*RuntimeConfig) {}); err != nil {
+			t.Fatalf(`applyRuntimeConfig(%v) = %v, want match for nil`, test.config, err)
+		}
+
+		nodes := map[string]*Node{"node-1": {Name: "node-1", Allocatable: Resources{Cpu: resource.MustParse("8")}}}
+		subtractPodRequests(pods, nodes)
+
+		if free := nodes["node-1"].Free.Cpu.AsApproximateFloat64(); free != test.free {
+			t.Fatalf(`free cpu with %v = %v, want match for %v`, test.config, free, test.free)
+		}
+
+		router := gin.New()
+		router.Use(freeAccountingHeaders())
+		router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if header := recorder.Header().Get(freeExcludedHeader); header != test.header {
+			t.Fatalf(`%v header with %v = %q, want match for %q`, freeExcludedHeader, test.config, header, test.header)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContainerJson contains the requests and limits of one of a pod's containers
+type ContainerJson struct {
+	Name     string        `json:"name"`
+	Init     bool          `json:"init,omitempty"`    // True for init containers
+	Sidecar  bool          `json:"sidecar,omitempty"` // True for restartable init containers, which keep running alongside the containers
+	Requests ResourcesJson `json:"requests"`
+	Limits   ResourcesJson `json:"limits"`
+}
+
+// PodContainers explains a pod's effective requests, which are the larger of what its containers and its init
+// containers need at once for each resource, plus the pod's overhead
+type PodContainers struct {
+	Containers         []ContainerJson `json:"containers"`         // Init containers in the order they run, then the containers
+	ContainersRequests ResourcesJson   `json:"containersRequests"` // Requests of the containers and any sidecars running alongside them
+	InitRequests       ResourcesJson   `json:"initRequests"`       // Most any init container needs while it runs, with the sidecars started before it
+	Overhead           ResourcesJson   `json:"overhead"`           // Overhead of the pod's RuntimeClass
+}
+
+// addResourceList adds each quantity in list to total.
+func addResourceList(total, list corev1.ResourceList) {
+	for name, quantity := range list {
+		if sum, ok := total[name]; ok {
+			sum.Add(quantity)
+			total[name] = sum
+		} else {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// maxResourceList sets each quantity in total to the larger of it and the quantity in list.
+func maxResourceList(total, list corev1.ResourceList) {
+	for name, quantity := range list {
+		if current, ok := total[name]; !ok || quantity.Cmp(current) > 0 {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// getPodContainers returns the requests and limits of each of a pod's containers, along with the requests of its
+// containers and its init containers that its effective requests are the larger of. Before 1.28, or with
+// --request-rules set to pre-1.28, init containers are never sidecars, and each runs on its own.
+func getPodContainers(pod *corev1.Pod) *PodContainers {
+	containers := make([]ContainerJson, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	containersRequests := make(corev1.ResourceList)
+	initRequests := make(corev1.ResourceList)
+	sidecarRequests := make(corev1.ResourceList)
+
+	for _, container := range pod.Spec.Containers {
+		addResourceList(containersRequests, container.Resources.Requests)
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		sidecar := !legacyRequestRules && container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
+
+		containers = append(containers, ContainerJson{
+			Name:     container.Name,
+			Init:     true,
+			Sidecar:  sidecar,
+			Requests: getResourcesStructured(getResourcesFromList(container.Resources.Requests)),
+			Limits:   getResourcesStructured(getResourcesFromList(container.Resources.Limits)),
+		})
+
+		// Sidecars keep running once started, so they add to the containers and to every init container after them
+		running := make(corev1.ResourceList)
+		if sidecar {
+			addResourceList(containersRequests, container.Resources.Requests)
+			addResourceList(sidecarRequests, container.Resources.Requests)
+			addResourceList(running, sidecarRequests)
+		} else {
+			addResourceList(running, sidecarRequests)
+			addResourceList(running, container.Resources.Requests)
+		}
+
+		maxResourceList(initRequests, running)
+	}
+
+	for _, container := range pod.Spec.Containers {
+		containers = append(containers, ContainerJson{
+			Name:     container.Name,
+			Requests: getResourcesStructured(getResourcesFromList(container.Resources.Requests)),
+			Limits:   getResourcesStructured(getResourcesFromList(container.Resources.Limits)),
+		})
+	}
+
+	return &PodContainers{
+		Containers:         containers,
+		ContainersRequests: getResourcesStructured(getResourcesFromList(containersRequests)),
+		InitRequests:       getResourcesStructured(getResourcesFromList(initRequests)),
+		Overhead:           getResourcesStructured(getResourcesFromList(pod.Spec.Overhead)),
+	}
+}
+
+// applyContainersParam leaves out the container breakdown of each pod unless the containers query parameter is true,
+// since it makes the list of pods several times as long.
+func applyContainersParam(containers string, pods []PodJson) {
+	if containers == "true" {
+		return
+	}
+
+	for i := range pods {
+		pods[i].Containers = nil
+	}
+}
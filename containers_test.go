@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// TestGetPodContainers breaks down the requests of a pod with a sidecar before an init container under both request
+// rules, checking that the larger of the containers' and init containers' requests plus overhead is the pod's
+// effective request.
+func TestGetPodContainers(t *testing.T) {
+	defer func() { legacyRequestRules = false }()
+
+	always := corev1.ContainerRestartPolicyAlways
+	container := func(name, cpu string, restartPolicy *corev1.ContainerRestartPolicy) corev1.Container {
+		return corev1.Container{
+			Name:          name,
+			RestartPolicy: restartPolicy,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+			},
+		}
+	}
+
+	pod := corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{container("proxy", "500m", &always), container("migrate", "2", nil)},
+			Containers:     []corev1.Container{container("app", "1", nil), container("logger", "250m", nil)},
+			Overhead:       corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		},
+	}
+
+	for _, test := range []struct {
+		legacy     bool
+		containers float64
+		init       float64
+		sidecar    bool
+	}{
+		{false, 1.75, 2.5, true},
+		{true, 1.25, 2, false},
+	} {
+		legacyRequestRules = test.legacy
+		breakdown := getPodContainers(&pod)
+		requests, _ := getPodRequestsAndLimits(&pod)
+
+		switch {
+		case len(breakdown.Containers) != 4:
+			t.Fatalf(`containers = %v, want 4 containers`, breakdown.Containers)
+		case breakdown.Containers[0].Name != "proxy" || !breakdown.Containers[0].Init || breakdown.Containers[0].Sidecar != test.sidecar:
+			t.Fatalf(`first container = %v, want match for the proxy init container with sidecar %v`, breakdown.Containers[0], test.sidecar)
+		case breakdown.Containers[3].Name != "logger" || breakdown.Containers[3].Init:
+			t.Fatalf(`last container = %v, want match for the logger container`, breakdown.Containers[3])
+		case breakdown.ContainersRequests.Cpu != test.containers || breakdown.InitRequests.Cpu != test.init:
+			t.Fatalf(`legacy %v requests = %v, %v, want match for %v, %v`, test.legacy, breakdown.ContainersRequests.Cpu, breakdown.InitRequests.Cpu, test.containers, test.init)
+		case max(breakdown.ContainersRequests.Cpu, breakdown.InitRequests.Cpu)+breakdown.Overhead.Cpu != requests.Cpu().AsApproximateFloat64():
+			t.Fatalf(`legacy %v breakdown = %v, want it to add up to %v`, test.legacy, breakdown, requests.Cpu())
+		}
+	}
+}
+
+// TestApplyContainersParam checks that pods are only broken down by container when asked for.
+func TestApplyContainersParam(t *testing.T) {
+	pods := []PodJson{{Name: "a", Containers: &PodContainers{}}}
+
+	applyContainersParam("true", pods)
+	if pods[0].Containers == nil {
+		t.Fatalf(`containers with containers=true = nil, want a breakdown`)
+	}
+
+	applyContainersParam("", pods)
+	if pods[0].Containers != nil {
+		t.Fatalf(`containers without containers=true = %v, want match for nil`, pods[0].Containers)
+	}
+}
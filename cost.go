@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// CostProvider estimates how much a node costs to run
+type CostProvider interface {
+	// HourlyCost returns the cost of running the node for an hour, or false if it isn't known
+	HourlyCost(node *Node) (float64, bool)
+}
+
+// StaticCostProvider looks up node prices in a fixed table keyed by the value of a node label,
+// usually the instance type
+type StaticCostProvider struct {
+	Label  string             `json:"label"`
+	Prices map[string]float64 `json:"prices"`
+}
+
+// loadStaticCostProvider reads a price table from the JSON file at path. The label defaults to the
+// well-known instance type label if the file doesn't set one.
+func loadStaticCostProvider(path string) (*StaticCostProvider, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var provider StaticCostProvider
+
+	if err := json.Unmarshal(data, &provider); err != nil {
+		return nil, err
+	}
+
+	if provider.Label == "" {
+		provider.Label = "node.kubernetes.io/instance-type"
+	}
+
+	return &provider, nil
+}
+
+// HourlyCost returns the price of the node's label value in the table.
+func (s *StaticCostProvider) HourlyCost(node *Node) (float64, bool) {
+	value, ok := node.Labels[s.Label]
+	if !ok {
+		return 0, false
+	}
+
+	price, ok := s.Prices[value]
+	return price, ok
+}
+
+// getIdleFraction returns the average fraction of the node's CPU, memory, and GPUs that is not requested,
+// ignoring resources the node doesn't have any of.
+func getIdleFraction(node NodeJson) float64 {
+	sum := 0.0
+	count := 0
+
+	for _, resource := range []string{"cpu", "memory", "gpu"} {
+		allocatable := getResource(node.Allocatable, resource)
+		if allocatable <= 0 {
+			continue
+		}
+
+		free := max(getResource(node.Free, resource), 0)
+		sum += free / allocatable
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return sum / float64(count)
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+// TestStaticCostProvider looks up the cost of nodes in a price table, checking that nodes without the label
+// or with an unknown label value have no cost.
+func TestStaticCostProvider(t *testing.T) {
+	costs := StaticCostProvider{
+		Label:  "node.kubernetes.io/instance-type",
+		Prices: map[string]float64{"m5.xlarge": 0.192},
+	}
+
+	tests := []struct {
+		labels map[string]string
+		cost   float64
+		ok     bool
+	}{
+		{map[string]string{"node.kubernetes.io/instance-type": "m5.xlarge"}, 0.192, true},
+		{map[string]string{"node.kubernetes.io/instance-type": "p3.2xlarge"}, 0, false},
+		{nil, 0, false},
+	}
+
+	for _, test := range tests {
+		cost, ok := costs.HourlyCost(&Node{Name: "node-1", Labels: test.labels})
+
+		if cost != test.cost || ok != test.ok {
+			t.Fatalf(`HourlyCost(%v) = %v, %v, want match for %v, %v`, test.labels, cost, ok, test.cost, test.ok)
+		}
+	}
+}
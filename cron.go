@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shorthands for common cron expressions
+var cronShorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// CronSchedule is a parsed five-field cron expression - minute, hour, day of month, month, and day of week. Each
+// field is a bit set of the values it matches.
+type CronSchedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+
+	// As in cron, if both day fields are restricted, a time matches if either of them does
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// parseCron parses a cron expression such as "0 9 * * 1-5" or "*/15 * * * *". Each field may be *, a value, a
+// range, or a comma-separated list of them, each optionally with a /step. Days of the week are 0-7, where both 0
+// and 7 are Sunday. The shorthands @hourly, @daily, @weekly, and @monthly are also accepted.
+func parseCron(expression string) (*CronSchedule, error) {
+	if shorthand, ok := cronShorthands[expression]; ok {
+		expression = shorthand
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields", expression)
+	}
+
+	var schedule CronSchedule
+	var err error
+
+	bounds := []struct {
+		field    *uint64
+		min, max int
+	}{
+		{&schedule.minute, 0, 59},
+		{&schedule.hour, 0, 23},
+		{&schedule.dayOfMonth, 1, 31},
+		{&schedule.month, 1, 12},
+		{&schedule.dayOfWeek, 0, 7},
+	}
+
+	for i, bound := range bounds {
+		*bound.field, err = parseCronField(fields[i], bound.min, bound.max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %v", expression, err)
+		}
+	}
+
+	// Sunday can be written as 7
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1
+	}
+
+	schedule.anyDayOfMonth = fields[2] == "*"
+	schedule.anyDayOfWeek = fields[4] == "*"
+
+	return &schedule, nil
+}
+
+// parseCronField returns the bit set of the values between min and max that a single cron field matches.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		valueRange, stepText, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepText)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+
+		start, end := min, max
+
+		if valueRange != "*" {
+			startText, endText, isRange := strings.Cut(valueRange, "-")
+
+			var err error
+			start, err = strconv.Atoi(startText)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", startText)
+			}
+
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(endText)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q", endText)
+				}
+			} else if hasStep {
+				// A single value with a step, such as 5/15, runs to the end of the field
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is outside %v-%v", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+// Matches returns whether the schedule fires in the minute containing t.
+func (s *CronSchedule) Matches(t time.Time) bool {
+	if s.minute&(1<<t.Minute()) == 0 || s.hour&(1<<t.Hour()) == 0 || s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	dayOfMonth := s.dayOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := s.dayOfWeek&(1<<int(t.Weekday())) != 0
+
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseCron parses cron expressions and checks which times they match, including steps, ranges, lists,
+// Sunday written as 7, and either day field matching when both are restricted.
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expression string
+		time       time.Time
+		want       bool
+	}{
+		{"0 9 * * 1", time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC), true},  // Monday
+		{"0 9 * * 1", time.Date(2024, 8, 6, 9, 0, 0, 0, time.UTC), false}, // Tuesday
+		{"*/15 * * * *", time.Date(2024, 8, 6, 3, 45, 0, 0, time.UTC), true},
+		{"*/15 * * * *", time.Date(2024, 8, 6, 3, 50, 0, 0, time.UTC), false},
+		{"0 8-17/3 * * 1-5", time.Date(2024, 8, 6, 14, 0, 0, 0, time.UTC), true},
+		{"0 8-17/3 * * 1-5", time.Date(2024, 8, 6, 15, 0, 0, 0, time.UTC), false},
+		{"30 6 1,15 * *", time.Date(2024, 8, 15, 6, 30, 0, 0, time.UTC), true},
+		{"0 0 * * 7", time.Date(2024, 8, 4, 0, 0, 0, 0, time.UTC), true}, // Sunday
+		{"0 0 1 * 1", time.Date(2024, 8, 5, 0, 0, 0, 0, time.UTC), true}, // A Monday that isn't the 1st
+		{"@monthly", time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), true},
+		{"@monthly", time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range tests {
+		schedule, err := parseCron(test.expression)
+
+		if err != nil {
+			t.Fatalf(`parseCron(%q) err = %v, want match for %v`, test.expression, err, nil)
+		}
+
+		if got := schedule.Matches(test.time); got != test.want {
+			t.Fatalf(`parseCron(%q).Matches(%v) = %v, want match for %v`, test.expression, test.time, got, test.want)
+		}
+	}
+
+	for _, expression := range []string{"0 9 * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := parseCron(expression); err == nil {
+			t.Fatalf(`parseCron(%q) err = %v, want an error`, expression, err)
+		}
+	}
+}
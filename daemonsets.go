@@ -0,0 +1,46 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// isDaemonSetPod returns whether a pod is run by a DaemonSet, which controls its pods directly.
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	ref := metav1.GetControllerOf(pod)
+	return ref != nil && ref.Kind == "DaemonSet"
+}
+
+// getDaemonSetRequests sums the requests of the DaemonSet pods on each node, keyed by node name. The same pods are
+// counted as when working out free resources, including those in excluded namespaces that are still counted.
+func getDaemonSetRequests(pods []corev1.Pod, nodes map[string]*Node) map[string]ResourcesJson {
+	requests := make(map[string]ResourcesJson)
+
+	for i := range pods {
+		pod := &pods[i]
+		name := getAccountingNode(pod)
+
+		if _, ok := nodes[name]; !ok || !isCountedNamespace(pod.Namespace) || !isDaemonSetPod(pod) {
+			continue
+		}
+
+		podReqs, _ := getPodRequestsAndLimits(pod)
+		requests[name] = addResources(requests[name], getResourcesStructured(getResourcesFromList(podReqs)))
+	}
+
+	return requests
+}
+
+// excludeDaemonSetPods returns copies of nodes with the requests of their DaemonSet pods added back to their free
+// resources, as if the pods weren't there. Every node runs its DaemonSets' pods, including new ones, so this is what
+// is free for other workloads once that overhead is set aside.
+func excludeDaemonSetPods(nodes []NodeJson) []NodeJson {
+	result := make([]NodeJson, 0, len(nodes))
+
+	for _, node := range nodes {
+		node.Free = addResources(node.Free, node.DaemonSetRequests)
+		result = append(result, node)
+	}
+
+	return result
+}
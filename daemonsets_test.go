@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestExcludeDaemonSets lists a node running a DaemonSet pod and a Deployment's pod with and without
+// excludeDaemonSets, checking that only the DaemonSet pod's requests are added back to the free resources, and that
+// the nodes of the collector's snapshot aren't changed.
+func TestExcludeDaemonSets(t *testing.T) {
+	controller := true
+	pod := func(name, kind, cpu string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "node-1",
+				Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+				}}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	collector := newCollector(fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")},
+			},
+		},
+		pod("node-exporter", "DaemonSet", "500m"),
+		pod("web", "ReplicaSet", "2"),
+	))
+
+	router := gin.New()
+	router.GET("/nodes", getNodesHandler(collector))
+
+	for _, test := range []struct {
+		query string
+		free  float64
+	}{
+		{"", 5.5},
+		{"?excludeDaemonSets=true", 6},
+		{"", 5.5},
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/nodes"+test.query, nil))
+
+		var nodes []NodeJson
+		json.Unmarshal(recorder.Body.Bytes(), &nodes)
+
+		switch {
+		case len(nodes) != 1:
+			t.Fatalf(`GET /nodes%v = %v, want 1 node`, test.query, nodes)
+		case nodes[0].DaemonSetRequests.Cpu != 0.5:
+			t.Fatalf(`daemonSetRequests = %v, want match for 0.5 CPU`, nodes[0].DaemonSetRequests)
+		case nodes[0].Free.Cpu != test.free:
+			t.Fatalf(`GET /nodes%v free CPU = %v, want match for %v`, test.query, nodes[0].Free.Cpu, test.free)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A single page showing every node's used and free resources, built from /nodes
+//
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// getDashboardHandler returns a HandlerFunc to return the dashboard page.
+func getDashboardHandler() gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", dashboardPage)
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SnapshotStats describes a snapshot taken from the cluster
+type SnapshotStats struct {
+	Time     time.Time `json:"time"`
+	Age      float64   `json:"age"`      // Seconds since the snapshot was taken, when it is reported
+	Duration float64   `json:"duration"` // Seconds it took to take the snapshot
+	Nodes    int       `json:"nodes"`
+	Pods     int       `json:"pods"`
+}
+
+// CachedSnapshotStatus describes the snapshot held by a CachedSnapshot
+type CachedSnapshotStatus struct {
+	Name string   `json:"name"`
+	Ttl  float64  `json:"ttl"`           // Seconds the snapshot is reused for
+	Age  *float64 `json:"age,omitempty"` // Seconds since the held snapshot was taken, if there is one
+}
+
+// SharedSnapshotStatus describes the snapshot shared between replicas through Redis
+type SharedSnapshotStatus struct {
+	Interval float64  `json:"interval"`        // Seconds between refreshes
+	Age      *float64 `json:"age,omitempty"`   // Seconds since the stored snapshot was taken, if there is one
+	Nodes    int      `json:"nodes"`           // Nodes in the stored snapshot
+	Error    string   `json:"error,omitempty"` // Error reading the stored snapshot from Redis
+}
+
+// MemoryStatus describes the memory used by the server
+type MemoryStatus struct {
+	HeapAlloc  uint64 `json:"heapAlloc"` // Bytes of allocated heap objects
+	HeapInuse  uint64 `json:"heapInuse"`
+	Sys        uint64 `json:"sys"` // Bytes of memory obtained from the OS
+	NumGC      uint32 `json:"numGC"`
+	Goroutines int    `json:"goroutines"`
+}
+
+// CacheStatus describes how fresh the data served by a replica is. There are no informers, so the cluster is listed
+// every time a snapshot is taken, and the data is only as old as the snapshot it is served from.
+type CacheStatus struct {
+	Source       string                 `json:"source"`                 // live, shared, sharded, or imported
+	LastSnapshot *SnapshotStats         `json:"lastSnapshot,omitempty"` // Last snapshot this replica took, if any
+	Version      uint64                 `json:"version"`                // Current snapshot version
+	Nodes        int                    `json:"nodes"`                  // Nodes in the snapshot the version is of
+	Refreshes    int64                  `json:"refreshes"`              // Snapshots forced with POST /refresh
+	Shared       *SharedSnapshotStatus  `json:"shared,omitempty"`
+	Cached       []CachedSnapshotStatus `json:"cached"`
+	Memory       MemoryStatus           `json:"memory"`
+}
+
+// getSecondsSince returns the seconds from t to now, rounded to milliseconds.
+func getSecondsSince(t time.Time, now time.Time) float64 {
+	return float64(now.Sub(t).Milliseconds()) / 1000
+}
+
+// CacheStatus returns the status of the collector's snapshots and caches at now.
+func (c *Collector) CacheStatus(now time.Time) CacheStatus {
+	status := CacheStatus{Source: "live", Refreshes: c.refreshes.Load(), Cached: make([]CachedSnapshotStatus, 0)}
+
+	switch {
+	case c.imported != nil:
+		status.Source = "imported"
+	case c.shared != nil:
+		status.Source = "shared"
+	case c.shard != nil:
+		status.Source = "sharded"
+	}
+
+	if stats := c.lastSnapshot.Load(); stats != nil {
+		last := *stats
+		last.Age = getSecondsSince(last.Time, now)
+		status.LastSnapshot = &last
+	}
+
+	c.tracker.mu.Lock()
+	status.Version, status.Nodes = c.tracker.version, len(c.tracker.nodes)
+	c.tracker.mu.Unlock()
+
+	if c.shared != nil {
+		status.Shared = &SharedSnapshotStatus{Interval: c.shared.interval.Seconds()}
+		snapshot, ok, err := c.shared.Load()
+
+		if err != nil {
+			status.Shared.Error = err.Error()
+		} else if ok {
+			age := getSecondsSince(snapshot.Time, now)
+			status.Shared.Age, status.Shared.Nodes = &age, len(snapshot.Nodes)
+		}
+	}
+
+	c.cachesMutex.Lock()
+	caches := c.caches
+	c.cachesMutex.Unlock()
+
+	for _, cache := range caches {
+		cached := CachedSnapshotStatus{Name: cache.name, Ttl: cache.ttl.Seconds()}
+
+		cache.mutex.Lock()
+		if cache.snapshot != nil {
+			age := getSecondsSince(cache.snapshot.Time, now)
+			cached.Age = &age
+		}
+		cache.mutex.Unlock()
+
+		status.Cached = append(status.Cached, cached)
+	}
+
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	status.Memory = MemoryStatus{
+		HeapAlloc:  memory.HeapAlloc,
+		HeapInuse:  memory.HeapInuse,
+		Sys:        memory.Sys,
+		NumGC:      memory.NumGC,
+		Goroutines: runtime.NumGoroutine(),
+	}
+
+	return status
+}
+
+// getDebugCacheHandler returns a HandlerFunc to return the status of a Collector's snapshots and caches, to tell
+// stale data apart from wrong data.
+func getDebugCacheHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		c.IndentedJSON(http.StatusOK, collector.CacheStatus(time.Now()))
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestCacheStatus checks the status of a collector before any snapshot is taken and after a cached snapshot is.
+func TestCacheStatus(t *testing.T) {
+	collector := newCollector(fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}))
+	cached := newCachedSnapshot(collector, "webhook", 30*time.Second)
+
+	status := collector.CacheStatus(time.Now())
+
+	switch {
+	case status.Source != "live" || status.LastSnapshot != nil || status.Version != 0:
+		t.Fatalf(`CacheStatus() before a snapshot = %v, want a live source without snapshots`, status)
+	case len(status.Cached) != 1 || status.Cached[0].Name != "webhook" || status.Cached[0].Ttl != 30 || status.Cached[0].Age != nil:
+		t.Fatalf(`CacheStatus().Cached before a snapshot = %v, want an empty webhook cache`, status.Cached)
+	}
+
+	snapshot, err := cached.Get()
+
+	if err != nil {
+		t.Fatalf(`Get() = %v, want match for nil`, err)
+	}
+
+	status = collector.CacheStatus(snapshot.Time.Add(10 * time.Second))
+
+	switch {
+	case status.LastSnapshot == nil || status.LastSnapshot.Age != 10 || status.LastSnapshot.Nodes != 1:
+		t.Fatalf(`CacheStatus().LastSnapshot = %v, want a snapshot of 1 node 10 seconds old`, status.LastSnapshot)
+	case status.Version != 1 || status.Nodes != 1:
+		t.Fatalf(`CacheStatus() version = %v, %v, want match for 1, 1`, status.Version, status.Nodes)
+	case status.Cached[0].Age == nil || *status.Cached[0].Age != 10:
+		t.Fatalf(`CacheStatus().Cached = %v, want a snapshot 10 seconds old`, status.Cached)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// GPU resource of the generated cluster's GPU nodes
+const demoGpuResource = corev1.ResourceName("nvidia.com/gpu")
+
+// Time the generated cluster's pods are started relative to, so the same seed always gives the same cluster
+var demoEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// DemoPool describes the nodes of one pool in the generated cluster
+type DemoPool struct {
+	Name         string
+	InstanceType string
+	CapacityType string
+	Weight       int // Relative share of the cluster's nodes in this pool
+	Cpu          string
+	Memory       string
+	Gpu          int64
+	GpuProduct   string
+}
+
+// Pools of the generated cluster - general purpose on-demand and spot nodes, with a few GPU nodes
+var demoPools = []DemoPool{
+	{Name: "general", InstanceType: "m5.4xlarge", CapacityType: "on-demand", Weight: 5, Cpu: "16", Memory: "64Gi"},
+	{Name: "spot", InstanceType: "m5.2xlarge", CapacityType: "spot", Weight: 3, Cpu: "8", Memory: "32Gi"},
+	{Name: "gpu", InstanceType: "p4d.24xlarge", CapacityType: "on-demand", Weight: 2, Cpu: "96", Memory: "1152Gi", Gpu: 8, GpuProduct: "NVIDIA-A100-SXM4-40GB"},
+}
+
+// Namespaces of the generated cluster's pods - pods in the ml namespace ask for GPUs
+var demoNamespaces = []string{"web", "api", "data", "monitoring", "ml"}
+
+// Requests of the generated cluster's pods, picked at random
+var demoCpuRequests = []string{"100m", "250m", "500m", "1", "2", "4"}
+var demoMemoryRequests = []string{"128Mi", "256Mi", "512Mi", "1Gi", "2Gi", "8Gi"}
+
+// newDemoClients returns clients for a generated cluster of about the given number of nodes and pods, along with the
+// usage of each pod. The cluster is generated from seed, so the same seed always gives the same cluster. Pods that
+// don't fit on any node are left pending.
+func newDemoClients(nodeCount, podCount int, seed int64) (kubernetes.Interface, metricsclient.Interface) {
+	objects, usage := getDemoCluster(nodeCount, podCount, seed)
+
+	client := fake.NewSimpleClientset(objects...)
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &k8sversion.Info{GitVersion: "v1.31.0", Major: "1", Minor: "31", Platform: "linux/amd64"}
+
+	// The fake client ignores field selectors, which are used to list the pods on a node or in a phase
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		object, err := client.Tracker().List(corev1.SchemeGroupVersion.WithResource("pods"), corev1.SchemeGroupVersion.WithKind("Pod"), action.GetNamespace())
+
+		if err != nil {
+			return true, nil, err
+		}
+
+		pods := filterPodsByFields(object.(*corev1.PodList).Items, "", action.(k8stesting.ListAction).GetListRestrictions().Fields)
+		return true, &corev1.PodList{Items: pods}, nil
+	})
+
+	metrics := metricsfake.NewSimpleClientset()
+	metrics.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.PodMetricsList{Items: usage}, nil
+	})
+
+	return client, metrics
+}
+
+// filterPodsByFields returns the pods in namespace that match selector, as the API server would for a list with a
+// field selector. Every namespace is searched if namespace is empty.
+func filterPodsByFields(pods []corev1.Pod, namespace string, selector fields.Selector) []corev1.Pod {
+	filtered := make([]corev1.Pod, 0, len(pods))
+
+	for _, pod := range pods {
+		podFields := fields.Set{
+			"metadata.name":      pod.Name,
+			"metadata.namespace": pod.Namespace,
+			"spec.nodeName":      pod.Spec.NodeName,
+			"status.phase":       string(pod.Status.Phase),
+		}
+
+		if (namespace == "" || pod.Namespace == namespace) && (selector == nil || selector.Matches(podFields)) {
+			filtered = append(filtered, pod)
+		}
+	}
+
+	return filtered
+}
+
+// getDemoCluster generates the nodes, namespaces, and pods of a cluster from seed, along with the usage of each
+// running pod.
+func getDemoCluster(nodeCount, podCount int, seed int64) ([]runtime.Object, []metricsv1beta1.PodMetrics) {
+	random := rand.New(rand.NewSource(seed))
+
+	objects := make([]runtime.Object, 0, nodeCount+podCount+len(demoNamespaces))
+	usage := make([]metricsv1beta1.PodMetrics, 0, podCount)
+
+	for _, namespace := range demoNamespaces {
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+	}
+
+	totalWeight := 0
+	for _, pool := range demoPools {
+		totalWeight += pool.Weight
+	}
+
+	// Deal the nodes out to pools by weight, so every pool has nodes even in a small cluster
+	nodes := make([]*corev1.Node, 0, nodeCount)
+	free := make(map[string]corev1.ResourceList)
+	poolSizes := make([]int, len(demoPools))
+
+	for i := 0; i < nodeCount; i++ {
+		position := i % totalWeight
+		pool := 0
+		for position >= demoPools[pool].Weight {
+			position -= demoPools[pool].Weight
+			pool++
+		}
+
+		node := getDemoNode(demoPools[pool], poolSizes[pool])
+		poolSizes[pool]++
+
+		nodes = append(nodes, node)
+		free[node.Name] = node.Status.Allocatable.DeepCopy()
+		objects = append(objects, node)
+	}
+
+	for i := 0; i < podCount; i++ {
+		pod := getDemoPod(random, i)
+
+		// Schedule the pod on a random node it fits on, trying each node at most once
+		for _, j := range random.Perm(len(nodes)) {
+			node := nodes[j]
+
+			if !fitsDemoNode(&pod, node, free[node.Name]) {
+				continue
+			}
+
+			for name, quantity := range pod.Spec.Containers[0].Resources.Requests {
+				remaining := free[node.Name][name]
+				remaining.Sub(quantity)
+				free[node.Name][name] = remaining
+			}
+
+			pod.Spec.NodeName = node.Name
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}}
+			usage = append(usage, getDemoUsage(random, &pod))
+			break
+		}
+
+		objects = append(objects, &pod)
+	}
+
+	return objects, usage
+}
+
+// getDemoNode returns the index-th node of a pool in the generated cluster, with a little of its capacity reserved
+// for the system.
+func getDemoNode(pool DemoPool, index int) *corev1.Node {
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:              resource.MustParse(pool.Cpu),
+		corev1.ResourceMemory:           resource.MustParse(pool.Memory),
+		corev1.ResourceEphemeralStorage: resource.MustParse("100Gi"),
+		corev1.ResourcePods:             resource.MustParse("110"),
+	}
+
+	allocatable := capacity.DeepCopy()
+	cpu := allocatable[corev1.ResourceCPU]
+	cpu.Sub(resource.MustParse("100m"))
+	allocatable[corev1.ResourceCPU] = cpu
+	memory := allocatable[corev1.ResourceMemory]
+	memory.Sub(resource.MustParse("1Gi"))
+	allocatable[corev1.ResourceMemory] = memory
+
+	name := fmt.Sprintf("demo-%v-%v", pool.Name, index)
+	labels := map[string]string{
+		"kubernetes.io/hostname":           name,
+		osLabel:                            "linux",
+		"karpenter.sh/nodepool":            pool.Name,
+		"karpenter.sh/capacity-type":       pool.CapacityType,
+		"node.kubernetes.io/instance-type": pool.InstanceType,
+	}
+
+	var taints []corev1.Taint
+
+	if pool.Gpu > 0 {
+		capacity[demoGpuResource] = *resource.NewQuantity(pool.Gpu, resource.DecimalSI)
+		allocatable[demoGpuResource] = *resource.NewQuantity(pool.Gpu, resource.DecimalSI)
+		labels[gpuProductLabel] = pool.GpuProduct
+		taints = append(taints, corev1.Taint{Key: string(demoGpuResource), Value: "present", Effect: corev1.TaintEffectNoSchedule})
+	}
+
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec:       corev1.NodeSpec{Taints: taints},
+		Status: corev1.NodeStatus{
+			Capacity:    capacity,
+			Allocatable: allocatable,
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+// getDemoPod returns the index-th pod of the generated cluster, pending until it is scheduled. Pods in the ml
+// namespace ask for a GPU and tolerate the taint on GPU nodes.
+func getDemoPod(random *rand.Rand, index int) corev1.Pod {
+	namespace := demoNamespaces[random.Intn(len(demoNamespaces))]
+	app := fmt.Sprintf("%v-app-%v", namespace, random.Intn(8))
+
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(demoCpuRequests[random.Intn(len(demoCpuRequests))]),
+		corev1.ResourceMemory: resource.MustParse(demoMemoryRequests[random.Intn(len(demoMemoryRequests))]),
+	}
+
+	// Give some pods limits of twice their requests, so there's a mix of QoS classes
+	var limits corev1.ResourceList
+	if random.Intn(2) == 0 {
+		limits = corev1.ResourceList{}
+		for name, quantity := range requests {
+			limit := quantity.DeepCopy()
+			limit.Add(quantity)
+			limits[name] = limit
+		}
+	}
+
+	var tolerations []corev1.Toleration
+	if namespace == "ml" {
+		requests[demoGpuResource] = *resource.NewQuantity(int64(1+random.Intn(2)), resource.DecimalSI)
+		tolerations = append(tolerations, corev1.Toleration{Key: string(demoGpuResource), Operator: corev1.TolerationOpExists})
+	}
+
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%v-%v", app, index),
+			Namespace:       namespace,
+			Labels:          map[string]string{"app": app},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: app}},
+		},
+		Spec: corev1.PodSpec{
+			Containers:  []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits}}},
+			Tolerations: tolerations,
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodPending,
+			StartTime:  &metav1.Time{Time: demoEpoch.Add(time.Duration(random.Intn(30*24)) * time.Hour)},
+			Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable}},
+		},
+	}
+}
+
+// fitsDemoNode returns whether pod's requests fit in the free resources of node and it tolerates the node's taints.
+func fitsDemoNode(pod *corev1.Pod, node *corev1.Node, free corev1.ResourceList) bool {
+	for _, taint := range node.Spec.Taints {
+		tolerated := false
+		for _, toleration := range pod.Spec.Tolerations {
+			tolerated = tolerated || toleration.ToleratesTaint(&taint)
+		}
+
+		if !tolerated {
+			return false
+		}
+	}
+
+	for name, quantity := range pod.Spec.Containers[0].Resources.Requests {
+		remaining, ok := free[name]
+		if !ok || remaining.Cmp(quantity) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getDemoUsage returns the usage of a running pod in the generated cluster, between a tenth of its requests and all
+// of them.
+func getDemoUsage(random *rand.Rand, pod *corev1.Pod) metricsv1beta1.PodMetrics {
+	requests := pod.Spec.Containers[0].Resources.Requests
+	fraction := 0.1 + 0.9*random.Float64()
+
+	return metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		Containers: []metricsv1beta1.ContainerMetrics{{
+			Name: "app",
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    *resource.NewMilliQuantity(int64(float64(requests.Cpu().MilliValue())*fraction), resource.DecimalSI),
+				corev1.ResourceMemory: *resource.NewQuantity(int64(float64(requests.Memory().Value())*fraction), resource.BinarySI),
+			},
+		}},
+	}
+}
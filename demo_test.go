@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestDemoCluster checks that the same seed gives the same cluster, and that the pods on a node can be listed with a
+// field selector as on a real cluster.
+func TestDemoCluster(t *testing.T) {
+	first, _ := getDemoCluster(10, 100, 1)
+	second, _ := getDemoCluster(10, 100, 1)
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf(`getDemoCluster() = %v, want match for %v`, first, second)
+	}
+
+	client, _ := newDemoClients(10, 100, 1)
+	collector := newCollector(client)
+
+	snapshot, err := collector.Snapshot()
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	pods, err := collector.NodePods("demo-gpu-0")
+
+	switch {
+	case err != nil:
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	case len(snapshot.Nodes) != 10:
+		t.Fatalf(`len(snapshot.Nodes) = %v, want match for %v`, len(snapshot.Nodes), 10)
+	case len(pods) == 0:
+		t.Fatalf(`len(pods) = %v, want some pods`, len(pods))
+	}
+
+	for _, pod := range pods {
+		if pod.Node != "demo-gpu-0" || pod.Namespace != "ml" {
+			t.Fatalf(`pod = %v, want match for %v`, pod, "an ml pod on demo-gpu-0")
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "math"
+
+// FsUsage contains the size and usage of a filesystem of a node, in bytes
+type FsUsage struct {
+	Capacity    int64   `json:"capacity"`
+	Used        int64   `json:"used"`
+	Available   int64   `json:"available"`
+	UsedPercent float64 `json:"usedPercent"`
+}
+
+// NodeDiskUsage contains the disk usage a node's kubelet reports, which is what DiskPressure evictions are based on
+// rather than the ephemeral-storage requests of pods
+type NodeDiskUsage struct {
+	NodeFs           *FsUsage `json:"nodeFs,omitempty"`  // Filesystem of the kubelet's root directory, which holds ephemeral storage
+	ImageFs          *FsUsage `json:"imageFs,omitempty"` // Filesystem holding container images, if separate from nodeFs
+	EphemeralStorage int64    `json:"ephemeralStorage"`  // Bytes of ephemeral storage used by pods
+}
+
+// getFsUsage returns the usage of a filesystem from a kubelet's stats of it, or nil if its capacity isn't reported.
+// Available bytes are those available to unprivileged users, so used and available don't always add up to capacity.
+func getFsUsage(stats *kubeletFsStats) *FsUsage {
+	if stats == nil || stats.CapacityBytes == nil || *stats.CapacityBytes == 0 {
+		return nil
+	}
+
+	usage := &FsUsage{Capacity: int64(*stats.CapacityBytes)}
+
+	if stats.UsedBytes != nil {
+		usage.Used = int64(*stats.UsedBytes)
+	}
+
+	if stats.AvailableBytes != nil {
+		usage.Available = int64(*stats.AvailableBytes)
+	}
+
+	usage.UsedPercent = math.Round(float64(usage.Used)/float64(usage.Capacity)*10000) / 100
+
+	return usage
+}
+
+// getNodeDiskUsage returns the disk usage of a node from its kubelet's stats summary. The image filesystem is only
+// reported if it is a different filesystem than the node's, which kubelets show by reporting a different capacity.
+func getNodeDiskUsage(stats *KubeletStats) *NodeDiskUsage {
+	usage := &NodeDiskUsage{NodeFs: getFsUsage(stats.Node.Fs)}
+
+	if stats.Node.Runtime != nil {
+		if imageFs := getFsUsage(stats.Node.Runtime.ImageFs); imageFs != nil && (usage.NodeFs == nil || imageFs.Capacity != usage.NodeFs.Capacity) {
+			usage.ImageFs = imageFs
+		}
+	}
+
+	for _, pod := range stats.Pods {
+		if pod.EphemeralStorage != nil && pod.EphemeralStorage.UsedBytes != nil {
+			usage.EphemeralStorage += int64(*pod.EphemeralStorage.UsedBytes)
+		}
+	}
+
+	return usage
+}
+
+// setNodeDiskUsage sets the disk usage of each node from its kubelet's stats summary, leaving nodes whose kubelet
+// couldn't be read without it.
+func setNodeDiskUsage(nodes []NodeJson, stats map[string]*KubeletStats) {
+	for i := range nodes {
+		if nodeStats, ok := stats[nodes[i].Name]; ok {
+			nodes[i].DiskUsage = getNodeDiskUsage(nodeStats)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Stats summary of a kubelet with a separate image filesystem and two pods using ephemeral storage
+const testKubeletStats = `{
+	"node": {
+		"nodeName": "node-1",
+		"fs": {"availableBytes": 20000000000, "capacityBytes": 100000000000, "usedBytes": 75000000000},
+		"runtime": {"imageFs": {"availableBytes": 150000000000, "capacityBytes": 200000000000, "usedBytes": 50000000000}}
+	},
+	"pods": [
+		{"podRef": {"name": "a", "namespace": "default"}, "ephemeral-storage": {"usedBytes": 3000000000}},
+		{"podRef": {"name": "b", "namespace": "default"}, "ephemeral-storage": {"usedBytes": 2000000000}},
+		{"podRef": {"name": "c", "namespace": "default"}}
+	]
+}`
+
+// TestNodeDiskUsage takes a snapshot of a node whose kubelet reports its disk usage and one whose kubelet can't be
+// read, checking the usage of the first and that the second has none.
+func TestNodeDiskUsage(t *testing.T) {
+	collector := newCollector(fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "unreachable"}},
+	))
+	collector.kubelet = &KubeletReader{
+		fetch: func(node, path string) ([]byte, error) {
+			if node == "unreachable" || path != "stats/summary" {
+				return nil, fmt.Errorf("node %v unreachable", node)
+			}
+
+			return []byte(testKubeletStats), nil
+		},
+	}
+	collector.kubeletStats = true
+
+	snapshot, err := collector.Snapshot()
+
+	if err != nil {
+		t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+	}
+
+	for _, node := range snapshot.Nodes {
+		usage := node.DiskUsage
+
+		switch {
+		case node.Name == "unreachable" && usage != nil:
+			t.Fatalf(`unreachable disk usage = %v, want match for nil`, usage)
+		case node.Name == "unreachable":
+		case usage == nil || usage.NodeFs == nil || usage.ImageFs == nil:
+			t.Fatalf(`node-1 disk usage = %v, want node and image filesystems`, usage)
+		case *usage.NodeFs != FsUsage{Capacity: 100000000000, Used: 75000000000, Available: 20000000000, UsedPercent: 75}:
+			t.Fatalf(`node-1 nodeFs = %v, want match for 75%% used`, *usage.NodeFs)
+		case usage.ImageFs.UsedPercent != 25:
+			t.Fatalf(`node-1 imageFs = %v, want match for 25%% used`, *usage.ImageFs)
+		case usage.EphemeralStorage != 5000000000:
+			t.Fatalf(`node-1 ephemeralStorage = %v, want match for %v`, usage.EphemeralStorage, 5000000000)
+		}
+	}
+
+	// Only the disk usage changing isn't a change to the node
+	tracker := newNodeTracker()
+	node := NodeJson{Name: "node-1", DiskUsage: &NodeDiskUsage{EphemeralStorage: 1}}
+	tracker.Update([]NodeJson{node})
+
+	node.DiskUsage = &NodeDiskUsage{EphemeralStorage: 2}
+
+	if version := tracker.Update([]NodeJson{node}); version != 1 {
+		t.Fatalf(`Update() with new disk usage = %v, want match for %v`, version, 1)
+	}
+}
+
+// TestGetNodeDiskUsageSharedImageFs checks that an image filesystem that is the node's filesystem isn't reported twice.
+func TestGetNodeDiskUsageSharedImageFs(t *testing.T) {
+	stats, err := parseKubeletStats([]byte(`{
+		"node": {
+			"fs": {"availableBytes": 60, "capacityBytes": 100, "usedBytes": 40},
+			"runtime": {"imageFs": {"availableBytes": 60, "capacityBytes": 100, "usedBytes": 40}}
+		}
+	}`))
+
+	if err != nil {
+		t.Fatalf(`parseKubeletStats() = %v, want match for nil`, err)
+	}
+
+	usage := getNodeDiskUsage(stats)
+
+	if usage.NodeFs == nil || usage.ImageFs != nil {
+		t.Fatalf(`getNodeDiskUsage() = %v, %v, want only a node filesystem`, usage.NodeFs, usage.ImageFs)
+	}
+}
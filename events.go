@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Types of resource-pressure events
+const (
+	eventOOMKilled        = "OOMKilled"
+	eventEvicted          = "Evicted"
+	eventFailedScheduling = "FailedScheduling"
+)
+
+// PressureEvent is a sign that the cluster or a node is running short of resources
+type PressureEvent struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Container string    `json:"container,omitempty"`
+	Node      string    `json:"node,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// PressureEvents lists every pod and FailedScheduling event in the cluster and returns the resource-pressure
+// events that happened after since.
+func (c *Collector) PressureEvents(since time.Time) ([]PressureEvent, error) {
+	// Evicted pods have failed, so every pod is needed rather than just the running ones
+	pods, err := c.client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := c.client.CoreV1().Events("").List(context.Background(), metav1.ListOptions{FieldSelector: "reason=" + eventFailedScheduling})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return getPressureEvents(pods.Items, events.Items, since), nil
+}
+
+// getPressureEvents finds the containers killed for running out of memory and the evicted pods in pods, and the
+// FailedScheduling events in events, returning those that happened after since with the newest first.
+func getPressureEvents(pods []corev1.Pod, events []corev1.Event, since time.Time) []PressureEvent {
+	result := make([]PressureEvent, 0)
+
+	for _, pod := range pods {
+		if pod.Status.Reason == eventEvicted {
+			result = append(result, PressureEvent{
+				Type:      eventEvicted,
+				Time:      getEvictionTime(&pod),
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Node:      pod.Spec.NodeName,
+				Message:   pod.Status.Message,
+			})
+		}
+
+		for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+			// A container that was OOMKilled and restarted has it in its last state rather than its current one
+			for _, state := range []corev1.ContainerState{status.State, status.LastTerminationState} {
+				if state.Terminated == nil || state.Terminated.Reason != eventOOMKilled {
+					continue
+				}
+
+				result = append(result, PressureEvent{
+					Type:      eventOOMKilled,
+					Time:      state.Terminated.FinishedAt.Time,
+					Namespace: pod.Namespace,
+					Pod:       pod.Name,
+					Container: status.Name,
+					Node:      pod.Spec.NodeName,
+					Message:   fmt.Sprintf("container exited with code %d after running out of memory", state.Terminated.ExitCode),
+				})
+			}
+		}
+	}
+
+	for _, event := range events {
+		if event.Reason != eventFailedScheduling || event.InvolvedObject.Kind != "Pod" {
+			continue
+		}
+
+		result = append(result, PressureEvent{
+			Type:      eventFailedScheduling,
+			Time:      getEventTime(&event),
+			Namespace: event.InvolvedObject.Namespace,
+			Pod:       event.InvolvedObject.Name,
+			Message:   event.Message,
+		})
+	}
+
+	filtered := make([]PressureEvent, 0, len(result))
+	for _, event := range result {
+		if event.Time.After(since) {
+			filtered = append(filtered, event)
+		}
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Time.After(filtered[j].Time)
+	})
+
+	return filtered
+}
+
+// getEvictionTime returns when a pod was evicted. The kubelet doesn't record this directly, so the time of the
+// DisruptionTarget condition is used if there is one, and otherwise the time the pod was created.
+func getEvictionTime(pod *corev1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget {
+			return condition.LastTransitionTime.Time
+		}
+	}
+
+	return pod.CreationTimestamp.Time
+}
+
+// getEventTime returns the last time an event happened, which is stored in a different field depending on
+// which API created it.
+func getEventTime(event *corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+
+	return event.FirstTimestamp.Time
+}
+
+// getPressureEventsHandler returns a HandlerFunc to return recent resource-pressure events given a Collector.
+// How far back to look is given by the since query parameter as a duration (default 1h), and the events can be
+// filtered with the node and namespace parameters.
+func getPressureEventsHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		since, err := time.ParseDuration(c.DefaultQuery("since", "1h"))
+
+		if err != nil || since <= 0 {
+			c.JSON(http.StatusBadRequest, "error: since must be a positive duration such as 30m or 24h")
+			return
+		}
+
+		events, err := collector.PressureEvents(time.Now().Add(-since))
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving event information")
+			return
+		}
+
+		node, namespace := c.Query("node"), c.Query("namespace")
+		filtered := make([]PressureEvent, 0, len(events))
+
+		for _, event := range events {
+			if (node != "" && event.Node != node) || (namespace != "" && event.Namespace != namespace) {
+				continue
+			}
+
+			filtered = append(filtered, event)
+		}
+
+		c.IndentedJSON(http.StatusOK, filtered)
+	}
+
+	return gin.HandlerFunc(handler)
+}
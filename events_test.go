@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestGetPressureEvents finds an OOMKilled container, an evicted pod, and a FailedScheduling event, checking that
+// old events and other event reasons are left out and that the newest event comes first.
+func TestGetPressureEvents(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	at := func(minutesAgo int) metav1.Time {
+		return metav1.NewTime(now.Add(-time.Duration(minutesAgo) * time.Minute))
+	}
+
+	pods := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "train", Namespace: "vision"},
+			Spec:       v1.PodSpec{NodeName: "node-1"},
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "trainer", LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137, FinishedAt: at(10)}}},
+					{Name: "sidecar", LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Error", FinishedAt: at(5)}}},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+			Spec:       v1.PodSpec{NodeName: "node-2"},
+			Status: v1.PodStatus{
+				Phase:      v1.PodFailed,
+				Reason:     "Evicted",
+				Message:    "The node was low on resource: memory.",
+				Conditions: []v1.PodCondition{{Type: v1.DisruptionTarget, LastTransitionTime: at(20)}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "old", Namespace: "apps"},
+			Status:     v1.PodStatus{Reason: "Evicted", Conditions: []v1.PodCondition{{Type: v1.DisruptionTarget, LastTransitionTime: at(120)}}},
+		},
+	}
+
+	events := []v1.Event{
+		{
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "vision", Name: "notebook"},
+			Reason:         "FailedScheduling",
+			Message:        "0/2 nodes are available: 2 Insufficient nvidia.com/gpu.",
+			LastTimestamp:  at(1),
+		},
+		{
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "vision", Name: "notebook"},
+			Reason:         "Scheduled",
+			LastTimestamp:  at(1),
+		},
+	}
+
+	result := getPressureEvents(pods, events, now.Add(-time.Hour))
+
+	if len(result) != 3 {
+		t.Fatalf(`getPressureEvents() = %v, want 3 events`, result)
+	}
+
+	want := []PressureEvent{
+		{Type: "FailedScheduling", Time: at(1).Time, Namespace: "vision", Pod: "notebook", Message: "0/2 nodes are available: 2 Insufficient nvidia.com/gpu."},
+		{Type: "OOMKilled", Time: at(10).Time, Namespace: "vision", Pod: "train", Container: "trainer", Node: "node-1", Message: "container exited with code 137 after running out of memory"},
+		{Type: "Evicted", Time: at(20).Time, Namespace: "apps", Pod: "web", Node: "node-2", Message: "The node was low on resource: memory."},
+	}
+
+	for i := range want {
+		if result[i] != want[i] {
+			t.Fatalf(`result[%v] = %v, want match for %v`, i, result[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Labels marking a node as part of the control plane, the second of which is used by clusters older than 1.24
+var controlPlaneLabels = []string{"node-role.kubernetes.io/control-plane", "node-role.kubernetes.io/master"}
+
+// NodeExclusion configures the nodes left out of every output and summary, such as control-plane and storage nodes
+// that workloads can't be scheduled on. A node is excluded if it matches any of the rules.
+type NodeExclusion struct {
+	Names         []string `json:"names"`         // Regular expressions matched against the whole node name
+	LabelSelector string   `json:"labelSelector"` // Label selector, e.g. node-role.kubernetes.io/storage
+	ControlPlane  bool     `json:"controlPlane"`  // Exclude nodes with the control-plane role
+}
+
+// nodeExclusion is a NodeExclusion, parsed
+type nodeExclusion struct {
+	names        []*regexp.Regexp
+	selector     labels.Selector // Nil if no selector is set
+	controlPlane bool
+}
+
+// compileNodeExclusion parses the rules of a NodeExclusion, returning nil if it has none.
+func compileNodeExclusion(exclusion NodeExclusion) (*nodeExclusion, error) {
+	if len(exclusion.Names) == 0 && exclusion.LabelSelector == "" && !exclusion.ControlPlane {
+		return nil, nil
+	}
+
+	compiled := &nodeExclusion{names: make([]*regexp.Regexp, 0, len(exclusion.Names)), controlPlane: exclusion.ControlPlane}
+
+	for _, name := range exclusion.Names {
+		pattern, err := regexp.Compile("^(?:" + name + ")$")
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid excluded node name %q: %v", name, err)
+		}
+
+		compiled.names = append(compiled.names, pattern)
+	}
+
+	if exclusion.LabelSelector != "" {
+		selector, err := labels.Parse(exclusion.LabelSelector)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid excluded node label selector: %v", err)
+		}
+
+		compiled.selector = selector
+	}
+
+	return compiled, nil
+}
+
+// isExcludedNode returns whether a node with the given name and labels is configured to be left out of outputs.
+func isExcludedNode(name string, nodeLabels map[string]string) bool {
+	exclusion := currentConfig().excludedNodes
+	if exclusion == nil {
+		return false
+	}
+
+	if exclusion.controlPlane {
+		for _, label := range controlPlaneLabels {
+			if _, ok := nodeLabels[label]; ok {
+				return true
+			}
+		}
+	}
+
+	if exclusion.selector != nil && exclusion.selector.Matches(labels.Set(nodeLabels)) {
+		return true
+	}
+
+	return slices.ContainsFunc(exclusion.names, func(pattern *regexp.Regexp) bool { return pattern.MatchString(name) })
+}
+
+// excludeNodes removes the excluded nodes from a list of nodes, along with the pods scheduled on them.
+func excludeNodes(nodes []corev1.Node, pods []corev1.Pod) ([]corev1.Node, []corev1.Pod) {
+	excluded := make(map[string]bool)
+
+	nodes = slices.DeleteFunc(nodes, func(node corev1.Node) bool {
+		excluded[node.Name] = isExcludedNode(node.Name, node.Labels)
+		return excluded[node.Name]
+	})
+
+	return nodes, excludeNodePods(pods, excluded)
+}
+
+// excludeNodePods removes the pods scheduled on the named nodes from a list of pods.
+func excludeNodePods(pods []corev1.Pod, excluded map[string]bool) []corev1.Pod {
+	if len(excluded) == 0 {
+		return pods
+	}
+
+	return slices.DeleteFunc(pods, func(pod corev1.Pod) bool { return excluded[pod.Spec.NodeName] })
+}
+
+// excludedNodeNames returns the names of the excluded nodes in the cluster. Nodes are only listed if any are
+// configured to be excluded.
+func (c *Collector) excludedNodeNames() (map[string]bool, error) {
+	excluded := make(map[string]bool)
+
+	if currentConfig().excludedNodes == nil {
+		return excluded, nil
+	}
+
+	nodeList, err := c.client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodeList.Items {
+		if isExcludedNode(node.Name, node.Labels) {
+			excluded[node.Name] = true
+		}
+	}
+
+	return excluded, nil
+}
+
+// getNode returns the named node. If the node doesn't exist or is excluded, a NotFound error is returned.
+func (c *Collector) getNode(name string) (*corev1.Node, error) {
+	node, err := c.client.CoreV1().Nodes().Get(context.Background(), name, metav1.GetOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isExcludedNode(node.Name, node.Labels) {
+		return nil, errors.NewNotFound(corev1.Resource("nodes"), name)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestExcludedNodes excludes nodes by name, label, and the control-plane role, checking that they and their pods
+// are left out of snapshots and pod listings, and that invalid rules are rejected.
+func TestExcludedNodes(t *testing.T) {
+	defer runtimeConfig.Store(nil)
+
+	config := `{"excludedNodes": {"names": ["ceph-[0-9]+"], "labelSelector": "role=storage", "controlPlane": true}}`
+	if err := applyRuntimeConfig([]byte(config), func(*RuntimeConfig) {}); err != nil {
+		t.Fatalf(`applyRuntimeConfig() = %v, want match for nil`, err)
+	}
+
+	node := func(name string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	pod := func(name, node string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Spec: corev1.PodSpec{NodeName: node}}
+	}
+
+	client := fake.NewSimpleClientset(
+		node("worker-1", nil),
+		node("ceph-1", nil),
+		node("nfs-1", map[string]string{"role": "storage"}),
+		node("master-1", map[string]string{"node-role.kubernetes.io/control-plane": ""}),
+		node("ceph-1-gpu", nil),
+		pod("web", "worker-1"),
+		pod("osd", "ceph-1"),
+		pod("etcd", "master-1"),
+		pod("pending", ""),
+	)
+	collector := newCollector(client)
+
+	snapshot, err := collector.Snapshot()
+
+	if err != nil {
+		t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+	}
+
+	names := make(map[string]bool)
+	for _, node := range snapshot.Nodes {
+		names[node.Name] = true
+	}
+
+	// Names must match in full, so ceph-1-gpu isn't excluded by ceph-[0-9]+ matching the start of it
+	if len(names) != 2 || !names["worker-1"] || !names["ceph-1-gpu"] {
+		t.Fatalf(`Snapshot().Nodes = %v, want worker-1 and ceph-1-gpu`, names)
+	}
+
+	if len(snapshot.Pods) != 2 {
+		t.Fatalf(`Snapshot().Pods = %v, want web and pending`, snapshot.Pods)
+	}
+
+	if pods, err := collector.Pods(); err != nil || len(pods) != 2 {
+		t.Fatalf(`Pods() = %v, %v, want web and pending`, pods, err)
+	}
+
+	if _, err := collector.NodePods("ceph-1"); !errors.IsNotFound(err) {
+		t.Fatalf(`NodePods() of an excluded node = %v, want a NotFound error`, err)
+	}
+
+	for _, config := range []string{`{"excludedNodes": {"names": ["ceph-("]}}`, `{"excludedNodes": {"labelSelector": "role in (storage"}}`} {
+		if err := applyRuntimeConfig([]byte(config), func(*RuntimeConfig) {}); err == nil {
+			t.Fatalf(`applyRuntimeConfig(%v) = nil, want an error`, config)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// SnapshotExporter writes snapshots as objects to an S3-compatible bucket (S3, GCS, or MinIO)
+type SnapshotExporter struct {
+	client   *minio.Client
+	bucket   string
+	prefix   string
+	format   string
+	interval time.Duration
+	last     time.Time
+}
+
+// newSnapshotExporter returns a SnapshotExporter that writes to bucket on the server at endpoint. Objects are written
+// under prefix in format ("json" or "csv"), at most once per interval. Credentials are read from the standard AWS or
+// MinIO environment variables, or from the pod's IAM role when running with IRSA.
+func newSnapshotExporter(endpoint, region, bucket, prefix, format string, secure bool, interval time.Duration) (*SnapshotExporter, error) {
+	if format != "json" && format != "csv" {
+		return nil, fmt.Errorf("unknown export format %q, expected json or csv", format)
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.IAM{},
+		}),
+		Region: region,
+		Secure: secure,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SnapshotExporter{
+		client:   client,
+		bucket:   bucket,
+		prefix:   prefix,
+		format:   format,
+		interval: interval,
+	}, nil
+}
+
+// Export writes the snapshot to the bucket if at least one interval has passed since the last export.
+// Objects are named by the time of the snapshot, e.g. prefix/2024/08/01/20240801T000000Z.json.
+func (e *SnapshotExporter) Export(snapshot *Snapshot) error {
+	if snapshot.Time.Sub(e.last) < e.interval {
+		return nil
+	}
+
+	data, contentType, err := encodeSnapshot(snapshot, e.format)
+
+	if err != nil {
+		return err
+	}
+
+	t := snapshot.Time.UTC()
+	key := path.Join(e.prefix, t.Format("2006/01/02"), t.Format("20060102T150405Z")+"."+e.format)
+
+	_, err = e.client.PutObject(context.Background(), e.bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	e.last = snapshot.Time
+
+	return nil
+}
+
+// encodeSnapshot encodes a snapshot as JSON or as CSV with one row per node, returning the data and its content type.
+func encodeSnapshot(snapshot *Snapshot, format string) ([]byte, string, error) {
+	if format == "json" {
+		data, err := json.Marshal(snapshot)
+		return data, "application/json", err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{"time", "node"}
+	for _, group := range []string{"allocatable", "capacity", "free"} {
+		for _, resource := range []string{"cpu", "memory", "gpu", "ephemeral"} {
+			header = append(header, group+"_"+resource)
+		}
+	}
+	writer.Write(header)
+
+	for _, node := range snapshot.Nodes {
+		row := []string{snapshot.Time.UTC().Format(time.RFC3339), node.Name}
+
+		for _, resources := range []ResourcesJson{node.Allocatable, node.Capacity, node.Free} {
+			row = append(row,
+				strconv.FormatFloat(resources.Cpu, 'f', -1, 64),
+				strconv.FormatInt(resources.Memory, 10),
+				strconv.FormatInt(resources.Gpu, 10),
+				strconv.FormatInt(resources.Ephemeral, 10),
+			)
+		}
+
+		writer.Write(row)
+	}
+
+	writer.Flush()
+
+	return buf.Bytes(), "text/csv", writer.Error()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+// TestEncodeSnapshot encodes a snapshot as CSV, checking that there is a header row and one row per node
+// with every resource in the expected column.
+func TestEncodeSnapshot(t *testing.T) {
+	snapshot := Snapshot{
+		Time: time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+		Nodes: []NodeJson{
+			{
+				Name:        "node-1",
+				Allocatable: ResourcesJson{Cpu: 6.5, Memory: 4000, Gpu: 2, Ephemeral: 8000},
+				Capacity:    ResourcesJson{Cpu: 8, Memory: 5000, Gpu: 2, Ephemeral: 9000},
+				Free:        ResourcesJson{Cpu: 1.25, Memory: 1000, Gpu: 1, Ephemeral: 7000},
+			},
+		},
+	}
+
+	data, contentType, err := encodeSnapshot(&snapshot, "csv")
+
+	if err != nil {
+		t.Fatalf(`encodeSnapshot returned error %v`, err)
+	}
+
+	if contentType != "text/csv" {
+		t.Fatalf(`contentType = %v, want match for %v`, contentType, "text/csv")
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+
+	if err != nil {
+		t.Fatalf(`csv.ReadAll returned error %v`, err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf(`len(rows) = %v, want match for %v`, len(rows), 2)
+	}
+
+	want := []string{"2024-08-01T00:00:00Z", "node-1", "6.5", "4000", "2", "8000", "8", "5000", "2", "9000", "1.25", "1000", "1", "7000"}
+	for i := range want {
+		if rows[1][i] != want[i] {
+			t.Fatalf(`rows[1][%v] (%v) = %v, want match for %v`, i, rows[0][i], rows[1][i], want[i])
+		}
+	}
+}
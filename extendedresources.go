@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerCollectorPlugin("extended-resources", func(collector *Collector) (CollectorPlugin, error) {
+		return &extendedResourcesPlugin{collector: collector}, nil
+	})
+}
+
+// ExtendedResource is the amount of an extended resource a node has and how much of it pods request
+type ExtendedResource struct {
+	Allocatable int64 `json:"allocatable"`
+	Requested   int64 `json:"requested"`
+	Free        int64 `json:"free"`
+}
+
+// extendedResourcesPlugin adds every extended resource other than GPUs to each node that has any, such as SR-IOV
+// virtual functions, RDMA devices, or software licenses advertised by a device plugin
+type extendedResourcesPlugin struct {
+	collector *Collector
+}
+
+func (p *extendedResourcesPlugin) Name() string {
+	return "extended-resources"
+}
+
+func (p *extendedResourcesPlugin) Collect(ctx context.Context, snapshot *Snapshot) error {
+	nodeList, err := p.collector.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+
+	if err != nil {
+		return err
+	}
+
+	pods, err := p.collector.listPods(nonTerminatedPodSelector)
+
+	if err != nil {
+		return err
+	}
+
+	resources := getExtendedResources(nodeList.Items, pods)
+
+	for i := range snapshot.Nodes {
+		if nodeResources, ok := resources[snapshot.Nodes[i].Name]; ok {
+			setNodeExtra(&snapshot.Nodes[i], p.Name(), nodeResources)
+		}
+	}
+
+	return nil
+}
+
+// isExtendedResource returns whether a resource is an extended resource - one with a domain prefix outside
+// kubernetes.io - that isn't counted as a GPU.
+func isExtendedResource(name string) bool {
+	domain, _, ok := strings.Cut(name, "/")
+	if !ok || domain == "kubernetes.io" || strings.HasSuffix(domain, ".kubernetes.io") {
+		return false
+	}
+
+	return !isGpuResource(name)
+}
+
+// getExtendedResources returns the extended resources of each node that has any, keyed by node and then by
+// resource name, after subtracting the requests of the pods scheduled on it.
+func getExtendedResources(nodes []corev1.Node, pods []corev1.Pod) map[string]map[string]ExtendedResource {
+	resources := make(map[string]map[string]ExtendedResource)
+
+	for _, node := range nodes {
+		for name, quantity := range node.Status.Allocatable {
+			if !isExtendedResource(name.String()) {
+				continue
+			}
+
+			if resources[node.Name] == nil {
+				resources[node.Name] = make(map[string]ExtendedResource)
+			}
+
+			resources[node.Name][name.String()] = ExtendedResource{Allocatable: quantity.Value(), Free: quantity.Value()}
+		}
+	}
+
+	for i := range pods {
+		nodeResources, ok := resources[pods[i].Spec.NodeName]
+		if !ok || !isCountedNamespace(pods[i].Namespace) {
+			continue
+		}
+
+		podReqs, _ := getPodRequestsAndLimits(&pods[i])
+		for name, quantity := range podReqs {
+			resource, ok := nodeResources[name.String()]
+			if !ok {
+				continue
+			}
+
+			resource.Requested += quantity.Value()
+			resource.Free -= quantity.Value()
+			nodeResources[name.String()] = resource
+		}
+	}
+
+	return resources
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Highest score a scheduler extender can give a node
+const maxExtenderScore = 10
+
+// ExtenderArgs is the request kube-scheduler sends to an extender, as in k8s.io/kube-scheduler/extender/v1. Only one
+// of Nodes and NodeNames is set, depending on whether the extender is configured as nodeCacheCapable.
+type ExtenderArgs struct {
+	Pod       *corev1.Pod
+	Nodes     *corev1.NodeList
+	NodeNames *[]string
+}
+
+// ExtenderFilterResult is an extender's response to a filter request, as in k8s.io/kube-scheduler/extender/v1
+type ExtenderFilterResult struct {
+	Nodes                      *corev1.NodeList
+	NodeNames                  *[]string
+	FailedNodes                map[string]string // Reasons the pod can't go on each node, keyed by node name
+	FailedAndUnresolvableNodes map[string]string // As FailedNodes, but for nodes preemption can't help
+	Error                      string
+}
+
+// HostPriority is an extender's score for one node, as in k8s.io/kube-scheduler/extender/v1
+type HostPriority struct {
+	Host  string
+	Score int64
+}
+
+// getExtenderNodeNames returns the names of the candidate nodes of a scheduler extender request.
+func getExtenderNodeNames(args ExtenderArgs) []string {
+	if args.NodeNames != nil {
+		return *args.NodeNames
+	}
+
+	names := make([]string, 0)
+	if args.Nodes != nil {
+		for _, node := range args.Nodes.Items {
+			names = append(names, node.Name)
+		}
+	}
+
+	return names
+}
+
+// getExtenderFilter returns the reason a pod can't go on each candidate node that it doesn't tolerate or match, or
+// that doesn't have room for its requests. Nodes missing from the snapshot, such as ones that have just joined, are
+// left for the scheduler to decide on.
+func getExtenderFilter(pod *corev1.Pod, names []string, nodes []NodeJson) map[string]string {
+	byName := make(map[string]NodeJson, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+
+	requests := getPodStructured(pod).Requests
+	failed := make(map[string]string)
+
+	for _, name := range names {
+		node, ok := byName[name]
+
+		switch {
+		case !ok:
+			continue
+		case !canSchedule(pod, node):
+			failed[name] = "pod doesn't tolerate the node's taints or match its labels"
+		case !fitsIn(requests, maxResources(node.Free, ResourcesJson{})):
+			failed[name] = fmt.Sprintf("not enough free resources: cpu %v, memory %v, and gpu %v are free", node.Free.Cpu, node.Free.Memory, node.Free.Gpu)
+		}
+	}
+
+	return failed
+}
+
+// getExtenderScore scores a node for a pod from 0 to maxExtenderScore. Pods that don't need GPUs score 0 on nodes
+// with GPUs, so GPU nodes are kept for pods that need them. Otherwise nodes score by how much of their most-used
+// requested resource would be allocated with the pod on them, packing pods onto busy nodes so that free capacity
+// isn't fragmented across many partly used ones.
+func getExtenderScore(requests ResourcesJson, node NodeJson) int64 {
+	if requests.Gpu == 0 && node.Allocatable.Gpu > 0 {
+		return 0
+	}
+
+	utilization := 0.0
+
+	for _, name := range resourceNames {
+		allocatable := getResource(node.Allocatable, name)
+		if getResource(requests, name) <= 0 || allocatable <= 0 {
+			continue
+		}
+
+		used := allocatable - getResource(node.Free, name) + getResource(requests, name)
+		utilization = max(utilization, min(used/allocatable, 1))
+	}
+
+	return int64(math.Round(utilization * maxExtenderScore))
+}
+
+// getExtenderFilterHandler returns a HandlerFunc for kube-scheduler's extender filter calls given a CachedSnapshot.
+func getExtenderFilterHandler(snapshots *CachedSnapshot) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var args ExtenderArgs
+
+		if err := c.ShouldBindJSON(&args); err != nil || args.Pod == nil {
+			c.JSON(http.StatusBadRequest, ExtenderFilterResult{Error: "expected ExtenderArgs with a pod"})
+			return
+		}
+
+		snapshot, err := snapshots.Get()
+
+		// Errors are returned to the scheduler in the result, which fails scheduling unless the extender is ignorable
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusOK, ExtenderFilterResult{Error: "error retrieving node information"})
+			return
+		}
+
+		failed := getExtenderFilter(args.Pod, getExtenderNodeNames(args), snapshot.Nodes)
+		result := ExtenderFilterResult{FailedNodes: failed, FailedAndUnresolvableNodes: map[string]string{}}
+
+		// Respond in the same form as the request
+		if args.NodeNames != nil {
+			names := make([]string, 0)
+			for _, name := range *args.NodeNames {
+				if _, ok := failed[name]; !ok {
+					names = append(names, name)
+				}
+			}
+
+			result.NodeNames = &names
+		} else if args.Nodes != nil {
+			result.Nodes = &corev1.NodeList{Items: make([]corev1.Node, 0)}
+			for _, node := range args.Nodes.Items {
+				if _, ok := failed[node.Name]; !ok {
+					result.Nodes.Items = append(result.Nodes.Items, node)
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getExtenderPrioritizeHandler returns a HandlerFunc for kube-scheduler's extender prioritize calls given a
+// CachedSnapshot. Nodes missing from the snapshot score 0.
+func getExtenderPrioritizeHandler(snapshots *CachedSnapshot) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var args ExtenderArgs
+
+		if err := c.ShouldBindJSON(&args); err != nil || args.Pod == nil {
+			c.JSON(http.StatusBadRequest, "error: expected ExtenderArgs with a pod")
+			return
+		}
+
+		snapshot, err := snapshots.Get()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		byName := make(map[string]NodeJson, len(snapshot.Nodes))
+		for _, node := range snapshot.Nodes {
+			byName[node.Name] = node
+		}
+
+		requests := getPodStructured(args.Pod).Requests
+		priorities := make([]HostPriority, 0)
+
+		for _, name := range getExtenderNodeNames(args) {
+			priority := HostPriority{Host: name}
+
+			if node, ok := byName[name]; ok {
+				priority.Score = getExtenderScore(requests, node)
+			}
+
+			priorities = append(priorities, priority)
+		}
+
+		c.JSON(http.StatusOK, priorities)
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// TestGetExtenderFilter checks that nodes without room for a pod, or with taints it doesn't tolerate, are filtered
+// out, and that nodes missing from the snapshot are left for the scheduler.
+func TestGetExtenderFilter(t *testing.T) {
+	nodes := []NodeJson{
+		{Name: "full", Free: ResourcesJson{Cpu: 1}},
+		{Name: "tainted", Free: ResourcesJson{Cpu: 8}, Taints: []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}}},
+		{Name: "free", Free: ResourcesJson{Cpu: 8}},
+	}
+
+	pod := newTestPod("web", "2", nil)
+	failed := getExtenderFilter(&pod, []string{"full", "tainted", "free", "new"}, nodes)
+
+	switch {
+	case len(failed) != 2:
+		t.Fatalf(`failed = %v, want match for %v`, failed, "full and tainted")
+	case failed["full"] == "" || failed["tainted"] == "":
+		t.Fatalf(`failed = %v, want match for %v`, failed, "full and tainted")
+	}
+}
+
+// TestGetExtenderScore checks that busier nodes score higher, and that GPU nodes score 0 for pods without GPUs.
+func TestGetExtenderScore(t *testing.T) {
+	requests := ResourcesJson{Cpu: 2}
+	busy := NodeJson{Allocatable: ResourcesJson{Cpu: 10}, Free: ResourcesJson{Cpu: 4}}
+	idle := NodeJson{Allocatable: ResourcesJson{Cpu: 10}, Free: ResourcesJson{Cpu: 10}}
+	gpu := NodeJson{Allocatable: ResourcesJson{Cpu: 10, Gpu: 4}, Free: ResourcesJson{Cpu: 4, Gpu: 4}}
+
+	gpuPod := newTestPod("train", "2", nil)
+	gpuPod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+	gpuRequests := getPodStructured(&gpuPod).Requests
+
+	switch {
+	case getExtenderScore(requests, busy) != 8:
+		t.Fatalf(`getExtenderScore(busy) = %v, want match for %v`, getExtenderScore(requests, busy), 8)
+	case getExtenderScore(requests, idle) != 2:
+		t.Fatalf(`getExtenderScore(idle) = %v, want match for %v`, getExtenderScore(requests, idle), 2)
+	case getExtenderScore(requests, gpu) != 0:
+		t.Fatalf(`getExtenderScore(gpu) = %v, want match for %v`, getExtenderScore(requests, gpu), 0)
+	case getExtenderScore(gpuRequests, gpu) != 8:
+		t.Fatalf(`getExtenderScore(gpu) for a GPU pod = %v, want match for %v`, getExtenderScore(gpuRequests, gpu), 8)
+	}
+}
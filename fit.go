@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// FitShape is a pod shape to place, with the number of replicas of it
+type FitShape struct {
+	Name         string            `json:"name"`
+	Requests     map[string]string `json:"requests"`               // Quantities keyed by cpu, memory, gpu, or ephemeral
+	Replicas     int               `json:"replicas"`               // Defaults to 1
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"` // Labels a node needs to have for the pods to be placed on it
+}
+
+// FitRequest is the body of a batch fit-check request
+type FitRequest struct {
+	Shapes    []FitShape `json:"shapes"`
+	Namespace string     `json:"namespace,omitempty"` // If set, the namespace's ResourceQuotas and LimitRanges are checked too
+}
+
+// ShapeFitResult says how many replicas of a shape could be placed, and on which nodes
+type ShapeFitResult struct {
+	Name     string         `json:"name"`
+	Replicas int            `json:"replicas"`
+	Placed   int            `json:"placed"`
+	Fits     bool           `json:"fits"`
+	Nodes    map[string]int `json:"nodes"`
+}
+
+// FitResult says whether every replica of every shape fits in the cluster at once, and what would be free after
+type FitResult struct {
+	Fits                 bool             `json:"fits"`
+	Verdict              string           `json:"verdict"` // One of the fitVerdict constants
+	Shapes               []ShapeFitResult `json:"shapes"`
+	Remaining            ResourcesJson    `json:"remaining"`
+	QuotaShortfalls      []QuotaShortfall `json:"quotaShortfalls,omitempty"`
+	LimitRangeViolations []string         `json:"limitRangeViolations,omitempty"`
+}
+
+// parseFitRequests converts the quantities of a shape's requests to numbers.
+func parseFitRequests(shape FitShape) (ResourcesJson, error) {
+	requests, err := parseQuantities(shape.Requests)
+	if err != nil {
+		return requests, fmt.Errorf("invalid requests of shape %q: %v", shape.Name, err)
+	}
+
+	return requests, nil
+}
+
+// parseQuantities converts quantities keyed by cpu, memory, gpu, or ephemeral to numbers.
+func parseQuantities(values map[string]string) (ResourcesJson, error) {
+	var resources ResourcesJson
+
+	for name, value := range values {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return resources, fmt.Errorf("invalid %v quantity: %v", name, err)
+		}
+
+		switch name {
+		case "cpu":
+			resources.Cpu = quantity.AsApproximateFloat64()
+		case "memory", "gpu", "ephemeral":
+			setResource(&resources, name, float64(quantity.Value()))
+		default:
+			return resources, fmt.Errorf("unknown resource %q", name)
+		}
+	}
+
+	return resources, nil
+}
+
+// fitsIn returns whether every resource in requests is available in free.
+func fitsIn(requests, free ResourcesJson) bool {
+	for _, name := range resourceNames {
+		if getResource(requests, name) > getResource(free, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// subtractResources returns the difference of each field of a and b.
+func subtractResources(a, b ResourcesJson) ResourcesJson {
+	return ResourcesJson{
+		Cpu:       a.Cpu - b.Cpu,
+		Memory:    a.Memory - b.Memory,
+		Gpu:       a.Gpu - b.Gpu,
+		Ephemeral: a.Ephemeral - b.Ephemeral,
+	}
+}
+
+// matchesSelector returns whether a node has every label in selector.
+func matchesSelector(node NodeJson, selector map[string]string) bool {
+	for key, value := range selector {
+		if node.Labels[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getBatchFit places every replica of every shape on the nodes' free resources together, as the scheduler would if
+// they were all created at once. The largest shapes are placed first, each replica on the first node it fits on,
+// which keeps big pods from being crowded out by small ones. Taints are not taken into account.
+func getBatchFit(nodes []NodeJson, shapes []FitShape, requests []ResourcesJson) FitResult {
+	free := make([]ResourcesJson, len(nodes))
+	for i, node := range nodes {
+		free[i] = maxResources(node.Free, ResourcesJson{})
+	}
+
+	// Place GPUs first since they are scarcest, then CPU, then memory
+	order := make([]int, len(shapes))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := requests[order[i]], requests[order[j]]
+		if a.Gpu != b.Gpu {
+			return a.Gpu > b.Gpu
+		}
+		if a.Cpu != b.Cpu {
+			return a.Cpu > b.Cpu
+		}
+		return a.Memory > b.Memory
+	})
+
+	result := FitResult{Fits: true, Shapes: make([]ShapeFitResult, len(shapes))}
+
+	for _, i := range order {
+		shape := shapes[i]
+		shapeResult := ShapeFitResult{Name: shape.Name, Replicas: shape.Replicas, Nodes: make(map[string]int)}
+
+		for replica := 0; replica < shape.Replicas; replica++ {
+			for j, node := range nodes {
+				if !matchesSelector(node, shape.NodeSelector) || !fitsIn(requests[i], free[j]) {
+					continue
+				}
+
+				free[j] = subtractResources(free[j], requests[i])
+				shapeResult.Nodes[node.Name]++
+				shapeResult.Placed++
+				break
+			}
+		}
+
+		shapeResult.Fits = shapeResult.Placed == shapeResult.Replicas
+		result.Fits = result.Fits && shapeResult.Fits
+		result.Shapes[i] = shapeResult
+	}
+
+	for _, remaining := range free {
+		result.Remaining = addResources(result.Remaining, remaining)
+	}
+
+	return result
+}
+
+// getBatchFitHandler returns a HandlerFunc to check whether a batch of pod shapes, given as a FitRequest in the
+// request body, fits in the cluster at once given a Collector, and in the request's namespace if it has one.
+func getBatchFitHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var request FitRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		requests := make([]ResourcesJson, len(request.Shapes))
+
+		for i := range request.Shapes {
+			if request.Shapes[i].Replicas == 0 {
+				request.Shapes[i].Replicas = 1
+			}
+
+			if request.Shapes[i].Replicas < 0 {
+				c.JSON(http.StatusBadRequest, fmt.Sprintf("error: replicas of shape %q must not be negative", request.Shapes[i].Name))
+				return
+			}
+
+			shapeRequests, err := parseFitRequests(request.Shapes[i])
+
+			if err != nil {
+				c.JSON(http.StatusBadRequest, "error: "+err.Error())
+				return
+			}
+
+			requests[i] = shapeRequests
+		}
+
+		var violations []string
+		var shortfalls []QuotaShortfall
+
+		// Check the namespace's constraints first, since LimitRange defaults change the requests to place
+		if request.Namespace != "" {
+			if collector.imported != nil {
+				c.JSON(http.StatusBadRequest, "error: namespaces can't be checked against an imported snapshot")
+				return
+			}
+
+			limitRanges, err := collector.LimitRanges(request.Namespace)
+
+			if err != nil {
+				fmt.Println(err)
+				c.JSON(http.StatusInternalServerError, "error retrieving LimitRange information")
+				return
+			}
+
+			quotas, err := collector.UnscopedQuotas(request.Namespace)
+
+			if err != nil {
+				fmt.Println(err)
+				c.JSON(http.StatusInternalServerError, "error retrieving ResourceQuota information")
+				return
+			}
+
+			violations = applyFitLimitRanges(request.Shapes, requests, limitRanges)
+			shortfalls = getQuotaShortfalls(quotas, request.Shapes, requests)
+		}
+
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		// Place on nodes in a consistent order so the same request gets the same answer
+		sort.Slice(snapshot.Nodes, func(i, j int) bool {
+			return snapshot.Nodes[i].Name < snapshot.Nodes[j].Name
+		})
+
+		result := getBatchFit(snapshot.Nodes, request.Shapes, requests)
+		result.LimitRangeViolations = violations
+		result.QuotaShortfalls = shortfalls
+		result.Verdict = getFitVerdict(result)
+
+		c.IndentedJSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(handler)
+}
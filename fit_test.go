@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+// TestGetBatchFit places a GPU shape and a CPU shape on two nodes, checking that the GPU shape is placed first
+// even though it is listed second, that node selectors are respected, and that the remaining resources are right.
+func TestGetBatchFit(t *testing.T) {
+	nodes := []NodeJson{
+		{Name: "cpu-1", Labels: map[string]string{"pool": "cpu"}, Free: ResourcesJson{Cpu: 4, Memory: 16}},
+		{Name: "gpu-1", Labels: map[string]string{"pool": "gpu"}, Free: ResourcesJson{Cpu: 10, Memory: 32, Gpu: 2}},
+	}
+
+	shapes := []FitShape{
+		{Name: "web", Replicas: 3},
+		{Name: "trainer", Replicas: 2, NodeSelector: map[string]string{"pool": "gpu"}},
+	}
+
+	requests := []ResourcesJson{
+		{Cpu: 2, Memory: 4},
+		{Cpu: 4, Memory: 8, Gpu: 1},
+	}
+
+	result := getBatchFit(nodes, shapes, requests)
+
+	switch {
+	case !result.Fits:
+		t.Fatalf(`result.Fits = %v, want match for %v`, result.Fits, true)
+	case result.Shapes[1].Nodes["gpu-1"] != 2:
+		t.Fatalf(`result.Shapes[1].Nodes = %v, want both trainers on gpu-1`, result.Shapes[1].Nodes)
+	case result.Shapes[0].Nodes["cpu-1"] != 2 || result.Shapes[0].Placed != 3:
+		t.Fatalf(`result.Shapes[0] = %v, want 3 placed with 2 on cpu-1`, result.Shapes[0])
+	case result.Remaining != (ResourcesJson{Cpu: 0, Memory: 20}):
+		t.Fatalf(`result.Remaining = %v, want match for %v`, result.Remaining, ResourcesJson{Cpu: 0, Memory: 20})
+	}
+
+	// A fourth web replica has nowhere to go
+	shapes[0].Replicas = 4
+	result = getBatchFit(nodes, shapes, requests)
+
+	if result.Fits || result.Shapes[0].Fits || result.Shapes[0].Placed != 3 || !result.Shapes[1].Fits {
+		t.Fatalf(`result = %v, want only 3 of 4 web replicas placed`, result)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Verdicts of a fit check, in the order they are checked
+const (
+	fitVerdictLimitRange = "blocked by limit range" // The API server would reject the pods
+	fitVerdictQuota      = "blocked by quota"       // The pods would exceed the namespace's ResourceQuota
+	fitVerdictNoNode     = "no node fits"           // Some replicas have no node with room for them
+	fitVerdictOk         = "ok"
+)
+
+// QuotaShortfall is a resource of a ResourceQuota that doesn't have room for every replica of every shape
+type QuotaShortfall struct {
+	Quota     string  `json:"quota"`
+	Resource  string  `json:"resource"`  // Resource name as written in the quota, e.g. requests.cpu
+	Requested float64 `json:"requested"` // Total of every replica of every shape
+	Remaining float64 `json:"remaining"` // Hard limit minus what is already used
+}
+
+// getFitResourceName returns the name of a Kubernetes resource in ResourcesJson, or false if it isn't one of them.
+func getFitResourceName(name string) (string, bool) {
+	switch {
+	case name == string(corev1.ResourceCPU):
+		return "cpu", true
+	case name == string(corev1.ResourceMemory):
+		return "memory", true
+	case name == string(corev1.ResourceEphemeralStorage):
+		return "ephemeral", true
+	case isGpuResource(name):
+		return "gpu", true
+	}
+
+	return "", false
+}
+
+// applyFitLimitRanges sets the resources each shape doesn't request to the default request of the namespace's
+// LimitRanges, as the API server would, and returns why the API server would reject any of the shapes for being
+// outside a LimitRange's minimum or maximum. Each shape is treated as a pod with a single container.
+func applyFitLimitRanges(shapes []FitShape, requests []ResourcesJson, limitRanges []corev1.LimitRange) []string {
+	violations := make([]string, 0)
+	defaults := make(map[string]float64)
+
+	for _, list := range getDefaultRequests(limitRanges) {
+		for name, quantity := range list {
+			if fitName, ok := getFitResourceName(string(name)); ok {
+				defaults[fitName] = quantity.AsApproximateFloat64()
+			}
+		}
+	}
+
+	for i, shape := range shapes {
+		for name, value := range defaults {
+			if _, ok := shape.Requests[name]; !ok {
+				setResource(&requests[i], name, value)
+			}
+		}
+
+		for _, limitRange := range limitRanges {
+			for _, item := range limitRange.Spec.Limits {
+				if item.Type != corev1.LimitTypeContainer && item.Type != corev1.LimitTypePod {
+					continue
+				}
+
+				for name, quantity := range item.Min {
+					fitName, ok := getFitResourceName(string(name))
+					if ok && getResource(requests[i], fitName) < quantity.AsApproximateFloat64() {
+						violations = append(violations, fmt.Sprintf("shape %q requests %v %v, below the %v minimum of %v in LimitRange %v",
+							shape.Name, getResource(requests[i], fitName), name, strings.ToLower(string(item.Type)), quantity.String(), limitRange.Name))
+					}
+				}
+
+				// Limits are at least the requests, so requests above the maximum limit mean limits above it too
+				for name, quantity := range item.Max {
+					fitName, ok := getFitResourceName(string(name))
+					if ok && getResource(requests[i], fitName) > quantity.AsApproximateFloat64() {
+						violations = append(violations, fmt.Sprintf("shape %q requests %v %v, above the %v maximum of %v in LimitRange %v",
+							shape.Name, getResource(requests[i], fitName), name, strings.ToLower(string(item.Type)), quantity.String(), limitRange.Name))
+					}
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// getQuotaShortfalls returns the resources of the given quotas that don't have room for every replica of every
+// shape. Quotas on requests and on pod counts are checked. Quotas on limits can't be, since shapes have no limits.
+func getQuotaShortfalls(quotas []QuotaJson, shapes []FitShape, requests []ResourcesJson) []QuotaShortfall {
+	var total ResourcesJson
+	pods := 0
+
+	for i, shape := range shapes {
+		for range shape.Replicas {
+			total = addResources(total, requests[i])
+		}
+
+		pods += shape.Replicas
+	}
+
+	shortfalls := make([]QuotaShortfall, 0)
+
+	for _, quota := range quotas {
+		for name, usage := range quota.Resources {
+			var requested float64
+
+			if name == string(corev1.ResourcePods) {
+				requested = float64(pods)
+			} else if fitName, ok := getFitResourceName(strings.TrimPrefix(name, "requests.")); ok {
+				requested = getResource(total, fitName)
+			} else {
+				continue
+			}
+
+			if remaining := usage.Hard - usage.Used; requested > remaining {
+				shortfalls = append(shortfalls, QuotaShortfall{Quota: quota.Name, Resource: name, Requested: requested, Remaining: remaining})
+			}
+		}
+	}
+
+	return shortfalls
+}
+
+// getFitVerdict returns the single verdict of a fit check, from the most fundamental reason the pods can't run.
+func getFitVerdict(result FitResult) string {
+	switch {
+	case len(result.LimitRangeViolations) > 0:
+		return fitVerdictLimitRange
+	case len(result.QuotaShortfalls) > 0:
+		return fitVerdictQuota
+	case !result.Fits:
+		return fitVerdictNoNode
+	}
+
+	return fitVerdictOk
+}
+
+// UnscopedQuotas returns the ResourceQuotas in namespace that apply to every pod. Quotas with scopes, such as ones
+// for a PriorityClass, are left out since whether they apply depends on more than a pod's requests.
+func (c *Collector) UnscopedQuotas(namespace string) ([]QuotaJson, error) {
+	quotaList, err := c.client.CoreV1().ResourceQuotas(namespace).List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	quotas := make([]QuotaJson, 0, len(quotaList.Items))
+	for i := range quotaList.Items {
+		if len(quotaList.Items[i].Spec.Scopes) == 0 && quotaList.Items[i].Spec.ScopeSelector == nil {
+			quotas = append(quotas, getQuotaStructured(&quotaList.Items[i]))
+		}
+	}
+
+	return quotas, nil
+}
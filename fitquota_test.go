@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestFitVerdict checks batches against a namespace with a GPU quota and a LimitRange, checking the verdict for
+// batches that fit, that the quota or LimitRange blocks, and that no node has room for.
+func TestFitVerdict(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu-1"},
+		Status: corev1.NodeStatus{
+			Capacity:    corev1.ResourceList{"cpu": resource.MustParse("16"), "memory": resource.MustParse("64Gi"), "nvidia.com/gpu": resource.MustParse("8")},
+			Allocatable: corev1.ResourceList{"cpu": resource.MustParse("16"), "memory": resource.MustParse("64Gi"), "nvidia.com/gpu": resource.MustParse("8")},
+		},
+	}
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpus", Namespace: "ml"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("4"), "pods": resource.MustParse("10")},
+			Used: corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("2"), "pods": resource.MustParse("1")},
+		},
+	}
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "ml"},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type:           corev1.LimitTypeContainer,
+			DefaultRequest: corev1.ResourceList{"cpu": resource.MustParse("4")},
+			Max:            corev1.ResourceList{"memory": resource.MustParse("32Gi")},
+		}}},
+	}
+
+	router := gin.New()
+	router.POST("/fit/batch", getBatchFitHandler(newCollector(fake.NewSimpleClientset(node, quota, limitRange))))
+
+	for _, test := range []struct {
+		body    string
+		verdict string
+	}{
+		{`{"namespace": "ml", "shapes": [{"name": "train", "requests": {"gpu": "1"}, "replicas": 2}]}`, fitVerdictOk},
+		{`{"namespace": "ml", "shapes": [{"name": "train", "requests": {"gpu": "1"}, "replicas": 3}]}`, fitVerdictQuota},
+		{`{"namespace": "ml", "shapes": [{"name": "train", "requests": {"memory": "48Gi"}}]}`, fitVerdictLimitRange},
+		// The default request of 4 CPUs only leaves room for 4 replicas, but the quota has room for 9 pods
+		{`{"namespace": "ml", "shapes": [{"name": "web", "requests": {"memory": "1Gi"}, "replicas": 5}]}`, fitVerdictNoNode},
+		{`{"shapes": [{"name": "web", "requests": {"memory": "1Gi"}, "replicas": 5}]}`, fitVerdictOk},
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/fit/batch", bytes.NewBufferString(test.body)))
+
+		var result FitResult
+		json.Unmarshal(recorder.Body.Bytes(), &result)
+
+		if recorder.Code != http.StatusOK || result.Verdict != test.verdict {
+			t.Fatalf(`POST /fit/batch %v = %v, %v, want match for %v, %v`, test.body, recorder.Code, result, http.StatusOK, test.verdict)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PoolForecast projects the requests of a resource in a node pool forward from a linear trend fitted to its history
+type PoolForecast struct {
+	Pool          string     `json:"pool"`
+	Resource      string     `json:"resource"`
+	Allocatable   float64    `json:"allocatable"`
+	Requested     float64    `json:"requested"`
+	TrendPerDay   float64    `json:"trendPerDay"`
+	Projected     float64    `json:"projected"`
+	ExhaustedAt   *time.Time `json:"exhaustedAt"`
+	HistoryPoints int        `json:"historyPoints"`
+}
+
+// getPoolRequestHistory sums the requested amount of a resource (allocatable minus free) over the nodes of each pool
+// for every hour, given the history of each node and the pool each node is in. Points within the same hour are
+// averaged per node first, so nodes with more frequent snapshots don't count more.
+func getPoolRequestHistory(history map[string][]HistoryPoint, pools map[string]string, resource string) map[string]map[time.Time]float64 {
+	result := make(map[string]map[time.Time]float64)
+
+	for node, points := range history {
+		pool, ok := pools[node]
+		if !ok {
+			continue
+		}
+
+		sums := make(map[time.Time]float64)
+		counts := make(map[time.Time]int)
+
+		for _, point := range points {
+			hour := point.Time.Truncate(time.Hour)
+			sums[hour] += getResource(point.Allocatable, resource) - getResource(point.Free, resource)
+			counts[hour]++
+		}
+
+		if _, ok := result[pool]; !ok {
+			result[pool] = make(map[time.Time]float64)
+		}
+
+		for hour, sum := range sums {
+			result[pool][hour] += sum / float64(counts[hour])
+		}
+	}
+
+	return result
+}
+
+// fitLinearTrend fits a straight line to a series of values by least squares and returns its change per hour.
+// With fewer than two points, the trend is flat.
+func fitLinearTrend(series map[time.Time]float64) float64 {
+	if len(series) < 2 {
+		return 0
+	}
+
+	// Measure time in hours from an arbitrary point in the series to keep the numbers small
+	var origin time.Time
+	for t := range series {
+		origin = t
+		break
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for t, y := range series {
+		x := t.Sub(origin).Hours()
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	n := float64(len(series))
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+}
+
+// getForecasts projects the requests of resource in every pool horizon past now, and estimates when each pool
+// will run out, given the current nodes and the history of each node. A pool only has an exhaustion time if its
+// trend reaches its current allocatable resources within the horizon.
+func getForecasts(nodes []NodeJson, history map[string][]HistoryPoint, resource string, now time.Time, horizon time.Duration) []PoolForecast {
+	pools := make(map[string]string)
+	forecasts := make(map[string]*PoolForecast)
+
+	for _, node := range nodes {
+		pools[node.Name] = node.Pool
+
+		if _, ok := forecasts[node.Pool]; !ok {
+			forecasts[node.Pool] = &PoolForecast{Pool: node.Pool, Resource: resource}
+		}
+
+		forecasts[node.Pool].Allocatable += getResource(node.Allocatable, resource)
+		forecasts[node.Pool].Requested += getResource(node.Allocatable, resource) - getResource(node.Free, resource)
+	}
+
+	// Pools without any history have a flat trend
+	trends := make(map[string]float64)
+	for pool, series := range getPoolRequestHistory(history, pools, resource) {
+		trends[pool] = fitLinearTrend(series)
+		forecasts[pool].HistoryPoints = len(series)
+	}
+
+	result := make([]PoolForecast, 0, len(forecasts))
+
+	for _, forecast := range forecasts {
+		perHour := trends[forecast.Pool]
+		forecast.TrendPerDay = perHour * 24
+
+		// Project from what is requested now rather than from the fitted line
+		forecast.Projected = forecast.Requested + perHour*horizon.Hours()
+
+		if forecast.Allocatable > 0 && forecast.Requested >= forecast.Allocatable {
+			exhausted := now
+			forecast.ExhaustedAt = &exhausted
+		} else if perHour > 0 {
+			hours := (forecast.Allocatable - forecast.Requested) / perHour
+			if hours <= horizon.Hours() {
+				exhausted := now.Add(time.Duration(hours * float64(time.Hour)))
+				forecast.ExhaustedAt = &exhausted
+			}
+		}
+
+		result = append(result, *forecast)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Pool < result[j].Pool
+	})
+
+	return result
+}
+
+// parseDays parses a duration that may also be given in days, such as 14d, which time.ParseDuration doesn't support.
+func parseDays(text string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(text, "d"); found {
+		count, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(count * 24 * float64(time.Hour)), nil
+	}
+
+	return time.ParseDuration(text)
+}
+
+// getForecastHandler returns a HandlerFunc to return the forecast of every node pool given a Collector and a
+// source of node history. The resource is given by the resource query parameter (default cpu), how far ahead to
+// project by the horizon parameter (default 14d), and how much history to fit the trend to by the window
+// parameter (default 30d).
+func getForecastHandler(collector *Collector, history HistoryBackend) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		resource := c.DefaultQuery("resource", "cpu")
+
+		if !slices.Contains(resourceNames, resource) {
+			c.JSON(http.StatusBadRequest, "error: resource must be one of cpu, memory, gpu, or ephemeral")
+			return
+		}
+
+		horizon, err := parseDays(c.DefaultQuery("horizon", "14d"))
+
+		if err != nil || horizon <= 0 {
+			c.JSON(http.StatusBadRequest, "error: horizon must be a positive duration such as 14d or 48h")
+			return
+		}
+
+		window, err := parseDays(c.DefaultQuery("window", "30d"))
+
+		if err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, "error: window must be a positive duration such as 30d or 48h")
+			return
+		}
+
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		nodeHistory := make(map[string][]HistoryPoint)
+
+		for _, node := range snapshot.Nodes {
+			points, err := history.Query(node.Name, snapshot.Time.Add(-window), snapshot.Time)
+
+			if err != nil {
+				fmt.Println(err)
+				c.JSON(http.StatusInternalServerError, "error retrieving node history")
+				return
+			}
+
+			nodeHistory[node.Name] = points
+		}
+
+		c.IndentedJSON(http.StatusOK, getForecasts(snapshot.Nodes, nodeHistory, resource, snapshot.Time, horizon))
+	}
+
+	return gin.HandlerFunc(handler)
+}
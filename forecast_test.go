@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestGetForecasts projects a pool whose GPU requests grow by one a day and a pool with no history, checking the
+// trend, the projection, and the exhaustion time.
+func TestGetForecasts(t *testing.T) {
+	now := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+
+	nodes := []NodeJson{
+		{Name: "gpu-1", Pool: "gpu", Allocatable: ResourcesJson{Gpu: 8}, Free: ResourcesJson{Gpu: 2}},
+		{Name: "gpu-2", Pool: "gpu", Allocatable: ResourcesJson{Gpu: 8}, Free: ResourcesJson{Gpu: 8}},
+		{Name: "cpu-1", Pool: "cpu", Allocatable: ResourcesJson{Gpu: 0}},
+	}
+
+	// gpu-1 has had one more GPU requested every day for the last 6 days, with two snapshots in each hour
+	history := map[string][]HistoryPoint{}
+	for day := 0; day <= 6; day++ {
+		at := now.Add(-time.Duration(6-day) * 24 * time.Hour)
+		for _, offset := range []time.Duration{0, 30 * time.Minute} {
+			history["gpu-1"] = append(history["gpu-1"], HistoryPoint{
+				Time:        at.Add(offset),
+				Allocatable: ResourcesJson{Gpu: 8},
+				Free:        ResourcesJson{Gpu: int64(8 - day)},
+			})
+		}
+	}
+
+	forecasts := getForecasts(nodes, history, "gpu", now, 14*24*time.Hour)
+
+	if len(forecasts) != 2 || forecasts[0].Pool != "cpu" || forecasts[1].Pool != "gpu" {
+		t.Fatalf(`getForecasts() = %v, want pools cpu and gpu`, forecasts)
+	}
+
+	cpu, gpu := forecasts[0], forecasts[1]
+
+	switch {
+	case cpu.TrendPerDay != 0 || cpu.ExhaustedAt != nil || cpu.HistoryPoints != 0:
+		t.Fatalf(`cpu = %v, want flat trend with no history`, cpu)
+	case gpu.HistoryPoints != 7 || gpu.Requested != 6:
+		t.Fatalf(`gpu.HistoryPoints, gpu.Requested = %v, %v, want match for %v, %v`, gpu.HistoryPoints, gpu.Requested, 7, 6)
+	case math.Abs(gpu.TrendPerDay-1) > 1e-9 || math.Abs(gpu.Projected-20) > 1e-9:
+		t.Fatalf(`gpu.TrendPerDay, gpu.Projected = %v, %v, want match for %v, %v`, gpu.TrendPerDay, gpu.Projected, 1, 20)
+	case gpu.ExhaustedAt == nil || gpu.ExhaustedAt.Sub(now.Add(10*24*time.Hour)).Abs() > time.Second:
+		t.Fatalf(`gpu.ExhaustedAt = %v, want match for %v`, gpu.ExhaustedAt, now.Add(10*24*time.Hour))
+	}
+}
+
+// TestParseDays checks that durations can be given in days or as Go durations.
+func TestParseDays(t *testing.T) {
+	tests := map[string]time.Duration{
+		"14d":  14 * 24 * time.Hour,
+		"0.5d": 12 * time.Hour,
+		"48h":  48 * time.Hour,
+	}
+
+	for text, want := range tests {
+		if have, err := parseDays(text); err != nil || have != want {
+			t.Fatalf(`parseDays(%q) = %v, %v, want match for %v, nil`, text, have, err, want)
+		}
+	}
+
+	if _, err := parseDays("twod"); err == nil {
+		t.Fatalf(`parseDays("twod") returned no error`)
+	}
+}
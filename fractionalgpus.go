@@ -0,0 +1,167 @@
+package main
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Labels set by GPU feature discovery to the number of GPUs of a node and the memory of each, in MiB
+const (
+	gpuCountLabel  = "nvidia.com/gpu.count"
+	gpuMemoryLabel = "nvidia.com/gpu.memory"
+)
+
+// Cores of a whole GPU, as GPU sharing schedulers request compute in percent of a GPU
+const coresPerGpu = 100
+
+// FractionalGpuScheme describes the resources a GPU sharing scheduler, such as HAMi or Volcano's vGPU plugin, lets
+// pods request fractions of GPUs with
+type FractionalGpuScheme struct {
+	Name       string `json:"name"`
+	Shares     string `json:"shares"`     // Resource counting the shares of GPUs pods hold, e.g. vGPUs
+	Memory     string `json:"memory"`     // Resource pods request GPU memory with, in MiB
+	Cores      string `json:"cores"`      // Resource pods request GPU compute with, in percent of a GPU
+	Annotation string `json:"annotation"` // If set, only nodes with this annotation use the scheme
+}
+
+// Schemes recognized when no config file sets them. HAMi advertises its shares under the usual nvidia.com/gpu, so
+// its nodes are told apart by the annotation it registers their GPUs in.
+var defaultFractionalGpuSchemes = []FractionalGpuScheme{
+	{
+		Name:       "hami",
+		Shares:     "nvidia.com/gpu",
+		Memory:     "nvidia.com/gpumem",
+		Cores:      "nvidia.com/gpucores",
+		Annotation: "hami.io/node-nvidia-register",
+	},
+	{
+		Name:   "volcano",
+		Shares: "volcano.sh/vgpu-number",
+		Memory: "volcano.sh/vgpu-memory",
+		Cores:  "volcano.sh/vgpu-cores",
+	},
+}
+
+// FractionalGpus contains the shares, memory, and compute of the GPUs of a node using a GPU sharing scheduler
+type FractionalGpus struct {
+	Scheme string    `json:"scheme"`
+	Shares GpuCount  `json:"shares"`
+	Memory *GpuCount `json:"memory,omitempty"` // In MiB, if the node's GPU memory is known
+	Cores  *GpuCount `json:"cores,omitempty"`  // In percent of a GPU, if the node's GPU count is known
+	Gpus   *GpuCount `json:"gpus,omitempty"`   // Whole GPUs, if the node's GPU count is known
+
+	scheme FractionalGpuScheme
+}
+
+// isFractionalGpuResource returns whether a resource name is the memory or compute of a configured GPU sharing
+// scheme, which is counted in MiB or percent rather than in GPUs.
+func isFractionalGpuResource(name string) bool {
+	for _, scheme := range currentConfig().FractionalGpus {
+		if name == scheme.Memory || name == scheme.Cores {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getFractionalGpuScheme returns the configured GPU sharing scheme a node uses, or false if it doesn't use one.
+func getFractionalGpuScheme(node *corev1.Node) (FractionalGpuScheme, bool) {
+	for _, scheme := range currentConfig().FractionalGpus {
+		if _, ok := node.Annotations[scheme.Annotation]; scheme.Annotation != "" && !ok {
+			continue
+		}
+
+		if shares, ok := node.Status.Allocatable[corev1.ResourceName(scheme.Shares)]; ok && !shares.IsZero() {
+			return scheme, true
+		}
+	}
+
+	return FractionalGpuScheme{}, false
+}
+
+// getLabelInt returns the value of a node label holding a positive integer, or 0 if the node doesn't have it.
+func getLabelInt(node *corev1.Node, label string) int64 {
+	value, err := strconv.ParseInt(node.Labels[label], 10, 64)
+
+	if err != nil || value < 0 {
+		return 0
+	}
+
+	return value
+}
+
+// newFractionalGpus returns the shares, memory, and compute of the GPUs of a node using scheme, all free. Memory is
+// taken from the node's allocatable resources if the scheme advertises it, or else from the GPU feature discovery
+// labels, and whole GPUs and compute are only known if the node has the GPU count label.
+func newFractionalGpus(node *corev1.Node, scheme FractionalGpuScheme) *FractionalGpus {
+	shares := node.Status.Allocatable[corev1.ResourceName(scheme.Shares)]
+	gpus := &FractionalGpus{
+		Scheme: scheme.Name,
+		Shares: GpuCount{Total: shares.Value(), Free: shares.Value()},
+		scheme: scheme,
+	}
+
+	count := getLabelInt(node, gpuCountLabel)
+
+	if memory, ok := node.Status.Allocatable[corev1.ResourceName(scheme.Memory)]; ok && !memory.IsZero() {
+		gpus.Memory = &GpuCount{Total: memory.Value(), Free: memory.Value()}
+	} else if memory := getLabelInt(node, gpuMemoryLabel) * count; memory > 0 && scheme.Memory != "" {
+		gpus.Memory = &GpuCount{Total: memory, Free: memory}
+	}
+
+	if count > 0 {
+		gpus.Gpus = &GpuCount{Total: count, Free: count}
+
+		if scheme.Cores != "" {
+			gpus.Cores = &GpuCount{Total: count * coresPerGpu, Free: count * coresPerGpu}
+		}
+	}
+
+	return gpus
+}
+
+// addToGpuCount allocates the amount of a resource in requests from count, if the count is known.
+func addToGpuCount(count *GpuCount, requests corev1.ResourceList, name string) {
+	quantity, ok := requests[corev1.ResourceName(name)]
+
+	if count == nil || !ok {
+		return
+	}
+
+	count.Allocated += quantity.Value()
+	count.Free -= quantity.Value()
+}
+
+// Allocate counts the requests of a pod on the node against its shares, memory, and compute, returning the number of
+// shares the pod holds.
+func (f *FractionalGpus) Allocate(requests corev1.ResourceList) int64 {
+	shares := requests[corev1.ResourceName(f.scheme.Shares)]
+
+	addToGpuCount(&f.Shares, requests, f.scheme.Shares)
+	addToGpuCount(f.Memory, requests, f.scheme.Memory)
+	addToGpuCount(f.Cores, requests, f.scheme.Cores)
+
+	return shares.Value()
+}
+
+// updateWholeGpus works out how many whole GPUs are free once every pod is allocated. Which GPU each share is on isn't
+// known, so the free GPUs are the most that can be unused: as many as the free fraction of the node's shares, memory,
+// and compute all cover.
+func (f *FractionalGpus) updateWholeGpus() {
+	if f.Gpus == nil {
+		return
+	}
+
+	free := f.Gpus.Total
+
+	for _, count := range []*GpuCount{&f.Shares, f.Memory, f.Cores} {
+		if count != nil && count.Total > 0 {
+			free = min(free, max(count.Free, 0)*f.Gpus.Total/count.Total)
+		}
+	}
+
+	f.Gpus.Free = free
+	f.Gpus.Allocated = f.Gpus.Total - free
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestGetGpuInventoryFractional counts the GPUs of a node shared with HAMi, checking its shares, memory, compute, and
+// whole GPUs, and of a node shared with Volcano whose GPU count isn't known, which is counted in shares.
+func TestGetGpuInventoryFractional(t *testing.T) {
+	nodes := []v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "hami-1",
+				Labels:      map[string]string{"nvidia.com/gpu.product": "A100", "nvidia.com/gpu.count": "4", "nvidia.com/gpu.memory": "40000"},
+				Annotations: map[string]string{"hami.io/node-nvidia-register": "GPU-0,10,40000,100,NVIDIA-A100,0,true:"},
+			},
+			Status: v1.NodeStatus{Allocatable: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("40")}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "volcano-1"},
+			Status:     v1.NodeStatus{Allocatable: v1.ResourceList{"volcano.sh/vgpu-number": resource.MustParse("20")}},
+		},
+	}
+
+	gpuPod := func(name, node string, requests v1.ResourceList) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vision"},
+			Spec: v1.PodSpec{
+				NodeName:   node,
+				Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: requests}}},
+			},
+		}
+	}
+
+	pods := []v1.Pod{
+		gpuPod("train", "hami-1", v1.ResourceList{
+			"nvidia.com/gpu":      resource.MustParse("1"),
+			"nvidia.com/gpumem":   resource.MustParse("20000"),
+			"nvidia.com/gpucores": resource.MustParse("50"),
+		}),
+		gpuPod("notebook", "hami-1", v1.ResourceList{"nvidia.com/gpu": resource.MustParse("2"), "nvidia.com/gpumem": resource.MustParse("5000")}),
+		gpuPod("infer", "volcano-1", v1.ResourceList{"volcano.sh/vgpu-number": resource.MustParse("3")}),
+	}
+
+	inventory := getGpuInventory(nodes, pods)
+
+	if len(inventory.Nodes) != 2 || inventory.Nodes[0].Fractional == nil || inventory.Nodes[1].Fractional == nil {
+		t.Fatalf(`inventory.Nodes = %v, want hami-1 and volcano-1 with fractional GPUs`, inventory.Nodes)
+	}
+
+	hami, volcano := inventory.Nodes[0], inventory.Nodes[1]
+
+	switch {
+	case hami.Fractional.Scheme != "hami":
+		t.Fatalf(`hami.Fractional.Scheme = %v, want match for %v`, hami.Fractional.Scheme, "hami")
+	case hami.Fractional.Shares != (GpuCount{Total: 40, Allocated: 3, Free: 37}):
+		t.Fatalf(`hami.Fractional.Shares = %v, want match for %v`, hami.Fractional.Shares, GpuCount{Total: 40, Allocated: 3, Free: 37})
+	case hami.Fractional.Memory == nil || *hami.Fractional.Memory != (GpuCount{Total: 160000, Allocated: 25000, Free: 135000}):
+		t.Fatalf(`hami.Fractional.Memory = %v, want match for %v`, hami.Fractional.Memory, GpuCount{Total: 160000, Allocated: 25000, Free: 135000})
+	case hami.Fractional.Cores == nil || *hami.Fractional.Cores != (GpuCount{Total: 400, Allocated: 50, Free: 350}):
+		t.Fatalf(`hami.Fractional.Cores = %v, want match for %v`, hami.Fractional.Cores, GpuCount{Total: 400, Allocated: 50, Free: 350})
+	case hami.GpuCount != (GpuCount{Total: 4, Allocated: 1, Free: 3}):
+		t.Fatalf(`hami.GpuCount = %v, want match for %v`, hami.GpuCount, GpuCount{Total: 4, Allocated: 1, Free: 3})
+	case len(hami.Pods) != 2 || hami.Pods[0] != (GpuPod{Name: "notebook", Namespace: "vision", Shares: 2}):
+		t.Fatalf(`hami.Pods = %v, want notebook holding 2 shares and train`, hami.Pods)
+	case volcano.Fractional.Gpus != nil || volcano.Fractional.Memory != nil:
+		t.Fatalf(`volcano.Fractional = %v, want no whole GPUs or memory`, volcano.Fractional)
+	case volcano.GpuCount != (GpuCount{Total: 20, Allocated: 3, Free: 17}):
+		t.Fatalf(`volcano.GpuCount = %v, want match for %v`, volcano.GpuCount, GpuCount{Total: 20, Allocated: 3, Free: 17})
+	}
+}
+
+// TestIsGpuResourceFractional checks that the memory and compute of GPU sharing schemes aren't counted as GPUs even
+// though they have a GPU prefix.
+func TestIsGpuResourceFractional(t *testing.T) {
+	for name, want := range map[string]bool{
+		"nvidia.com/gpu":         true,
+		"nvidia.com/mig-1g.5gb":  true,
+		"nvidia.com/gpumem":      false,
+		"nvidia.com/gpucores":    false,
+		"volcano.sh/vgpu-number": false,
+	} {
+		if isGpuResource(name) != want {
+			t.Fatalf(`isGpuResource(%v) = %v, want match for %v`, name, !want, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Shapes the fragmentation report uses when none are given: a typical GPU pod and a typical CPU-only pod
+var defaultShapes = []string{
+	"cpu=4,memory=16Gi,gpu=1",
+	"cpu=4,memory=16Gi",
+}
+
+// WorkloadShape is the requests of a single pod that capacity is measured against
+type WorkloadShape struct {
+	Name     string        `json:"name"`
+	Requests ResourcesJson `json:"requests"`
+}
+
+// ShapeFit contains how many pods of a shape fit in the free capacity of a group of nodes, and how much of
+// the free capacity is left over once they are placed. Stranded resources can't be used by the shape, e.g.
+// free CPU on nodes that have no free GPUs for a GPU shape.
+type ShapeFit struct {
+	Shape    WorkloadShape `json:"shape"`
+	Fits     int           `json:"fits"`
+	Free     ResourcesJson `json:"free"`
+	Stranded ResourcesJson `json:"stranded"`
+}
+
+// PoolFragmentation contains the fit of every shape in a node pool
+type PoolFragmentation struct {
+	Pool   string     `json:"pool"`
+	Nodes  int        `json:"nodes"`
+	Shapes []ShapeFit `json:"shapes"`
+}
+
+// parseWorkloadShape parses a shape of the form cpu=4,memory=16Gi,gpu=1. Resources that aren't given
+// are not requested.
+func parseWorkloadShape(text string) (WorkloadShape, error) {
+	shape := WorkloadShape{Name: text}
+
+	for _, field := range strings.Split(text, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(field), "=")
+		if !found {
+			return shape, fmt.Errorf("invalid shape %q: expected resource=quantity", text)
+		}
+
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return shape, fmt.Errorf("invalid shape %q: %v", text, err)
+		}
+
+		switch name {
+		case "cpu":
+			shape.Requests.Cpu = quantity.AsApproximateFloat64()
+		case "memory", "gpu", "ephemeral":
+			setResource(&shape.Requests, name, float64(quantity.Value()))
+		default:
+			return shape, fmt.Errorf("invalid shape %q: unknown resource %q", text, name)
+		}
+	}
+
+	return shape, nil
+}
+
+// getShapeFit returns how many pods of shape fit on a node, and the free resources left over afterwards.
+func getShapeFit(node NodeJson, shape WorkloadShape) (int, ResourcesJson) {
+	free := maxResources(node.Free, ResourcesJson{})
+	fits := math.MaxInt
+
+	for _, name := range resourceNames {
+		requested := getResource(shape.Requests, name)
+		if requested <= 0 {
+			continue
+		}
+
+		fits = min(fits, int(getResource(free, name)/requested))
+	}
+
+	// A shape that requests nothing is meaningless, so say nothing fits rather than infinitely many
+	if fits == math.MaxInt {
+		return 0, free
+	}
+
+	for _, name := range resourceNames {
+		setResource(&free, name, getResource(free, name)-float64(fits)*getResource(shape.Requests, name))
+	}
+
+	return fits, free
+}
+
+// getFragmentation calculates how many pods of each shape fit in the free capacity of each node pool, packing
+// each node separately since a pod can't span nodes. Pools are sorted by name.
+func getFragmentation(nodes []NodeJson, shapes []WorkloadShape) []PoolFragmentation {
+	pools := make(map[string]*PoolFragmentation)
+
+	for _, node := range nodes {
+		if _, ok := pools[node.Pool]; !ok {
+			pool := &PoolFragmentation{Pool: node.Pool, Shapes: make([]ShapeFit, len(shapes))}
+			for i, shape := range shapes {
+				pool.Shapes[i].Shape = shape
+			}
+			pools[node.Pool] = pool
+		}
+
+		pool := pools[node.Pool]
+		pool.Nodes++
+
+		for i, shape := range shapes {
+			fits, stranded := getShapeFit(node, shape)
+
+			pool.Shapes[i].Fits += fits
+			pool.Shapes[i].Free = addResources(pool.Shapes[i].Free, maxResources(node.Free, ResourcesJson{}))
+			pool.Shapes[i].Stranded = addResources(pool.Shapes[i].Stranded, stranded)
+		}
+	}
+
+	result := make([]PoolFragmentation, 0, len(pools))
+	for _, pool := range pools {
+		result = append(result, *pool)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Pool < result[j].Pool
+	})
+
+	return result
+}
+
+// getFragmentationHandler returns a HandlerFunc to return the fragmentation report given a Collector. Shapes are
+// given by repeating the shape query parameter, e.g. ?shape=cpu=4,memory=16Gi,gpu=1&shape=cpu=8,memory=32Gi.
+func getFragmentationHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		shapeParams := c.QueryArray("shape")
+		if len(shapeParams) == 0 {
+			shapeParams = defaultShapes
+		}
+
+		shapes := make([]WorkloadShape, 0, len(shapeParams))
+
+		for _, param := range shapeParams {
+			shape, err := parseWorkloadShape(param)
+
+			if err != nil {
+				c.JSON(http.StatusBadRequest, "error: "+err.Error())
+				return
+			}
+
+			shapes = append(shapes, shape)
+		}
+
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getFragmentation(snapshot.Nodes, shapes))
+	}
+
+	return gin.HandlerFunc(handler)
+}
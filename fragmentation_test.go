@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+// TestParseWorkloadShape parses a valid shape and checks that unknown resources and missing quantities are errors.
+func TestParseWorkloadShape(t *testing.T) {
+	shape, err := parseWorkloadShape("cpu=500m,memory=1Gi,gpu=1")
+	want := ResourcesJson{Cpu: 0.5, Memory: 1 << 30, Gpu: 1}
+
+	if err != nil || shape.Requests != want {
+		t.Fatalf(`parseWorkloadShape("cpu=500m,memory=1Gi,gpu=1") = %v, %v, want match for %v, nil`, shape.Requests, err, want)
+	}
+
+	for _, text := range []string{"cpu=4,disk=1Gi", "cpu", "memory=lots"} {
+		if _, err := parseWorkloadShape(text); err == nil {
+			t.Fatalf(`parseWorkloadShape(%q) returned no error`, text)
+		}
+	}
+}
+
+// TestGetFragmentation checks that free CPU on a node with no free GPUs is stranded for a GPU shape but usable
+// by a CPU-only shape, and that pools are kept separate.
+func TestGetFragmentation(t *testing.T) {
+	gpuShape := WorkloadShape{Name: "gpu", Requests: ResourcesJson{Cpu: 4, Memory: 16, Gpu: 1}}
+	cpuShape := WorkloadShape{Name: "cpu", Requests: ResourcesJson{Cpu: 4, Memory: 16}}
+
+	nodes := []NodeJson{
+		{Name: "node-1", Pool: "gpu", Free: ResourcesJson{Cpu: 32, Memory: 128, Gpu: 0}},
+		{Name: "node-2", Pool: "gpu", Free: ResourcesJson{Cpu: 6, Memory: 64, Gpu: 2}},
+		{Name: "node-3", Pool: "cpu", Free: ResourcesJson{Cpu: -2, Memory: 64}},
+	}
+
+	result := getFragmentation(nodes, []WorkloadShape{gpuShape, cpuShape})
+
+	switch {
+	case len(result) != 2 || result[0].Pool != "cpu" || result[1].Pool != "gpu":
+		t.Fatalf(`getFragmentation() = %v, want pools cpu and gpu`, result)
+	case result[0].Shapes[1].Fits != 0:
+		t.Fatalf(`cpu pool cpu shape fits = %v, want match for %v`, result[0].Shapes[1].Fits, 0)
+	case result[1].Shapes[0].Fits != 1:
+		t.Fatalf(`gpu pool gpu shape fits = %v, want match for %v`, result[1].Shapes[0].Fits, 1)
+	case result[1].Shapes[0].Stranded != (ResourcesJson{Cpu: 34, Memory: 176, Gpu: 1}):
+		t.Fatalf(`gpu pool gpu shape stranded = %v, want match for %v`, result[1].Shapes[0].Stranded, ResourcesJson{Cpu: 34, Memory: 176, Gpu: 1})
+	case result[1].Shapes[1].Fits != 9:
+		t.Fatalf(`gpu pool cpu shape fits = %v, want match for %v`, result[1].Shapes[1].Fits, 9)
+	case result[1].Shapes[1].Free != (ResourcesJson{Cpu: 38, Memory: 192, Gpu: 2}):
+		t.Fatalf(`gpu pool cpu shape free = %v, want match for %v`, result[1].Shapes[1].Free, ResourcesJson{Cpu: 38, Memory: 192, Gpu: 2})
+	}
+}
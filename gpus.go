@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Label set by GPU feature discovery to the model of a node's GPUs
+const gpuProductLabel = "nvidia.com/gpu.product"
+
+// Prefix of the resources advertised for each MIG profile
+const migResourcePrefix = "nvidia.com/mig-"
+
+// GpuCount contains the number of GPUs, or MIG devices or shares of GPUs, of a type that exist, are requested, and
+// are free
+type GpuCount struct {
+	Total     int64 `json:"total"`
+	Allocated int64 `json:"allocated"`
+	Free      int64 `json:"free"`
+}
+
+// GpuPod is a pod holding GPUs on a node
+type GpuPod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Gpus      int64  `json:"gpus"`
+	Shares    int64  `json:"shares,omitempty"` // Shares of GPUs held on nodes using a GPU sharing scheduler
+}
+
+// GpuNode contains the GPUs of a single node and the pods holding them
+type GpuNode struct {
+	Name       string              `json:"name"`
+	Model      string              `json:"model"`
+	GpuCount                       // Embedded so the counts appear at the top level of the JSON object
+	Mig        map[string]GpuCount `json:"mig,omitempty"`
+	Fractional *FractionalGpus     `json:"fractional,omitempty"`
+	Pods       []GpuPod            `json:"pods"`
+}
+
+// GpuModel contains the GPUs of every node with a model of GPU
+type GpuModel struct {
+	Model    string `json:"model"`
+	Nodes    int    `json:"nodes"`
+	GpuCount        // Embedded so the counts appear at the top level of the JSON object
+}
+
+// GpuAllocation is a pod holding GPUs, with the workload it belongs to and how long it has been running
+type GpuAllocation struct {
+	Name      string     `json:"name"`
+	Namespace string     `json:"namespace"`
+	Node      string     `json:"node"`
+	Model     string     `json:"model"`
+	Gpus      int64      `json:"gpus"`
+	Owner     *OwnerJson `json:"owner,omitempty"`
+	Started   time.Time  `json:"started"`
+	Age       float64    `json:"age"` // In hours
+}
+
+// GpuInventory contains the GPUs of every GPU node and the totals for each model
+type GpuInventory struct {
+	Models []GpuModel `json:"models"`
+	Nodes  []GpuNode  `json:"nodes"`
+}
+
+// Gpus lists the nodes and pods in the cluster and returns an inventory of their GPUs.
+func (c *Collector) Gpus() (*GpuInventory, error) {
+	nodes, pods, err := c.listNodesAndPods()
+
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := getGpuInventory(nodes, pods)
+	return &inventory, nil
+}
+
+// GpuAllocations lists the nodes and pods in the cluster and returns every pod holding GPUs.
+func (c *Collector) GpuAllocations() ([]GpuAllocation, error) {
+	nodes, pods, err := c.listNodesAndPods()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return getGpuAllocations(nodes, pods, time.Now()), nil
+}
+
+// listNodesAndPods returns every node in the cluster and every pod that isn't terminated, other than excluded nodes
+// and the pods on them.
+func (c *Collector) listNodesAndPods() ([]corev1.Node, []corev1.Pod, error) {
+	nodeList, err := c.client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pods, err := c.listPods(nonTerminatedPodSelector)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes, pods := excludeNodes(nodeList.Items, pods)
+
+	return nodes, pods, nil
+}
+
+// getGpuInventory counts the GPUs and MIG devices of each node with any, and the GPUs each pod on them requests.
+// Nodes using a GPU sharing scheduler also have their shares, memory, and compute counted, and are counted in whole
+// GPUs where their GPU count is known. Nodes and models are sorted by name.
+func getGpuInventory(nodes []corev1.Node, pods []corev1.Pod) GpuInventory {
+	gpuNodes := make(map[string]*GpuNode)
+
+	for _, node := range nodes {
+		allocatable := getResourcesFromList(node.Status.Allocatable)
+		total := allocatable.Gpu.Value()
+		scheme, shared := getFractionalGpuScheme(&node)
+		if total == 0 && !shared {
+			continue
+		}
+
+		gpuNode := &GpuNode{
+			Name:     node.Name,
+			Model:    node.Labels[gpuProductLabel],
+			GpuCount: GpuCount{Total: total, Free: total},
+			Pods:     make([]GpuPod, 0),
+		}
+
+		if gpuNode.Model == "" {
+			gpuNode.Model = "unknown"
+		}
+
+		if shared {
+			gpuNode.Fractional = newFractionalGpus(&node, scheme)
+		}
+
+		for name, quantity := range node.Status.Allocatable {
+			if profile, ok := strings.CutPrefix(name.String(), migResourcePrefix); ok && !quantity.IsZero() {
+				if gpuNode.Mig == nil {
+					gpuNode.Mig = make(map[string]GpuCount)
+				}
+				gpuNode.Mig[profile] = GpuCount{Total: quantity.Value(), Free: quantity.Value()}
+			}
+		}
+
+		gpuNodes[node.Name] = gpuNode
+	}
+
+	for i := range pods {
+		gpuNode, ok := gpuNodes[pods[i].Spec.NodeName]
+		if !ok || !isCountedNamespace(pods[i].Namespace) {
+			continue
+		}
+
+		podReqs, _ := getPodRequestsAndLimits(&pods[i])
+
+		// Count MIG devices against their own profile
+		for name, quantity := range podReqs {
+			if profile, ok := strings.CutPrefix(name.String(), migResourcePrefix); ok {
+				if count, ok := gpuNode.Mig[profile]; ok {
+					count.Allocated += quantity.Value()
+					count.Free -= quantity.Value()
+					gpuNode.Mig[profile] = count
+				}
+			}
+		}
+
+		// Pods on nodes sharing GPUs hold shares rather than whole GPUs
+		if gpuNode.Fractional != nil {
+			if shares := gpuNode.Fractional.Allocate(podReqs); shares > 0 {
+				gpuNode.Pods = append(gpuNode.Pods, GpuPod{Name: pods[i].Name, Namespace: pods[i].Namespace, Shares: shares})
+			}
+			continue
+		}
+
+		requests := getResourcesFromList(podReqs)
+		gpus := requests.Gpu.Value()
+		if gpus == 0 {
+			continue
+		}
+
+		gpuNode.Allocated += gpus
+		gpuNode.Free -= gpus
+		gpuNode.Pods = append(gpuNode.Pods, GpuPod{Name: pods[i].Name, Namespace: pods[i].Namespace, Gpus: gpus})
+	}
+
+	// Count nodes sharing GPUs in whole GPUs, so models total the same physical GPUs whether they're shared or not,
+	// falling back to shares for nodes whose GPU count isn't known
+	for _, gpuNode := range gpuNodes {
+		if gpuNode.Fractional == nil {
+			continue
+		}
+
+		gpuNode.Fractional.updateWholeGpus()
+		gpuNode.GpuCount = gpuNode.Fractional.Shares
+
+		if gpuNode.Fractional.Gpus != nil {
+			gpuNode.GpuCount = *gpuNode.Fractional.Gpus
+		}
+	}
+
+	inventory := GpuInventory{
+		Models: make([]GpuModel, 0),
+		Nodes:  make([]GpuNode, 0, len(gpuNodes)),
+	}
+
+	models := make(map[string]*GpuModel)
+
+	for _, gpuNode := range gpuNodes {
+		if _, ok := models[gpuNode.Model]; !ok {
+			models[gpuNode.Model] = &GpuModel{Model: gpuNode.Model}
+		}
+
+		model := models[gpuNode.Model]
+		model.Nodes++
+		model.Total += gpuNode.Total
+		model.Allocated += gpuNode.Allocated
+		model.Free += gpuNode.Free
+
+		sort.Slice(gpuNode.Pods, func(i, j int) bool {
+			if gpuNode.Pods[i].Namespace != gpuNode.Pods[j].Namespace {
+				return gpuNode.Pods[i].Namespace < gpuNode.Pods[j].Namespace
+			}
+			return gpuNode.Pods[i].Name < gpuNode.Pods[j].Name
+		})
+
+		inventory.Nodes = append(inventory.Nodes, *gpuNode)
+	}
+
+	for _, model := range models {
+		inventory.Models = append(inventory.Models, *model)
+	}
+
+	sort.Slice(inventory.Nodes, func(i, j int) bool {
+		return inventory.Nodes[i].Name < inventory.Nodes[j].Name
+	})
+
+	sort.Slice(inventory.Models, func(i, j int) bool {
+		return inventory.Models[i].Model < inventory.Models[j].Model
+	})
+
+	return inventory
+}
+
+// getGpuAllocations returns every pod in pods that requests GPUs on one of nodes, with the model of the node's GPUs
+// and how long the pod has been running as of now, longest running first.
+func getGpuAllocations(nodes []corev1.Node, pods []corev1.Pod, now time.Time) []GpuAllocation {
+	models := make(map[string]string)
+	for _, node := range nodes {
+		models[node.Name] = node.Labels[gpuProductLabel]
+		if models[node.Name] == "" {
+			models[node.Name] = "unknown"
+		}
+	}
+
+	allocations := make([]GpuAllocation, 0)
+
+	for i := range pods {
+		pod := &pods[i]
+
+		model, ok := models[pod.Spec.NodeName]
+		if !ok {
+			continue
+		}
+
+		podReqs, _ := getPodRequestsAndLimits(pod)
+		requests := getResourcesFromList(podReqs)
+		if requests.Gpu.IsZero() {
+			continue
+		}
+
+		// Pods that haven't started yet are counted from when they were created
+		started := pod.CreationTimestamp.Time
+		if pod.Status.StartTime != nil {
+			started = pod.Status.StartTime.Time
+		}
+
+		allocations = append(allocations, GpuAllocation{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Node:      pod.Spec.NodeName,
+			Model:     model,
+			Gpus:      requests.Gpu.Value(),
+			Owner:     getPodOwner(pod),
+			Started:   started,
+			Age:       now.Sub(started).Hours(),
+		})
+	}
+
+	sort.SliceStable(allocations, func(i, j int) bool {
+		return allocations[i].Started.Before(allocations[j].Started)
+	})
+
+	return allocations
+}
+
+// getGpuAllocationsHandler returns a HandlerFunc to return every pod holding GPUs given a Collector. The pods can be
+// filtered with the node and namespace query parameters, and to those running for at least the minAge parameter.
+func getGpuAllocationsHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		minAge := time.Duration(0)
+
+		if value := c.Query("minAge"); value != "" {
+			var err error
+			minAge, err = parseDays(value)
+
+			if err != nil || minAge < 0 {
+				c.JSON(http.StatusBadRequest, "error: minAge must be a duration such as 7d or 12h")
+				return
+			}
+		}
+
+		allocations, err := collector.GpuAllocations()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving GPU information")
+			return
+		}
+
+		node, namespace := c.Query("node"), c.Query("namespace")
+		filtered := make([]GpuAllocation, 0, len(allocations))
+
+		for _, allocation := range allocations {
+			if (node != "" && allocation.Node != node) || (namespace != "" && allocation.Namespace != namespace) {
+				continue
+			}
+
+			if allocation.Age < minAge.Hours() {
+				continue
+			}
+
+			filtered = append(filtered, allocation)
+		}
+
+		c.IndentedJSON(http.StatusOK, filtered)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getGpusHandler returns a HandlerFunc to return the GPU inventory of the cluster given a Collector.
+func getGpusHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		inventory, err := collector.Gpus()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving GPU information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, inventory)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// GpuQuota is a GPU resource limited by a ResourceQuota
+type GpuQuota struct {
+	Quota    string `json:"quota"`
+	Resource string `json:"resource"`
+	QuotaUsage
+}
+
+// NamespaceGpus compares the GPUs the pods in a namespace request with the GPU limits of its ResourceQuotas
+type NamespaceGpus struct {
+	Namespace string     `json:"namespace"`
+	Pods      int        `json:"pods"`
+	Requested int64      `json:"requested"`
+	Quotas    []GpuQuota `json:"quotas"`
+}
+
+// NamespaceGpus returns the GPU requests and GPU quotas of a namespace.
+func (c *Collector) NamespaceGpus(namespace string) (*NamespaceGpus, error) {
+	quotas, err := c.Quotas(namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := c.listPods(nonTerminatedPodSelector + ",metadata.namespace=" + namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	podJsons := make([]PodJson, 0, len(pods))
+	for i := range pods {
+		podJsons = append(podJsons, getPodStructured(&pods[i]))
+	}
+
+	result := getNamespaceGpus(namespace, quotas, podJsons)
+	return &result, nil
+}
+
+// getNamespaceGpus sums the GPU requests of the pods in namespace and finds the GPU resources limited by its quotas.
+func getNamespaceGpus(namespace string, quotas []QuotaJson, pods []PodJson) NamespaceGpus {
+	result := NamespaceGpus{Namespace: namespace, Quotas: make([]GpuQuota, 0)}
+
+	for _, pod := range pods {
+		if pod.Namespace != namespace || pod.Requests.Gpu == 0 {
+			continue
+		}
+
+		result.Pods++
+		result.Requested += pod.Requests.Gpu
+	}
+
+	for _, quota := range quotas {
+		if quota.Namespace != namespace {
+			continue
+		}
+
+		for name, usage := range quota.Resources {
+			// Extended resources can only be limited by their requests in a quota, e.g. requests.nvidia.com/gpu
+			if isGpuResource(strings.TrimPrefix(name, "requests.")) {
+				result.Quotas = append(result.Quotas, GpuQuota{Quota: quota.Name, Resource: name, QuotaUsage: usage})
+			}
+		}
+	}
+
+	sort.Slice(result.Quotas, func(i, j int) bool {
+		if result.Quotas[i].Quota != result.Quotas[j].Quota {
+			return result.Quotas[i].Quota < result.Quotas[j].Quota
+		}
+		return result.Quotas[i].Resource < result.Quotas[j].Resource
+	})
+
+	return result
+}
+
+// getNamespaceGpusHandler returns a HandlerFunc to return the GPU requests and quotas of the namespace given by the
+// ns path parameter given a Collector.
+func getNamespaceGpusHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		result, err := collector.NamespaceGpus(c.Param("ns"))
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving GPU information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(handler)
+}
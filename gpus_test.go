@@ -0,0 +1,146 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestGetGpuInventory counts the GPUs of two nodes of the same model, one of them using MIG, checking the
+// allocated counts, the pods, and that nodes without GPUs are left out.
+func TestGetGpuInventory(t *testing.T) {
+	gpuNode := func(name string, allocatable v1.ResourceList) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"nvidia.com/gpu.product": "A100"}},
+			Status:     v1.NodeStatus{Allocatable: allocatable},
+		}
+	}
+
+	nodes := []v1.Node{
+		gpuNode("gpu-1", v1.ResourceList{"nvidia.com/gpu": resource.MustParse("4")}),
+		gpuNode("gpu-2", v1.ResourceList{"nvidia.com/gpu": resource.MustParse("0"), "nvidia.com/mig-1g.10gb": resource.MustParse("7")}),
+		{ObjectMeta: metav1.ObjectMeta{Name: "cpu-1"}, Status: v1.NodeStatus{Allocatable: v1.ResourceList{"cpu": resource.MustParse("8")}}},
+	}
+
+	gpuPod := func(name, node string, requests v1.ResourceList) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vision"},
+			Spec: v1.PodSpec{
+				NodeName:   node,
+				Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: requests}}},
+			},
+		}
+	}
+
+	pods := []v1.Pod{
+		gpuPod("train", "gpu-1", v1.ResourceList{"nvidia.com/gpu": resource.MustParse("3")}),
+		gpuPod("notebook", "gpu-2", v1.ResourceList{"nvidia.com/mig-1g.10gb": resource.MustParse("2")}),
+		gpuPod("web", "gpu-1", v1.ResourceList{"cpu": resource.MustParse("1")}),
+	}
+
+	inventory := getGpuInventory(nodes, pods)
+
+	if len(inventory.Nodes) != 2 {
+		t.Fatalf(`inventory.Nodes = %v, want gpu-1 and gpu-2`, inventory.Nodes)
+	}
+
+	gpu1, gpu2 := inventory.Nodes[0], inventory.Nodes[1]
+
+	switch {
+	case gpu1.GpuCount != (GpuCount{Total: 4, Allocated: 3, Free: 1}):
+		t.Fatalf(`gpu1.GpuCount = %v, want match for %v`, gpu1.GpuCount, GpuCount{Total: 4, Allocated: 3, Free: 1})
+	case len(gpu1.Pods) != 1 || gpu1.Pods[0] != (GpuPod{Name: "train", Namespace: "vision", Gpus: 3}):
+		t.Fatalf(`gpu1.Pods = %v, want only train`, gpu1.Pods)
+	case gpu2.Mig["1g.10gb"] != (GpuCount{Total: 7, Allocated: 2, Free: 5}):
+		t.Fatalf(`gpu2.Mig["1g.10gb"] = %v, want match for %v`, gpu2.Mig["1g.10gb"], GpuCount{Total: 7, Allocated: 2, Free: 5})
+	case len(inventory.Models) != 1 || inventory.Models[0].Nodes != 2 || inventory.Models[0].Total != 11 || inventory.Models[0].Allocated != 5:
+		t.Fatalf(`inventory.Models = %v, want one A100 model with 11 GPUs and 5 allocated`, inventory.Models)
+	}
+}
+
+// TestGetGpuAllocations finds the pods holding GPUs on a node, checking their owner and age and that the longest
+// running pod comes first.
+func TestGetGpuAllocations(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	controller := true
+
+	nodes := []v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "gpu-1"}}}
+
+	pods := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "train", Namespace: "vision", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+			Spec: v1.PodSpec{
+				NodeName:   "gpu-1",
+				Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}}}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "notebook-0",
+				Namespace:       "vision",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "notebook", Controller: &controller}},
+			},
+			Spec: v1.PodSpec{
+				NodeName:   "gpu-1",
+				Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")}}}},
+			},
+			Status: v1.PodStatus{StartTime: &metav1.Time{Time: now.Add(-48 * time.Hour)}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "vision"},
+			Spec:       v1.PodSpec{NodeName: "gpu-1", Containers: []v1.Container{{}}},
+		},
+	}
+
+	allocations := getGpuAllocations(nodes, pods, now)
+
+	switch {
+	case len(allocations) != 2:
+		t.Fatalf(`getGpuAllocations() = %v, want 2 allocations`, allocations)
+	case allocations[0].Name != "notebook-0" || allocations[0].Gpus != 2 || allocations[0].Age != 48 || allocations[0].Model != "unknown":
+		t.Fatalf(`allocations[0] = %v, want notebook-0 holding 2 GPUs for 48 hours`, allocations[0])
+	case allocations[0].Owner == nil || *allocations[0].Owner != (OwnerJson{Kind: "StatefulSet", Name: "notebook"}):
+		t.Fatalf(`allocations[0].Owner = %v, want StatefulSet notebook`, allocations[0].Owner)
+	case allocations[1].Name != "train" || allocations[1].Age != 1:
+		t.Fatalf(`allocations[1] = %v, want train created an hour ago`, allocations[1])
+	}
+}
+
+// TestGetNamespaceGpus sums the GPU requests of a namespace and picks the GPU resources out of its quotas, ignoring
+// other namespaces.
+func TestGetNamespaceGpus(t *testing.T) {
+	quotas := []QuotaJson{
+		{Name: "compute", Namespace: "vision", Resources: map[string]QuotaUsage{
+			"requests.cpu":                   {Hard: 64, Used: 8, Percent: 12.5},
+			"requests.nvidia.com/gpu":        {Hard: 8, Used: 6, Percent: 75},
+			"requests.nvidia.com/mig-1g.5gb": {Hard: 4, Used: 0, Percent: 0},
+		}},
+		{Name: "compute", Namespace: "audio", Resources: map[string]QuotaUsage{
+			"requests.nvidia.com/gpu": {Hard: 2, Used: 2, Percent: 100},
+		}},
+	}
+
+	pods := []PodJson{
+		{Name: "train", Namespace: "vision", Requests: ResourcesJson{Gpu: 4}},
+		{Name: "notebook", Namespace: "vision", Requests: ResourcesJson{Gpu: 2}},
+		{Name: "web", Namespace: "vision", Requests: ResourcesJson{Cpu: 1}},
+		{Name: "speech", Namespace: "audio", Requests: ResourcesJson{Gpu: 2}},
+	}
+
+	result := getNamespaceGpus("vision", quotas, pods)
+
+	want := []GpuQuota{
+		{Quota: "compute", Resource: "requests.nvidia.com/gpu", QuotaUsage: QuotaUsage{Hard: 8, Used: 6, Percent: 75}},
+		{Quota: "compute", Resource: "requests.nvidia.com/mig-1g.5gb", QuotaUsage: QuotaUsage{Hard: 4}},
+	}
+
+	switch {
+	case result.Pods != 2 || result.Requested != 6:
+		t.Fatalf(`result.Pods, result.Requested = %v, %v, want match for %v, %v`, result.Pods, result.Requested, 2, 6)
+	case len(result.Quotas) != 2 || result.Quotas[0] != want[0] || result.Quotas[1] != want[1]:
+		t.Fatalf(`result.Quotas = %v, want match for %v`, result.Quotas, want)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fields of a history point that can be charted
+var grafanaFields = []string{"allocatable", "capacity", "free"}
+
+// GrafanaSearchRequest is the body of a request from Grafana for the targets that can be queried
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaQueryRequest is the body of a request from Grafana for the datapoints of some targets over a time range
+type GrafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs    int64 `json:"intervalMs"`
+	MaxDataPoints int64 `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+// GrafanaSeries is a time series in the format Grafana expects, with each datapoint a value and a time in
+// milliseconds since the epoch
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// getGrafanaTargets returns every target that can be queried - "<field>.<resource>" for the sum over the cluster,
+// and "<field>.<resource>:<node>" for each node - that contains filter. Cluster targets come first, then nodes by
+// name.
+func getGrafanaTargets(nodes []NodeJson, filter string) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	sort.Strings(names)
+
+	targets := make([]string, 0)
+
+	for _, node := range append([]string{""}, names...) {
+		for _, field := range grafanaFields {
+			for _, resource := range resourceNames {
+				target := field + "." + resource
+				if node != "" {
+					target += ":" + node
+				}
+
+				if strings.Contains(target, filter) {
+					targets = append(targets, target)
+				}
+			}
+		}
+	}
+
+	return targets
+}
+
+// parseGrafanaTarget splits a target into its field, resource, and node, which is empty for the cluster.
+func parseGrafanaTarget(target string) (field, resource, node string, err error) {
+	series, node, _ := strings.Cut(target, ":")
+	field, resource, _ = strings.Cut(series, ".")
+
+	if !slices.Contains(grafanaFields, field) || !slices.Contains(resourceNames, resource) {
+		return "", "", "", fmt.Errorf("unknown target %q: expected <field>.<resource> or <field>.<resource>:<node>", target)
+	}
+
+	return field, resource, node, nil
+}
+
+// getGrafanaInterval returns the width of the buckets datapoints are averaged into - the interval Grafana asks for,
+// widened if needed so the range has no more than the maximum number of datapoints.
+func getGrafanaInterval(request GrafanaQueryRequest) time.Duration {
+	interval := time.Duration(request.IntervalMs) * time.Millisecond
+
+	if request.MaxDataPoints > 0 {
+		interval = max(interval, request.Range.To.Sub(request.Range.From)/time.Duration(request.MaxDataPoints))
+	}
+
+	if interval <= 0 {
+		return time.Minute
+	}
+
+	return interval
+}
+
+// getGrafanaDatapoints sums a field of a resource over the given history of each node. Points are first averaged
+// per node within buckets of interval, so nodes whose snapshots don't line up exactly are still summed together.
+// The datapoints are sorted by time.
+func getGrafanaDatapoints(history map[string][]HistoryPoint, field, resource string, interval time.Duration) [][2]float64 {
+	totals := make(map[time.Time]float64)
+
+	for _, points := range history {
+		sums := make(map[time.Time]float64)
+		counts := make(map[time.Time]int)
+
+		for _, point := range points {
+			var resources ResourcesJson
+			switch field {
+			case "allocatable":
+				resources = point.Allocatable
+			case "capacity":
+				resources = point.Capacity
+			case "free":
+				resources = point.Free
+			}
+
+			bucket := point.Time.Truncate(interval)
+			sums[bucket] += getResource(resources, resource)
+			counts[bucket]++
+		}
+
+		for bucket, sum := range sums {
+			totals[bucket] += sum / float64(counts[bucket])
+		}
+	}
+
+	buckets := make([]time.Time, 0, len(totals))
+	for bucket := range totals {
+		buckets = append(buckets, bucket)
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Before(buckets[j])
+	})
+
+	datapoints := make([][2]float64, 0, len(buckets))
+	for _, bucket := range buckets {
+		datapoints = append(datapoints, [2]float64{totals[bucket], float64(bucket.UnixMilli())})
+	}
+
+	return datapoints
+}
+
+// getGrafanaHealthHandler returns a HandlerFunc that Grafana calls to test the connection to the datasource.
+func getGrafanaHealthHandler() gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getGrafanaSearchHandler returns a HandlerFunc to return the targets Grafana can query given a Collector.
+func getGrafanaSearchHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var request GrafanaSearchRequest
+
+		// Grafana may send an empty body when listing every target
+		if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getGrafanaTargets(snapshot.Nodes, request.Target))
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getGrafanaQueryHandler returns a HandlerFunc to return the datapoints of the targets Grafana asks for given a
+// Collector and a HistoryBackend. Cluster targets sum over the nodes currently in the cluster.
+func getGrafanaQueryHandler(collector *Collector, store HistoryBackend) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var request GrafanaQueryRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		interval := getGrafanaInterval(request)
+		series := make([]GrafanaSeries, 0, len(request.Targets))
+
+		// Node histories are shared between targets, since a dashboard usually charts several fields of each
+		history := make(map[string][]HistoryPoint)
+		var clusterNodes []string
+
+		for _, target := range request.Targets {
+			field, resource, node, err := parseGrafanaTarget(target.Target)
+
+			if err != nil {
+				c.JSON(http.StatusBadRequest, "error: "+err.Error())
+				return
+			}
+
+			nodes := []string{node}
+			if node == "" {
+				if clusterNodes == nil {
+					snapshot, err := collector.Snapshot()
+
+					if err != nil {
+						fmt.Println(err)
+						c.JSON(http.StatusInternalServerError, "error retrieving node information")
+						return
+					}
+
+					clusterNodes = make([]string, 0, len(snapshot.Nodes))
+					for _, node := range snapshot.Nodes {
+						clusterNodes = append(clusterNodes, node.Name)
+					}
+				}
+
+				nodes = clusterNodes
+			}
+
+			targetHistory := make(map[string][]HistoryPoint, len(nodes))
+
+			for _, name := range nodes {
+				if _, ok := history[name]; !ok {
+					points, err := store.Query(name, request.Range.From, request.Range.To)
+
+					if err != nil {
+						fmt.Println(err)
+						c.JSON(http.StatusInternalServerError, "error retrieving node history")
+						return
+					}
+
+					history[name] = points
+				}
+
+				targetHistory[name] = history[name]
+			}
+
+			series = append(series, GrafanaSeries{
+				Target:     target.Target,
+				Datapoints: getGrafanaDatapoints(targetHistory, field, resource, interval),
+			})
+		}
+
+		c.IndentedJSON(http.StatusOK, series)
+	}
+
+	return gin.HandlerFunc(handler)
+}
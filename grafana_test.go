@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestGetGrafanaDatapoints sums the free CPU of two nodes whose snapshots are a few seconds apart, checking that
+// they are bucketed together and that a node's points within a bucket are averaged.
+func TestGetGrafanaDatapoints(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	history := map[string][]HistoryPoint{
+		"node-1": {
+			{Time: start, Free: ResourcesJson{Cpu: 4}},
+			{Time: start.Add(30 * time.Second), Free: ResourcesJson{Cpu: 2}},
+			{Time: start.Add(5 * time.Minute), Free: ResourcesJson{Cpu: 1}},
+		},
+		"node-2": {
+			{Time: start.Add(3 * time.Second), Free: ResourcesJson{Cpu: 8}},
+		},
+	}
+
+	datapoints := getGrafanaDatapoints(history, "free", "cpu", time.Minute)
+	want := [][2]float64{
+		{11, float64(start.UnixMilli())},
+		{1, float64(start.Add(5 * time.Minute).UnixMilli())},
+	}
+
+	if !slices.Equal(datapoints, want) {
+		t.Fatalf(`datapoints = %v, want match for %v`, datapoints, want)
+	}
+
+	var request GrafanaQueryRequest
+	request.Range.From = start
+	request.Range.To = start.Add(24 * time.Hour)
+	request.IntervalMs = 60000
+	request.MaxDataPoints = 100
+
+	if interval := getGrafanaInterval(request); interval != 24*time.Hour/100 {
+		t.Fatalf(`interval = %v, want match for %v`, interval, 24*time.Hour/100)
+	}
+}
+
+// TestGrafanaTargets checks that targets are listed for the cluster and each node and parse back into their parts.
+func TestGrafanaTargets(t *testing.T) {
+	targets := getGrafanaTargets([]NodeJson{{Name: "node-b"}, {Name: "node-a"}}, "free.gpu")
+	want := []string{"free.gpu", "free.gpu:node-a", "free.gpu:node-b"}
+
+	if !slices.Equal(targets, want) {
+		t.Fatalf(`targets = %v, want match for %v`, targets, want)
+	}
+
+	field, resource, node, err := parseGrafanaTarget("allocatable.memory:node-a.example.com")
+
+	switch {
+	case err != nil:
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	case field != "allocatable" || resource != "memory" || node != "node-a.example.com":
+		t.Fatalf(`target = %v %v %v, want match for %v`, field, resource, node, "allocatable memory node-a.example.com")
+	}
+
+	if _, _, _, err := parseGrafanaTarget("used.cpu"); err == nil {
+		t.Fatalf(`err = %v, want match for %v`, err, "an error")
+	}
+}
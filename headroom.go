@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// HeadroomTarget is the free capacity a node pool should keep on top of what pending pods need, and the bounds
+// on its size
+type HeadroomTarget struct {
+	Pool     string            `json:"pool"`
+	Buffer   map[string]string `json:"buffer"`   // Quantities keyed by cpu, memory, gpu, or ephemeral
+	MinNodes int               `json:"minNodes"` // Never recommend fewer nodes than this
+	MaxNodes int               `json:"maxNodes"` // Never recommend more nodes than this, unless 0
+}
+
+// HeadroomRecommendation is how many nodes a pool should have to fit its pending pods and keep its buffer free
+type HeadroomRecommendation struct {
+	Pool          string        `json:"pool"`
+	Nodes         int           `json:"nodes"`
+	Desired       int           `json:"desired"`
+	Change        int           `json:"change"` // Nodes to add if positive, or remove if negative
+	NodeShape     ResourcesJson `json:"nodeShape"`
+	Free          ResourcesJson `json:"free"`
+	Buffer        ResourcesJson `json:"buffer"`
+	PendingPods   int           `json:"pendingPods"`
+	PendingDemand ResourcesJson `json:"pendingDemand"`
+}
+
+// loadHeadroomTargets reads a list of headroom targets from the JSON file at path, keyed by pool. Buffers are
+// parsed up front so a bad quantity is found at startup.
+func loadHeadroomTargets(path string) (map[string]HeadroomTarget, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var list []HeadroomTarget
+
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]HeadroomTarget)
+
+	for _, target := range list {
+		if _, err := parseQuantities(target.Buffer); err != nil {
+			return nil, fmt.Errorf("invalid buffer of pool %q: %v", target.Pool, err)
+		}
+
+		targets[target.Pool] = target
+	}
+
+	return targets, nil
+}
+
+// getHeadroomRecommendations recommends how many nodes each pool should have. Each pending pod is counted against
+// the first pool, by name, whose nodes it could be scheduled on. A pool grows by enough nodes of its average shape
+// to cover its pending demand plus its buffer, or shrinks by as many whole nodes as it could lose while still
+// covering them. Pools are sorted by name.
+func getHeadroomRecommendations(nodes []NodeJson, pending []corev1.Pod, targets map[string]HeadroomTarget) []HeadroomRecommendation {
+	members := make(map[string][]NodeJson)
+
+	for _, node := range nodes {
+		members[node.Pool] = append(members[node.Pool], node)
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	recommendations := make(map[string]*HeadroomRecommendation)
+
+	for _, name := range names {
+		poolNodes := members[name]
+		sort.Slice(poolNodes, func(i, j int) bool {
+			return poolNodes[i].Name < poolNodes[j].Name
+		})
+
+		recommendation := &HeadroomRecommendation{Pool: name, Nodes: len(poolNodes)}
+
+		var allocatable ResourcesJson
+		for _, node := range poolNodes {
+			allocatable = addResources(allocatable, node.Allocatable)
+			recommendation.Free = addResources(recommendation.Free, maxResources(node.Free, ResourcesJson{}))
+		}
+
+		for _, resource := range resourceNames {
+			setResource(&recommendation.NodeShape, resource, getResource(allocatable, resource)/float64(len(poolNodes)))
+		}
+
+		// The buffers were checked when the targets were loaded
+		recommendation.Buffer, _ = parseQuantities(targets[name].Buffer)
+
+		recommendations[name] = recommendation
+	}
+
+	for i := range pending {
+		for _, name := range names {
+			if !canSchedule(&pending[i], members[name][0]) {
+				continue
+			}
+
+			podJson := getPodStructured(&pending[i])
+			recommendations[name].PendingPods++
+			recommendations[name].PendingDemand = addResources(recommendations[name].PendingDemand, podJson.Requests)
+			break
+		}
+	}
+
+	result := make([]HeadroomRecommendation, 0, len(names))
+
+	for _, name := range names {
+		recommendation := recommendations[name]
+		recommendation.Change = getNodeChange(*recommendation)
+
+		target := targets[name]
+		recommendation.Desired = max(recommendation.Nodes+recommendation.Change, target.MinNodes, 0)
+		if target.MaxNodes > 0 {
+			recommendation.Desired = min(recommendation.Desired, target.MaxNodes)
+		}
+
+		recommendation.Change = recommendation.Desired - recommendation.Nodes
+		result = append(result, *recommendation)
+	}
+
+	return result
+}
+
+// getNodeChange returns how many nodes of a pool's shape have to be added to cover its pending demand and buffer,
+// or, if nothing is short, minus how many could be removed. Resources the pool's nodes don't have are ignored.
+func getNodeChange(recommendation HeadroomRecommendation) int {
+	needed := 0
+	spare := math.MaxInt
+
+	for _, resource := range resourceNames {
+		shape := getResource(recommendation.NodeShape, resource)
+		if shape <= 0 {
+			continue
+		}
+
+		surplus := getResource(recommendation.Free, resource) - getResource(recommendation.PendingDemand, resource) - getResource(recommendation.Buffer, resource)
+
+		if surplus < 0 {
+			needed = max(needed, int(math.Ceil(-surplus/shape)))
+		} else {
+			spare = min(spare, int(math.Floor(surplus/shape)))
+		}
+	}
+
+	if needed > 0 {
+		return needed
+	}
+
+	// A pool with no resources at all can't be sized
+	if spare == math.MaxInt {
+		return 0
+	}
+
+	return -spare
+}
+
+// getHeadroomHandler returns a HandlerFunc to return how many nodes each pool should have given a Collector and
+// the headroom targets of each pool.
+func getHeadroomHandler(collector *Collector, targets map[string]HeadroomTarget) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		pending, err := collector.UnschedulablePods()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getHeadroomRecommendations(snapshot.Nodes, pending, targets))
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// TestGetHeadroomRecommendations sizes a GPU pool that is short of GPUs for its pending pod and buffer, and a CPU
+// pool with a spare node that is held at its minimum size.
+func TestGetHeadroomRecommendations(t *testing.T) {
+	nodes := []NodeJson{
+		{Name: "gpu-1", Pool: "gpu", Labels: map[string]string{"pool": "gpu"}, Allocatable: ResourcesJson{Cpu: 32, Gpu: 4}, Free: ResourcesJson{Cpu: 20, Gpu: 1}},
+		{Name: "gpu-2", Pool: "gpu", Labels: map[string]string{"pool": "gpu"}, Allocatable: ResourcesJson{Cpu: 32, Gpu: 4}, Free: ResourcesJson{Cpu: 20, Gpu: 0}},
+		{Name: "cpu-1", Pool: "cpu", Labels: map[string]string{"pool": "cpu"}, Allocatable: ResourcesJson{Cpu: 8}, Free: ResourcesJson{Cpu: 8}},
+		{Name: "cpu-2", Pool: "cpu", Labels: map[string]string{"pool": "cpu"}, Allocatable: ResourcesJson{Cpu: 8}, Free: ResourcesJson{Cpu: 8}},
+		{Name: "cpu-3", Pool: "cpu", Labels: map[string]string{"pool": "cpu"}, Allocatable: ResourcesJson{Cpu: 8}, Free: ResourcesJson{Cpu: 3}},
+	}
+
+	trainer := newTestPod("trainer", "4", nil)
+	trainer.Spec.NodeSelector = map[string]string{"pool": "gpu"}
+	trainer.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("4")
+
+	targets := map[string]HeadroomTarget{
+		"gpu": {Pool: "gpu", Buffer: map[string]string{"gpu": "2"}},
+		"cpu": {Pool: "cpu", Buffer: map[string]string{"cpu": "4"}, MinNodes: 3},
+	}
+
+	result := getHeadroomRecommendations(nodes, []corev1.Pod{trainer}, targets)
+
+	if len(result) != 2 {
+		t.Fatalf(`len(result) = %v, want match for %v`, len(result), 2)
+	}
+
+	// 19 free CPUs less a buffer of 4 would let the CPU pool lose one node, but its minimum is 3 nodes
+	cpu, gpu := result[0], result[1]
+
+	switch {
+	case gpu.PendingPods != 1 || gpu.PendingDemand.Gpu != 4:
+		t.Fatalf(`gpu.PendingPods, gpu.PendingDemand.Gpu = %v, %v, want match for %v, %v`, gpu.PendingPods, gpu.PendingDemand.Gpu, 1, 4)
+	case gpu.Desired != 4 || gpu.Change != 2:
+		t.Fatalf(`gpu.Desired, gpu.Change = %v, %v, want match for %v, %v`, gpu.Desired, gpu.Change, 4, 2)
+	case cpu.PendingPods != 0 || cpu.Desired != 3 || cpu.Change != 0:
+		t.Fatalf(`cpu = %v, want 3 desired nodes`, cpu)
+	}
+}
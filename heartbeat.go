@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Namespace the kubelet of every node renews a Lease in to show that the node is alive
+const nodeLeaseNamespace = "kube-node-lease"
+
+// Longest a node can go without renewing its Lease before it is stale, matching the default node-monitor-grace-period
+// after which the node controller marks the node NotReady
+const defaultStaleThreshold = 40 * time.Second
+
+// getNodeHeartbeats returns the time the kubelet of each node last renewed its Lease, keyed by node name. Leases are
+// named after their nodes.
+func (c *Collector) getNodeHeartbeats() (map[string]time.Time, error) {
+	leases, err := c.client.CoordinationV1().Leases(nodeLeaseNamespace).List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	heartbeats := make(map[string]time.Time, len(leases.Items))
+	for _, lease := range leases.Items {
+		switch {
+		case lease.Spec.RenewTime != nil:
+			heartbeats[lease.Name] = lease.Spec.RenewTime.Time
+		case lease.Spec.AcquireTime != nil:
+			heartbeats[lease.Name] = lease.Spec.AcquireTime.Time
+		}
+	}
+
+	return heartbeats, nil
+}
+
+// setNodeHeartbeats sets the seconds since each node's last heartbeat at now, and marks nodes as stale if it is
+// longer than threshold. Nodes without a Lease are left without a heartbeat age and aren't stale, since clusters
+// running kubelets without Leases can't be told apart from dead nodes.
+func setNodeHeartbeats(nodes []NodeJson, heartbeats map[string]time.Time, now time.Time, threshold time.Duration) {
+	for i := range nodes {
+		heartbeat, ok := heartbeats[nodes[i].Name]
+		if !ok {
+			continue
+		}
+
+		age := math.Round(max(now.Sub(heartbeat).Seconds(), 0))
+		nodes[i].HeartbeatAge = &age
+		nodes[i].Stale = now.Sub(heartbeat) > threshold
+	}
+}
+
+// addNodeHeartbeats adds the heartbeat age and staleness of every node to a snapshot. Failing to read Leases, such
+// as when the API isn't allowed to, is logged and leaves the nodes without heartbeats rather than failing the
+// snapshot.
+func (c *Collector) addNodeHeartbeats(snapshot *Snapshot) {
+	heartbeats, err := c.getNodeHeartbeats()
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	setNodeHeartbeats(snapshot.Nodes, heartbeats, snapshot.Time, c.staleThreshold)
+}
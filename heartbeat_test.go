@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestNodeHeartbeats takes a snapshot of a node that renewed its Lease recently, one that stopped renewing it, and
+// one without a Lease, checking the heartbeat age and staleness of each.
+func TestNodeHeartbeats(t *testing.T) {
+	now := time.Now()
+	lease := func(name string, renewed time.Time) *coordinationv1.Lease {
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: nodeLeaseNamespace},
+			Spec:       coordinationv1.LeaseSpec{RenewTime: &metav1.MicroTime{Time: renewed}},
+		}
+	}
+
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "alive"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "dead"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "no-lease"}},
+		lease("alive", now.Add(-5*time.Second)),
+		lease("dead", now.Add(-5*time.Minute)),
+	)
+
+	snapshot, err := newCollector(client).Snapshot()
+
+	if err != nil {
+		t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+	}
+
+	for _, node := range snapshot.Nodes {
+		switch node.Name {
+		case "alive":
+			if node.HeartbeatAge == nil || *node.HeartbeatAge > 10 || node.Stale {
+				t.Fatalf(`alive node heartbeat = %v, %v, want a recent heartbeat that isn't stale`, node.HeartbeatAge, node.Stale)
+			}
+		case "dead":
+			if node.HeartbeatAge == nil || *node.HeartbeatAge < 300 || !node.Stale {
+				t.Fatalf(`dead node heartbeat = %v, %v, want an old heartbeat that is stale`, node.HeartbeatAge, node.Stale)
+			}
+		case "no-lease":
+			if node.HeartbeatAge != nil || node.Stale {
+				t.Fatalf(`node without a Lease heartbeat = %v, %v, want match for nil, false`, node.HeartbeatAge, node.Stale)
+			}
+		}
+	}
+
+	// Only the heartbeat age changing isn't a change to the node
+	tracker := newNodeTracker()
+	age := 5.0
+	node := NodeJson{Name: "alive", HeartbeatAge: &age}
+	tracker.Update([]NodeJson{node})
+
+	age2 := 15.0
+	node.HeartbeatAge = &age2
+
+	if version := tracker.Update([]NodeJson{node}); version != 1 {
+		t.Fatalf(`Update() with a new heartbeat age = %v, want match for %v`, version, 1)
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Names of the top-level buckets containing the raw snapshots and the hourly rollups of older snapshots -
+// each node has its own nested bucket in both
+var (
+	rawBucket    = []byte("raw")
+	hourlyBucket = []byte("hourly")
+)
+
+// Name of the top-level bucket containing the pods of each snapshot, keyed by the snapshot time
+var podsBucket = []byte("pods")
+
+// Names of the top-level buckets containing the log of capacity changes, and the last capacity of each node that
+// new snapshots are compared against
+var (
+	changesBucket  = []byte("changes")
+	capacityBucket = []byte("capacity")
+)
+
+// HistoryPoint contains the resources of a single node at a point in time
+type HistoryPoint struct {
+	Time        time.Time     `json:"time"`
+	Allocatable ResourcesJson `json:"allocatable"`
+	Capacity    ResourcesJson `json:"capacity"`
+	Free        ResourcesJson `json:"free"`
+}
+
+// HistoryBackend is anything that can return the history of a node over a time range
+type HistoryBackend interface {
+	Query(node string, from, to time.Time) ([]HistoryPoint, error)
+}
+
+// HistoryStore persists node snapshots in an embedded bbolt database
+type HistoryStore struct {
+	db *bolt.DB
+}
+
+// openHistoryStore opens the bbolt database at path, creating it if it doesn't exist.
+func openHistoryStore(path string) (*HistoryStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the top-level buckets exist so readers never have to check for them
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{rawBucket, hourlyBucket, podsBucket, changesBucket, capacityBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &HistoryStore{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+// timeKey encodes a time as a big-endian byte slice so keys sort chronologically in the database
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// Record adds a point to the history of every node in the snapshot, and saves the pods in the snapshot.
+func (h *HistoryStore) Record(snapshot *Snapshot) error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(rawBucket)
+
+		// Save every pod together, since pods are always read as a whole snapshot
+		if len(snapshot.Pods) > 0 {
+			value, err := json.Marshal(snapshot.Pods)
+
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Bucket(podsBucket).Put(timeKey(snapshot.Time), value); err != nil {
+				return err
+			}
+		}
+
+		for _, node := range snapshot.Nodes {
+			// Each node gets its own bucket keyed by the snapshot time
+			bucket, err := raw.CreateBucketIfNotExists([]byte(node.Name))
+
+			if err != nil {
+				return err
+			}
+
+			value, err := json.Marshal(HistoryPoint{
+				Time:        snapshot.Time,
+				Allocatable: node.Allocatable,
+				Capacity:    node.Capacity,
+				Free:        node.Free,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(timeKey(snapshot.Time), value)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Query returns the history points of a node between from and to (inclusive), ordered by time.
+// Hourly rollups are returned for the part of the range that has already been compacted.
+func (h *HistoryStore) Query(node string, from, to time.Time) ([]HistoryPoint, error) {
+	points := make([]HistoryPoint, 0)
+
+	err := h.db.View(func(tx *bolt.Tx) error {
+		// Rollups are always older than the raw points, so reading them first keeps the points in order
+		for _, name := range [][]byte{hourlyBucket, rawBucket} {
+			bucket := tx.Bucket(name).Bucket([]byte(node))
+
+			// A node with no bucket has no history
+			if bucket == nil {
+				continue
+			}
+
+			cursor := bucket.Cursor()
+			end := timeKey(to)
+
+			// Seek to the first point at or after from and walk forward until passing to
+			for key, value := cursor.Seek(timeKey(from)); key != nil && bytes.Compare(key, end) <= 0; key, value = cursor.Next() {
+				var point HistoryPoint
+
+				if err := json.Unmarshal(value, &point); err != nil {
+					return err
+				}
+
+				points = append(points, point)
+			}
+		}
+
+		return nil
+	})
+
+	return points, err
+}
+
+// QueryPods returns the pods of every snapshot between from and to (inclusive), ordered by time.
+// The returned snapshots don't contain any nodes.
+func (h *HistoryStore) QueryPods(from, to time.Time) ([]Snapshot, error) {
+	snapshots := make([]Snapshot, 0)
+
+	err := h.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(podsBucket).Cursor()
+		end := timeKey(to)
+
+		for key, value := cursor.Seek(timeKey(from)); key != nil && bytes.Compare(key, end) <= 0; key, value = cursor.Next() {
+			snapshot := Snapshot{Time: time.Unix(0, int64(binary.BigEndian.Uint64(key))).UTC()}
+
+			if err := json.Unmarshal(value, &snapshot.Pods); err != nil {
+				return err
+			}
+
+			snapshots = append(snapshots, snapshot)
+		}
+
+		return nil
+	})
+
+	return snapshots, err
+}
+
+// Compact replaces raw points older than rawRetention with one averaged point per hour, and deletes
+// hourly points older than rollupRetention. Only whole hours are compacted so each hour is rolled up exactly once.
+// Pods aren't rolled up, so they are deleted along with the raw points.
+func (h *HistoryStore) Compact(now time.Time, rawRetention, rollupRetention time.Duration) error {
+	rawCutoff := timeKey(now.Add(-rawRetention).Truncate(time.Hour))
+	rollupCutoff := timeKey(now.Add(-rollupRetention))
+
+	return h.db.Update(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(rawBucket)
+		hourly := tx.Bucket(hourlyBucket)
+
+		// Delete the pods of every snapshot older than the cutoff
+		var expiredPods [][]byte
+		podsCursor := tx.Bucket(podsBucket).Cursor()
+		for key, _ := podsCursor.First(); key != nil && bytes.Compare(key, rawCutoff) < 0; key, _ = podsCursor.Next() {
+			expiredPods = append(expiredPods, key)
+		}
+
+		for _, key := range expiredPods {
+			if err := tx.Bucket(podsBucket).Delete(key); err != nil {
+				return err
+			}
+		}
+
+		// Collect the node names first, since buckets can't be created while iterating over raw
+		var nodes [][]byte
+		err := raw.ForEachBucket(func(name []byte) error {
+			nodes = append(nodes, append([]byte(nil), name...))
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			rawNode := raw.Bucket(node)
+
+			// Group every raw point older than the cutoff by the hour it was taken in
+			hours := make(map[time.Time][]HistoryPoint)
+			var expired [][]byte
+
+			cursor := rawNode.Cursor()
+			for key, value := cursor.First(); key != nil && bytes.Compare(key, rawCutoff) < 0; key, value = cursor.Next() {
+				var point HistoryPoint
+
+				if err := json.Unmarshal(value, &point); err != nil {
+					return err
+				}
+
+				hour := point.Time.Truncate(time.Hour)
+				hours[hour] = append(hours[hour], point)
+				expired = append(expired, key)
+			}
+
+			hourlyNode, err := hourly.CreateBucketIfNotExists(node)
+
+			if err != nil {
+				return err
+			}
+
+			// Write one averaged point for each hour
+			for hour, points := range hours {
+				value, err := json.Marshal(averageHistoryPoints(hour, points))
+
+				if err != nil {
+					return err
+				}
+
+				if err := hourlyNode.Put(timeKey(hour), value); err != nil {
+					return err
+				}
+			}
+
+			// Remove the raw points that have been rolled up
+			for _, key := range expired {
+				if err := rawNode.Delete(key); err != nil {
+					return err
+				}
+			}
+
+			// Remove rollups that have passed their own retention
+			expired = expired[:0]
+			cursor = hourlyNode.Cursor()
+			for key, _ := cursor.First(); key != nil && bytes.Compare(key, rollupCutoff) < 0; key, _ = cursor.Next() {
+				expired = append(expired, key)
+			}
+
+			for _, key := range expired {
+				if err := hourlyNode.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// averageHistoryPoints returns a single point at time t with each resource averaged over points.
+func averageHistoryPoints(t time.Time, points []HistoryPoint) HistoryPoint {
+	allocatable := make([]ResourcesJson, len(points))
+	capacity := make([]ResourcesJson, len(points))
+	free := make([]ResourcesJson, len(points))
+
+	for i, point := range points {
+		allocatable[i] = point.Allocatable
+		capacity[i] = point.Capacity
+		free[i] = point.Free
+	}
+
+	return HistoryPoint{
+		Time:        t,
+		Allocatable: averageResources(allocatable),
+		Capacity:    averageResources(capacity),
+		Free:        averageResources(free),
+	}
+}
+
+// averageResources returns the average of each field of a list of ResourcesJson struct instances.
+func averageResources(list []ResourcesJson) ResourcesJson {
+	var sum ResourcesJson
+
+	if len(list) == 0 {
+		return sum
+	}
+
+	for _, resources := range list {
+		sum.Cpu += resources.Cpu
+		sum.Memory += resources.Memory
+		sum.Gpu += resources.Gpu
+		sum.Ephemeral += resources.Ephemeral
+	}
+
+	count := int64(len(list))
+
+	return ResourcesJson{
+		Cpu:       sum.Cpu / float64(count),
+		Memory:    sum.Memory / count,
+		Gpu:       sum.Gpu / count,
+		Ephemeral: sum.Ephemeral / count,
+	}
+}
+
+// runCompactionLoop compacts the history store every interval with the given retention periods.
+// It blocks forever, so it should be run in its own goroutine.
+func runCompactionLoop(store *HistoryStore, interval, rawRetention, rollupRetention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := store.Compact(time.Now(), rawRetention, rollupRetention); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// getNodeHistoryHandler returns a HandlerFunc to return the history of a node given a HistoryBackend.
+// The node is given by the node query parameter, and the range by the from and to parameters in RFC 3339 format.
+func getNodeHistoryHandler(store HistoryBackend) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		node := c.Query("node")
+
+		if node == "" {
+			c.JSON(http.StatusBadRequest, "error: expected node parameter")
+			return
+		}
+
+		// Default to the last day of history
+		from, to, ok := getTimeRange(c, 24*time.Hour)
+		if !ok {
+			return
+		}
+
+		points, err := store.Query(node, from, to)
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node history")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, points)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getTimeRange parses the from and to query parameters of a request as RFC 3339 timestamps. If to is missing it
+// defaults to now, and if from is missing it defaults to def before to. If either is invalid, an error is sent
+// as the response and ok is false.
+func getTimeRange(c *gin.Context, def time.Duration) (from, to time.Time, ok bool) {
+	var err error
+
+	to = time.Now()
+	if value := c.Query("to"); value != "" {
+		to, err = time.Parse(time.RFC3339, value)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "error: to must be an RFC 3339 timestamp")
+			return from, to, false
+		}
+	}
+
+	from = to.Add(-def)
+	if value := c.Query("from"); value != "" {
+		from, err = time.Parse(time.RFC3339, value)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "error: from must be an RFC 3339 timestamp")
+			return from, to, false
+		}
+	}
+
+	return from, to, true
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHistoryStore records snapshots in a HistoryStore, checking that Query returns only the points of the
+// requested node within the requested time range, ordered by time.
+func TestHistoryStore(t *testing.T) {
+	store, err := openHistoryStore(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatalf(`openHistoryStore returned error %v`, err)
+	}
+
+	defer store.Close()
+
+	start := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	// Record one snapshot per hour with node-1's free CPU going down by one each time
+	for i := 0; i < 5; i++ {
+		snapshot := Snapshot{
+			Time: start.Add(time.Duration(i) * time.Hour),
+			Nodes: []NodeJson{
+				{Name: "node-1", Free: ResourcesJson{Cpu: float64(10 - i)}},
+				{Name: "node-2", Free: ResourcesJson{Cpu: 20}},
+			},
+		}
+
+		if err := store.Record(&snapshot); err != nil {
+			t.Fatalf(`Record returned error %v`, err)
+		}
+	}
+
+	points, err := store.Query("node-1", start.Add(time.Hour), start.Add(3*time.Hour))
+
+	if err != nil {
+		t.Fatalf(`Query returned error %v`, err)
+	}
+
+	if len(points) != 3 {
+		t.Fatalf(`len(points) = %v, want match for %v`, len(points), 3)
+	}
+
+	for i, point := range points {
+		wantTime := start.Add(time.Duration(i+1) * time.Hour)
+		wantCpu := float64(10 - (i + 1))
+
+		switch {
+		case !point.Time.Equal(wantTime):
+			t.Fatalf(`points[%v].Time = %v, want match for %v`, i, point.Time, wantTime)
+		case point.Free.Cpu != wantCpu:
+			t.Fatalf(`points[%v].Free.Cpu = %v, want match for %v`, i, point.Free.Cpu, wantCpu)
+		}
+	}
+
+	// A node that was never recorded should have no history
+	points, err = store.Query("node-3", start, start.Add(5*time.Hour))
+
+	if err != nil {
+		t.Fatalf(`Query returned error %v`, err)
+	}
+
+	if len(points) != 0 {
+		t.Fatalf(`len(points) = %v, want match for %v`, len(points), 0)
+	}
+}
+
+// TestHistoryStoreCompact records a day of snapshots every 30 minutes and compacts them, checking that old raw
+// points are averaged into hourly points, recent raw points are kept, and expired rollups are deleted.
+func TestHistoryStoreCompact(t *testing.T) {
+	store, err := openHistoryStore(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatalf(`openHistoryStore returned error %v`, err)
+	}
+
+	defer store.Close()
+
+	start := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	// Record a snapshot every 30 minutes for 24 hours, alternating node-1's free CPU between 2 and 4
+	for i := 0; i < 48; i++ {
+		snapshot := Snapshot{
+			Time:  start.Add(time.Duration(i) * 30 * time.Minute),
+			Nodes: []NodeJson{{Name: "node-1", Free: ResourcesJson{Cpu: float64(2 + 2*(i%2)), Memory: int64(100 * (i % 2))}}},
+		}
+
+		if err := store.Record(&snapshot); err != nil {
+			t.Fatalf(`Record returned error %v`, err)
+		}
+	}
+
+	// Keep the last 12 hours raw and the last 18 hours of rollups
+	now := start.Add(24 * time.Hour)
+	if err := store.Compact(now, 12*time.Hour, 18*time.Hour); err != nil {
+		t.Fatalf(`Compact returned error %v`, err)
+	}
+
+	points, err := store.Query("node-1", start, now)
+
+	if err != nil {
+		t.Fatalf(`Query returned error %v`, err)
+	}
+
+	// Hours 6 through 11 should be rolled up, and hours 12 through 23 should still have two raw points each
+	if len(points) != 6+24 {
+		t.Fatalf(`len(points) = %v, want match for %v`, len(points), 6+24)
+	}
+
+	for i, point := range points[:6] {
+		wantTime := start.Add(time.Duration(6+i) * time.Hour)
+
+		switch {
+		case !point.Time.Equal(wantTime):
+			t.Fatalf(`points[%v].Time = %v, want match for %v`, i, point.Time, wantTime)
+		case point.Free.Cpu != 3:
+			t.Fatalf(`points[%v].Free.Cpu = %v, want match for %v`, i, point.Free.Cpu, 3)
+		case point.Free.Memory != 50:
+			t.Fatalf(`points[%v].Free.Memory = %v, want match for %v`, i, point.Free.Memory, 50)
+		}
+	}
+
+	if wantTime := start.Add(12 * time.Hour); !points[6].Time.Equal(wantTime) {
+		t.Fatalf(`points[6].Time = %v, want match for %v`, points[6].Time, wantTime)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdleTotals contains the resources of a group of nodes that no pod has requested
+type IdleTotals struct {
+	Nodes          int           `json:"nodes"`
+	Allocatable    ResourcesJson `json:"allocatable"`
+	Idle           ResourcesJson `json:"idle"`
+	IdleHourlyCost *float64      `json:"idleHourlyCost,omitempty"`
+}
+
+// IdleNode contains the resources of a single node that no pod has requested
+type IdleNode struct {
+	Name           string        `json:"name"`
+	Pool           string        `json:"pool"`
+	CapacityType   string        `json:"capacityType"`
+	Idle           ResourcesJson `json:"idle"`
+	IdleFraction   float64       `json:"idleFraction"`
+	IdleHourlyCost *float64      `json:"idleHourlyCost,omitempty"`
+}
+
+// IdleReport contains the idle capacity of the cluster, each node pool, each capacity type, and each node
+type IdleReport struct {
+	Cluster        IdleTotals            `json:"cluster"`
+	ByPool         map[string]IdleTotals `json:"byPool"`
+	ByCapacityType map[string]IdleTotals `json:"byCapacityType"`
+	Nodes          []IdleNode            `json:"nodes"`
+}
+
+// getIdleReport calculates the allocatable resources minus the requested resources of every node, and sums them
+// for the whole cluster, each pool, and each capacity type. Overcommitted nodes count as having nothing idle rather
+// than cancelling out idle capacity elsewhere. Nodes are sorted by idle fraction, most idle first.
+func getIdleReport(nodes []NodeJson) IdleReport {
+	report := IdleReport{
+		ByPool:         make(map[string]IdleTotals),
+		ByCapacityType: make(map[string]IdleTotals),
+		Nodes:          make([]IdleNode, 0, len(nodes)),
+	}
+
+	for _, node := range nodes {
+		idleNode := IdleNode{
+			Name:         node.Name,
+			Pool:         node.Pool,
+			CapacityType: node.CapacityType,
+			Idle:         maxResources(node.Free, ResourcesJson{}),
+			IdleFraction: getIdleFraction(node),
+		}
+
+		if node.HourlyCost != nil {
+			cost := *node.HourlyCost * idleNode.IdleFraction
+			idleNode.IdleHourlyCost = &cost
+		}
+
+		report.Nodes = append(report.Nodes, idleNode)
+		report.Cluster = addToIdleTotals(report.Cluster, node, idleNode)
+		report.ByPool[node.Pool] = addToIdleTotals(report.ByPool[node.Pool], node, idleNode)
+		report.ByCapacityType[node.CapacityType] = addToIdleTotals(report.ByCapacityType[node.CapacityType], node, idleNode)
+	}
+
+	sort.SliceStable(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].IdleFraction > report.Nodes[j].IdleFraction
+	})
+
+	return report
+}
+
+// addToIdleTotals adds the allocatable and idle resources of a node to an IdleTotals struct instance.
+func addToIdleTotals(totals IdleTotals, node NodeJson, idleNode IdleNode) IdleTotals {
+	totals.Nodes++
+	totals.Allocatable = addResources(totals.Allocatable, node.Allocatable)
+	totals.Idle = addResources(totals.Idle, idleNode.Idle)
+
+	if idleNode.IdleHourlyCost != nil {
+		cost := *idleNode.IdleHourlyCost
+		if totals.IdleHourlyCost != nil {
+			cost += *totals.IdleHourlyCost
+		}
+		totals.IdleHourlyCost = &cost
+	}
+
+	return totals
+}
+
+// maxResources returns the larger of each field of a and b.
+func maxResources(a, b ResourcesJson) ResourcesJson {
+	return ResourcesJson{
+		Cpu:       max(a.Cpu, b.Cpu),
+		Memory:    max(a.Memory, b.Memory),
+		Gpu:       max(a.Gpu, b.Gpu),
+		Ephemeral: max(a.Ephemeral, b.Ephemeral),
+	}
+}
+
+// getIdleReportHandler returns a HandlerFunc to return the idle capacity report given a Collector.
+func getIdleReportHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getIdleReport(snapshot.Nodes))
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+// TestGetIdleReport calculates the idle capacity of three nodes, checking the cluster, pool, and capacity type
+// totals, that overcommitted resources count as zero, and that idle cost is only added up for nodes with a cost.
+func TestGetIdleReport(t *testing.T) {
+	cost := 4.0
+
+	nodes := []NodeJson{
+		{Name: "node-1", Pool: "cpu", CapacityType: "spot", Allocatable: ResourcesJson{Cpu: 4, Memory: 100}, Free: ResourcesJson{Cpu: 4, Memory: 100}, HourlyCost: &cost},
+		{Name: "node-2", Pool: "cpu", CapacityType: "on-demand", Allocatable: ResourcesJson{Cpu: 4, Memory: 100}, Free: ResourcesJson{Cpu: -1, Memory: 50}},
+		{Name: "node-3", Pool: "gpu", CapacityType: "on-demand", Allocatable: ResourcesJson{Cpu: 8, Memory: 100, Gpu: 2}, Free: ResourcesJson{Cpu: 2, Memory: 0, Gpu: 1}},
+	}
+
+	report := getIdleReport(nodes)
+
+	switch {
+	case report.Cluster.Nodes != 3:
+		t.Fatalf(`report.Cluster.Nodes = %v, want match for %v`, report.Cluster.Nodes, 3)
+	case report.Cluster.Idle != (ResourcesJson{Cpu: 6, Memory: 150, Gpu: 1}):
+		t.Fatalf(`report.Cluster.Idle = %v, want match for %v`, report.Cluster.Idle, ResourcesJson{Cpu: 6, Memory: 150, Gpu: 1})
+	case report.Cluster.IdleHourlyCost == nil || *report.Cluster.IdleHourlyCost != 4:
+		t.Fatalf(`report.Cluster.IdleHourlyCost = %v, want match for %v`, report.Cluster.IdleHourlyCost, 4)
+	case report.ByPool["cpu"].Idle != (ResourcesJson{Cpu: 4, Memory: 150}):
+		t.Fatalf(`report.ByPool["cpu"].Idle = %v, want match for %v`, report.ByPool["cpu"].Idle, ResourcesJson{Cpu: 4, Memory: 150})
+	case report.ByCapacityType["on-demand"].Nodes != 2 || report.ByCapacityType["on-demand"].IdleHourlyCost != nil:
+		t.Fatalf(`report.ByCapacityType["on-demand"] = %v, want 2 nodes with no cost`, report.ByCapacityType["on-demand"])
+	case report.Nodes[0].Name != "node-1" || report.Nodes[0].IdleFraction != 1:
+		t.Fatalf(`report.Nodes[0] = %v, want node-1 fully idle`, report.Nodes[0])
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Image garbage collection thresholds of kubelets that don't set them, in percent of the image filesystem used
+const (
+	defaultImageGcHighThreshold = 85
+	defaultImageGcLowThreshold  = 80
+)
+
+// Percentage points below the high threshold at which a node is near image garbage collection
+const imageGcMargin = 5
+
+// NodeImages contains the container images on a node. Kubelets only report their 50 largest images by default, so
+// nodes with more have more than are counted here.
+type NodeImages struct {
+	Count           int      `json:"count"`
+	Size            int64    `json:"size"`                      // Bytes of the images counted
+	GcHighThreshold *int32   `json:"gcHighThreshold,omitempty"` // Percent of the image filesystem used past which images are deleted
+	GcLowThreshold  *int32   `json:"gcLowThreshold,omitempty"`  // Percent of the image filesystem images are deleted down to
+	UsedPercent     *float64 `json:"usedPercent,omitempty"`     // Percent of the image filesystem used, if the kubelet's stats were read
+	NearGc          bool     `json:"nearGc"`                    // True if the image filesystem is used within 5 points of the high threshold
+}
+
+// getNodeImages returns the number and total size of the images in a node's status.
+func getNodeImages(node *corev1.Node) NodeImages {
+	images := NodeImages{Count: len(node.Status.Images)}
+
+	for _, image := range node.Status.Images {
+		images.Size += image.SizeBytes
+	}
+
+	return images
+}
+
+// setNodeImageGc sets the image garbage collection thresholds of each node from its kubelet's configuration, and
+// whether the filesystem holding its images is near the high threshold, past which the kubelet deletes unused images
+// and pods pulling new ones may wait on it. Images are on the node's filesystem unless its kubelet reports a separate
+// image filesystem, and a high threshold of 100 turns garbage collection off. Nodes whose kubelet configuration
+// wasn't read are assumed to use the default thresholds, and nodes whose kubelet stats weren't read are never near
+// them.
+func setNodeImageGc(nodes []NodeJson, configs map[string]*KubeletConfig) {
+	for i := range nodes {
+		images := &nodes[i].Images
+
+		high, low := int32(defaultImageGcHighThreshold), int32(defaultImageGcLowThreshold)
+		if config, ok := configs[nodes[i].Name]; ok {
+			if config.ImageGCHighThresholdPercent != nil {
+				high = *config.ImageGCHighThresholdPercent
+			}
+
+			if config.ImageGCLowThresholdPercent != nil {
+				low = *config.ImageGCLowThresholdPercent
+			}
+
+			images.GcHighThreshold = &high
+			images.GcLowThreshold = &low
+		}
+
+		usage := nodes[i].DiskUsage
+		if usage == nil {
+			continue
+		}
+
+		fs := usage.ImageFs
+		if fs == nil {
+			fs = usage.NodeFs
+		}
+
+		if fs == nil {
+			continue
+		}
+
+		usedPercent := fs.UsedPercent
+		images.UsedPercent = &usedPercent
+		images.NearGc = high < 100 && usedPercent >= float64(high-imageGcMargin)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestNodeImages takes a snapshot of a node with two images in its status, checking their count and size.
+func TestNodeImages(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Images: []corev1.ContainerImage{
+				{Names: []string{"nginx:1.27"}, SizeBytes: 70000000},
+				{Names: []string{"pytorch:2.4"}, SizeBytes: 9000000000},
+			},
+		},
+	})
+
+	snapshot, err := newCollector(client).Snapshot()
+
+	if err != nil {
+		t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+	}
+
+	images := snapshot.Nodes[0].Images
+
+	switch {
+	case images.Count != 2 || images.Size != 9070000000:
+		t.Fatalf(`images = %v, %v, want match for 2, %v`, images.Count, images.Size, 9070000000)
+	case images.UsedPercent != nil || images.NearGc:
+		t.Fatalf(`images without kubelet stats = %v, %v, want match for nil, false`, images.UsedPercent, images.NearGc)
+	}
+}
+
+// TestSetNodeImageGc checks which nodes are near image garbage collection with the default thresholds and with those
+// of their kubelet's configuration, using the image filesystem if it is separate and the node's otherwise.
+func TestSetNodeImageGc(t *testing.T) {
+	high, disabled := int32(95), int32(100)
+	usage := func(nodeFs float64, imageFs float64) *NodeDiskUsage {
+		usage := &NodeDiskUsage{NodeFs: &FsUsage{UsedPercent: nodeFs}}
+		if imageFs > 0 {
+			usage.ImageFs = &FsUsage{UsedPercent: imageFs}
+		}
+		return usage
+	}
+
+	nodes := []NodeJson{
+		{Name: "default-near", DiskUsage: usage(82, 0)},
+		{Name: "default-far", DiskUsage: usage(60, 0)},
+		{Name: "image-fs-near", DiskUsage: usage(20, 90)},
+		{Name: "configured-far", DiskUsage: usage(85, 0)},
+		{Name: "disabled", DiskUsage: usage(99, 0)},
+		{Name: "no-stats"},
+	}
+
+	configs := map[string]*KubeletConfig{
+		"configured-far": {ImageGCHighThresholdPercent: &high},
+		"disabled":       {ImageGCHighThresholdPercent: &disabled},
+	}
+
+	setNodeImageGc(nodes, configs)
+
+	want := map[string]bool{"default-near": true, "image-fs-near": true}
+
+	for _, node := range nodes {
+		switch {
+		case node.Images.NearGc != want[node.Name]:
+			t.Fatalf(`%v nearGc = %v, want match for %v`, node.Name, node.Images.NearGc, want[node.Name])
+		case node.Name == "configured-far" && (node.Images.GcHighThreshold == nil || *node.Images.GcHighThreshold != 95):
+			t.Fatalf(`configured-far gcHighThreshold = %v, want match for 95`, node.Images.GcHighThreshold)
+		case node.Name == "configured-far" && *node.Images.GcLowThreshold != defaultImageGcLowThreshold:
+			t.Fatalf(`configured-far gcLowThreshold = %v, want match for %v`, *node.Images.GcLowThreshold, defaultImageGcLowThreshold)
+		case node.Name == "default-near" && node.Images.GcHighThreshold != nil:
+			t.Fatalf(`default-near gcHighThreshold = %v, want match for nil`, *node.Images.GcHighThreshold)
+		case node.Name == "no-stats" && node.Images.UsedPercent != nil:
+			t.Fatalf(`no-stats usedPercent = %v, want match for nil`, *node.Images.UsedPercent)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Largest snapshot that can be imported, in bytes
+const maxImportSize = 64 << 20
+
+// ImportedSnapshotInfo describes a snapshot imported through POST /snapshots
+type ImportedSnapshotInfo struct {
+	Id       string    `json:"id"`
+	Time     time.Time `json:"time"` // Time the snapshot was taken
+	Imported time.Time `json:"imported"`
+	Nodes    int       `json:"nodes"`
+	Pods     int       `json:"pods"`
+}
+
+// ImportedSnapshots holds snapshots uploaded for offline analysis, each served by its own Collector. Only the most
+// recent imports are kept, so memory use is bounded.
+type ImportedSnapshots struct {
+	mutex      sync.Mutex
+	max        int
+	collectors map[string]*Collector
+	infos      []ImportedSnapshotInfo // In order of import
+}
+
+// newImportedSnapshots returns an ImportedSnapshots that keeps at most max snapshots, forgetting the oldest import
+// when another is added.
+func newImportedSnapshots(max int) *ImportedSnapshots {
+	return &ImportedSnapshots{max: max, collectors: make(map[string]*Collector), infos: make([]ImportedSnapshotInfo, 0)}
+}
+
+// Add stores a snapshot and returns the information it can be found by.
+func (s *ImportedSnapshots) Add(snapshot *Snapshot) (ImportedSnapshotInfo, error) {
+	id := make([]byte, 8)
+
+	if _, err := rand.Read(id); err != nil {
+		return ImportedSnapshotInfo{}, err
+	}
+
+	info := ImportedSnapshotInfo{
+		Id:       hex.EncodeToString(id),
+		Time:     snapshot.Time,
+		Imported: time.Now(),
+		Nodes:    len(snapshot.Nodes),
+		Pods:     len(snapshot.Pods),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for len(s.infos) >= s.max {
+		delete(s.collectors, s.infos[0].Id)
+		s.infos = s.infos[1:]
+	}
+
+	s.collectors[info.Id] = &Collector{imported: snapshot, tracker: newNodeTracker()}
+	s.infos = append(s.infos, info)
+
+	return info, nil
+}
+
+// Get returns the Collector serving the snapshot with the given ID, or false if there is no such snapshot.
+func (s *ImportedSnapshots) Get(id string) (*Collector, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	collector, ok := s.collectors[id]
+
+	return collector, ok
+}
+
+// List returns every stored snapshot in order of import.
+func (s *ImportedSnapshots) List() []ImportedSnapshotInfo {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return slices.Clone(s.infos)
+}
+
+// Delete forgets the snapshot with the given ID, returning false if there is no such snapshot.
+func (s *ImportedSnapshots) Delete(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.collectors[id]; !ok {
+		return false
+	}
+
+	delete(s.collectors, id)
+	s.infos = slices.DeleteFunc(s.infos, func(info ImportedSnapshotInfo) bool { return info.Id == id })
+
+	return true
+}
+
+// importedSnapshot returns a copy of the imported snapshot. Handlers sort and filter the nodes and pods of the
+// snapshots they're given, so each gets its own slices.
+func (c *Collector) importedSnapshot() *Snapshot {
+	snapshot := *c.imported
+	snapshot.Nodes = slices.Clone(c.imported.Nodes)
+	snapshot.Pods = slices.Clone(c.imported.Pods)
+
+	return &snapshot
+}
+
+// importedNodePods returns the pods of the imported snapshot on the named node. If the node isn't in the snapshot,
+// a NotFound error is returned.
+func (c *Collector) importedNodePods(name string) ([]PodJson, error) {
+	if !slices.ContainsFunc(c.imported.Nodes, func(node NodeJson) bool { return node.Name == name }) {
+		return nil, errors.NewNotFound(corev1.Resource("nodes"), name)
+	}
+
+	pods := make([]PodJson, 0)
+	for _, pod := range c.imported.Pods {
+		if pod.Node == name {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods, nil
+}
+
+// serve returns a HandlerFunc that runs the handler newHandler creates for the Collector of the snapshot given by the
+// id path parameter, so the usual endpoints can be served for imported snapshots.
+func (s *ImportedSnapshots) serve(newHandler func(*Collector) gin.HandlerFunc) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		collector, ok := s.Get(c.Param("id"))
+
+		if !ok {
+			c.JSON(http.StatusNotFound, "error: snapshot not found")
+			return
+		}
+
+		newHandler(collector)(c)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getImportSnapshotHandler returns a HandlerFunc to import a snapshot, given in the request body in the JSON format
+// it is exported in, into an ImportedSnapshots.
+func getImportSnapshotHandler(snapshots *ImportedSnapshots) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+		var snapshot Snapshot
+
+		if err := c.ShouldBindJSON(&snapshot); err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		if len(snapshot.Nodes) == 0 {
+			c.JSON(http.StatusBadRequest, "error: snapshot has no nodes")
+			return
+		}
+
+		info, err := snapshots.Add(&snapshot)
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error importing snapshot")
+			return
+		}
+
+		c.IndentedJSON(http.StatusCreated, info)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getImportedSnapshotsHandler returns a HandlerFunc to list the snapshots in an ImportedSnapshots.
+func getImportedSnapshotsHandler(snapshots *ImportedSnapshots) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		c.IndentedJSON(http.StatusOK, snapshots.List())
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getDeleteImportedSnapshotHandler returns a HandlerFunc to delete the snapshot given by the id path parameter from
+// an ImportedSnapshots.
+func getDeleteImportedSnapshotHandler(snapshots *ImportedSnapshots) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		if !snapshots.Delete(c.Param("id")) {
+			c.JSON(http.StatusNotFound, "error: snapshot not found")
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestImportedSnapshots imports an exported snapshot and queries it, checking that its nodes and pods are served and
+// that the oldest import is forgotten once too many are stored.
+func TestImportedSnapshots(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	snapshot := Snapshot{
+		Nodes: []NodeJson{
+			{Name: "node-1", Allocatable: ResourcesJson{Cpu: 8}, Free: ResourcesJson{Cpu: 2}},
+			{Name: "node-2", Allocatable: ResourcesJson{Cpu: 8}, Free: ResourcesJson{Cpu: 6}},
+		},
+		Pods: []PodJson{{Name: "web", Namespace: "default", Node: "node-1"}},
+	}
+
+	body, _, _ := encodeSnapshot(&snapshot, "json")
+	imported := newImportedSnapshots(1)
+
+	router := gin.New()
+	router.POST("/snapshots", getImportSnapshotHandler(imported))
+	router.GET("/snapshots/:id/summary", imported.serve(getSummaryHandler))
+	router.GET("/snapshots/:id/nodes/:name/pods", imported.serve(getNodePodsHandler))
+
+	post := func() ImportedSnapshotInfo {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/snapshots", strings.NewReader(string(body))))
+
+		var info ImportedSnapshotInfo
+		if err := json.Unmarshal(recorder.Body.Bytes(), &info); err != nil || recorder.Code != http.StatusCreated {
+			t.Fatalf(`POST /snapshots = %v, %v, want match for %v`, recorder.Code, recorder.Body.String(), http.StatusCreated)
+		}
+
+		return info
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	first := post()
+
+	var summary ClusterSummary
+	recorder := get("/snapshots/" + first.Id + "/summary")
+	json.Unmarshal(recorder.Body.Bytes(), &summary)
+
+	switch {
+	case first.Nodes != 2 || first.Pods != 1:
+		t.Fatalf(`info = %v, want match for %v nodes and %v pods`, first, 2, 1)
+	case summary.Nodes != 2 || summary.Free.Cpu != 8:
+		t.Fatalf(`summary = %v, want match for %v nodes with %v free CPU`, summary, 2, 8)
+	case !strings.Contains(get("/snapshots/"+first.Id+"/nodes/node-1/pods").Body.String(), `"web"`):
+		t.Fatalf(`pods of node-1 = %v, want match for %v`, get("/snapshots/"+first.Id+"/nodes/node-1/pods").Body.String(), "web")
+	case get("/snapshots/"+first.Id+"/nodes/node-3/pods").Code != http.StatusNotFound:
+		t.Fatalf(`status = %v, want match for %v`, get("/snapshots/"+first.Id+"/nodes/node-3/pods").Code, http.StatusNotFound)
+	}
+
+	second := post()
+
+	if get("/snapshots/"+first.Id+"/summary").Code != http.StatusNotFound || get("/snapshots/"+second.Id+"/summary").Code != http.StatusOK {
+		t.Fatalf(`first snapshot still stored, want match for %v`, "only the second")
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Escapes special characters in line protocol tag keys and values
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+
+// InfluxWriter writes snapshots to an InfluxDB bucket through the v2 HTTP write API
+type InfluxWriter struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// newInfluxWriter returns an InfluxWriter for bucket in org on the server at baseUrl, authenticating with token.
+func newInfluxWriter(baseUrl, org, bucket, token string) *InfluxWriter {
+	params := url.Values{}
+	params.Set("org", org)
+	params.Set("bucket", bucket)
+	params.Set("precision", "s")
+
+	return &InfluxWriter{
+		url:    strings.TrimSuffix(baseUrl, "/") + "/api/v2/write?" + params.Encode(),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Write sends a snapshot to InfluxDB as line protocol.
+func (w *InfluxWriter) Write(snapshot *Snapshot) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(encodeLineProtocol(snapshot)))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("influxdb write returned status %v", resp.Status)
+	}
+
+	return nil
+}
+
+// encodeLineProtocol formats a snapshot as InfluxDB line protocol. Each node has a node_resources point per
+// resource, tagged with the node, pool, capacity type, and resource, with allocatable, capacity, and free fields.
+// The sums over the cluster are written the same way as cluster_resources points. Every field is a float so that
+// CPUs and bytes can share a field without a type conflict. Nodes are sorted by name.
+func encodeLineProtocol(snapshot *Snapshot) []byte {
+	var buf bytes.Buffer
+	timestamp := strconv.FormatInt(snapshot.Time.Unix(), 10)
+
+	nodes := append([]NodeJson(nil), snapshot.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	writePoint := func(measurement string, tags [][2]string, allocatable, capacity, free ResourcesJson) {
+		for _, resource := range resourceNames {
+			buf.WriteString(measurement)
+
+			for _, tag := range append(tags, [2]string{"resource", resource}) {
+				// Line protocol doesn't allow empty tag values
+				if tag[1] == "" {
+					continue
+				}
+
+				fmt.Fprintf(&buf, ",%v=%v", tag[0], influxTagEscaper.Replace(tag[1]))
+			}
+
+			fmt.Fprintf(&buf, " allocatable=%v,capacity=%v,free=%v %v\n",
+				strconv.FormatFloat(getResource(allocatable, resource), 'f', -1, 64),
+				strconv.FormatFloat(getResource(capacity, resource), 'f', -1, 64),
+				strconv.FormatFloat(getResource(free, resource), 'f', -1, 64),
+				timestamp,
+			)
+		}
+	}
+
+	summary := getClusterSummary(nodes)
+	writePoint("cluster_resources", nil, summary.Allocatable, summary.Capacity, summary.Free)
+
+	for _, node := range nodes {
+		tags := [][2]string{{"node", node.Name}, {"pool", node.Pool}, {"capacity_type", node.CapacityType}}
+		writePoint("node_resources", tags, node.Allocatable, node.Capacity, node.Free)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestInfluxWriter writes a snapshot to a fake InfluxDB server, checking the query parameters, the token, and that
+// tag values are escaped.
+func TestInfluxWriter(t *testing.T) {
+	var body, authorization, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, authorization, query = string(data), r.Header.Get("Authorization"), r.URL.RawQuery
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	snapshot := &Snapshot{
+		Time: time.Unix(1714564800, 0),
+		Nodes: []NodeJson{
+			{Name: "node-1", Pool: "gpu pool", CapacityType: "spot", Allocatable: ResourcesJson{Cpu: 7.5, Memory: 1024, Gpu: 4}, Capacity: ResourcesJson{Cpu: 8, Memory: 2048, Gpu: 4}, Free: ResourcesJson{Cpu: 1.5, Gpu: 2}},
+		},
+	}
+
+	if err := newInfluxWriter(server.URL, "nrp", "capacity", "secret").Write(snapshot); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+
+	switch {
+	case query != "bucket=capacity&org=nrp&precision=s":
+		t.Fatalf(`query = %v, want match for %v`, query, "bucket=capacity&org=nrp&precision=s")
+	case authorization != "Token secret":
+		t.Fatalf(`authorization = %v, want match for %v`, authorization, "Token secret")
+	case len(lines) != 8:
+		t.Fatalf(`len(lines) = %v, want match for %v`, len(lines), 8)
+	case lines[0] != "cluster_resources,resource=cpu allocatable=7.5,capacity=8,free=1.5 1714564800":
+		t.Fatalf(`lines[0] = %v, want match for %v`, lines[0], "cluster_resources,resource=cpu allocatable=7.5,capacity=8,free=1.5 1714564800")
+	case lines[6] != `node_resources,node=node-1,pool=gpu\ pool,capacity_type=spot,resource=gpu allocatable=4,capacity=4,free=2 1714564800`:
+		t.Fatalf(`lines[6] = %v, want match for %v`, lines[6], `node_resources,node=node-1,pool=gpu\ pool,capacity_type=spot,resource=gpu allocatable=4,capacity=4,free=2 1714564800`)
+	}
+}
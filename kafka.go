@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// Version of the messages published to Kafka, increased whenever a field is removed or changes meaning
+const kafkaSchemaVersion = 1
+
+// Key of the records published in snapshot mode, so that every snapshot goes to the same partition in order
+const kafkaSnapshotKey = "snapshot"
+
+// KafkaSnapshotMessage is published for every snapshot in snapshot mode
+type KafkaSnapshotMessage struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Kind          string         `json:"kind"` // Always "snapshot"
+	Time          time.Time      `json:"time"`
+	Nodes         []NodeJson     `json:"nodes"`
+	Summary       ClusterSummary `json:"summary"`
+}
+
+// KafkaChangeMessage is published for every node whose capacity changed in deltas mode
+type KafkaChangeMessage struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Kind          string `json:"kind"` // Always "change"
+	CapacityChange
+}
+
+// kafkaProducer is the part of the Kafka client the publisher uses
+type kafkaProducer interface {
+	ProduceSync(ctx context.Context, records ...*kgo.Record) kgo.ProduceResults
+}
+
+// KafkaPublisher publishes snapshots, or the changes between them, to a Kafka topic
+type KafkaPublisher struct {
+	producer kafkaProducer
+	deltas   bool
+	previous map[string]NodeCapacity // Capacity of each node in the last snapshot published in deltas mode
+}
+
+// newKafkaClient returns a Kafka client that produces to topic on the cluster with the given seed brokers. Records
+// are only acknowledged once every in-sync replica has them, and the producer is idempotent, so retries don't
+// write duplicates. If useTls is set, brokers are connected to over TLS, and if mechanism is set, the client
+// authenticates with SASL PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512.
+func newKafkaClient(brokers []string, topic string, useTls bool, mechanism, username, password string) (*kgo.Client, error) {
+	options := []kgo.Opt{
+		kgo.SeedBrokers(brokers...),
+		kgo.DefaultProduceTopic(topic),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+	}
+
+	if useTls {
+		options = append(options, kgo.DialTLSConfig(&tls.Config{}))
+	}
+
+	switch mechanism {
+	case "":
+	case "PLAIN":
+		options = append(options, kgo.SASL(plain.Auth{User: username, Pass: password}.AsMechanism()))
+	case "SCRAM-SHA-256":
+		options = append(options, kgo.SASL(scram.Auth{User: username, Pass: password}.AsSha256Mechanism()))
+	case "SCRAM-SHA-512":
+		options = append(options, kgo.SASL(scram.Auth{User: username, Pass: password}.AsSha512Mechanism()))
+	default:
+		return nil, fmt.Errorf("unknown Kafka SASL mechanism %q, expected PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512", mechanism)
+	}
+
+	return kgo.NewClient(options...)
+}
+
+// newKafkaPublisher returns a KafkaPublisher that produces records with producer. mode is either snapshot, to
+// publish every snapshot, or deltas, to publish a message for each node whose capacity changed.
+func newKafkaPublisher(producer kafkaProducer, mode string) (*KafkaPublisher, error) {
+	if mode != "snapshot" && mode != "deltas" {
+		return nil, fmt.Errorf("unknown Kafka mode %q, expected snapshot or deltas", mode)
+	}
+
+	return &KafkaPublisher{
+		producer: producer,
+		deltas:   mode == "deltas",
+		previous: make(map[string]NodeCapacity),
+	}, nil
+}
+
+// Publish sends the records for a snapshot. In deltas mode, every node is published as added the first time, so
+// consumers can build the current state, and changes are only forgotten once they have been published. Changes are
+// keyed by node name, so each node's changes are read in order.
+func (k *KafkaPublisher) Publish(snapshot *Snapshot) error {
+	if !k.deltas {
+		record, err := newKafkaRecord(kafkaSnapshotKey, KafkaSnapshotMessage{
+			SchemaVersion: kafkaSchemaVersion,
+			Kind:          "snapshot",
+			Time:          snapshot.Time,
+			Nodes:         snapshot.Nodes,
+			Summary:       getClusterSummary(snapshot.Nodes),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return k.send([]*kgo.Record{record})
+	}
+
+	changes := getCapacityChanges(k.previous, snapshot.Nodes, snapshot.Time)
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	records := make([]*kgo.Record, 0, len(changes))
+	for _, change := range changes {
+		record, err := newKafkaRecord(change.Node, KafkaChangeMessage{
+			SchemaVersion:  kafkaSchemaVersion,
+			Kind:           "change",
+			CapacityChange: change,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := k.send(records); err != nil {
+		return err
+	}
+
+	k.previous = getNodeCapacities(snapshot.Nodes)
+
+	return nil
+}
+
+// newKafkaRecord returns a record of message encoded as JSON. Records with the same key are written to the same
+// partition.
+func newKafkaRecord(key string, message interface{}) (*kgo.Record, error) {
+	value, err := json.Marshal(message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &kgo.Record{Key: []byte(key), Value: value}, nil
+}
+
+// send produces records and waits until every one is acknowledged.
+func (k *KafkaPublisher) send(records []*kgo.Record) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return k.producer.ProduceSync(ctx, records...).FirstErr()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// kafkaTestMessage is the part of a published message the tests check
+type kafkaTestMessage struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Kind          string `json:"kind"`
+	Node          string `json:"node"`
+	Type          string `json:"type"`
+}
+
+// fakeKafkaProducer records every batch of records it is sent
+type fakeKafkaProducer struct {
+	batches [][]*kgo.Record
+}
+
+func (f *fakeKafkaProducer) ProduceSync(ctx context.Context, records ...*kgo.Record) kgo.ProduceResults {
+	f.batches = append(f.batches, records)
+
+	results := make(kgo.ProduceResults, 0, len(records))
+	for _, record := range records {
+		results = append(results, kgo.ProduceResult{Record: record})
+	}
+
+	return results
+}
+
+// TestKafkaPublisherDeltas publishes three snapshots in deltas mode, checking that every node is first published as
+// added and then only the node that changed, keyed by node name.
+func TestKafkaPublisherDeltas(t *testing.T) {
+	producer := &fakeKafkaProducer{}
+
+	publisher, err := newKafkaPublisher(producer, "deltas")
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	nodes := []NodeJson{
+		{Name: "node-1", Allocatable: ResourcesJson{Gpu: 4}, Capacity: ResourcesJson{Gpu: 4}},
+		{Name: "node-2", Allocatable: ResourcesJson{Cpu: 8}, Capacity: ResourcesJson{Cpu: 8}},
+	}
+
+	for _, snapshot := range []*Snapshot{
+		{Time: time.Unix(1714564800, 0), Nodes: nodes},
+		{Time: time.Unix(1714565100, 0), Nodes: nodes},
+		{Time: time.Unix(1714565400, 0), Nodes: nodes[1:]},
+	} {
+		if err := publisher.Publish(snapshot); err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+	}
+
+	if len(producer.batches) != 2 {
+		t.Fatalf(`len(batches) = %v, want match for %v`, len(producer.batches), 2)
+	}
+
+	first := producer.batches[0]
+
+	var added, removed kafkaTestMessage
+	json.Unmarshal(first[0].Value, &added)
+	json.Unmarshal(producer.batches[1][0].Value, &removed)
+
+	switch {
+	case len(first) != 2 || string(first[0].Key) != "node-1" || added.Type != changeAdded || added.SchemaVersion != kafkaSchemaVersion:
+		t.Fatalf(`batches[0] = %v, want match for %v`, first, "both nodes added")
+	case len(producer.batches[1]) != 1 || string(producer.batches[1][0].Key) != "node-1" || removed.Type != changeRemoved || removed.Kind != "change":
+		t.Fatalf(`batches[1] = %v, want match for %v`, producer.batches[1], "node-1 removed")
+	}
+}
+
+// TestKafkaPublisherSnapshot checks that each snapshot is published as one record with the snapshot key.
+func TestKafkaPublisherSnapshot(t *testing.T) {
+	producer := &fakeKafkaProducer{}
+
+	publisher, err := newKafkaPublisher(producer, "snapshot")
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	if err := publisher.Publish(&Snapshot{Time: time.Unix(1714564800, 0), Nodes: []NodeJson{{Name: "node-1"}}}); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	var message kafkaTestMessage
+	json.Unmarshal(producer.batches[0][0].Value, &message)
+
+	switch {
+	case len(producer.batches) != 1 || len(producer.batches[0]) != 1:
+		t.Fatalf(`batches = %v, want match for %v`, producer.batches, "one record")
+	case string(producer.batches[0][0].Key) != kafkaSnapshotKey || message.Kind != "snapshot":
+		t.Fatalf(`record = %v, want match for %v`, producer.batches[0][0], "a snapshot record")
+	}
+}
+
+// TestKafkaPublisherMode checks that an unknown mode or SASL mechanism is rejected.
+func TestKafkaPublisherMode(t *testing.T) {
+	if _, err := newKafkaPublisher(&fakeKafkaProducer{}, "nodes"); err == nil {
+		t.Fatalf(`err = %v, want error`, err)
+	}
+
+	if _, err := newKafkaClient([]string{"localhost:9092"}, "capacity", false, "GSSAPI", "user", "password"); err == nil {
+		t.Fatalf(`err = %v, want error`, err)
+	}
+}
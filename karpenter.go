@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Group, version, and resource of Karpenter NodePool and NodeClaim objects
+var (
+	karpenterNodePoolResource = schema.GroupVersionResource{
+		Group:    "karpenter.sh",
+		Version:  "v1",
+		Resource: "nodepools",
+	}
+	karpenterNodeClaimResource = schema.GroupVersionResource{
+		Group:    "karpenter.sh",
+		Version:  "v1",
+		Resource: "nodeclaims",
+	}
+)
+
+// PendingNodeClaim is a node Karpenter has decided to launch that isn't ready yet
+type PendingNodeClaim struct {
+	Name         string    `json:"name"`
+	InstanceType string    `json:"instanceType,omitempty"`
+	Created      time.Time `json:"created"`
+	Reason       string    `json:"reason,omitempty"`
+}
+
+// KarpenterPool compares the resources a Karpenter NodePool has provisioned with its limits. Limits and headroom
+// only contain the resources the pool limits.
+type KarpenterPool struct {
+	Name              string             `json:"name"`
+	Limits            map[string]float64 `json:"limits"`
+	Provisioned       ResourcesJson      `json:"provisioned"`
+	Headroom          map[string]float64 `json:"headroom"`
+	NodeClaims        int                `json:"nodeClaims"`
+	PendingNodeClaims []PendingNodeClaim `json:"pendingNodeClaims"`
+}
+
+// KarpenterPools gets every Karpenter NodePool and NodeClaim in the cluster and returns the capacity of each pool.
+// If the Karpenter CRDs aren't installed, no pools are returned.
+func (c *Collector) KarpenterPools() ([]KarpenterPool, error) {
+	if c.dynamic == nil {
+		return []KarpenterPool{}, nil
+	}
+
+	pools, err := c.dynamic.Resource(karpenterNodePoolResource).List(context.Background(), metav1.ListOptions{})
+
+	// The CRD not existing just means Karpenter isn't installed
+	if errors.IsNotFound(err) || errors.IsForbidden(err) {
+		return []KarpenterPool{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := c.dynamic.Resource(karpenterNodeClaimResource).List(context.Background(), metav1.ListOptions{})
+
+	if err != nil && !errors.IsNotFound(err) && !errors.IsForbidden(err) {
+		return nil, err
+	}
+
+	var claimItems []unstructured.Unstructured
+	if err == nil {
+		claimItems = claims.Items
+	}
+
+	return parseKarpenterPools(pools.Items, claimItems), nil
+}
+
+// parseKarpenterPools reads the limits and provisioned resources of each NodePool object and counts the NodeClaim
+// objects belonging to it. Pools are sorted by name.
+func parseKarpenterPools(poolItems, claimItems []unstructured.Unstructured) []KarpenterPool {
+	pools := make(map[string]*KarpenterPool)
+
+	for _, item := range poolItems {
+		limits, _, _ := unstructured.NestedMap(item.Object, "spec", "limits")
+		provisioned, _, _ := unstructured.NestedMap(item.Object, "status", "resources")
+
+		pool := &KarpenterPool{
+			Name:              item.GetName(),
+			Limits:            make(map[string]float64),
+			Provisioned:       getResourcesStructured(getResourcesFromList(parseResourceList(provisioned))),
+			Headroom:          make(map[string]float64),
+			PendingNodeClaims: make([]PendingNodeClaim, 0),
+		}
+
+		for name, quantity := range parseResourceList(limits) {
+			if resourceName := getResourceName(name); resourceName != "" {
+				pool.Limits[resourceName] = quantity.AsApproximateFloat64()
+			}
+		}
+
+		for name, limit := range pool.Limits {
+			pool.Headroom[name] = limit - getResource(pool.Provisioned, name)
+		}
+
+		pools[pool.Name] = pool
+	}
+
+	for _, item := range claimItems {
+		pool, ok := pools[item.GetLabels()["karpenter.sh/nodepool"]]
+		if !ok {
+			continue
+		}
+
+		pool.NodeClaims++
+
+		if ready, reason := getNodeClaimReady(item); !ready {
+			pool.PendingNodeClaims = append(pool.PendingNodeClaims, PendingNodeClaim{
+				Name:         item.GetName(),
+				InstanceType: item.GetLabels()[corev1.LabelInstanceTypeStable],
+				Created:      item.GetCreationTimestamp().Time,
+				Reason:       reason,
+			})
+		}
+	}
+
+	result := make([]KarpenterPool, 0, len(pools))
+	for _, pool := range pools {
+		result = append(result, *pool)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// getNodeClaimReady returns whether a NodeClaim object's Ready condition is true, and the reason it isn't if not.
+func getNodeClaimReady(item unstructured.Unstructured) (bool, string) {
+	conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+
+	for _, condition := range conditions {
+		fields, ok := condition.(map[string]interface{})
+		if !ok || fields["type"] != "Ready" {
+			continue
+		}
+
+		reason, _ := fields["reason"].(string)
+		return fields["status"] == "True", reason
+	}
+
+	return false, ""
+}
+
+// parseResourceList converts an unstructured resource list into a ResourceList, skipping invalid quantities.
+func parseResourceList(list map[string]interface{}) corev1.ResourceList {
+	result := make(corev1.ResourceList)
+
+	for name, value := range list {
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		if quantity, err := resource.ParseQuantity(text); err == nil {
+			result[corev1.ResourceName(name)] = quantity
+		}
+	}
+
+	return result
+}
+
+// getResourceName returns the name this API uses for a Kubernetes resource, or an empty string if it isn't one
+// of the resources the API reports on.
+func getResourceName(name corev1.ResourceName) string {
+	switch {
+	case name == corev1.ResourceCPU:
+		return "cpu"
+	case name == corev1.ResourceMemory:
+		return "memory"
+	case name == corev1.ResourceEphemeralStorage:
+		return "ephemeral"
+	case isGpuResource(name.String()):
+		return "gpu"
+	}
+
+	return ""
+}
+
+// getKarpenterHandler returns a HandlerFunc to return the capacity of every Karpenter NodePool given a Collector.
+func getKarpenterHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		pools, err := collector.KarpenterPools()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving Karpenter information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, pools)
+	}
+
+	return gin.HandlerFunc(handler)
+}
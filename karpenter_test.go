@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// TestParseKarpenterPools parses a NodePool with CPU and GPU limits and two NodeClaims, checking the headroom and
+// that only the claim that isn't ready is pending. Claims for unknown pools are ignored.
+func TestParseKarpenterPools(t *testing.T) {
+	pools := []unstructured.Unstructured{
+		{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "gpu"},
+				"spec": map[string]interface{}{
+					"limits": map[string]interface{}{"cpu": "100", "nvidia.com/gpu": "8"},
+				},
+				"status": map[string]interface{}{
+					"resources": map[string]interface{}{"cpu": "48", "memory": "192Gi", "nvidia.com/gpu": "6"},
+				},
+			},
+		},
+	}
+
+	claim := func(name, pool, ready string) unstructured.Unstructured {
+		return unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":   name,
+					"labels": map[string]interface{}{"karpenter.sh/nodepool": pool, "node.kubernetes.io/instance-type": "p3.8xlarge"},
+				},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Launched", "status": "True"},
+						map[string]interface{}{"type": "Ready", "status": ready, "reason": "NotRegistered"},
+					},
+				},
+			},
+		}
+	}
+
+	claims := []unstructured.Unstructured{
+		claim("gpu-1", "gpu", "True"),
+		claim("gpu-2", "gpu", "Unknown"),
+		claim("cpu-1", "cpu", "Unknown"),
+	}
+
+	result := parseKarpenterPools(pools, claims)
+
+	if len(result) != 1 {
+		t.Fatalf(`parseKarpenterPools() = %v, want 1 pool`, result)
+	}
+
+	pool := result[0]
+
+	switch {
+	case pool.Provisioned != (ResourcesJson{Cpu: 48, Memory: 192 << 30, Gpu: 6}):
+		t.Fatalf(`pool.Provisioned = %v, want match for %v`, pool.Provisioned, ResourcesJson{Cpu: 48, Memory: 192 << 30, Gpu: 6})
+	case len(pool.Headroom) != 2 || pool.Headroom["cpu"] != 52 || pool.Headroom["gpu"] != 2:
+		t.Fatalf(`pool.Headroom = %v, want match for %v`, pool.Headroom, map[string]float64{"cpu": 52, "gpu": 2})
+	case pool.NodeClaims != 2:
+		t.Fatalf(`pool.NodeClaims = %v, want match for %v`, pool.NodeClaims, 2)
+	case len(pool.PendingNodeClaims) != 1 || pool.PendingNodeClaims[0].Name != "gpu-2" || pool.PendingNodeClaims[0].Reason != "NotRegistered":
+		t.Fatalf(`pool.PendingNodeClaims = %v, want gpu-2 not registered`, pool.PendingNodeClaims)
+	case pool.PendingNodeClaims[0].InstanceType != "p3.8xlarge":
+		t.Fatalf(`pool.PendingNodeClaims[0].InstanceType = %v, want match for %v`, pool.PendingNodeClaims[0].InstanceType, "p3.8xlarge")
+	}
+}
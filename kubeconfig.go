@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// reloadingTransport sends requests through a transport built from the latest kubeconfig, so every client created
+// from it picks up new credentials without being recreated
+type reloadingTransport struct {
+	current atomic.Pointer[http.RoundTripper]
+}
+
+// RoundTrip sends a request through the current transport.
+func (t *reloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return (*t.current.Load()).RoundTrip(req)
+}
+
+// Update replaces the current transport with one built from config, closing the idle connections of the old one.
+func (t *reloadingTransport) Update(config *rest.Config) error {
+	transport, err := rest.TransportFor(config)
+
+	if err != nil {
+		return err
+	}
+
+	if old := t.current.Swap(&transport); old != nil {
+		utilnet.CloseIdleConnectionsFor(*old)
+	}
+
+	return nil
+}
+
+// newReloadingConfig returns a copy of config whose credentials and TLS settings are replaced by a reloadingTransport
+// built from them, along with the transport so it can be updated.
+func newReloadingConfig(config *rest.Config) (*rest.Config, *reloadingTransport, error) {
+	transport := &reloadingTransport{}
+
+	if err := transport.Update(config); err != nil {
+		return nil, nil, err
+	}
+
+	// A config with its own transport can't also have TLS settings
+	reloading := rest.AnonymousClientConfig(config)
+	reloading.TLSClientConfig = rest.TLSClientConfig{}
+	reloading.Transport = transport
+
+	return reloading, transport, nil
+}
+
+// kubeconfigPaths returns the kubeconfig files rules load from - the explicit path if set, or else the files in
+// order of precedence.
+func kubeconfigPaths(rules *clientcmd.ClientConfigLoadingRules) []string {
+	if rules.ExplicitPath != "" {
+		return []string{rules.ExplicitPath}
+	}
+
+	return rules.Precedence
+}
+
+// readKubeconfigs returns the contents of the kubeconfig files at paths joined together, to compare for changes.
+// Missing files are skipped, as they are when the files are merged.
+func readKubeconfigs(paths []string) ([]byte, error) {
+	var data []byte
+
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, contents...)
+	}
+
+	return data, nil
+}
+
+// loadKubeconfig merges the kubeconfig files loaded by rules and returns a config for the named context, or the
+// current context if name is empty.
+func loadKubeconfig(rules *clientcmd.ClientConfigLoadingRules, context string) (*rest.Config, error) {
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// withProtobuf returns a copy of config that sends and asks for protobuf, falling back to JSON for anything the API
+// server can't encode as protobuf. Only built-in types have protobuf encodings, so this is for the typed clientset
+// rather than the dynamic or metrics clients.
+func withProtobuf(config *rest.Config) *rest.Config {
+	config = rest.CopyConfig(config)
+	config.ContentType = runtime.ContentTypeProtobuf
+	config.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+
+	return config
+}
+
+// getContextNames returns the names of the contexts in the kubeconfig files loaded by rules, sorted, along with the
+// current context.
+func getContextNames(rules *clientcmd.ClientConfigLoadingRules) ([]string, string, error) {
+	config, err := rules.Load()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, config.CurrentContext, nil
+}
+
+// watchKubeconfig rereads the kubeconfig files loaded by rules every interval, updating transport with the named
+// context whenever their contents differ from last. This picks up credentials rotated by external tooling, which
+// would otherwise cause every request to fail with 401 Unauthorized until a restart. The server can't change, since
+// clients are created for it at startup. Files that can't be read or used are logged and the previous credentials
+// are kept. It blocks forever, so it should be run in its own goroutine.
+func watchKubeconfig(rules *clientcmd.ClientConfigLoadingRules, context string, interval time.Duration, last []byte, host string, transport *reloadingTransport) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	paths := kubeconfigPaths(rules)
+
+	for range ticker.C {
+		data, err := readKubeconfigs(paths)
+
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		if bytes.Equal(data, last) {
+			continue
+		}
+
+		last = data
+
+		config, err := loadKubeconfig(rules, context)
+
+		if err == nil && config.Host != host {
+			err = fmt.Errorf("server changed from %v to %v, restart to use it", host, config.Host)
+		}
+
+		if err == nil {
+			err = transport.Update(config)
+		}
+
+		if err != nil {
+			fmt.Printf("error reloading %v, keeping the previous credentials: %v\n", strings.Join(paths, ":"), err)
+			continue
+		}
+
+		fmt.Printf("reloaded credentials from %v\n", strings.Join(paths, ":"))
+	}
+}
+
+// newKubeClients creates the clients for the cluster in the kubeconfig given by args, or else by the standard rules,
+// using the named context if it isn't empty. The kubeconfig is watched so that rotated credentials are used without a
+// restart.
+func newKubeClients(kubeContext string, args []string) (kubernetes.Interface, dynamic.Interface, metricsclient.Interface, error) {
+	// Load kubeconfig files by the standard rules - the path given as an argument, or else the colon-separated list
+	// of files in KUBECONFIG merged together, or else ~/.kube/config
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if len(args) > 0 {
+		rules.ExplicitPath = args[0]
+	}
+
+	// List the available contexts so it's clear which ones --context can select
+	contexts, currentContext, err := getContextNames(rules)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if kubeContext != "" {
+		currentContext = kubeContext
+	}
+
+	fmt.Printf("kubeconfig contexts: %v, using %q\n", strings.Join(contexts, ", "), currentContext)
+
+	// Read the kubeconfig files once to compare against when watching them for changes
+	kubeconfigData, err := readKubeconfigs(kubeconfigPaths(rules))
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Create a config from the kubeconfig files
+	config, err := loadKubeconfig(rules, kubeContext)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Send every client's requests through a transport that is rebuilt when the credentials in the kubeconfig change
+	host := config.Host
+	config, transport, err := newReloadingConfig(config)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	go watchKubeconfig(rules, kubeContext, getEnvDuration("KUBECONFIG_RELOAD_INTERVAL", 30*time.Second), kubeconfigData, host, transport)
+
+	// Create a Kubernetes clientset from the config, using protobuf to cut the size of the cluster-wide pod lists
+	// unless it's turned off
+	clientsetConfig := config
+	if os.Getenv("KUBE_API_PROTOBUF") != "false" {
+		clientsetConfig = withProtobuf(config)
+	}
+
+	clientset, err := kubernetes.NewForConfig(clientsetConfig)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Create a dynamic client for reading custom resources
+	dynamicClient, err := dynamic.NewForConfig(config)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Create a metrics-server client for reading the usage of pods when Prometheus isn't used
+	metricsClient, err := metricsclient.NewForConfig(config)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return clientset, dynamicClient, metricsClient, nil
+}
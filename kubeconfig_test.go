@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// TestReloadingTransport sends requests through a reloadingTransport before and after updating its credentials,
+// checking that the new token is used without recreating the client.
+func TestReloadingTransport(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	config, transport, err := newReloadingConfig(&rest.Config{Host: server.URL, BearerToken: "old"})
+
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	if config.BearerToken != "" || config.Transport != transport {
+		t.Fatalf(`config = %v, want match for %v`, config, "a config using the reloading transport")
+	}
+
+	client := &http.Client{Transport: transport}
+
+	for _, token := range []string{"old", "new"} {
+		if err := transport.Update(&rest.Config{Host: server.URL, BearerToken: token}); err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+
+		response, err := client.Get(server.URL)
+
+		if err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+
+		response.Body.Close()
+
+		if authorization != "Bearer "+token {
+			t.Fatalf(`authorization = %v, want match for %v`, authorization, "Bearer "+token)
+		}
+	}
+}
+
+// writeKubeconfig writes a kubeconfig with a single cluster, user, and context all called name to a file in dir.
+func writeKubeconfig(t *testing.T, dir, name, server string) string {
+	path := filepath.Join(dir, name)
+	data := "apiVersion: v1\nkind: Config\ncurrent-context: " + name + "\n" +
+		"clusters:\n- name: " + name + "\n  cluster:\n    server: " + server + "\n" +
+		"users:\n- name: " + name + "\n  user:\n    token: " + name + "-token\n" +
+		"contexts:\n- name: " + name + "\n  context:\n    cluster: " + name + "\n    user: " + name + "\n"
+
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	return path
+}
+
+// TestLoadKubeconfig merges two kubeconfig files and a missing one, checking that the first file's current context
+// is used by default and that another context can be selected by name.
+func TestLoadKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: []string{
+		writeKubeconfig(t, dir, "east", "https://east.example.com"),
+		filepath.Join(dir, "missing"),
+		writeKubeconfig(t, dir, "west", "https://west.example.com"),
+	}}
+
+	names, current, err := getContextNames(rules)
+
+	switch {
+	case err != nil:
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	case !slices.Equal(names, []string{"east", "west"}):
+		t.Fatalf(`names = %v, want match for %v`, names, []string{"east", "west"})
+	case current != "east":
+		t.Fatalf(`current = %v, want match for %v`, current, "east")
+	}
+
+	for context, host := range map[string]string{"": "https://east.example.com", "west": "https://west.example.com"} {
+		config, err := loadKubeconfig(rules, context)
+
+		switch {
+		case err != nil:
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		case config.Host != host:
+			t.Fatalf(`config.Host = %v, want match for %v`, config.Host, host)
+		}
+	}
+
+	if _, err := loadKubeconfig(rules, "north"); err == nil {
+		t.Fatalf(`err = %v, want match for %v`, err, "an error")
+	}
+
+	data, err := readKubeconfigs(kubeconfigPaths(rules))
+
+	if err != nil || len(data) == 0 {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+}
+
+// TestWithProtobuf checks that protobuf is requested with a JSON fallback without changing the original config.
+func TestWithProtobuf(t *testing.T) {
+	config := &rest.Config{Host: "https://cluster.example.com"}
+	protobuf := withProtobuf(config)
+
+	switch {
+	case protobuf.ContentType != "application/vnd.kubernetes.protobuf":
+		t.Fatalf(`ContentType = %v, want match for %v`, protobuf.ContentType, "application/vnd.kubernetes.protobuf")
+	case protobuf.AcceptContentTypes != "application/vnd.kubernetes.protobuf,application/json":
+		t.Fatalf(`AcceptContentTypes = %v, want match for %v`, protobuf.AcceptContentTypes, "application/vnd.kubernetes.protobuf,application/json")
+	case config.ContentType != "" || protobuf.Host != config.Host:
+		t.Fatalf(`config = %v, want match for %v`, config, "the original config unchanged")
+	}
+}
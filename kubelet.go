@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// How long the configuration read from a kubelet is reused before it is read again, as it rarely changes
+const defaultKubeletConfigTTL = 10 * time.Minute
+
+// KubeletConfig is the part of a kubelet's configuration, as served at /configz, that the API reports on
+type KubeletConfig struct {
+	FailSwapOn bool `json:"failSwapOn"`
+	MemorySwap struct {
+		SwapBehavior string `json:"swapBehavior"`
+	} `json:"memorySwap"`
+	ImageGCHighThresholdPercent *int32            `json:"imageGCHighThresholdPercent"`
+	ImageGCLowThresholdPercent  *int32            `json:"imageGCLowThresholdPercent"`
+	KubeReserved                map[string]string `json:"kubeReserved"`
+	SystemReserved              map[string]string `json:"systemReserved"`
+	EvictionHard                map[string]string `json:"evictionHard"`
+}
+
+// kubeletFsStats are the stats of a filesystem in a kubelet's stats summary, in bytes
+type kubeletFsStats struct {
+	AvailableBytes *uint64 `json:"availableBytes"`
+	CapacityBytes  *uint64 `json:"capacityBytes"`
+	UsedBytes      *uint64 `json:"usedBytes"`
+}
+
+// KubeletStats is the part of a kubelet's stats summary, as served at /stats/summary, that the API reports on
+type KubeletStats struct {
+	Node struct {
+		Fs      *kubeletFsStats `json:"fs"`
+		Runtime *struct {
+			ImageFs *kubeletFsStats `json:"imageFs"`
+		} `json:"runtime"`
+	} `json:"node"`
+	Pods []struct {
+		EphemeralStorage *kubeletFsStats `json:"ephemeral-storage"`
+	} `json:"pods"`
+}
+
+// cachedKubeletConfig is the configuration of a node's kubelet and when it was read
+type cachedKubeletConfig struct {
+	config *KubeletConfig
+	read   time.Time
+}
+
+// KubeletReader reads the configuration and stats of each node's kubelet through the apiserver's node proxy, reusing
+// the configuration for a while since every snapshot would otherwise query every kubelet
+type KubeletReader struct {
+	fetch   func(node, path string) ([]byte, error) // Reads a path from the kubelet of the named node
+	ttl     time.Duration
+	mutex   sync.Mutex
+	configs map[string]cachedKubeletConfig
+}
+
+// newKubeletReader returns a KubeletReader that reads kubelets through the node proxy of the apiserver client
+// connects to, reusing their configuration for ttl.
+func newKubeletReader(client kubernetes.Interface, ttl time.Duration) *KubeletReader {
+	return &KubeletReader{
+		fetch: func(node, path string) ([]byte, error) {
+			// Fake clients, such as those of demo and replay mode, have no REST client to proxy through
+			if restClient, ok := client.CoreV1().RESTClient().(*rest.RESTClient); ok && restClient == nil {
+				return nil, fmt.Errorf("client can't proxy to kubelets")
+			}
+
+			return client.CoreV1().RESTClient().Get().
+				Resource("nodes").
+				Name(node).
+				SubResource("proxy").
+				Suffix(path).
+				DoRaw(context.Background())
+		},
+		ttl:     ttl,
+		configs: make(map[string]cachedKubeletConfig),
+	}
+}
+
+// parseKubeletConfig reads the configuration served at a kubelet's /configz, which wraps it in a kubeletconfig field.
+func parseKubeletConfig(data []byte) (*KubeletConfig, error) {
+	var configz struct {
+		KubeletConfig *KubeletConfig `json:"kubeletconfig"`
+	}
+
+	if err := json.Unmarshal(data, &configz); err != nil {
+		return nil, err
+	}
+
+	if configz.KubeletConfig == nil {
+		return nil, fmt.Errorf("kubelet configz has no kubeletconfig")
+	}
+
+	return configz.KubeletConfig, nil
+}
+
+// parseKubeletStats reads the stats summary served at a kubelet's /stats/summary.
+func parseKubeletStats(data []byte) (*KubeletStats, error) {
+	var stats KubeletStats
+
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// Configs returns the configuration of the kubelet of each named node at now, keyed by node name. Configuration read
+// within the reader's TTL is reused, and the rest is read with at most podQueryWorkers kubelets queried at once.
+// Failing to read a kubelet, such as when the API isn't allowed to proxy to nodes, is logged and leaves the node out,
+// so that one unreachable node doesn't fail the snapshot.
+func (k *KubeletReader) Configs(names []string, now time.Time) map[string]*KubeletConfig {
+	configs := make(map[string]*KubeletConfig, len(names))
+	stale := make([]string, 0)
+
+	k.mutex.Lock()
+	for _, name := range names {
+		if cached, ok := k.configs[name]; ok && now.Sub(cached.read) < k.ttl {
+			configs[name] = cached.config
+		} else {
+			stale = append(stale, name)
+		}
+	}
+	k.mutex.Unlock()
+
+	results := make([]*KubeletConfig, len(stale))
+	workers := make(chan struct{}, podQueryWorkers)
+
+	var wg sync.WaitGroup
+
+	for i, name := range stale {
+		wg.Add(1)
+		workers <- struct{}{}
+
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			data, err := k.fetch(name, "configz")
+
+			if err == nil {
+				results[i], err = parseKubeletConfig(data)
+			}
+
+			if err != nil {
+				fmt.Printf("error reading kubelet config of node %v: %v\n", name, err)
+			}
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	for i, name := range stale {
+		if results[i] == nil {
+			continue
+		}
+
+		configs[name] = results[i]
+		k.configs[name] = cachedKubeletConfig{config: results[i], read: now}
+	}
+
+	// Forget nodes that are gone, so the cache doesn't grow as nodes come and go
+	current := make(map[string]bool, len(names))
+	for _, name := range names {
+		current[name] = true
+	}
+
+	for name := range k.configs {
+		if !current[name] {
+			delete(k.configs, name)
+		}
+	}
+
+	return configs
+}
+
+// Stats returns the stats summary of the kubelet of each named node, keyed by node name. Stats change with every
+// snapshot, so they are always read, with at most podQueryWorkers kubelets queried at once. Failing to read a kubelet
+// is logged and leaves the node out.
+func (k *KubeletReader) Stats(names []string) map[string]*KubeletStats {
+	results := make([]*KubeletStats, len(names))
+	workers := make(chan struct{}, podQueryWorkers)
+
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		wg.Add(1)
+		workers <- struct{}{}
+
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			data, err := k.fetch(name, "stats/summary")
+
+			if err == nil {
+				results[i], err = parseKubeletStats(data)
+			}
+
+			if err != nil {
+				fmt.Printf("error reading kubelet stats of node %v: %v\n", name, err)
+			}
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	stats := make(map[string]*KubeletStats, len(names))
+	for i, name := range names {
+		if results[i] != nil {
+			stats[name] = results[i]
+		}
+	}
+
+	return stats
+}
+
+// addKubeletInfo adds what is read from each node's kubelet to a snapshot, if the collector reads kubelets. Each
+// kubelet's configuration and stats summary are read once and shared by everything that uses them.
+func (c *Collector) addKubeletInfo(snapshot *Snapshot) {
+	if c.kubelet == nil {
+		return
+	}
+
+	names := make([]string, 0, len(snapshot.Nodes))
+	for _, node := range snapshot.Nodes {
+		names = append(names, node.Name)
+	}
+
+	var configs map[string]*KubeletConfig
+	if c.kubeletConfig {
+		configs = c.kubelet.Configs(names, snapshot.Time)
+		c.addNodeSwap(snapshot, configs)
+	}
+
+	if c.kubeletStats {
+		setNodeDiskUsage(snapshot.Nodes, c.kubelet.Stats(names))
+	}
+
+	setNodeImageGc(snapshot.Nodes, configs)
+	setNodeReserved(snapshot.Nodes, configs)
+}
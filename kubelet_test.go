@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestKubeletReaderConfigs reads the configuration of two kubelets, one of which can't be reached, checking that the
+// reachable one is parsed and reused within the TTL, that the unreachable one is left out, and that nodes that are
+// gone are forgotten.
+func TestKubeletReaderConfigs(t *testing.T) {
+	var fetches atomic.Int64
+	reader := &KubeletReader{
+		fetch: func(node, path string) ([]byte, error) {
+			fetches.Add(1)
+
+			if node == "unreachable" || path != "configz" {
+				return nil, fmt.Errorf("node %v unreachable", node)
+			}
+
+			return []byte(`{"kubeletconfig": {"failSwapOn": false, "memorySwap": {"swapBehavior": "LimitedSwap"}}}`), nil
+		},
+		ttl:     time.Minute,
+		configs: make(map[string]cachedKubeletConfig),
+	}
+
+	now := time.Now()
+	configs := reader.Configs([]string{"node-1", "unreachable"}, now)
+
+	switch {
+	case len(configs) != 1 || configs["node-1"] == nil:
+		t.Fatalf(`Configs() = %v, want match for only node-1`, configs)
+	case configs["node-1"].MemorySwap.SwapBehavior != swapBehaviorLimited || configs["node-1"].FailSwapOn:
+		t.Fatalf(`node-1 config = %+v, want match for LimitedSwap without failSwapOn`, *configs["node-1"])
+	case fetches.Load() != 2:
+		t.Fatalf(`fetches = %v, want match for 2`, fetches.Load())
+	}
+
+	// Within the TTL, only the node that couldn't be read is read again
+	reader.Configs([]string{"node-1", "unreachable"}, now.Add(30*time.Second))
+	if fetches.Load() != 3 {
+		t.Fatalf(`fetches within TTL = %v, want match for 3`, fetches.Load())
+	}
+
+	reader.Configs([]string{"node-1"}, now.Add(2*time.Minute))
+	if fetches.Load() != 4 {
+		t.Fatalf(`fetches after TTL = %v, want match for 4`, fetches.Load())
+	}
+
+	reader.Configs([]string{"node-2"}, now.Add(2*time.Minute))
+	if _, ok := reader.configs["node-1"]; ok {
+		t.Fatalf(`cached configs = %v, want node-1 forgotten`, reader.configs)
+	}
+}
+
+// TestParseKubeletConfig parses configz responses with and without the kubeletconfig field.
+func TestParseKubeletConfig(t *testing.T) {
+	config, err := parseKubeletConfig([]byte(`{"kubeletconfig": {"failSwapOn": true}}`))
+
+	switch {
+	case err != nil:
+		t.Fatalf(`parseKubeletConfig() = %v, want match for nil`, err)
+	case !config.FailSwapOn || config.MemorySwap.SwapBehavior != "":
+		t.Fatalf(`parseKubeletConfig() = %+v, want match for failSwapOn without a swap behavior`, *config)
+	}
+
+	if _, err := parseKubeletConfig([]byte(`{"failSwapOn": true}`)); err == nil {
+		t.Fatalf(`parseKubeletConfig() without kubeletconfig = nil, want an error`)
+	}
+}
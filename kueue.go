@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Group, version, and resource of Kueue ClusterQueue, LocalQueue, and ResourceFlavor objects
+var (
+	kueueClusterQueueResource = schema.GroupVersionResource{
+		Group:    "kueue.x-k8s.io",
+		Version:  "v1beta1",
+		Resource: "clusterqueues",
+	}
+	kueueLocalQueueResource = schema.GroupVersionResource{
+		Group:    "kueue.x-k8s.io",
+		Version:  "v1beta1",
+		Resource: "localqueues",
+	}
+	kueueResourceFlavorResource = schema.GroupVersionResource{
+		Group:    "kueue.x-k8s.io",
+		Version:  "v1beta1",
+		Resource: "resourceflavors",
+	}
+)
+
+// QueueResource compares a ClusterQueue's quota of a resource in one flavor with what its admitted workloads use and
+// what is free on the flavor's nodes. CPU is given in cores and memory in bytes.
+type QueueResource struct {
+	NominalQuota   float64  `json:"nominalQuota"`
+	BorrowingLimit *float64 `json:"borrowingLimit,omitempty"` // Most that can be borrowed from the cohort, if limited
+	Usage          float64  `json:"usage"`                    // Used by admitted workloads
+	Borrowed       float64  `json:"borrowed"`                 // Part of the usage borrowed from the cohort
+	Free           *float64 `json:"free,omitempty"`           // Free on the flavor's nodes, for the resources the API reports on
+}
+
+// QueueFlavor contains the quotas of a ClusterQueue in one ResourceFlavor, keyed by the name this API uses for each
+// resource, or by the Kubernetes name for resources it doesn't report on
+type QueueFlavor struct {
+	Name      string                   `json:"name"`
+	Nodes     int                      `json:"nodes"` // Nodes with the flavor's node labels
+	Resources map[string]QueueResource `json:"resources"`
+}
+
+// LocalQueue is a namespaced Kueue queue submitting workloads to a ClusterQueue
+type LocalQueue struct {
+	Name              string             `json:"name"`
+	Namespace         string             `json:"namespace"`
+	Usage             map[string]float64 `json:"usage"` // Used by admitted workloads, summed across flavors
+	AdmittedWorkloads int64              `json:"admittedWorkloads"`
+	PendingWorkloads  int64              `json:"pendingWorkloads"`
+}
+
+// ClusterQueue is a Kueue ClusterQueue with its quotas in each flavor and the LocalQueues submitting to it
+type ClusterQueue struct {
+	Name              string        `json:"name"`
+	Cohort            string        `json:"cohort,omitempty"`
+	Flavors           []QueueFlavor `json:"flavors"`
+	AdmittedWorkloads int64         `json:"admittedWorkloads"`
+	PendingWorkloads  int64         `json:"pendingWorkloads"`
+	LocalQueues       []LocalQueue  `json:"localQueues"`
+}
+
+// listOptionalResources lists every object of a custom resource, returning no objects if its CRD isn't installed or
+// the API isn't allowed to read it.
+func (c *Collector) listOptionalResources(resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	list, err := c.dynamic.Resource(resource).List(context.Background(), metav1.ListOptions{})
+
+	// The CRD not existing just means the scheduler it belongs to isn't installed
+	if errors.IsNotFound(err) || errors.IsForbidden(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// KueueQueues gets every Kueue ClusterQueue, LocalQueue, and ResourceFlavor in the cluster and returns the quotas and
+// usage of each ClusterQueue, along with the free resources of each flavor's nodes in nodes. If the Kueue CRDs aren't
+// installed, no queues are returned.
+func (c *Collector) KueueQueues(nodes []NodeJson) ([]ClusterQueue, error) {
+	clusterQueues, err := c.listOptionalResources(kueueClusterQueueResource)
+
+	if err != nil || len(clusterQueues) == 0 {
+		return []ClusterQueue{}, err
+	}
+
+	localQueues, err := c.listOptionalResources(kueueLocalQueueResource)
+
+	if err != nil {
+		return nil, err
+	}
+
+	flavors, err := c.listOptionalResources(kueueResourceFlavorResource)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseKueueQueues(clusterQueues, localQueues, flavors, nodes), nil
+}
+
+// parseQueueQuantity reads a quantity from an unstructured field, which is a string unless it was written as a plain
+// number.
+func parseQueueQuantity(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case string:
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return 0, false
+		}
+		return quantity.AsApproximateFloat64(), true
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+
+	return 0, false
+}
+
+// getQueueResourceName returns the name this API uses for a resource, or its Kubernetes name if the API doesn't report
+// on it, such as pods.
+func getQueueResourceName(name string) string {
+	if resourceName := getResourceName(corev1.ResourceName(name)); resourceName != "" {
+		return resourceName
+	}
+
+	return name
+}
+
+// getQueueUsage reads the usage of each resource in each flavor from a ClusterQueue or LocalQueue status field,
+// calling add with the flavor, resource, total, and borrowed amount of each.
+func getQueueUsage(item unstructured.Unstructured, field string, add func(flavor, name string, total, borrowed float64)) {
+	usages, _, _ := unstructured.NestedSlice(item.Object, "status", field)
+
+	for _, usage := range usages {
+		flavor, ok := usage.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		flavorName, _ := flavor["name"].(string)
+		resources, _ := flavor["resources"].([]interface{})
+
+		for _, entry := range resources {
+			fields, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			name, _ := fields["name"].(string)
+			total, _ := parseQueueQuantity(fields["total"])
+			borrowed, _ := parseQueueQuantity(fields["borrowed"])
+
+			add(flavorName, getQueueResourceName(name), total, borrowed)
+		}
+	}
+}
+
+// getFlavorFree sums the free resources of the nodes with every one of a flavor's node labels, returning the number
+// of nodes and their free resources. A flavor without node labels covers every node.
+func getFlavorFree(nodeLabels map[string]string, nodes []NodeJson) (int, ResourcesJson) {
+	count := 0
+	free := ResourcesJson{}
+
+	for _, node := range nodes {
+		matches := true
+		for key, value := range nodeLabels {
+			if node.Labels[key] != value {
+				matches = false
+				break
+			}
+		}
+
+		if !matches {
+			continue
+		}
+
+		count++
+		free = addResources(free, node.Free)
+	}
+
+	return count, free
+}
+
+// parseKueueQueues reads the quotas and usage of each ClusterQueue object, the free resources of the nodes of each
+// flavor it uses, and the LocalQueue objects submitting to it. Flavors that aren't defined by a ResourceFlavor object
+// have no nodes. Queues are sorted by name, and LocalQueues by namespace and name.
+func parseKueueQueues(clusterQueueItems, localQueueItems, flavorItems []unstructured.Unstructured, nodes []NodeJson) []ClusterQueue {
+	type flavorNodes struct {
+		count int
+		free  ResourcesJson
+	}
+
+	flavorFree := make(map[string]flavorNodes)
+	for _, item := range flavorItems {
+		nodeLabels, _, _ := unstructured.NestedStringMap(item.Object, "spec", "nodeLabels")
+		count, free := getFlavorFree(nodeLabels, nodes)
+		flavorFree[item.GetName()] = flavorNodes{count: count, free: free}
+	}
+
+	queues := make(map[string]*ClusterQueue)
+
+	for _, item := range clusterQueueItems {
+		cohort, _, _ := unstructured.NestedString(item.Object, "spec", "cohort")
+		admitted, _, _ := unstructured.NestedInt64(item.Object, "status", "admittedWorkloads")
+		pending, _, _ := unstructured.NestedInt64(item.Object, "status", "pendingWorkloads")
+
+		queue := &ClusterQueue{
+			Name:              item.GetName(),
+			Cohort:            cohort,
+			Flavors:           make([]QueueFlavor, 0),
+			AdmittedWorkloads: admitted,
+			PendingWorkloads:  pending,
+			LocalQueues:       make([]LocalQueue, 0),
+		}
+
+		// Index of each flavor in queue.Flavors, to add usage to
+		flavorIndexes := make(map[string]int)
+
+		groups, _, _ := unstructured.NestedSlice(item.Object, "spec", "resourceGroups")
+		for _, group := range groups {
+			groupFields, _ := group.(map[string]interface{})
+			groupFlavors, _ := groupFields["flavors"].([]interface{})
+
+			for _, groupFlavor := range groupFlavors {
+				fields, ok := groupFlavor.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				name, _ := fields["name"].(string)
+				flavor := QueueFlavor{Name: name, Nodes: flavorFree[name].count, Resources: make(map[string]QueueResource)}
+
+				resources, _ := fields["resources"].([]interface{})
+				for _, entry := range resources {
+					resourceFields, ok := entry.(map[string]interface{})
+					if !ok {
+						continue
+					}
+
+					resourceName, _ := resourceFields["name"].(string)
+					quota := QueueResource{}
+					quota.NominalQuota, _ = parseQueueQuantity(resourceFields["nominalQuota"])
+
+					if limit, ok := parseQueueQuantity(resourceFields["borrowingLimit"]); ok {
+						quota.BorrowingLimit = &limit
+					}
+
+					name := getQueueResourceName(resourceName)
+					if _, ok := flavorFree[flavor.Name]; ok && slices.Contains(resourceNames, name) {
+						free := getResource(flavorFree[flavor.Name].free, name)
+						quota.Free = &free
+					}
+
+					flavor.Resources[name] = quota
+				}
+
+				flavorIndexes[flavor.Name] = len(queue.Flavors)
+				queue.Flavors = append(queue.Flavors, flavor)
+			}
+		}
+
+		getQueueUsage(item, "flavorsUsage", func(flavor, name string, total, borrowed float64) {
+			index, ok := flavorIndexes[flavor]
+			if !ok {
+				return
+			}
+
+			quota := queue.Flavors[index].Resources[name]
+			quota.Usage = total
+			quota.Borrowed = borrowed
+			queue.Flavors[index].Resources[name] = quota
+		})
+
+		queues[queue.Name] = queue
+	}
+
+	for _, item := range localQueueItems {
+		clusterQueue, _, _ := unstructured.NestedString(item.Object, "spec", "clusterQueue")
+		queue, ok := queues[clusterQueue]
+		if !ok {
+			continue
+		}
+
+		admitted, _, _ := unstructured.NestedInt64(item.Object, "status", "admittedWorkloads")
+		pending, _, _ := unstructured.NestedInt64(item.Object, "status", "pendingWorkloads")
+
+		localQueue := LocalQueue{
+			Name:              item.GetName(),
+			Namespace:         item.GetNamespace(),
+			Usage:             make(map[string]float64),
+			AdmittedWorkloads: admitted,
+			PendingWorkloads:  pending,
+		}
+
+		getQueueUsage(item, "flavorUsage", func(flavor, name string, total, borrowed float64) {
+			localQueue.Usage[name] += total
+		})
+
+		queue.LocalQueues = append(queue.LocalQueues, localQueue)
+	}
+
+	result := make([]ClusterQueue, 0, len(queues))
+	for _, queue := range queues {
+		sort.Slice(queue.LocalQueues, func(i, j int) bool {
+			if queue.LocalQueues[i].Namespace != queue.LocalQueues[j].Namespace {
+				return queue.LocalQueues[i].Namespace < queue.LocalQueues[j].Namespace
+			}
+			return queue.LocalQueues[i].Name < queue.LocalQueues[j].Name
+		})
+
+		result = append(result, *queue)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// TestParseKueueQueues parses a ClusterQueue with a GPU flavor and a LocalQueue submitting to it, checking the quota,
+// usage, and borrowing of each resource and that the free resources come from the nodes with the flavor's labels.
+// LocalQueues for unknown ClusterQueues are ignored.
+func TestParseKueueQueues(t *testing.T) {
+	clusterQueues := []unstructured.Unstructured{
+		{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "research"},
+				"spec": map[string]interface{}{
+					"cohort": "university",
+					"resourceGroups": []interface{}{
+						map[string]interface{}{
+							"coveredResources": []interface{}{"cpu", "nvidia.com/gpu", "pods"},
+							"flavors": []interface{}{
+								map[string]interface{}{
+									"name": "a100",
+									"resources": []interface{}{
+										map[string]interface{}{"name": "cpu", "nominalQuota": "64"},
+										map[string]interface{}{"name": "nvidia.com/gpu", "nominalQuota": int64(8), "borrowingLimit": "4"},
+										map[string]interface{}{"name": "pods", "nominalQuota": "100"},
+									},
+								},
+							},
+						},
+					},
+				},
+				"status": map[string]interface{}{
+					"admittedWorkloads": int64(3),
+					"pendingWorkloads":  int64(2),
+					"flavorsUsage": []interface{}{
+						map[string]interface{}{
+							"name": "a100",
+							"resources": []interface{}{
+								map[string]interface{}{"name": "cpu", "total": "48", "borrowed": "0"},
+								map[string]interface{}{"name": "nvidia.com/gpu", "total": "10", "borrowed": "2"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	localQueue := func(name, clusterQueue string) unstructured.Unstructured {
+		return unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": name, "namespace": "vision"},
+				"spec":     map[string]interface{}{"clusterQueue": clusterQueue},
+				"status": map[string]interface{}{
+					"admittedWorkloads": int64(1),
+					"flavorUsage": []interface{}{
+						map[string]interface{}{
+							"name":      "a100",
+							"resources": []interface{}{map[string]interface{}{"name": "nvidia.com/gpu", "total": "4"}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	localQueues := []unstructured.Unstructured{localQueue("training", "research"), localQueue("orphan", "missing")}
+
+	flavors := []unstructured.Unstructured{
+		{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "a100"},
+				"spec":     map[string]interface{}{"nodeLabels": map[string]interface{}{"nvidia.com/gpu.product": "A100"}},
+			},
+		},
+	}
+
+	nodes := []NodeJson{
+		{Name: "gpu-1", Labels: map[string]string{"nvidia.com/gpu.product": "A100"}, Free: ResourcesJson{Cpu: 10, Gpu: 1}},
+		{Name: "gpu-2", Labels: map[string]string{"nvidia.com/gpu.product": "A100"}, Free: ResourcesJson{Cpu: 6, Gpu: 2}},
+		{Name: "cpu-1", Labels: map[string]string{}, Free: ResourcesJson{Cpu: 32}},
+	}
+
+	result := parseKueueQueues(clusterQueues, localQueues, flavors, nodes)
+
+	if len(result) != 1 || len(result[0].Flavors) != 1 {
+		t.Fatalf(`parseKueueQueues() = %v, want 1 queue with 1 flavor`, result)
+	}
+
+	queue, flavor := result[0], result[0].Flavors[0]
+	cpu, gpu, pods := flavor.Resources["cpu"], flavor.Resources["gpu"], flavor.Resources["pods"]
+
+	switch {
+	case queue.Cohort != "university" || queue.AdmittedWorkloads != 3 || queue.PendingWorkloads != 2:
+		t.Fatalf(`queue = %v, want cohort university with 3 admitted and 2 pending workloads`, queue)
+	case flavor.Nodes != 2:
+		t.Fatalf(`flavor.Nodes = %v, want match for %v`, flavor.Nodes, 2)
+	case cpu.NominalQuota != 64 || cpu.Usage != 48 || cpu.Free == nil || *cpu.Free != 16:
+		t.Fatalf(`cpu = %v, want quota 64, usage 48, and 16 free`, cpu)
+	case gpu.NominalQuota != 8 || gpu.Usage != 10 || gpu.Borrowed != 2 || gpu.BorrowingLimit == nil || *gpu.BorrowingLimit != 4:
+		t.Fatalf(`gpu = %v, want quota 8, usage 10 with 2 borrowed, and a borrowing limit of 4`, gpu)
+	case gpu.Free == nil || *gpu.Free != 3:
+		t.Fatalf(`gpu.Free = %v, want match for %v`, gpu.Free, 3)
+	case pods.NominalQuota != 100 || pods.Free != nil:
+		t.Fatalf(`pods = %v, want quota 100 and no free count`, pods)
+	case len(queue.LocalQueues) != 1 || queue.LocalQueues[0].Name != "training" || queue.LocalQueues[0].Usage["gpu"] != 4:
+		t.Fatalf(`queue.LocalQueues = %v, want training using 4 GPUs`, queue.LocalQueues)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// File containing the namespace of the pod, mounted with its service account token
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Whether leader election is enabled, and whether this replica currently holds the lease
+var (
+	electionEnabled atomic.Bool
+	leading         atomic.Bool
+)
+
+// isLeader returns whether this replica should run work that must only happen once across replicas, such as sending
+// alerts. Every replica leads when leader election is disabled.
+func isLeader() bool {
+	return !electionEnabled.Load() || leading.Load()
+}
+
+// leaderOnly wraps a snapshot handler so that it only runs on the leader.
+func leaderOnly(handler func(*Snapshot)) func(*Snapshot) {
+	return func(snapshot *Snapshot) {
+		if isLeader() {
+			handler(snapshot)
+		}
+	}
+}
+
+// getPodNamespace returns the namespace the API is running in, or default when running outside the cluster.
+func getPodNamespace() string {
+	if data, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+		if namespace := strings.TrimSpace(string(data)); namespace != "" {
+			return namespace
+		}
+	}
+
+	return "default"
+}
+
+// runLeaderElection competes for the Lease named name in namespace as id, recording whether this replica holds it.
+// A replica that loses the lease goes back to competing for it. It blocks forever, so it should be run in its own
+// goroutine, after electionEnabled is set so that no replica leads before it holds the lease.
+func runLeaderElection(client kubernetes.Interface, namespace, name, id string) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Client:     client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: id},
+	}
+
+	for {
+		leaderelection.RunOrDie(context.Background(), leaderelection.LeaderElectionConfig{
+			Lock:          lock,
+			Name:          name,
+			LeaseDuration: 15 * time.Second,
+			RenewDeadline: 10 * time.Second,
+			RetryPeriod:   2 * time.Second,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					leading.Store(true)
+					fmt.Printf("%v is now the leader\n", id)
+				},
+				OnStoppedLeading: func() {
+					leading.Store(false)
+					fmt.Printf("%v is no longer the leader\n", id)
+				},
+				OnNewLeader: func(identity string) {
+					debugf("leader is %v", identity)
+				},
+			},
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+// TestLeaderOnly checks that wrapped handlers run on every replica without leader election, and only on the leader
+// with it.
+func TestLeaderOnly(t *testing.T) {
+	defer electionEnabled.Store(false)
+	defer leading.Store(false)
+
+	calls := 0
+	handler := leaderOnly(func(*Snapshot) { calls++ })
+
+	handler(&Snapshot{})
+
+	electionEnabled.Store(true)
+	handler(&Snapshot{})
+
+	leading.Store(true)
+	handler(&Snapshot{})
+
+	if calls != 2 {
+		t.Fatalf(`calls = %v, want match for %v`, calls, 2)
+	}
+}
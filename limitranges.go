@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LimitRangeItemJson contains the constraints a LimitRange places on one type of object, keyed by resource name
+type LimitRangeItemJson struct {
+	Type                 string             `json:"type"`
+	Default              map[string]float64 `json:"default,omitempty"`
+	DefaultRequest       map[string]float64 `json:"defaultRequest,omitempty"`
+	Min                  map[string]float64 `json:"min,omitempty"`
+	Max                  map[string]float64 `json:"max,omitempty"`
+	MaxLimitRequestRatio map[string]float64 `json:"maxLimitRequestRatio,omitempty"`
+}
+
+// LimitRangeJson contains the constraints of a LimitRange
+type LimitRangeJson struct {
+	Name      string               `json:"name"`
+	Namespace string               `json:"namespace"`
+	Limits    []LimitRangeItemJson `json:"limits"`
+}
+
+// LimitRanges returns every LimitRange in namespace, or in the whole cluster if namespace is empty. If the
+// API doesn't allow reading LimitRanges, none are returned.
+func (c *Collector) LimitRanges(namespace string) ([]corev1.LimitRange, error) {
+	list, err := c.client.CoreV1().LimitRanges(namespace).List(context.Background(), metav1.ListOptions{})
+
+	// Not being allowed to read LimitRanges shouldn't stop the rest of the response
+	if errors.IsForbidden(err) {
+		return []corev1.LimitRange{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// getDefaultRequests returns the default request of each resource for containers in each namespace, from the
+// Container limits of every LimitRange. As in the LimitRanger admission plugin, the default limit is used as
+// the default request if there isn't one.
+func getDefaultRequests(limitRanges []corev1.LimitRange) map[string]corev1.ResourceList {
+	defaults := make(map[string]corev1.ResourceList)
+
+	for _, limitRange := range limitRanges {
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+
+			if _, ok := defaults[limitRange.Namespace]; !ok {
+				defaults[limitRange.Namespace] = make(corev1.ResourceList)
+			}
+
+			for name, quantity := range item.Default {
+				defaults[limitRange.Namespace][name] = quantity.DeepCopy()
+			}
+
+			for name, quantity := range item.DefaultRequest {
+				defaults[limitRange.Namespace][name] = quantity.DeepCopy()
+			}
+		}
+	}
+
+	return defaults
+}
+
+// applyLimitRangeDefaults sets the requests of containers that don't request a resource to the default request
+// of their namespace, or to their limit if they have one, which is what the API server does to pods created
+// after the LimitRange. Pods created before it would otherwise be counted as requesting nothing.
+func applyLimitRangeDefaults(pods []corev1.Pod, limitRanges []corev1.LimitRange) {
+	defaults := getDefaultRequests(limitRanges)
+
+	for i := range pods {
+		namespaceDefaults, ok := defaults[pods[i].Namespace]
+		if !ok {
+			continue
+		}
+
+		for _, containers := range [][]corev1.Container{pods[i].Spec.InitContainers, pods[i].Spec.Containers} {
+			for j := range containers {
+				resources := &containers[j].Resources
+
+				for name, quantity := range namespaceDefaults {
+					if _, ok := resources.Requests[name]; ok {
+						continue
+					}
+
+					if resources.Requests == nil {
+						resources.Requests = make(corev1.ResourceList)
+					}
+
+					if limit, ok := resources.Limits[name]; ok {
+						resources.Requests[name] = limit.DeepCopy()
+					} else {
+						resources.Requests[name] = quantity.DeepCopy()
+					}
+				}
+			}
+		}
+	}
+}
+
+// getLimitRangeStructured takes a pointer to a LimitRange and returns a LimitRangeJson struct instance with its
+// quantities converted to numbers.
+func getLimitRangeStructured(limitRange *corev1.LimitRange) LimitRangeJson {
+	limitRangeJson := LimitRangeJson{
+		Name:      limitRange.Name,
+		Namespace: limitRange.Namespace,
+		Limits:    make([]LimitRangeItemJson, 0, len(limitRange.Spec.Limits)),
+	}
+
+	for _, item := range limitRange.Spec.Limits {
+		limitRangeJson.Limits = append(limitRangeJson.Limits, LimitRangeItemJson{
+			Type:                 string(item.Type),
+			Default:              getResourceListStructured(item.Default),
+			DefaultRequest:       getResourceListStructured(item.DefaultRequest),
+			Min:                  getResourceListStructured(item.Min),
+			Max:                  getResourceListStructured(item.Max),
+			MaxLimitRequestRatio: getResourceListStructured(item.MaxLimitRequestRatio),
+		})
+	}
+
+	return limitRangeJson
+}
+
+// getResourceListStructured converts every quantity in a ResourceList to a number, keyed by resource name.
+func getResourceListStructured(list corev1.ResourceList) map[string]float64 {
+	if len(list) == 0 {
+		return nil
+	}
+
+	result := make(map[string]float64, len(list))
+	for name, quantity := range list {
+		result[string(name)] = quantity.AsApproximateFloat64()
+	}
+
+	return result
+}
+
+// getLimitRangesHandler returns a HandlerFunc to return every LimitRange given a Collector. The LimitRanges can
+// be filtered with the namespace query parameter.
+func getLimitRangesHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		limitRanges, err := collector.LimitRanges(c.Query("namespace"))
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving LimitRange information")
+			return
+		}
+
+		result := make([]LimitRangeJson, 0, len(limitRanges))
+		for i := range limitRanges {
+			result = append(result, getLimitRangeStructured(&limitRanges[i]))
+		}
+
+		// Sort by namespace and then name so the order is stable
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Namespace != result[j].Namespace {
+				return result[i].Namespace < result[j].Namespace
+			}
+			return result[i].Name < result[j].Name
+		})
+
+		c.IndentedJSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestApplyLimitRangeDefaults applies a LimitRange to pods in and out of its namespace, checking that explicit
+// requests are kept, limits are used as requests before defaults, and the default limit is used when there is
+// no default request.
+func TestApplyLimitRangeDefaults(t *testing.T) {
+	limitRanges := []v1.LimitRange{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "vision"},
+			Spec: v1.LimitRangeSpec{
+				Limits: []v1.LimitRangeItem{
+					{
+						Type:           v1.LimitTypeContainer,
+						Default:        v1.ResourceList{"cpu": resource.MustParse("2"), "memory": resource.MustParse("4Gi")},
+						DefaultRequest: v1.ResourceList{"cpu": resource.MustParse("500m")},
+					},
+					{
+						Type: v1.LimitTypePod,
+						Max:  v1.ResourceList{"cpu": resource.MustParse("64")},
+					},
+				},
+			},
+		},
+	}
+
+	pods := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "vision"},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{Name: "besteffort"},
+					{Name: "limited", Resources: v1.ResourceRequirements{Limits: v1.ResourceList{"cpu": resource.MustParse("1")}}},
+					{Name: "explicit", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{"cpu": resource.MustParse("4"), "memory": resource.MustParse("1Gi")}}},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "other"},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "besteffort"}}},
+		},
+	}
+
+	applyLimitRangeDefaults(pods, limitRanges)
+
+	want := []ResourcesJson{
+		{Cpu: 0.5, Memory: 4 << 30},
+		{Cpu: 1, Memory: 4 << 30},
+		{Cpu: 4, Memory: 1 << 30},
+	}
+
+	for i, container := range pods[0].Spec.Containers {
+		got := getResourcesStructured(getResourcesFromList(container.Resources.Requests))
+		if got != want[i] {
+			t.Fatalf(`requests of container %v = %v, want match for %v`, container.Name, got, want[i])
+		}
+	}
+
+	if len(pods[1].Spec.Containers[0].Resources.Requests) != 0 {
+		t.Fatalf(`requests of pod in other namespace = %v, want none`, pods[1].Spec.Containers[0].Resources.Requests)
+	}
+}
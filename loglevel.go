@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/subtle"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+)
+
+// Log levels that can be switched between at runtime
+const (
+	logLevelInfo  = "info"
+	logLevelDebug = "debug" // Also logs every request client-go makes to the API server
+)
+
+// Verbosity of client-go's logging at each level - 6 logs the method, URL, status, and latency of every request
+var klogVerbosity = map[string]string{logLevelInfo: "0", logLevelDebug: "6"}
+
+// Whether debug messages are logged
+var debugLogging atomic.Bool
+
+// Flags controlling client-go's logging, registered the first time the level is set
+var (
+	klogFlags     *flag.FlagSet
+	klogFlagsOnce sync.Once
+)
+
+// LogLevelRequest is the body of a request to change the log level
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// setLogLevel switches between the info and debug log levels.
+func setLogLevel(level string) error {
+	verbosity, ok := klogVerbosity[level]
+	if !ok {
+		return fmt.Errorf("unknown log level %q: expected %v or %v", level, logLevelInfo, logLevelDebug)
+	}
+
+	klogFlagsOnce.Do(func() {
+		klogFlags = flag.NewFlagSet("klog", flag.ContinueOnError)
+		klog.InitFlags(klogFlags)
+	})
+
+	if err := klogFlags.Set("v", verbosity); err != nil {
+		return err
+	}
+
+	debugLogging.Store(level == logLevelDebug)
+
+	return nil
+}
+
+// getLogLevel returns the current log level.
+func getLogLevel() string {
+	if debugLogging.Load() {
+		return logLevelDebug
+	}
+
+	return logLevelInfo
+}
+
+// debugf logs a message only at the debug level.
+func debugf(format string, args ...interface{}) {
+	if debugLogging.Load() {
+		fmt.Printf("debug: "+format+"\n", args...)
+	}
+}
+
+// requireToken returns a HandlerFunc that rejects requests without token as their bearer token.
+func requireToken(token string) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, "error: expected a valid bearer token")
+			return
+		}
+
+		c.Next()
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getLogLevelHandler returns a HandlerFunc to return the current log level.
+func getLogLevelHandler() gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		c.IndentedJSON(http.StatusOK, LogLevelRequest{Level: getLogLevel()})
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getSetLogLevelHandler returns a HandlerFunc to change the log level to the one in the request body.
+func getSetLogLevelHandler() gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var request LogLevelRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, "error: expected a JSON body with a level")
+			return
+		}
+
+		if err := setLogLevel(request.Level); err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		fmt.Printf("log level set to %v\n", request.Level)
+		c.IndentedJSON(http.StatusOK, LogLevelRequest{Level: getLogLevel()})
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSetLogLevelHandler changes the log level through the admin endpoint, checking that requests without the
+// token and unknown levels are rejected.
+func TestSetLogLevelHandler(t *testing.T) {
+	defer setLogLevel(logLevelInfo)
+
+	router := gin.New()
+	router.PUT("/admin/log-level", requireToken("secret"), getSetLogLevelHandler())
+
+	tests := []struct {
+		token  string
+		body   string
+		status int
+		level  string
+	}{
+		{"", `{"level": "debug"}`, http.StatusUnauthorized, logLevelInfo},
+		{"wrong", `{"level": "debug"}`, http.StatusUnauthorized, logLevelInfo},
+		{"secret", `{"level": "trace"}`, http.StatusBadRequest, logLevelInfo},
+		{"secret", `{"level": "debug"}`, http.StatusOK, logLevelDebug},
+		{"secret", `{"level": "info"}`, http.StatusOK, logLevelInfo},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("PUT", "/admin/log-level", strings.NewReader(test.body))
+		if test.token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.token)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		switch {
+		case recorder.Code != test.status:
+			t.Fatalf(`status = %v, want match for %v`, recorder.Code, test.status)
+		case getLogLevel() != test.level:
+			t.Fatalf(`getLogLevel() = %v, want match for %v`, getLogLevel(), test.level)
+		}
+	}
+}
@@ -2,20 +2,24 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	resourcehelper "k8s.io/kubectl/pkg/util/resource"
+	_ "k8s.io/client-go/plugin/pkg/client/auth" // Register the OIDC, GCP, and Azure auth providers
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
 )
 
 // Define resources struct containing the resource types we want to return
@@ -29,7 +33,14 @@ type Resources struct {
 // Define node struct for storing resources and other node information
 type Node struct {
 	Name        string
+	Labels      map[string]string
 	Taints      []corev1.Taint
+	Pressure    []string              // Names of the pressure conditions that are true
+	Ready       *corev1.NodeCondition // The Ready condition, or nil if the kubelet hasn't reported one
+	Images      NodeImages            // Container images the kubelet reports in the node's status
+	Os          string                // Operating system of the node, such as linux or windows
+	Addresses   []corev1.NodeAddress  // Addresses of the node, such as its InternalIP
+	Created     time.Time             // When the Node object was created, which is about when the machine joined
 	Allocatable Resources
 	Capacity    Resources
 	Free        Resources
@@ -45,11 +56,30 @@ type ResourcesJson struct {
 
 // Node information in JSON format to be returned by the API
 type NodeJson struct {
-	Name        string         `json:"name"`
-	Taints      []corev1.Taint `json:"taints"`
-	Allocatable ResourcesJson  `json:"allocatable"`
-	Capacity    ResourcesJson  `json:"capacity"`
-	Free        ResourcesJson  `json:"free"`
+	Name              string                 `json:"name"`
+	Labels            map[string]string      `json:"labels"`
+	Taints            []corev1.Taint         `json:"taints"`
+	Addresses         []corev1.NodeAddress   `json:"addresses,omitempty"` // Only with --node-addresses
+	Allocatable       ResourcesJson          `json:"allocatable"`
+	Capacity          ResourcesJson          `json:"capacity"`
+	Free              ResourcesJson          `json:"free"`
+	DaemonSetRequests ResourcesJson          `json:"daemonSetRequests"` // Requests of the DaemonSet pods on the node
+	Os                string                 `json:"os"`
+	Created           time.Time              `json:"creationTimestamp"`
+	Age               float64                `json:"age"` // Seconds since the node was created
+	Pool              string                 `json:"pool"`
+	CapacityType      string                 `json:"capacityType"`
+	HourlyCost        *float64               `json:"hourlyCost,omitempty"`
+	EvictionRisk      EvictionRisk           `json:"evictionRisk"`
+	HeartbeatAge      *float64               `json:"heartbeatAge,omitempty"` // Seconds since the node's kubelet last renewed its Lease
+	Stale             bool                   `json:"stale"`                  // True if the node hasn't sent a heartbeat within the stale threshold
+	Readiness         *NodeReadiness         `json:"readiness,omitempty"`
+	Swap              *NodeSwap              `json:"swap,omitempty"`
+	DiskUsage         *NodeDiskUsage         `json:"diskUsage,omitempty"`
+	Images            NodeImages             `json:"images"`
+	Reserved          NodeReserved           `json:"reserved"`
+	Extra             map[string]interface{} `json:"extra,omitempty"`    // Added by collector plugins, keyed by plugin name
+	Computed          map[string]interface{} `json:"computed,omitempty"` // Computed fields from the config file, keyed by name
 }
 
 func main() {
@@ -59,33 +89,81 @@ func main() {
 		fmt.Println("error loading .env file")
 	}
 
-	// Declare Kubernetes client
-	var config *rest.Config
+	// Use the named kubeconfig context rather than the current context - must come before the kubeconfig path
+	kubeContext := flag.String("context", "", "name of the kubeconfig context to use")
 
-	// Get arguments after program name - this will represent the path to a kubeconfig file
-	args := os.Args[1:]
+	// Serve every route under a prefix, for hosting behind a shared ingress path without rewriting requests
+	basePath := flag.String("base-path", "", "prefix to serve every route under, e.g. /capacity")
 
-	// Exit with error if kubeconfig path not provided
-	if len(args) == 0 {
-		fmt.Println("error: expected kubeconfig path")
-		os.Exit(1)
-	}
+	// Serve a generated cluster rather than a real one, for developing against and testing without a kubeconfig
+	demo := flag.Bool("demo", false, "serve a generated cluster instead of connecting to one")
+	demoNodes := flag.Int("demo-nodes", 20, "number of nodes in the generated cluster")
+	demoPods := flag.Int("demo-pods", 300, "number of pods in the generated cluster")
+	demoSeed := flag.Int64("demo-seed", 1, "seed for generating the cluster - the same seed always gives the same cluster")
+
+	// Append the raw state of the cluster to a file every snapshot interval, or serve a file recorded that way
+	record := flag.String("record", "", "file to append the nodes and pods of the cluster to every snapshot interval")
+	replay := flag.String("replay", "", "file recorded with --record to serve instead of connecting to a cluster")
+	replayAdvance := flag.Bool("replay-advance", false, "step through the frames of the recording at the pace they were recorded")
+
+	// Sum pod requests as the cluster's scheduler does, which changed with sidecar containers in 1.28
+	requestRules := flag.String("request-rules", requestRulesSidecar, "rules for summing pod requests: "+requestRulesSidecar+" counts restartable init containers as sidecars, "+requestRulesLegacy+" doesn't")
+	// Include the addresses of each node, for tooling that maps capacity back to machines
+	nodeAddresses := flag.Bool("node-addresses", false, "include the InternalIP, ExternalIP, and Hostname addresses of each node")
 
-	// Get pointer to first argument after program name
-	kubeconfig := &args[0]
+	// Stop counting the requests of pods that are terminating past their grace period or on nodes that have been not
+	// ready for longer than the pods tolerate, since their resources are about to be freed
+	releaseTerminating := flag.Bool("release-terminating-pods", false, "don't count pods past their grace period or evicted from nodes that aren't ready against free resources")
 
-	// Create a config from the kubeconfig file
-	config, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	flag.Parse()
+
+	legacyRequestRules, err = parseRequestRules(*requestRules)
 
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	// Create a Kubernetes clientset from the config
-	clientset, err := kubernetes.NewForConfig(config)
+	// Declare Kubernetes clients
+	var clientset kubernetes.Interface
+	var dynamicClient dynamic.Interface
+	var metricsClient metricsclient.Interface
 
-	if err != nil {
+	if *demo {
+		fmt.Printf("serving a generated cluster of %v nodes and %v pods from seed %v\n", *demoNodes, *demoPods, *demoSeed)
+		clientset, metricsClient = newDemoClients(*demoNodes, *demoPods, *demoSeed)
+	} else if *replay != "" {
+		var recording *Replay
+		clientset, recording, err = newReplayClient(*replay)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		// Usage isn't recorded, so the rightsizing report is empty
+		metricsClient = metricsfake.NewSimpleClientset()
+
+		fmt.Printf("replaying %v frames from %v\n", len(recording.frames), *replay)
+		if *replayAdvance {
+			go recording.Run()
+		}
+	} else {
+		clientset, dynamicClient, metricsClient, err = newKubeClients(*kubeContext, flag.Args())
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Start at the given log level, which can be changed later through /admin/log-level
+	logLevel := os.Getenv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = logLevelInfo
+	}
+
+	if err := setLogLevel(logLevel); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -96,10 +174,820 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := gin.Default()
+	// Only believe X-Forwarded-For and X-Forwarded-Proto from the given proxies - set to an empty list to trust none
+	trustedProxiesValue, ok := os.LookupEnv("TRUSTED_PROXIES")
+	if !ok {
+		trustedProxiesValue = defaultTrustedProxies
+	}
+
+	proxies, err := parseTrustedProxies(trustedProxiesValue)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	router := gin.New()
+	router.Use(gin.LoggerWithFormatter(getLogFormatter(proxies)), gin.Recovery(), freeAccountingHeaders())
+
+	if err := router.SetTrustedProxies(proxies.Strings()); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	routes := router.Group(normalizeBasePath(*basePath))
+
+	// Create a collector to get the state of the cluster's nodes
+	collector := newCollector(clientset)
+	collector.dynamic = dynamicClient
+	collector.nodeAddresses = *nodeAddresses
+	collector.releasePods = *releaseTerminating
+
+	// Group nodes into pools by the given label - if unset, well-known pool labels are used
+	collector.poolLabel = os.Getenv("NODE_POOL_LABEL")
+
+	// Read the configuration that can be changed without a restart, if a file containing it is provided
+	configPath := os.Getenv("CONFIG_FILE")
+	var configData []byte
+	if configPath != "" {
+		configData, err = os.ReadFile(configPath)
+
+		if err == nil {
+			err = applyRuntimeConfig(configData, func(*RuntimeConfig) {})
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Mark nodes as stale after a different time without a heartbeat, e.g. to match a custom node-monitor-grace-period
+	if staleThreshold := os.Getenv("NODE_STALE_THRESHOLD"); staleThreshold != "" {
+		threshold, err := time.ParseDuration(staleThreshold)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		collector.staleThreshold = threshold
+	}
+
+	// Read each node's swap behavior from its kubelet's configuration through the apiserver's node proxy, reusing it for
+	// KUBELET_CONFIG_TTL, and its disk usage from its kubelet's stats summary on every snapshot - needs RBAC to get
+	// nodes/proxy
+	collector.kubeletConfig = os.Getenv("KUBELET_CONFIG") == "true"
+	collector.kubeletStats = os.Getenv("KUBELET_STATS") == "true"
+
+	if collector.kubeletConfig || collector.kubeletStats {
+		ttl := defaultKubeletConfigTTL
+
+		if value := os.Getenv("KUBELET_CONFIG_TTL"); value != "" {
+			ttl, err = time.ParseDuration(value)
+
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		collector.kubelet = newKubeletReader(clientset, ttl)
+	}
+
+	// Read the cluster-autoscaler status from somewhere other than kube-system if it is installed elsewhere
+	if autoscalerStatus := os.Getenv("AUTOSCALER_STATUS_CONFIGMAP"); autoscalerStatus != "" {
+		collector.autoscalerStatus = autoscalerStatus
+	}
+
+	// Enrich every snapshot with the comma-separated list of collector plugins, if any
+	if pluginNames := os.Getenv("COLLECTOR_PLUGINS"); pluginNames != "" {
+		plugins, err := newCollectorPlugins(collector, strings.Split(pluginNames, ","))
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		collector.plugins = plugins
+	}
+
+	// Estimate the cost of each node if a price table is provided
+	costTablePath := os.Getenv("COST_TABLE")
+	if costTablePath != "" {
+		costs, err := loadStaticCostProvider(costTablePath)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		collector.costs = costs
+	}
+
+	// Pods have unique hostnames, which identify this replica to the others
+	replicaId, err := os.Hostname()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Elect one replica to run the work that must only happen once, such as alerting, if enabled
+	if os.Getenv("LEADER_ELECTION") == "true" {
+		namespace := os.Getenv("LEADER_ELECTION_NAMESPACE")
+		if namespace == "" {
+			namespace = getPodNamespace()
+		}
+
+		leaseName := os.Getenv("LEADER_ELECTION_LEASE")
+		if leaseName == "" {
+			leaseName = "kubernetes-resource-api"
+		}
+
+		// No replica leads until it holds the lease, so this is set before anything that checks isLeader can run
+		electionEnabled.Store(true)
+		go runLeaderElection(clientset, namespace, leaseName, replicaId)
+	}
+
+	// Split the nodes between replicas if there is more than one shard, merging every shard's nodes in each snapshot
+	if shardCount := os.Getenv("SHARD_COUNT"); shardCount != "" && shardCount != "1" {
+		count, err := strconv.Atoi(shardCount)
+
+		// Each replica of a StatefulSet takes the shard matching its ordinal unless told otherwise
+		index := 0
+		if err == nil {
+			if shardIndex := os.Getenv("SHARD_INDEX"); shardIndex != "" {
+				index, err = strconv.Atoi(shardIndex)
+			} else {
+				index, err = getShardIndex(replicaId)
+			}
+		}
+
+		var shard *ShardConfig
+		if err == nil {
+			shard, err = newShardConfig(index, count, strings.Split(os.Getenv("SHARD_PEERS"), ","), os.Getenv("SHARD_TOKEN"))
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		collector.shard = shard
+
+		// Create an endpoint at /internal/shard that returns a snapshot of only this replica's shard
+		if shard.token != "" {
+			routes.GET("/internal/shard", requireToken(shard.token), getShardHandler(collector))
+		} else {
+			routes.GET("/internal/shard", getShardHandler(collector))
+		}
+	}
+
+	// Share one snapshot between replicas through Redis if a server is provided, so they serve the same view and
+	// only one of them lists the cluster
+	if redisUrl := os.Getenv("REDIS_URL"); redisUrl != "" {
+		// Connect through several seed addresses to a Redis Cluster, or through Sentinels if a master name is given
+		var addrs []string
+		if list := os.Getenv("REDIS_ADDRS"); list != "" {
+			addrs = strings.Split(list, ",")
+		}
+
+		redis, err := newRedisClient(redisUrl, addrs, os.Getenv("REDIS_SENTINEL_MASTER"))
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		prefix := os.Getenv("REDIS_KEY_PREFIX")
+		if prefix == "" {
+			prefix = "kubernetes-resource-api"
+		}
+
+		collector.shared = newSharedSnapshotCache(redis, prefix, replicaId, getEnvDuration("REDIS_REFRESH_INTERVAL", 30*time.Second))
+		go runSharedSnapshotLoop(collector, collector.shared)
+	}
+
+	// Time out slow clients on every port, rather than serving with gin's defaults, which have no timeouts
+	serverLimits, err := parseServerLimits()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Serve the operational endpoints on their own port if one is given, so they can be kept off the public API and
+	// restricted with a NetworkPolicy, or else on the API's port if a token to protect them is provided
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if adminPort := os.Getenv("ADMIN_PORT"); adminPort != "" {
+		adminRouter := gin.New()
+		adminRouter.Use(gin.LoggerWithFormatter(getLogFormatter(proxies)), gin.Recovery())
+
+		if adminToken != "" {
+			adminRouter.Use(requireToken(adminToken))
+		}
+
+		registerAdminRoutes(adminRouter, collector)
+
+		go func() {
+			if err := newServer(":"+adminPort, adminRouter, serverLimits).ListenAndServe(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}()
+	} else if adminToken != "" {
+		registerAdminRoutes(routes.Group("", requireToken(adminToken)), collector)
+	}
+
+	// Routes for cluster components such as kube-scheduler, which call the API directly rather than as a user
+	components := routes.Group("")
+
+	// Only show callers the node pools and namespaces RBAC allows them to see, if enabled
+	var reviewer *AccessReviewer
+	if os.Getenv("ACCESS_REVIEW") == "true" {
+		userHeader := os.Getenv("ACCESS_REVIEW_USER_HEADER")
+		if userHeader == "" {
+			userHeader = defaultAccessUserHeader
+		}
+
+		groupHeader := os.Getenv("ACCESS_REVIEW_GROUP_HEADER")
+		if groupHeader == "" {
+			groupHeader = defaultAccessGroupHeader
+		}
+
+		reviewer = newAccessReviewer(clientset, userHeader, groupHeader, getEnvDuration("ACCESS_REVIEW_CACHE_TTL", time.Minute))
+		routes.Use(reviewer.Middleware())
+	}
+
+	// filtered creates an endpoint at relativePath that serves only what the caller may see if access review is
+	// enabled, and everything otherwise
+	filtered := func(relativePath string, newHandler func(*Collector) gin.HandlerFunc) {
+		if reviewer == nil {
+			routes.GET(relativePath, newHandler(collector))
+			return
+		}
+
+		reviewer.route(routes, relativePath, collector, newHandler)
+	}
+
+	// Create an endpoint at / that returns a dashboard of the nodes for browsers
+	routes.GET("/", getDashboardHandler())
+
+	// Create an endpoint at /version that returns the version of the API and of the cluster
+	routes.GET("/version", getVersionHandler(clientset.Discovery()))
 
 	// Create an endpoint at /nodes that calls a function returned by getNodesHandler
-	router.GET("/nodes", getNodesHandler(clientset))
+	filtered("/nodes", getNodesHandler)
+
+	// Create an endpoint at /nodes/:name/pods that returns the pods scheduled on a single node
+	filtered("/nodes/:name/pods", getNodePodsHandler)
+
+	// Create an endpoint at /nodes/diff that returns the nodes that changed after a snapshot version
+	routes.GET("/nodes/diff", getNodesDiffHandler(collector))
+
+	// Create an endpoint at /nodes/at-risk that returns the nodes most likely to start evicting pods
+	filtered("/nodes/at-risk", getAtRiskNodesHandler)
+
+	// Create an endpoint at /summary that returns the total resources of the cluster
+	filtered("/summary", getSummaryHandler)
+
+	// Create an endpoint at /nodepools that returns the total resources of each node pool
+	filtered("/nodepools", getNodePoolsHandler)
+
+	// Create an endpoint at /gpus that returns the GPUs of every node and the pods holding them
+	routes.GET("/gpus", getGpusHandler(collector))
+
+	// Create an endpoint at /gpus/allocations that returns every pod holding GPUs and how long it has held them
+	routes.GET("/gpus/allocations", getGpuAllocationsHandler(collector))
+
+	// Create an endpoint at /karpenter that returns the capacity and limits of every Karpenter NodePool
+	routes.GET("/karpenter", getKarpenterHandler(collector))
+
+	// Create an endpoint at /queues that returns the quotas and usage of every Kueue ClusterQueue and Volcano Queue
+	routes.GET("/queues", getQueuesHandler(collector))
+
+	// Create an endpoint at /autoscaler that returns the status of cluster-autoscaler and its node groups
+	routes.GET("/autoscaler", getAutoscalerHandler(collector))
+
+	// Create an endpoint at /namespaces/top that returns the namespaces with the highest requests
+	filtered("/namespaces/top", getTopNamespacesHandler)
+
+	// Create an endpoint at /namespaces/:ns/gpus that compares a namespace's GPU requests with its GPU quotas
+	routes.GET("/namespaces/:ns/gpus", getNamespaceGpusHandler(collector))
+
+	// Create an endpoint at /pods that returns every pod and its requests
+	filtered("/pods", getPodsHandler)
+
+	// Create an endpoint at /pods/top that returns the pods with the highest requests
+	filtered("/pods/top", getTopPodsHandler)
+
+	// Create an endpoint at /quotas that returns every ResourceQuota and how much of it is used
+	routes.GET("/quotas", getQuotasHandler(collector))
+
+	// Create an endpoint at /limitranges that returns every LimitRange
+	routes.GET("/limitranges", getLimitRangesHandler(collector))
+
+	// Create an endpoint at /pdbs that returns every PodDisruptionBudget and how many disruptions it allows
+	routes.GET("/pdbs", getPdbsHandler(collector))
+
+	// Create an endpoint at /events that returns recent signs of resource pressure
+	routes.GET("/events", getPressureEventsHandler(collector))
+
+	// Create an endpoint at /storage that returns the capacity of every StorageClass and PersistentVolume
+	routes.GET("/storage", getStorageHandler(collector))
+
+	// Create an endpoint at /pvcs that returns every PersistentVolumeClaim and the totals of each namespace
+	routes.GET("/pvcs", getClaimsHandler(collector))
+
+	// Functions to call with every periodic snapshot of the cluster
+	snapshotHandlers := make([]func(*Snapshot), 0)
+
+	// Where to get the history of nodes from, if anywhere
+	var historyBackend HistoryBackend
+
+	// Get the Prometheus server to query for historical usage, if one is provided
+	var prometheus *PrometheusClient
+	if prometheusUrl := os.Getenv("PROMETHEUS_URL"); prometheusUrl != "" {
+		prometheus = newPrometheusClient(prometheusUrl)
+	}
+
+	// Get the actual usage of pods from Prometheus if possible, or from metrics-server otherwise
+	var usageSource UsageSource
+	if prometheus != nil {
+		usageSource = &PrometheusUsage{
+			client: prometheus,
+			window: getEnvDuration("RIGHTSIZING_WINDOW", 7*24*time.Hour),
+		}
+	} else {
+		usageSource = &MetricsServerUsage{client: metricsClient}
+	}
+
+	// Create an endpoint at /fit/batch that checks whether a set of pods would fit in the cluster together
+	routes.POST("/fit/batch", getBatchFitHandler(collector))
+
+	// Create an endpoint at /simulate/drain that works out what would happen if a node were drained
+	routes.POST("/simulate/drain", getDrainHandler(collector))
+
+	// Create an endpoint at /simulate/remove-nodes that works out what would happen if a set of nodes were removed
+	routes.POST("/simulate/remove-nodes", getRemoveNodesHandler(collector))
+
+	// Create an endpoint at /simulate/add-nodes that works out what adding nodes of a given shape would free up
+	routes.POST("/simulate/add-nodes", getAddNodesHandler(collector))
+
+	// Read the free capacity each node pool should keep from a file if one is provided - pools without a target
+	// keep no buffer
+	headroomTargets := make(map[string]HeadroomTarget)
+	if headroomTargetsPath := os.Getenv("HEADROOM_TARGETS"); headroomTargetsPath != "" {
+		headroomTargets, err = loadHeadroomTargets(headroomTargetsPath)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Create an endpoint at /reports/headroom that recommends how many nodes each pool should have
+	routes.GET("/reports/headroom", getHeadroomHandler(collector, headroomTargets))
+
+	// Create an endpoint at /reports/rebalance that suggests pod moves to relieve overpacked nodes and empty underused ones
+	routes.GET("/reports/rebalance", getRebalanceHandler(collector))
+
+	// Create an endpoint at /render that applies the Go template in the request body to the current snapshot
+	routes.POST("/render", getRenderHandler(collector))
+
+	// Create an endpoint at /render/:name that applies a stored Go template, if a directory containing them is provided
+	renderTemplatesPath := os.Getenv("RENDER_TEMPLATES")
+	if renderTemplatesPath != "" {
+		templates, err := loadStoredTemplates(renderTemplatesPath)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		routes.GET("/render/:name", getRenderStoredHandler(collector, templates))
+	}
+
+	// Create an endpoint at /reports/cost that joins requests with the cost OpenCost allocates, if OpenCost is provided
+	if openCostUrl := os.Getenv("OPENCOST_URL"); openCostUrl != "" {
+		routes.GET("/reports/cost", getCostHandler(collector, newOpenCostClient(openCostUrl)))
+	}
+
+	// Create an endpoint at /reports/idle that returns the capacity no pod has requested
+	filtered("/reports/idle", getIdleReportHandler)
+
+	// Create an endpoint at /reports/fragmentation that returns how much free capacity workloads of a given shape can use
+	filtered("/reports/fragmentation", getFragmentationHandler)
+
+	// Create endpoints at /scheduler/filter and /scheduler/prioritize for kube-scheduler to use as an extender,
+	// reusing snapshots for a few seconds since the scheduler calls them for every pod
+	extenderSnapshots := newCachedSnapshot(collector, "scheduler-extender", getEnvDuration("SCHEDULER_EXTENDER_SNAPSHOT_TTL", 5*time.Second))
+	components.POST("/scheduler/filter", getExtenderFilterHandler(extenderSnapshots))
+	components.POST("/scheduler/prioritize", getExtenderPrioritizeHandler(extenderSnapshots))
+
+	// Keep the given number of imported snapshots, 10 by default
+	importedMax := 10
+	if value := os.Getenv("IMPORTED_SNAPSHOTS_MAX"); value != "" {
+		importedMax, err = strconv.Atoi(value)
+
+		if err != nil || importedMax < 1 {
+			fmt.Println("IMPORTED_SNAPSHOTS_MAX must be a positive integer")
+			os.Exit(1)
+		}
+	}
+
+	imported := newImportedSnapshots(importedMax)
+
+	// Create endpoints at /snapshots to import an exported snapshot, list imported snapshots, and delete them
+	routes.POST("/snapshots", getImportSnapshotHandler(imported))
+	routes.GET("/snapshots", getImportedSnapshotsHandler(imported))
+	routes.DELETE("/snapshots/:id", getDeleteImportedSnapshotHandler(imported))
+
+	// Create endpoints under /snapshots/:id that serve the reports that only need a snapshot from an imported one
+	importedRoutes := routes.Group("/snapshots/:id")
+	importedRoutes.GET("/nodes", imported.serve(getNodesHandler))
+	importedRoutes.GET("/nodes/:name/pods", imported.serve(getNodePodsHandler))
+	importedRoutes.GET("/nodes/at-risk", imported.serve(getAtRiskNodesHandler))
+	importedRoutes.GET("/summary", imported.serve(getSummaryHandler))
+	importedRoutes.GET("/nodepools", imported.serve(getNodePoolsHandler))
+	importedRoutes.GET("/pods", imported.serve(getPodsHandler))
+	importedRoutes.GET("/pods/top", imported.serve(getTopPodsHandler))
+	importedRoutes.GET("/namespaces/top", imported.serve(getTopNamespacesHandler))
+	importedRoutes.POST("/fit/batch", imported.serve(getBatchFitHandler))
+	importedRoutes.GET("/reports/idle", imported.serve(getIdleReportHandler))
+	importedRoutes.GET("/reports/fragmentation", imported.serve(getFragmentationHandler))
+
+	// Create an endpoint at /reports/rightsizing that compares what workloads request with what they use
+	routes.GET("/reports/rightsizing", getRightsizingHandler(collector, usageSource, getEnvFloat("RIGHTSIZING_HEADROOM", 0.15)))
+
+	// Record snapshots to the history database if a path to one is provided
+	historyPath := os.Getenv("HISTORY_DB")
+	if historyPath != "" {
+		history, err := openHistoryStore(historyPath)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		defer history.Close()
+
+		snapshotHandlers = append(snapshotHandlers, func(snapshot *Snapshot) {
+			if err := history.Record(snapshot); err != nil {
+				fmt.Println(err)
+			}
+
+			if _, err := history.RecordChanges(snapshot); err != nil {
+				fmt.Println(err)
+			}
+		})
+
+		// Roll up old snapshots into hourly averages and eventually delete them - by default,
+		// raw snapshots are kept for 7 days and hourly rollups for 90 days
+		rawRetention := getEnvDuration("HISTORY_RAW_RETENTION", 7*24*time.Hour)
+		go runCompactionLoop(
+			history,
+			getEnvDuration("HISTORY_COMPACT_INTERVAL", time.Hour),
+			rawRetention,
+			getEnvDuration("HISTORY_ROLLUP_RETENTION", 90*24*time.Hour),
+		)
+
+		historyBackend = history
+
+		// Create an endpoint at /reports/chargeback that returns the resources requested by each team over time
+		routes.GET("/reports/chargeback", getChargebackHandler(history, rawRetention))
+
+		// Create an endpoint at /changes that returns the log of nodes being added, removed, or resized
+		routes.GET("/changes", getChangesHandler(history))
+	} else if prometheus != nil {
+		// Without a local database, use the kube-state-metrics series already stored in Prometheus
+		historyBackend = &PrometheusHistory{
+			client: prometheus,
+			step:   getEnvDuration("PROMETHEUS_STEP", 5*time.Minute),
+		}
+	}
+
+	// Create an endpoint at /nodes/history that returns the history of a single node
+	if historyBackend != nil {
+		routes.GET("/nodes/history", getNodeHistoryHandler(historyBackend))
+
+		// Create an endpoint at /forecast that projects the requests of each node pool from its history
+		routes.GET("/forecast", getForecastHandler(collector, historyBackend))
+
+		// Create endpoints at /grafana that let Grafana's JSON datasources chart the history of each node and the cluster
+		grafana := routes.Group("/grafana")
+		grafana.GET("/", getGrafanaHealthHandler())
+		grafana.POST("/search", getGrafanaSearchHandler(collector))
+		grafana.POST("/query", getGrafanaQueryHandler(collector, historyBackend))
+	}
+
+	// Evaluate alert rules on every snapshot if a file containing them is provided, or if the config file could
+	// provide them later
+	var alerts *AlertManager
+	var fileRules []AlertRule
+
+	alertRulesPath := os.Getenv("ALERT_RULES")
+	if alertRulesPath != "" || configPath != "" {
+		if alertRulesPath != "" {
+			fileRules, err = loadAlertRules(alertRulesPath)
+
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		// Rules in the config file take precedence over those in ALERT_RULES
+		rules := fileRules
+		if configRules := currentConfig().AlertRules; configRules != nil {
+			rules = configRules
+		}
+
+		notifiers := make([]Notifier, 0)
+
+		if webhookUrl := os.Getenv("ALERT_WEBHOOK_URL"); webhookUrl != "" {
+			notifiers = append(notifiers, newWebhookNotifier(webhookUrl))
+		}
+
+		if slackUrl := os.Getenv("SLACK_WEBHOOK_URL"); slackUrl != "" {
+			notifiers = append(notifiers, newSlackNotifier(slackUrl))
+		}
+
+		alerts = newAlertManager(rules, notifiers)
+		snapshotHandlers = append(snapshotHandlers, leaderOnly(alerts.Evaluate))
+	}
+
+	// Apply changes to the config file without restarting, which would lose the state built up since startup
+	if configPath != "" {
+		go watchRuntimeConfig(configPath, getEnvDuration("CONFIG_RELOAD_INTERVAL", 30*time.Second), configData, func(config *RuntimeConfig) {
+			if config.AlertRules != nil {
+				alerts.SetRules(config.AlertRules)
+			} else {
+				alerts.SetRules(fileRules)
+			}
+		})
+	}
+
+	// Export snapshots to object storage if a bucket is provided
+	exportBucket := os.Getenv("EXPORT_BUCKET")
+	if exportBucket != "" {
+		endpoint := os.Getenv("EXPORT_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "s3.amazonaws.com" // Default to AWS S3
+		}
+
+		format := os.Getenv("EXPORT_FORMAT")
+		if format == "" {
+			format = "json"
+		}
+
+		exporter, err := newSnapshotExporter(
+			endpoint,
+			os.Getenv("EXPORT_REGION"),
+			exportBucket,
+			os.Getenv("EXPORT_PREFIX"),
+			format,
+			os.Getenv("EXPORT_INSECURE") != "true",
+			getEnvDuration("EXPORT_INTERVAL", time.Hour),
+		)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		snapshotHandlers = append(snapshotHandlers, leaderOnly(func(snapshot *Snapshot) {
+			if err := exporter.Export(snapshot); err != nil {
+				fmt.Println(err)
+			}
+		}))
+	}
+
+	// Write snapshots to InfluxDB if a bucket is provided
+	influxBucket := os.Getenv("INFLUX_BUCKET")
+	if influxBucket != "" {
+		influxUrl := os.Getenv("INFLUX_URL")
+		if influxUrl == "" {
+			influxUrl = "http://localhost:8086"
+		}
+
+		influx := newInfluxWriter(influxUrl, os.Getenv("INFLUX_ORG"), influxBucket, os.Getenv("INFLUX_TOKEN"))
+
+		snapshotHandlers = append(snapshotHandlers, leaderOnly(func(snapshot *Snapshot) {
+			if err := influx.Write(snapshot); err != nil {
+				fmt.Println(err)
+			}
+		}))
+	}
+
+	// Send gauges to a DogStatsD agent if a host is provided
+	if statsdHost := os.Getenv("DOGSTATSD_HOST"); statsdHost != "" {
+		prefix := os.Getenv("DOGSTATSD_PREFIX")
+		if prefix == "" {
+			prefix = "kube_resources"
+		}
+
+		labelTags := make([]string, 0)
+		for _, label := range strings.Split(os.Getenv("DOGSTATSD_LABEL_TAGS"), ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				labelTags = append(labelTags, label)
+			}
+		}
+
+		statsd, err := newStatsdEmitter(statsdHost, prefix, labelTags)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		snapshotHandlers = append(snapshotHandlers, leaderOnly(func(snapshot *Snapshot) {
+			if err := statsd.Emit(snapshot); err != nil {
+				fmt.Println(err)
+			}
+		}))
+	}
+
+	// Publish metrics to CloudWatch if a namespace is provided
+	if cloudWatchNamespace := os.Getenv("CLOUDWATCH_NAMESPACE"); cloudWatchNamespace != "" {
+		region := os.Getenv("CLOUDWATCH_REGION")
+		if region == "" {
+			region = os.Getenv("AWS_REGION") // Set by EKS on pods using IRSA
+		}
+
+		if region == "" {
+			fmt.Println("CLOUDWATCH_REGION or AWS_REGION must be set to publish to CloudWatch")
+			os.Exit(1)
+		}
+
+		cloudWatch, err := newCloudWatchPublisher(os.Getenv("CLOUDWATCH_ENDPOINT"), region, cloudWatchNamespace, os.Getenv("CLOUDWATCH_CLUSTER"))
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		snapshotHandlers = append(snapshotHandlers, leaderOnly(func(snapshot *Snapshot) {
+			if err := cloudWatch.Publish(snapshot); err != nil {
+				fmt.Println(err)
+			}
+		}))
+	}
+
+	// Push metrics to an OpenTelemetry collector if an OTLP endpoint is provided, using the standard variables
+	if os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		otlp, err := newOtlpExporter(context.Background())
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		snapshotHandlers = append(snapshotHandlers, leaderOnly(func(snapshot *Snapshot) {
+			if err := otlp.Export(snapshot); err != nil {
+				fmt.Println(err)
+			}
+		}))
+	}
+
+	// Publish snapshots to Kafka if brokers are provided
+	if kafkaBrokers := os.Getenv("KAFKA_BROKERS"); kafkaBrokers != "" {
+		topic := os.Getenv("KAFKA_TOPIC")
+		if topic == "" {
+			fmt.Println("KAFKA_TOPIC must be set to publish to Kafka")
+			os.Exit(1)
+		}
+
+		mode := os.Getenv("KAFKA_MODE")
+		if mode == "" {
+			mode = "snapshot"
+		}
+
+		client, err := newKafkaClient(strings.Split(kafkaBrokers, ","), topic, os.Getenv("KAFKA_TLS") == "true",
+			os.Getenv("KAFKA_SASL_MECHANISM"), os.Getenv("KAFKA_SASL_USERNAME"), os.Getenv("KAFKA_SASL_PASSWORD"))
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		kafka, err := newKafkaPublisher(client, mode)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		snapshotHandlers = append(snapshotHandlers, leaderOnly(func(snapshot *Snapshot) {
+			if err := kafka.Publish(snapshot); err != nil {
+				fmt.Println(err)
+			}
+		}))
+	}
+
+	// Publish node changes to NATS if a server is provided
+	if natsUrl := os.Getenv("NATS_URL"); natsUrl != "" {
+		prefix := os.Getenv("NATS_SUBJECT_PREFIX")
+		if prefix == "" {
+			prefix = "capacity.node"
+		}
+
+		conn, err := connectNats(natsUrl, os.Getenv("NATS_CREDS"), os.Getenv("NATS_NKEY"))
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		nats := newNatsPublisher(conn, prefix)
+
+		snapshotHandlers = append(snapshotHandlers, leaderOnly(func(snapshot *Snapshot) {
+			if err := nats.Publish(snapshot); err != nil {
+				fmt.Println(err)
+			}
+		}))
+	}
+
+	// Deliver capacity reports on a schedule if a file containing the schedules is provided
+	reportSchedulesPath := os.Getenv("REPORT_SCHEDULES")
+	if reportSchedulesPath != "" {
+		schedules, err := loadReportSchedules(reportSchedulesPath)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		// Send emailed reports through an SMTP server if one is provided
+		var smtpConfig *SmtpConfig
+		if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+			smtpConfig = &SmtpConfig{
+				Host:     smtpHost,
+				Port:     os.Getenv("SMTP_PORT"),
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+				From:     os.Getenv("SMTP_FROM"),
+			}
+
+			if smtpConfig.Port == "" {
+				smtpConfig.Port = "587" // Default submission port
+			}
+		}
+
+		scheduler, err := newReportScheduler(collector, schedules, smtpConfig)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		go scheduler.Run()
+	}
+
+	// Record the state of the cluster every snapshot, to be replayed later with --replay
+	if *record != "" {
+		recorder := newRecorder(clientset, *record)
+
+		snapshotHandlers = append(snapshotHandlers, func(*Snapshot) {
+			if err := recorder.Record(); err != nil {
+				fmt.Println(err)
+			}
+		})
+	}
+
+	// Only take periodic snapshots if something needs them
+	if len(snapshotHandlers) > 0 {
+		interval := getEnvDuration("SNAPSHOT_INTERVAL", 5*time.Minute)
+		go runSnapshotLoop(collector, interval, snapshotHandlers)
+	}
+
+	// Serve a validating admission webhook for pods over HTTPS on its own port, if a certificate for it is provided
+	if webhookCert := os.Getenv("WEBHOOK_CERT_FILE"); webhookCert != "" {
+		webhookKey := os.Getenv("WEBHOOK_KEY_FILE")
+		if webhookKey == "" {
+			fmt.Println("WEBHOOK_KEY_FILE must be set along with WEBHOOK_CERT_FILE")
+			os.Exit(1)
+		}
+
+		webhookPort := os.Getenv("WEBHOOK_PORT")
+		if webhookPort == "" {
+			webhookPort = "8443"
+		}
+
+		webhook := newCapacityWebhook(collector, getEnvDuration("WEBHOOK_SNAPSHOT_TTL", 30*time.Second))
+
+		webhookRouter := gin.New()
+		webhookRouter.Use(gin.LoggerWithFormatter(getLogFormatter(proxies)), gin.Recovery())
+		webhookRouter.POST("/validate-pods", getWebhookHandler(webhook))
+
+		go func() {
+			if err := newServer(":"+webhookPort, webhookRouter, serverLimits).ListenAndServeTLS(webhookCert, webhookKey); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}()
+	}
 
 	// Get port to run API on
 	port := os.Getenv("PORT")
@@ -107,18 +995,66 @@ func main() {
 		port = "8080" // Default port
 	}
 
-	router.Run(":" + port)
+	if err := newServer(":"+port, router, serverLimits).ListenAndServe(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// normalizeBasePath returns a route prefix with a leading slash and no trailing slash, or an empty string if the
+// prefix is empty or just a slash.
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(basePath, "/")
+	if basePath == "" {
+		return ""
+	}
+
+	return "/" + basePath
 }
 
-// getNodesHandler returns a HandlerFunc to return a list of nodes given a Kubernetes clientset.
-func getNodesHandler(client kubernetes.Interface) gin.HandlerFunc {
+// getEnvDuration returns the duration in the environment variable name, or def if it is unset or invalid.
+func getEnvDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil {
+		fmt.Printf("error parsing %v, using default of %v: %v\n", name, def, err)
+		return def
+	}
+
+	return duration
+}
+
+// getEnvFloat returns the number in the environment variable name, or def if it is unset or invalid.
+func getEnvFloat(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		fmt.Printf("error parsing %v, using default of %v: %v\n", name, def, err)
+		return def
+	}
+
+	return number
+}
+
+// Columns of the nodes in CSV and table output when none are asked for
+const nodeColumns = "name,pool,capacityType,free.cpu,free.memory,free.gpu,allocatable.cpu,allocatable.memory,allocatable.gpu"
+
+// getNodesHandler returns a HandlerFunc to return a list of nodes given a Collector.
+func getNodesHandler(collector *Collector) gin.HandlerFunc {
 	// Define a handler function to return
 	handler := func(c *gin.Context) {
-		// Create a map of string to Node struct instances
-		nodes := make(map[string]*Node)
-
-		// Get the node capacity, allocatable resources, name, and taints
-		err := getNodeInfo(client, nodes)
+		// Get the capacity, allocatable, and free resources of every node
+		snapshot, err := collector.Snapshot()
 
 		if err != nil {
 			fmt.Println(err)
@@ -126,25 +1062,41 @@ func getNodesHandler(client kubernetes.Interface) gin.HandlerFunc {
 			return
 		}
 
-		// Get the available resources of the nodes
-		err = getNodeFreeResources(client, nodes)
+		version, nodes := snapshot.Version, snapshot.Nodes
 
-		if err != nil {
-			fmt.Println(err)
-			c.JSON(http.StatusInternalServerError, "error retrieving available node resources")
-			return
-		}
+		// If the client is long-polling and already has this version, wait for the nodes to change
+		if c.Query("wait") != "" {
+			wait, since, ok := getLongPollParams(c)
+			if !ok {
+				return
+			}
+
+			if version == since {
+				version, nodes, ok, err = waitForNodes(c.Request.Context(), collector, since, wait)
 
-		// Create a slice to return the nodes instead of a map
-		nodeSlice := make([]NodeJson, 0, len(nodes))
+				if err != nil {
+					fmt.Println(err)
+					c.JSON(http.StatusInternalServerError, "error retrieving node information")
+					return
+				}
+
+				// Nothing changed before the wait ran out
+				if !ok {
+					c.Header(snapshotVersionHeader, strconv.FormatUint(since, 10))
+					c.Status(http.StatusNotModified)
+					return
+				}
+			}
+		}
 
-		// Loop through the nodes and convert each struct instance to JSON
-		for _, value := range nodes {
-			nodeSlice = append(nodeSlice, getNodeStructured(value))
+		// Send JSON node data as response, along with its version for fetching only what changed later
+		c.Header(snapshotVersionHeader, strconv.FormatUint(version, 10))
+		nodes, ok := applyNodeFilters(c, nodes)
+		if !ok {
+			return
 		}
 
-		// Send JSON node data as response
-		c.IndentedJSON(http.StatusOK, nodeSlice)
+		writeList(c, nodes, nodeColumns)
 	}
 
 	return gin.HandlerFunc(handler)
@@ -158,6 +1110,16 @@ func getNodeStructured(node *Node) NodeJson {
 	// Copy name field
 	nodeJson.Name = node.Name
 
+	// If the node has no labels, add an empty map - otherwise, copy the labels from the Node struct instance
+	if node.Labels == nil {
+		nodeJson.Labels = make(map[string]string)
+	} else {
+		nodeJson.Labels = node.Labels
+	}
+
+	// Find out whether the node is a spot instance from its labels
+	nodeJson.CapacityType = getCapacityType(node.Labels)
+
 	// If the node has no taints, add an empty slice - otherwise, copy the taints from the Node struct instance
 	if node.Taints == nil {
 		nodeJson.Taints = make([]corev1.Taint, 0)
@@ -165,33 +1127,30 @@ func getNodeStructured(node *Node) NodeJson {
 		nodeJson.Taints = node.Taints
 	}
 
-	// Copy the resource capacity fields and convert to numbers
-	nodeJson.Capacity = ResourcesJson{
-		Cpu:       node.Capacity.Cpu.AsApproximateFloat64(),
-		Memory:    node.Capacity.Memory.Value(),
-		Gpu:       node.Capacity.Gpu.Value(),
-		Ephemeral: node.Capacity.Ephemeral.Value(),
-	}
+	// Copy the resource capacity, allocatable, and free fields and convert to numbers
+	nodeJson.Capacity = getResourcesStructured(node.Capacity)
+	nodeJson.Allocatable = getResourcesStructured(node.Allocatable)
+	nodeJson.Free = getResourcesStructured(node.Free)
 
-	// Copy the resource allocatable fields and convert to numbers
-	nodeJson.Allocatable = ResourcesJson{
-		Cpu:       node.Allocatable.Cpu.AsApproximateFloat64(),
-		Memory:    node.Allocatable.Memory.Value(),
-		Gpu:       node.Allocatable.Gpu.Value(),
-		Ephemeral: node.Allocatable.Ephemeral.Value(),
-	}
-
-	// Copy the free resource fields and convert to numbers
-	nodeJson.Free = ResourcesJson{
-		Cpu:       node.Free.Cpu.AsApproximateFloat64(),
-		Memory:    node.Free.Memory.Value(),
-		Gpu:       node.Free.Gpu.Value(),
-		Ephemeral: node.Free.Ephemeral.Value(),
-	}
+	nodeJson.Os = node.Os
+	nodeJson.Created = node.Created
+	nodeJson.Images = node.Images
+	nodeJson.Reserved = NodeReserved{Total: subtractResources(nodeJson.Capacity, nodeJson.Allocatable)}
 
 	return nodeJson
 }
 
+// getResourcesStructured takes a Resources struct instance and returns a ResourcesJson struct instance
+// with each quantity converted to a number
+func getResourcesStructured(resources Resources) ResourcesJson {
+	return ResourcesJson{
+		Cpu:       resources.Cpu.AsApproximateFloat64(),
+		Memory:    resources.Memory.Value(),
+		Gpu:       resources.Gpu.Value(),
+		Ephemeral: resources.Ephemeral.Value(),
+	}
+}
+
 // getNodeInfo modifies a map of Node instances, adding entries with the node name as a key.
 // It gets the name of the node, its taints, capacity, and allocatable resources. These are added to the nodes map.
 func getNodeInfo(client kubernetes.Interface, nodes map[string]*Node) error {
@@ -205,20 +1164,27 @@ func getNodeInfo(client kubernetes.Interface, nodes map[string]*Node) error {
 	// Loop through the nodes
 	for _, node := range nodeList.Items {
 		// Get the GPU capacity of the node - default 0
-		gpuCapacity := node.Status.Capacity["nvidia.com/gpu"]
+		var gpuCapacity resource.Quantity
 
 		// Loop through the fields of the node capacity
 		for key, value := range node.Status.Capacity {
 			// If the node is a GPU node, set the gpuCapacity to its GPU count
-			if strings.HasPrefix(key.String(), "nvidia.com") && !value.IsZero() {
+			if isGpuResource(key.String()) && !value.IsZero() {
 				gpuCapacity = value
 			}
 		}
 
 		// Create a new Node with the correct resources -copy the Capacity and Allocatable values from the node status into a Node struct instance
 		newNode := Node{
-			Name:   node.Name,
-			Taints: node.Spec.Taints,
+			Name:      node.Name,
+			Labels:    node.Labels,
+			Taints:    node.Spec.Taints,
+			Pressure:  getPressureConditions(&node),
+			Ready:     getReadyCondition(&node),
+			Images:    getNodeImages(&node),
+			Os:        getNodeOs(&node),
+			Addresses: node.Status.Addresses,
+			Created:   node.CreationTimestamp.Time,
 			Capacity: Resources{
 				Cpu:       node.Status.Capacity.Cpu().DeepCopy(),
 				Memory:    node.Status.Capacity.Memory().DeepCopy(),
@@ -244,14 +1210,52 @@ func getNodeInfo(client kubernetes.Interface, nodes map[string]*Node) error {
 // of each resource for every pod in every node, subtracting them from the
 // Allocatable resourcs.
 func getNodeFreeResources(kubeClient kubernetes.Interface, nodes map[string]*Node) error {
-	// Get a list of every pod in the cluster that isn't terminated - uses Kubernetes clientset
-	// to find every pod with phase not PodSucceeded or PodFailed
-	nonTerminatedPods, err := kubeClient.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{FieldSelector: "status.phase!=" + string(corev1.PodSucceeded) + ",status.phase!=" + string(corev1.PodFailed)})
+	nonTerminatedPods, err := listNonTerminatedPods(kubeClient)
 
 	if err != nil {
 		return err
 	}
 
+	subtractPodRequests(nonTerminatedPods, nodes)
+
+	return nil
+}
+
+// Field selector matching pods that haven't finished running and so may still be holding resources
+const nonTerminatedPodSelector = "status.phase!=" + string(corev1.PodSucceeded) + ",status.phase!=" + string(corev1.PodFailed)
+
+// listNonTerminatedPods returns every pod in the cluster that isn't terminated - uses Kubernetes clientset
+// to find every pod with phase not PodSucceeded or PodFailed
+func listNonTerminatedPods(kubeClient kubernetes.Interface) ([]corev1.Pod, error) {
+	podList, err := kubeClient.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{FieldSelector: nonTerminatedPodSelector})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return podList.Items, nil
+}
+
+// getAccountingNode returns the name of the node whose free resources a pod's requests are subtracted from: the node
+// it is scheduled on, or else the node it was nominated for while the pods it is preempting there terminate, so that
+// a node isn't shown as free while it is being preempted for another pod. Pods held back by scheduling gates are
+// pending demand rather than using any node, so an empty string is returned for them.
+func getAccountingNode(pod *corev1.Pod) string {
+	if pod.Spec.NodeName != "" {
+		return pod.Spec.NodeName
+	}
+
+	if len(pod.Spec.SchedulingGates) > 0 {
+		return ""
+	}
+
+	return pod.Status.NominatedNodeName
+}
+
+// subtractPodRequests sets the Free resources of each node in a map of Node instances to its Allocatable
+// resources minus the requests of every pod in pods that is scheduled on or nominated for it, other than pods in
+// namespaces that aren't counted.
+func subtractPodRequests(pods []corev1.Pod, nodes map[string]*Node) {
 	// For each node, copy the allocatable resources into the free resources to be subtracted from
 	// Once all resources have been subtracted, what is left over will be the free resources
 	for _, node := range nodes {
@@ -264,39 +1268,80 @@ func getNodeFreeResources(kubeClient kubernetes.Interface, nodes map[string]*Nod
 	}
 
 	// Loop through every pod in cluster
-	for _, pod := range nonTerminatedPods.Items {
-		// Only get pod requests if the nodes map has an entry for the node
-		if _, ok := nodes[pod.Spec.NodeName]; !ok {
+	for i := range pods {
+		pod := &pods[i]
+
+		// Only get pod requests if the nodes map has an entry for the node, and the pod's namespace is counted
+		node, ok := nodes[getAccountingNode(pod)]
+		if !ok || !isCountedNamespace(pod.Namespace) {
 			continue
 		}
 
 		// Get the requests and limits for the pod
-		podReqs, _ := resourcehelper.PodRequestsAndLimits(&pod)
+		podReqs, _ := getPodRequestsAndLimits(pod)
 
 		// Get the relevant resource requests from the pod
-		cpuReq := podReqs[corev1.ResourceCPU]
-		memReq := podReqs[corev1.ResourceMemory]
+		requests := getResourcesFromList(podReqs)
 
-		// Get the GPU capacity of the node - default 0
-		gpuReq := podReqs["nvidia.com/gpu"]
+		// Subtract each value from the current Free resources in the Node struct instance
+		node.Free.Cpu.Sub(requests.Cpu)
+		node.Free.Memory.Sub(requests.Memory)
+		node.Free.Gpu.Sub(requests.Gpu)
+		node.Free.Ephemeral.Sub(requests.Ephemeral)
+	}
+}
 
-		// Loop through the fields of the podReqs
-		for key, value := range podReqs {
-			// If the node is a GPU node, set the gpuCapacity to its GPU count
-			if strings.HasPrefix(key.String(), "nvidia.com") && !value.IsZero() {
-				gpuReq = value
-			}
+// getResourcesFromList takes a ResourceList, such as the requests of a pod, and returns the resource
+// types we care about in a Resources struct instance
+func getResourcesFromList(list corev1.ResourceList) Resources {
+	// Get the GPU count - default 0
+	var gpu resource.Quantity
+
+	// Loop through the fields of the list
+	for key, value := range list {
+		// If there are GPUs of any configured resource type, use that count instead
+		if isGpuResource(key.String()) && !value.IsZero() {
+			gpu = value
 		}
+	}
 
-		// Get ephemeral storage from the pod requests
-		ephemeralReq := podReqs[corev1.ResourceEphemeralStorage]
+	return Resources{
+		Cpu:       list.Cpu().DeepCopy(),
+		Memory:    list.Memory().DeepCopy(),
+		Gpu:       gpu.DeepCopy(),
+		Ephemeral: list.StorageEphemeral().DeepCopy(),
+	}
+}
 
-		// Subtract each value from the current Free resources in the Node struct instance
-		nodes[pod.Spec.NodeName].Free.Cpu.Sub(cpuReq)
-		nodes[pod.Spec.NodeName].Free.Memory.Sub(memReq)
-		nodes[pod.Spec.NodeName].Free.Gpu.Sub(gpuReq)
-		nodes[pod.Spec.NodeName].Free.Ephemeral.Sub(ephemeralReq)
+// Names of the resources in a ResourcesJson struct instance, as used in query parameters and configuration
+var resourceNames = []string{"cpu", "memory", "gpu", "ephemeral"}
+
+// getResource returns the field of resources with the given name as a float.
+func getResource(resources ResourcesJson, name string) float64 {
+	switch name {
+	case "cpu":
+		return resources.Cpu
+	case "memory":
+		return float64(resources.Memory)
+	case "gpu":
+		return float64(resources.Gpu)
+	case "ephemeral":
+		return float64(resources.Ephemeral)
 	}
 
-	return nil
+	return 0
+}
+
+// setResource sets the field of resources with the given name to value.
+func setResource(resources *ResourcesJson, name string, value float64) {
+	switch name {
+	case "cpu":
+		resources.Cpu = value
+	case "memory":
+		resources.Memory = int64(value)
+	case "gpu":
+		resources.Gpu = int64(value)
+	case "ephemeral":
+		resources.Ephemeral = int64(value)
+	}
 }
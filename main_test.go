@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	v1 "k8s.io/api/core/v1"
@@ -403,3 +404,96 @@ func TestGetNodeFreeResources(t *testing.T) {
 		t.Fatalf(`nodes[%v].Free.Gpu = %v, want match for %v`, "node-2", &nodes["node-2"].Free.Gpu, resource.NewQuantity(1, resource.DecimalSI).Value())
 	}
 }
+
+// TestSubtractPodRequestsNominated checks that a pod nominated for a node while it preempts other pods uses up that
+// node's resources, and that pods held back by scheduling gates or not yet nominated don't use up any node's.
+func TestSubtractPodRequestsNominated(t *testing.T) {
+	nodes := map[string]*Node{
+		"node-1": {Name: "node-1", Allocatable: Resources{Cpu: resource.MustParse("8")}},
+		"node-2": {Name: "node-2", Allocatable: Resources{Cpu: resource.MustParse("8")}},
+	}
+
+	pod := func(name, node, nominated string, gated bool) v1.Pod {
+		pod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PodSpec{
+				NodeName:   node,
+				Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}}}},
+			},
+			Status: v1.PodStatus{NominatedNodeName: nominated},
+		}
+
+		if gated {
+			pod.Spec.SchedulingGates = []v1.PodSchedulingGate{{Name: "example.com/quota"}}
+		}
+
+		return pod
+	}
+
+	pods := []v1.Pod{
+		pod("running", "node-1", "", false),
+		pod("preempting", "", "node-2", false),
+		pod("gated", "", "node-2", true),
+		pod("pending", "", "", false),
+	}
+
+	subtractPodRequests(pods, nodes)
+
+	switch {
+	case !nodes["node-1"].Free.Cpu.Equal(resource.MustParse("6")):
+		t.Fatalf(`nodes[%v].Free.Cpu = %v, want match for %v`, "node-1", &nodes["node-1"].Free.Cpu, 6)
+	case !nodes["node-2"].Free.Cpu.Equal(resource.MustParse("6")):
+		t.Fatalf(`nodes[%v].Free.Cpu = %v, want match for %v`, "node-2", &nodes["node-2"].Free.Cpu, 6)
+	}
+
+	podJson := getPodStructured(&pods[2])
+
+	if podJson.NominatedNode != "node-2" || len(podJson.SchedulingGates) != 1 || podJson.SchedulingGates[0] != "example.com/quota" {
+		t.Fatalf(`getPodStructured() = %v, want nominated for node-2 and gated by example.com/quota`, podJson)
+	}
+}
+
+// TestNormalizeBasePath checks that route prefixes are given exactly one leading slash and no trailing slash.
+func TestNormalizeBasePath(t *testing.T) {
+	for basePath, want := range map[string]string{"": "", "/": "", "capacity": "/capacity", "/capacity/": "/capacity", "/a/b": "/a/b"} {
+		if got := normalizeBasePath(basePath); got != want {
+			t.Fatalf(`normalizeBasePath(%q) = %v, want match for %v`, basePath, got, want)
+		}
+	}
+}
+
+// TestNodeAddresses takes snapshots of a node with addresses with and without --node-addresses, checking that the
+// addresses are only included when asked for.
+func TestNodeAddresses(t *testing.T) {
+	addresses := []v1.NodeAddress{
+		{Type: v1.NodeInternalIP, Address: "10.0.0.7"},
+		{Type: v1.NodeHostName, Address: "node-7"},
+	}
+	client := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-7"},
+		Status:     v1.NodeStatus{Addresses: addresses},
+	})
+
+	collector := newCollector(client)
+	snapshot, err := collector.Snapshot()
+
+	if err != nil {
+		t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+	}
+
+	if snapshot.Nodes[0].Addresses != nil {
+		t.Fatalf(`addresses without --node-addresses = %v, want match for nil`, snapshot.Nodes[0].Addresses)
+	}
+
+	collector = newCollector(client)
+	collector.nodeAddresses = true
+	snapshot, err = collector.Snapshot()
+
+	if err != nil {
+		t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+	}
+
+	if !reflect.DeepEqual(snapshot.Nodes[0].Addresses, addresses) {
+		t.Fatalf(`addresses = %v, want match for %v`, snapshot.Nodes[0].Addresses, addresses)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NamespaceUsage contains the summed requests and limits of the pods in a namespace
+type NamespaceUsage struct {
+	Namespace string        `json:"namespace"`
+	Pods      int           `json:"pods"`
+	Requests  ResourcesJson `json:"requests"`
+	Limits    ResourcesJson `json:"limits"`
+}
+
+// getNamespaceUsage sums the requests and limits of a list of pods by namespace.
+func getNamespaceUsage(pods []PodJson) []NamespaceUsage {
+	usage := make(map[string]*NamespaceUsage)
+
+	for _, pod := range pods {
+		if _, ok := usage[pod.Namespace]; !ok {
+			usage[pod.Namespace] = &NamespaceUsage{Namespace: pod.Namespace}
+		}
+
+		usage[pod.Namespace].Pods++
+		usage[pod.Namespace].Requests = addResources(usage[pod.Namespace].Requests, pod.Requests)
+		usage[pod.Namespace].Limits = addResources(usage[pod.Namespace].Limits, pod.Limits)
+	}
+
+	result := make([]NamespaceUsage, 0, len(usage))
+	for _, namespace := range usage {
+		result = append(result, *namespace)
+	}
+
+	return result
+}
+
+// getTopNamespaces returns the limit namespaces with the highest summed requests of resource, largest first.
+func getTopNamespaces(pods []PodJson, resource string, limit int) []NamespaceUsage {
+	usage := getNamespaceUsage(pods)
+
+	// Sort by requests, breaking ties by name so the order is stable
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := getResource(usage[i].Requests, resource), getResource(usage[j].Requests, resource)
+		if a != b {
+			return a > b
+		}
+		return usage[i].Namespace < usage[j].Namespace
+	})
+
+	if len(usage) > limit {
+		usage = usage[:limit]
+	}
+
+	return usage
+}
+
+// getTopNamespacesHandler returns a HandlerFunc to return the namespaces with the highest requests given a
+// Collector. The resource to sort by is given by the by query parameter (default cpu), and the
+// number of namespaces by the limit parameter (default 10).
+func getTopNamespacesHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		by, limit, ok := getTopParams(c)
+		if !ok {
+			return
+		}
+
+		pods, err := collector.Pods()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getTopNamespaces(pods, by, limit))
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getTopParams parses the by and limit query parameters of a request for a top-N list. by defaults to cpu
+// and limit defaults to 10. If either is invalid, an error is sent as the response and ok is false.
+func getTopParams(c *gin.Context) (by string, limit int, ok bool) {
+	by = c.DefaultQuery("by", "cpu")
+
+	if !slices.Contains(resourceNames, by) {
+		c.JSON(http.StatusBadRequest, "error: by must be one of cpu, memory, gpu, or ephemeral")
+		return by, limit, false
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, "error: limit must be a positive integer")
+		return by, limit, false
+	}
+
+	return by, limit, true
+}
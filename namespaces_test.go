@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+// TestGetTopNamespaces sums pod requests by namespace, checking that namespaces are sorted by the chosen resource
+// and cut off at the limit.
+func TestGetTopNamespaces(t *testing.T) {
+	pods := []PodJson{
+		{Name: "a", Namespace: "ml", Requests: ResourcesJson{Cpu: 1, Gpu: 2}, Limits: ResourcesJson{Cpu: 2, Gpu: 2}},
+		{Name: "b", Namespace: "ml", Requests: ResourcesJson{Cpu: 1, Gpu: 1}, Limits: ResourcesJson{Cpu: 2, Gpu: 1}},
+		{Name: "c", Namespace: "web", Requests: ResourcesJson{Cpu: 4}},
+		{Name: "d", Namespace: "batch", Requests: ResourcesJson{Cpu: 3}},
+	}
+
+	top := getTopNamespaces(pods, "cpu", 2)
+
+	switch {
+	case len(top) != 2:
+		t.Fatalf(`len(top) = %v, want match for %v`, len(top), 2)
+	case top[0].Namespace != "web" || top[1].Namespace != "batch":
+		t.Fatalf(`top = %v, %v, want match for %v, %v`, top[0].Namespace, top[1].Namespace, "web", "batch")
+	}
+
+	top = getTopNamespaces(pods, "gpu", 10)
+
+	switch {
+	case len(top) != 3:
+		t.Fatalf(`len(top) = %v, want match for %v`, len(top), 3)
+	case top[0].Namespace != "ml":
+		t.Fatalf(`top[0].Namespace = %v, want match for %v`, top[0].Namespace, "ml")
+	case top[0].Pods != 2 || top[0].Requests.Gpu != 3 || top[0].Limits.Cpu != 4:
+		t.Fatalf(`top[0] = %v, want 2 pods, 3 requested GPUs, and 4 CPU limit`, top[0])
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// fakeNatsConn records the subject and payload of every message it is sent
+type fakeNatsConn struct {
+	published [][2]string
+}
+
+func (f *fakeNatsConn) Publish(subject string, data []byte) error {
+	f.published = append(f.published, [2]string{subject, string(data)})
+	return nil
+}
+
+// TestNatsPublisher publishes three snapshots, checking that nothing is published for the first and that a GPU
+// change is then published to the node's subject.
+func TestNatsPublisher(t *testing.T) {
+	conn := &fakeNatsConn{}
+	publisher := newNatsPublisher(conn, "capacity.node")
+
+	before := []NodeJson{{Name: "node-1", Allocatable: ResourcesJson{Gpu: 4}, Capacity: ResourcesJson{Gpu: 4}}}
+	after := []NodeJson{{Name: "node-1", Allocatable: ResourcesJson{Gpu: 3}, Capacity: ResourcesJson{Gpu: 4}}}
+
+	for _, snapshot := range []*Snapshot{
+		{Time: time.Unix(1714564800, 0), Nodes: before},
+		{Time: time.Unix(1714565100, 0), Nodes: before},
+		{Time: time.Unix(1714565400, 0), Nodes: after},
+	} {
+		if err := publisher.Publish(snapshot); err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+	}
+
+	if len(conn.published) != 1 {
+		t.Fatalf(`len(published) = %v, want match for %v`, len(conn.published), 1)
+	}
+
+	var change CapacityChange
+	json.Unmarshal([]byte(conn.published[0][1]), &change)
+
+	switch {
+	case conn.published[0][0] != "capacity.node.node-1":
+		t.Fatalf(`subject = %v, want match for %v`, conn.published[0][0], "capacity.node.node-1")
+	case change.Type != changeGpus || change.After.Allocatable.Gpu != 3:
+		t.Fatalf(`change = %v, want match for %v`, change, "3 allocatable GPUs")
+	}
+}
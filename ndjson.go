@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Content type of newline-delimited JSON, with one object per line
+const ndjsonContentType = "application/x-ndjson"
+
+// Content type of CSV, with one row per item
+const csvContentType = "text/csv"
+
+// writeList sends a list of items as the response. If the client accepts newline-delimited JSON, the items are
+// streamed one per line so that large lists can be processed as they arrive. With ?format=csv or an Accept header
+// of text/csv, or with ?format=table, the items are sent as CSV or as an aligned plain-text table with the columns
+// given by the columns query parameter, or defaultColumns if there is none. Otherwise, they are sent as an indented
+// JSON array.
+func writeList[T any](c *gin.Context, items []T, defaultColumns string) {
+	format := c.Query("format")
+	if format == "" {
+		switch accept := c.GetHeader("Accept"); {
+		case strings.Contains(accept, ndjsonContentType):
+			format = "ndjson"
+		case strings.Contains(accept, csvContentType):
+			format = "csv"
+		}
+	}
+
+	switch format {
+	case "", "json":
+		c.IndentedJSON(http.StatusOK, items)
+	case "ndjson":
+		writeNdjson(c, items)
+	case "csv", "table":
+		columns, err := parseColumns(c.DefaultQuery("columns", defaultColumns))
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "error: invalid columns: "+err.Error())
+			return
+		}
+
+		rows := make([][]string, 0, len(items))
+		for _, item := range items {
+			values, err := getColumnValues(item)
+
+			if err != nil {
+				fmt.Println(err)
+				c.JSON(http.StatusInternalServerError, "error encoding list")
+				return
+			}
+
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				row[i] = column.Evaluate(values)
+			}
+
+			rows = append(rows, row)
+		}
+
+		writeRows(c, format, columns, rows)
+	default:
+		c.JSON(http.StatusBadRequest, "error: format must be json, ndjson, csv, or table")
+	}
+}
+
+// writeNdjson streams items as newline-delimited JSON, one item per line.
+func writeNdjson[T any](c *gin.Context, items []T) {
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+
+	for _, item := range items {
+		// The status has already been sent, so all that can be done is stop
+		if err := encoder.Encode(item); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+// writeRows sends rows as CSV or as a plain-text table, with the text of each column as its header.
+func writeRows(c *gin.Context, format string, columns []ColumnPath, rows [][]string) {
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Text
+	}
+
+	if format == "csv" {
+		c.Header("Content-Type", csvContentType)
+		c.Status(http.StatusOK)
+
+		writer := csv.NewWriter(c.Writer)
+		writer.Write(header)
+		writer.WriteAll(rows)
+
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	writer := tabwriter.NewWriter(c.Writer, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	writer.Flush()
+}
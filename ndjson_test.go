@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestWriteList sends a list with and without an Accept header for newline-delimited JSON, checking the content
+// type and body of each.
+func TestWriteList(t *testing.T) {
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		writeList(c, []OwnerJson{{Kind: "Deployment", Name: "web"}, {Kind: "Job", Name: "train"}}, "kind,name")
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept", "application/x-ndjson")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	want := "{\"kind\":\"Deployment\",\"name\":\"web\"}\n{\"kind\":\"Job\",\"name\":\"train\"}\n"
+
+	if recorder.Header().Get("Content-Type") != "application/x-ndjson" || recorder.Body.String() != want {
+		t.Fatalf(`writeList() with NDJSON = %q, %q, want match for %q, %q`, recorder.Header().Get("Content-Type"), recorder.Body.String(), "application/x-ndjson", want)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Header().Get("Content-Type") != "application/json; charset=utf-8" || recorder.Body.String()[0] != '[' {
+		t.Fatalf(`writeList() without NDJSON = %q, %q, want a JSON array`, recorder.Header().Get("Content-Type"), recorder.Body.String())
+	}
+}
+
+// TestWriteListColumns sends a list as CSV and as a table with the columns given in the request, and with invalid
+// columns.
+func TestWriteListColumns(t *testing.T) {
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		writeList(c, []OwnerJson{{Kind: "Deployment", Name: "web"}, {Kind: "Job", Name: "train"}}, "kind,name")
+	})
+
+	for _, test := range []struct {
+		target string
+		status int
+		want   string
+	}{
+		{"/?format=csv", http.StatusOK, "kind,name\nDeployment,web\nJob,train\n"},
+		{"/?format=csv&columns=name,missing", http.StatusOK, "name,missing\nweb,\ntrain,\n"},
+		{"/?format=table&columns=name", http.StatusOK, "name\nweb\ntrain\n"},
+		{"/?format=csv&columns=name[", http.StatusBadRequest, ""},
+		{"/?format=xml", http.StatusBadRequest, ""},
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.target, nil))
+
+		switch {
+		case recorder.Code != test.status:
+			t.Fatalf(`writeList() for %v status = %v, want match for %v`, test.target, recorder.Code, test.status)
+		case test.status == http.StatusOK && recorder.Body.String() != test.want:
+			t.Fatalf(`writeList() for %v = %q, want match for %q`, test.target, recorder.Body.String(), test.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setNodeAges sets the seconds since each node was created at now. Nodes without a creation time, which only fake
+// clients return, are left at 0.
+func setNodeAges(nodes []NodeJson, now time.Time) {
+	for i := range nodes {
+		if nodes[i].Created.IsZero() {
+			continue
+		}
+
+		nodes[i].Age = math.Round(max(now.Sub(nodes[i].Created).Seconds(), 0))
+	}
+}
+
+// filterNodesByAge returns the nodes that are at least minAge old.
+func filterNodesByAge(nodes []NodeJson, minAge time.Duration) []NodeJson {
+	filtered := make([]NodeJson, 0)
+
+	for _, node := range nodes {
+		if node.Age >= minAge.Seconds() {
+			filtered = append(filtered, node)
+		}
+	}
+
+	return filtered
+}
+
+// applyNodeFilters returns the nodes running the operating system given by the os query parameter that are at least
+// as old as the minAge parameter, ignoring either if it isn't given. Nodes that have been up a while are less likely
+// to be spot nodes about to be reclaimed, so their capacity is more stable. If the excludeDaemonSets parameter is
+// true, the requests of DaemonSet pods aren't subtracted from the free resources of the nodes. If minAge isn't a
+// valid duration, a 400 response is sent and false is returned.
+func applyNodeFilters(c *gin.Context, nodes []NodeJson) ([]NodeJson, bool) {
+	nodes = filterNodesByOs(nodes, c.Query("os"))
+
+	if c.Query("excludeDaemonSets") == "true" {
+		nodes = excludeDaemonSetPods(nodes)
+	}
+
+	if c.Query("minAge") == "" {
+		return nodes, true
+	}
+
+	minAge, err := time.ParseDuration(c.Query("minAge"))
+
+	if err != nil || minAge < 0 {
+		c.JSON(http.StatusBadRequest, "error: minAge must be a duration such as 24h")
+		return nil, false
+	}
+
+	return filterNodesByAge(nodes, minAge), true
+}
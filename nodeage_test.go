@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestNodeAgeFilter lists the nodes of a cluster with a node created days ago and one created minutes ago, checking
+// their ages and that minAge leaves out the young one.
+func TestNodeAgeFilter(t *testing.T) {
+	now := time.Now()
+	node := func(name string, created time.Time) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)}}
+	}
+
+	router := gin.New()
+	router.GET("/nodes", getNodesHandler(newCollector(fake.NewSimpleClientset(
+		node("stable", now.Add(-72*time.Hour)),
+		node("new", now.Add(-10*time.Minute)),
+	))))
+
+	for _, test := range []struct {
+		query string
+		code  int
+		nodes []string
+	}{
+		{"", http.StatusOK, []string{"new", "stable"}},
+		{"?minAge=24h", http.StatusOK, []string{"stable"}},
+		{"?minAge=5m", http.StatusOK, []string{"new", "stable"}},
+		{"?minAge=3d", http.StatusBadRequest, nil},
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/nodes"+test.query, nil))
+
+		if recorder.Code != test.code {
+			t.Fatalf(`GET /nodes%v = %v, want match for %v`, test.query, recorder.Code, test.code)
+		}
+
+		if test.code != http.StatusOK {
+			continue
+		}
+
+		var nodes []NodeJson
+		json.Unmarshal(recorder.Body.Bytes(), &nodes)
+
+		names := make(map[string]NodeJson)
+		for _, node := range nodes {
+			names[node.Name] = node
+		}
+
+		if len(nodes) != len(test.nodes) {
+			t.Fatalf(`GET /nodes%v = %v nodes, want match for %v`, test.query, len(nodes), test.nodes)
+		}
+
+		for _, name := range test.nodes {
+			if _, ok := names[name]; !ok {
+				t.Fatalf(`GET /nodes%v is missing %v, want match for %v`, test.query, name, test.nodes)
+			}
+		}
+
+		if stable, ok := names["stable"]; ok && (stable.Age < 72*3600-5 || stable.Age > 72*3600+5) {
+			t.Fatalf(`stable age = %v, want about %v`, stable.Age, 72*3600)
+		}
+	}
+}
+
+// TestSetNodeAgesWithoutCreation checks that nodes without a creation time are left at age 0.
+func TestSetNodeAgesWithoutCreation(t *testing.T) {
+	nodes := []NodeJson{{Name: "fake"}}
+	setNodeAges(nodes, time.Now())
+
+	if nodes[0].Age != 0 {
+		t.Fatalf(`age without a creation time = %v, want match for 0`, nodes[0].Age)
+	}
+}
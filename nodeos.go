@@ -0,0 +1,56 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Well-known label set by the kubelet to the operating system of its node
+const osLabel = "kubernetes.io/os"
+
+// Operating systems of nodes, with unknown for nodes that don't say which they run
+const (
+	windowsOs = "windows"
+	unknownOs = "unknown"
+)
+
+// getNodeOs returns the operating system of a node, such as linux or windows, from its well-known label, or from
+// what its kubelet reports if the label was removed.
+func getNodeOs(node *corev1.Node) string {
+	if os := node.Labels[osLabel]; os != "" {
+		return os
+	}
+
+	if os := node.Status.NodeInfo.OperatingSystem; os != "" {
+		return os
+	}
+
+	return unknownOs
+}
+
+// filterNodesByOs returns the nodes running the given operating system, or every node if os is empty.
+func filterNodesByOs(nodes []NodeJson, os string) []NodeJson {
+	if os == "" {
+		return nodes
+	}
+
+	filtered := make([]NodeJson, 0)
+
+	for _, node := range nodes {
+		if node.Os == os {
+			filtered = append(filtered, node)
+		}
+	}
+
+	return filtered
+}
+
+// getTotalsByOs sums a list of nodes separately for each operating system.
+func getTotalsByOs(nodes []NodeJson) map[string]ResourceTotals {
+	totals := make(map[string]ResourceTotals)
+
+	for _, node := range nodes {
+		totals[node.Os] = addToTotals(totals[node.Os], node)
+	}
+
+	return totals
+}
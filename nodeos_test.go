@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestGetNodeOs checks that the operating system label is preferred, with what the kubelet reports as a fallback.
+func TestGetNodeOs(t *testing.T) {
+	tests := []struct {
+		name string
+		node corev1.Node
+		want string
+	}{
+		{"label", corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{osLabel: "windows"}}}, "windows"},
+		{"node info", corev1.Node{Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OperatingSystem: "linux"}}}, "linux"},
+		{"neither", corev1.Node{}, unknownOs},
+	}
+
+	for _, test := range tests {
+		if os := getNodeOs(&test.node); os != test.want {
+			t.Fatalf(`%v: getNodeOs() = %v, want match for %v`, test.name, os, test.want)
+		}
+	}
+}
+
+// TestFilterNodesByOs filters a mixed cluster to its Windows nodes, and sums each operating system separately.
+func TestFilterNodesByOs(t *testing.T) {
+	nodes := []NodeJson{
+		{Name: "linux-1", Os: "linux", Allocatable: ResourcesJson{Cpu: 8}},
+		{Name: "windows-1", Os: windowsOs, Allocatable: ResourcesJson{Cpu: 4}},
+		{Name: "windows-2", Os: windowsOs, Allocatable: ResourcesJson{Cpu: 4}},
+	}
+
+	if filtered := filterNodesByOs(nodes, ""); len(filtered) != 3 {
+		t.Fatalf(`filterNodesByOs() without an os = %v, want every node`, filtered)
+	}
+
+	filtered := filterNodesByOs(nodes, windowsOs)
+	if len(filtered) != 2 || filtered[0].Name != "windows-1" || filtered[1].Name != "windows-2" {
+		t.Fatalf(`filterNodesByOs(windows) = %v, want match for windows-1 and windows-2`, filtered)
+	}
+
+	totals := getTotalsByOs(nodes)
+	if len(totals) != 2 || totals[windowsOs].Allocatable.Cpu != 8 || totals["linux"].Allocatable.Cpu != 8 {
+		t.Fatalf(`getTotalsByOs() = %v, want 8 CPUs each for linux and windows`, totals)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Labels used by common autoscalers and cloud providers to name the pool a node belongs to, in order of preference
+var nodePoolLabels = []string{
+	"karpenter.sh/nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"cloud.google.com/gke-nodepool",
+	"kubernetes.azure.com/agentpool",
+	"agentpool",
+}
+
+// Name of the pool for nodes that don't have any pool label
+const unassignedPool = "unassigned"
+
+// ResourceTotals contains the summed resources of a group of nodes
+type ResourceTotals struct {
+	Nodes       int           `json:"nodes"`
+	Allocatable ResourcesJson `json:"allocatable"`
+	Capacity    ResourcesJson `json:"capacity"`
+	Free        ResourcesJson `json:"free"`
+}
+
+// NodePool contains the summed resources of the nodes in a pool, broken down by capacity type
+type NodePool struct {
+	Name           string                    `json:"name"`
+	ResourceTotals                           // Embedded so the totals appear at the top level of the JSON object
+	ByCapacityType map[string]ResourceTotals `json:"byCapacityType"`
+}
+
+// getCapacityType returns "spot" or "on-demand" depending on the well-known capacity type labels used by
+// Karpenter, EKS, GKE, and AKS, or "unknown" if the node has none of them.
+func getCapacityType(labels map[string]string) string {
+	if value, ok := labels["karpenter.sh/capacity-type"]; ok {
+		return strings.ToLower(value)
+	}
+
+	if value, ok := labels["eks.amazonaws.com/capacityType"]; ok {
+		if value == "SPOT" {
+			return "spot"
+		}
+		return "on-demand"
+	}
+
+	if labels["cloud.google.com/gke-spot"] == "true" || labels["cloud.google.com/gke-preemptible"] == "true" {
+		return "spot"
+	}
+
+	if value, ok := labels["kubernetes.azure.com/scalesetpriority"]; ok && value == "spot" {
+		return "spot"
+	}
+
+	return "unknown"
+}
+
+// getNodePool returns the value of poolLabel on the node. If poolLabel is empty, the first well-known
+// pool label the node has is used instead.
+func getNodePool(labels map[string]string, poolLabel string) string {
+	if poolLabel != "" {
+		if value, ok := labels[poolLabel]; ok {
+			return value
+		}
+		return unassignedPool
+	}
+
+	for _, label := range nodePoolLabels {
+		if value, ok := labels[label]; ok {
+			return value
+		}
+	}
+
+	return unassignedPool
+}
+
+// addToTotals adds the resources of a node to a ResourceTotals struct instance.
+func addToTotals(totals ResourceTotals, node NodeJson) ResourceTotals {
+	return ResourceTotals{
+		Nodes:       totals.Nodes + 1,
+		Allocatable: addResources(totals.Allocatable, node.Allocatable),
+		Capacity:    addResources(totals.Capacity, node.Capacity),
+		Free:        addResources(totals.Free, node.Free),
+	}
+}
+
+// getTotalsByCapacityType sums a list of nodes separately for each capacity type.
+func getTotalsByCapacityType(nodes []NodeJson) map[string]ResourceTotals {
+	totals := make(map[string]ResourceTotals)
+
+	for _, node := range nodes {
+		totals[node.CapacityType] = addToTotals(totals[node.CapacityType], node)
+	}
+
+	return totals
+}
+
+// Key of the nodes without taints in totals by taint effect
+const untaintedEffect = "untainted"
+
+// getStrictestTaintEffect returns the effect of a node's taint that keeps the most pods off it, or untaintedEffect
+// if it has no taints.
+func getStrictestTaintEffect(node NodeJson) string {
+	strictest := untaintedEffect
+
+	for _, taint := range node.Taints {
+		switch {
+		case taint.Effect == corev1.TaintEffectNoExecute:
+			return string(corev1.TaintEffectNoExecute)
+		case taint.Effect == corev1.TaintEffectNoSchedule:
+			strictest = string(corev1.TaintEffectNoSchedule)
+		case taint.Effect == corev1.TaintEffectPreferNoSchedule && strictest == untaintedEffect:
+			strictest = string(corev1.TaintEffectPreferNoSchedule)
+		}
+	}
+
+	return strictest
+}
+
+// getTotalsByTaintEffect sums a list of nodes separately for the strictest effect of each node's taints, since free
+// capacity behind a NoSchedule or NoExecute taint is only usable by pods that tolerate it.
+func getTotalsByTaintEffect(nodes []NodeJson) map[string]ResourceTotals {
+	totals := make(map[string]ResourceTotals)
+
+	for _, node := range nodes {
+		effect := getStrictestTaintEffect(node)
+		totals[effect] = addToTotals(totals[effect], node)
+	}
+
+	return totals
+}
+
+// getNodePools groups a list of nodes by pool, sorted by pool name.
+func getNodePools(nodes []NodeJson) []NodePool {
+	members := make(map[string][]NodeJson)
+
+	for _, node := range nodes {
+		members[node.Pool] = append(members[node.Pool], node)
+	}
+
+	pools := make([]NodePool, 0, len(members))
+
+	for name, poolNodes := range members {
+		pool := NodePool{
+			Name:           name,
+			ByCapacityType: getTotalsByCapacityType(poolNodes),
+		}
+
+		for _, node := range poolNodes {
+			pool.ResourceTotals = addToTotals(pool.ResourceTotals, node)
+		}
+
+		pools = append(pools, pool)
+	}
+
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+
+	return pools
+}
+
+// getNodePoolsHandler returns a HandlerFunc to return the resources of each node pool given a Collector.
+func getNodePoolsHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getNodePools(snapshot.Nodes))
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+// TestGetCapacityType checks that spot and on-demand nodes are detected from each provider's labels.
+func TestGetCapacityType(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		want   string
+	}{
+		{map[string]string{"karpenter.sh/capacity-type": "spot"}, "spot"},
+		{map[string]string{"karpenter.sh/capacity-type": "on-demand"}, "on-demand"},
+		{map[string]string{"eks.amazonaws.com/capacityType": "SPOT"}, "spot"},
+		{map[string]string{"eks.amazonaws.com/capacityType": "ON_DEMAND"}, "on-demand"},
+		{map[string]string{"cloud.google.com/gke-spot": "true"}, "spot"},
+		{map[string]string{"kubernetes.azure.com/scalesetpriority": "spot"}, "spot"},
+		{map[string]string{"kubernetes.io/hostname": "node-1"}, "unknown"},
+		{nil, "unknown"},
+	}
+
+	for _, test := range tests {
+		if have := getCapacityType(test.labels); have != test.want {
+			t.Fatalf(`getCapacityType(%v) = %v, want match for %v`, test.labels, have, test.want)
+		}
+	}
+}
+
+// TestGetNodePools groups nodes into pools, checking the pool names, their totals, and the capacity type breakdown.
+func TestGetNodePools(t *testing.T) {
+	if pool := getNodePool(map[string]string{"eks.amazonaws.com/nodegroup": "gpu"}, ""); pool != "gpu" {
+		t.Fatalf(`getNodePool = %v, want match for %v`, pool, "gpu")
+	}
+
+	if pool := getNodePool(map[string]string{"eks.amazonaws.com/nodegroup": "gpu"}, "pool"); pool != unassignedPool {
+		t.Fatalf(`getNodePool = %v, want match for %v`, pool, unassignedPool)
+	}
+
+	nodes := []NodeJson{
+		{Name: "node-1", Pool: "gpu", CapacityType: "spot", Free: ResourcesJson{Gpu: 1}},
+		{Name: "node-2", Pool: "gpu", CapacityType: "on-demand", Free: ResourcesJson{Gpu: 2}},
+		{Name: "node-3", Pool: "cpu", CapacityType: "on-demand", Free: ResourcesJson{Cpu: 4}},
+	}
+
+	pools := getNodePools(nodes)
+
+	switch {
+	case len(pools) != 2:
+		t.Fatalf(`len(pools) = %v, want match for %v`, len(pools), 2)
+	case pools[0].Name != "cpu" || pools[1].Name != "gpu":
+		t.Fatalf(`pool names = %v, %v, want match for %v, %v`, pools[0].Name, pools[1].Name, "cpu", "gpu")
+	case pools[1].Nodes != 2 || pools[1].Free.Gpu != 3:
+		t.Fatalf(`pools[1] = %v nodes with %v free GPUs, want match for %v, %v`, pools[1].Nodes, pools[1].Free.Gpu, 2, 3)
+	case pools[1].ByCapacityType["spot"].Free.Gpu != 1:
+		t.Fatalf(`pools[1].ByCapacityType["spot"].Free.Gpu = %v, want match for %v`, pools[1].ByCapacityType["spot"].Free.Gpu, 1)
+	}
+}
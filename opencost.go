@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Name OpenCost gives to the cost of resources that no workload requested
+const openCostIdle = "__idle__"
+
+// OpenCostClient reads cost allocations from the API of an OpenCost installation
+type OpenCostClient struct {
+	url    string
+	client *http.Client
+}
+
+// OpenCostAllocation is the cost of a namespace or workload over a window, as returned by OpenCost
+type OpenCostAllocation struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Namespace      string `json:"namespace"`
+		Controller     string `json:"controller"`
+		ControllerKind string `json:"controllerKind"`
+	} `json:"properties"`
+	CpuCost     float64 `json:"cpuCost"`
+	GpuCost     float64 `json:"gpuCost"`
+	RamCost     float64 `json:"ramCost"`
+	PvCost      float64 `json:"pvCost"`
+	NetworkCost float64 `json:"networkCost"`
+	TotalCost   float64 `json:"totalCost"`
+}
+
+// openCostResponse is the body of a response from the OpenCost allocation API - data has one set of allocations,
+// keyed by name, for each step of the window, or only one when accumulating
+type openCostResponse struct {
+	Code    int                             `json:"code"`
+	Message string                          `json:"message"`
+	Data    []map[string]OpenCostAllocation `json:"data"`
+}
+
+// CostBreakdown is the cost of a namespace or workload by resource, in the currency OpenCost is configured with
+type CostBreakdown struct {
+	Cpu     float64 `json:"cpu"`
+	Gpu     float64 `json:"gpu"`
+	Memory  float64 `json:"memory"`
+	Storage float64 `json:"storage"`
+	Network float64 `json:"network"`
+	Total   float64 `json:"total"`
+}
+
+// CostItem combines what a namespace or workload currently requests with what it cost over the window
+type CostItem struct {
+	Namespace string        `json:"namespace"`
+	Kind      string        `json:"kind,omitempty"` // Only set when aggregating by workload
+	Name      string        `json:"name,omitempty"`
+	Pods      int           `json:"pods"`
+	Requests  ResourcesJson `json:"requests"`
+	Cost      CostBreakdown `json:"cost"`
+}
+
+// CostReport is the cost of every namespace or workload over a window
+type CostReport struct {
+	Window    string     `json:"window"`
+	Aggregate string     `json:"aggregate"`
+	Items     []CostItem `json:"items"`
+	IdleCost  float64    `json:"idleCost"` // Cost of the resources nothing requested
+	TotalCost float64    `json:"totalCost"`
+}
+
+// newOpenCostClient returns an OpenCostClient for the API at baseUrl.
+func newOpenCostClient(baseUrl string) *OpenCostClient {
+	return &OpenCostClient{
+		url:    strings.TrimSuffix(baseUrl, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Allocations returns the cost of every namespace, or every workload if byWorkload is set, accumulated over window,
+// such as 1d or 7d.
+func (o *OpenCostClient) Allocations(window string, byWorkload bool) ([]OpenCostAllocation, error) {
+	params := url.Values{}
+	params.Set("window", window)
+	params.Set("accumulate", "true")
+	params.Set("aggregate", "namespace")
+	if byWorkload {
+		params.Set("aggregate", "namespace,controllerKind,controller")
+	}
+
+	resp, err := o.client.Get(o.url + "/allocation/compute?" + params.Encode())
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var body openCostResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	if body.Code != http.StatusOK {
+		return nil, fmt.Errorf("opencost allocation query failed: %v", body.Message)
+	}
+
+	allocations := make([]OpenCostAllocation, 0)
+	for _, set := range body.Data {
+		for _, allocation := range set {
+			allocations = append(allocations, allocation)
+		}
+	}
+
+	return allocations, nil
+}
+
+// getCostItemKey returns the key a namespace or workload is joined on. Kinds are compared in lower case, since
+// OpenCost reports them that way.
+func getCostItemKey(namespace, kind, name string) string {
+	return namespace + "/" + strings.ToLower(kind) + "/" + name
+}
+
+// getCostReport joins the current requests of pods with the cost OpenCost allocated over a window, by namespace or,
+// if byWorkload is set, by the workload controlling each pod. Namespaces and workloads that only appear on one side
+// are still included. Items are sorted by total cost, largest first.
+func getCostReport(pods []PodJson, allocations []OpenCostAllocation, byWorkload bool) CostReport {
+	report := CostReport{Aggregate: "namespace", Items: make([]CostItem, 0)}
+	if byWorkload {
+		report.Aggregate = "workload"
+	}
+
+	items := make(map[string]*CostItem)
+	item := func(namespace, kind, name string) *CostItem {
+		if !byWorkload {
+			kind, name = "", ""
+		}
+
+		key := getCostItemKey(namespace, kind, name)
+		if _, ok := items[key]; !ok {
+			items[key] = &CostItem{Namespace: namespace, Kind: kind, Name: name}
+		}
+
+		return items[key]
+	}
+
+	for _, pod := range pods {
+		var kind, name string
+		if pod.Owner != nil {
+			kind, name = strings.ToLower(pod.Owner.Kind), pod.Owner.Name
+		}
+
+		entry := item(pod.Namespace, kind, name)
+		entry.Pods++
+		entry.Requests = addResources(entry.Requests, pod.Requests)
+	}
+
+	for _, allocation := range allocations {
+		report.TotalCost += allocation.TotalCost
+
+		if allocation.Name == openCostIdle {
+			report.IdleCost += allocation.TotalCost
+			continue
+		}
+
+		properties := allocation.Properties
+		entry := item(properties.Namespace, properties.ControllerKind, properties.Controller)
+		entry.Cost.Cpu += allocation.CpuCost
+		entry.Cost.Gpu += allocation.GpuCost
+		entry.Cost.Memory += allocation.RamCost
+		entry.Cost.Storage += allocation.PvCost
+		entry.Cost.Network += allocation.NetworkCost
+		entry.Cost.Total += allocation.TotalCost
+	}
+
+	for _, entry := range items {
+		report.Items = append(report.Items, *entry)
+	}
+
+	// Sort by cost, breaking ties by key so the order is stable
+	sort.Slice(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.Cost.Total != b.Cost.Total {
+			return a.Cost.Total > b.Cost.Total
+		}
+		return getCostItemKey(a.Namespace, a.Kind, a.Name) < getCostItemKey(b.Namespace, b.Kind, b.Name)
+	})
+
+	return report
+}
+
+// getCostHandler returns a HandlerFunc to return the requests and cost of every namespace or workload given a
+// Collector and an OpenCostClient. The window query parameter is passed to OpenCost, and the aggregate query
+// parameter is either namespace or workload.
+func getCostHandler(collector *Collector, openCost *OpenCostClient) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		window := c.DefaultQuery("window", "1d")
+
+		aggregate := c.DefaultQuery("aggregate", "namespace")
+		if aggregate != "namespace" && aggregate != "workload" {
+			c.JSON(http.StatusBadRequest, "error: aggregate must be namespace or workload")
+			return
+		}
+
+		pods, err := collector.Pods()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		allocations, err := openCost.Allocations(window, aggregate == "workload")
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving cost information")
+			return
+		}
+
+		report := getCostReport(pods, allocations, aggregate == "workload")
+		report.Window = window
+
+		c.IndentedJSON(http.StatusOK, report)
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestOpenCostCostReport reads allocations from a fake OpenCost server and joins them with the requests of pods
+// by workload, checking that idle cost is split out and that workloads only on one side are kept.
+func TestOpenCostCostReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/allocation/compute" || r.URL.Query().Get("aggregate") != "namespace,controllerKind,controller" {
+			t.Errorf(`request = %v, want match for %v`, r.URL, "a workload allocation query")
+		}
+
+		w.Write([]byte(`{"code": 200, "data": [{
+			"vision/deployment/inference": {"name": "vision/deployment/inference", "properties": {"namespace": "vision", "controller": "inference", "controllerKind": "deployment"}, "cpuCost": 3, "gpuCost": 10, "ramCost": 1, "totalCost": 14},
+			"batch/job/train": {"name": "batch/job/train", "properties": {"namespace": "batch", "controller": "train", "controllerKind": "job"}, "cpuCost": 2, "totalCost": 2},
+			"__idle__": {"name": "__idle__", "totalCost": 5}
+		}]}`))
+	}))
+	defer server.Close()
+
+	allocations, err := newOpenCostClient(server.URL+"/").Allocations("7d", true)
+
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	pods := []PodJson{
+		{Name: "inference-1", Namespace: "vision", Owner: &OwnerJson{Kind: "Deployment", Name: "inference"}, Requests: ResourcesJson{Cpu: 2, Gpu: 1}},
+		{Name: "inference-2", Namespace: "vision", Owner: &OwnerJson{Kind: "Deployment", Name: "inference"}, Requests: ResourcesJson{Cpu: 2, Gpu: 1}},
+		{Name: "web-1", Namespace: "web", Owner: &OwnerJson{Kind: "StatefulSet", Name: "web"}, Requests: ResourcesJson{Cpu: 1}},
+	}
+
+	report := getCostReport(pods, allocations, true)
+
+	switch {
+	case report.IdleCost != 5 || report.TotalCost != 21:
+		t.Fatalf(`idle, total = %v, %v, want match for %v, %v`, report.IdleCost, report.TotalCost, 5, 21)
+	case len(report.Items) != 3:
+		t.Fatalf(`len(items) = %v, want match for %v`, len(report.Items), 3)
+	case report.Items[0].Name != "inference" || report.Items[0].Pods != 2 || report.Items[0].Requests.Gpu != 2 || report.Items[0].Cost.Gpu != 10:
+		t.Fatalf(`items[0] = %v, want match for %v`, report.Items[0], "inference with 2 pods, 2 GPUs, and 10 GPU cost")
+	case report.Items[1].Name != "train" || report.Items[1].Pods != 0:
+		t.Fatalf(`items[1] = %v, want match for %v`, report.Items[1], "train with no pods")
+	case report.Items[2].Name != "web" || report.Items[2].Kind != "statefulset" || report.Items[2].Cost.Total != 0:
+		t.Fatalf(`items[2] = %v, want match for %v`, report.Items[2], "web with no cost")
+	}
+}
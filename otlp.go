@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Name of the instrumentation scope metrics are exported under, and the service name if OTEL_SERVICE_NAME isn't set
+const otlpScopeName = "kubernetes-resource-api"
+
+// OtlpExporter pushes the capacity of the cluster and each node to an OpenTelemetry collector. Its gauges are
+// observed from the latest snapshot whenever the SDK collects them.
+type OtlpExporter struct {
+	provider *sdkmetric.MeterProvider
+	snapshot atomic.Pointer[Snapshot]
+}
+
+// newOtlpExporter returns an OtlpExporter that sends metrics over OTLP/HTTP. The endpoint, headers, compression,
+// timeout, TLS, temporality, and export interval are read by the SDK from the standard OTEL_EXPORTER_OTLP_* and
+// OTEL_METRIC_EXPORT_* variables, and the resource from OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES.
+func newOtlpExporter(ctx context.Context) (*OtlpExporter, error) {
+	exporter, err := otlpmetrichttp.New(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Attributes from the environment are detected last, so OTEL_SERVICE_NAME overrides the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", otlpScopeName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newOtlpExporterWithReader(sdkmetric.NewPeriodicReader(exporter), res)
+}
+
+// newOtlpExporterWithReader returns an OtlpExporter whose metrics are collected by reader, describing them as coming
+// from res. There are kube_resources.cluster.<field> and kube_resources.node.<field> gauges for the allocatable,
+// capacity, and free fields, with a data point per resource. Node data points also have node, pool, and
+// capacity_type attributes.
+func newOtlpExporterWithReader(reader sdkmetric.Reader, res *resource.Resource) (*OtlpExporter, error) {
+	e := &OtlpExporter{
+		provider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res)),
+	}
+
+	meter := e.provider.Meter(otlpScopeName, metric.WithInstrumentationVersion(version))
+
+	clusterGauges := make(map[string]metric.Float64ObservableGauge)
+	nodeGauges := make(map[string]metric.Float64ObservableGauge)
+	instruments := make([]metric.Observable, 0, 2*len(grafanaFields))
+
+	for _, group := range []struct {
+		name        string
+		description string
+		gauges      map[string]metric.Float64ObservableGauge
+	}{
+		{"cluster", "Sum of the %v resources of every node, in cores, bytes, or units", clusterGauges},
+		{"node", "The %v resources of a node, in cores, bytes, or units", nodeGauges},
+	} {
+		for _, field := range grafanaFields {
+			gauge, err := meter.Float64ObservableGauge("kube_resources."+group.name+"."+field, metric.WithDescription(fmt.Sprintf(group.description, field)))
+
+			if err != nil {
+				return nil, err
+			}
+
+			group.gauges[field] = gauge
+			instruments = append(instruments, gauge)
+		}
+	}
+
+	_, err := meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		snapshot := e.snapshot.Load()
+		if snapshot == nil {
+			return nil
+		}
+
+		observe := func(gauges map[string]metric.Float64ObservableGauge, fields map[string]ResourcesJson, attributes ...attribute.KeyValue) {
+			for _, field := range grafanaFields {
+				for _, resource := range resourceNames {
+					set := metric.WithAttributes(append(append([]attribute.KeyValue(nil), attributes...), attribute.String("resource", resource))...)
+					observer.ObserveFloat64(gauges[field], getResource(fields[field], resource), set)
+				}
+			}
+		}
+
+		nodes := append([]NodeJson(nil), snapshot.Nodes...)
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Name < nodes[j].Name
+		})
+
+		summary := getClusterSummary(nodes)
+		observe(clusterGauges, map[string]ResourcesJson{
+			"allocatable": summary.Allocatable,
+			"capacity":    summary.Capacity,
+			"free":        summary.Free,
+		})
+
+		for _, node := range nodes {
+			observe(nodeGauges, map[string]ResourcesJson{
+				"allocatable": node.Allocatable,
+				"capacity":    node.Capacity,
+				"free":        node.Free,
+			}, attribute.String("node", node.Name), attribute.String("pool", node.Pool), attribute.String("capacity_type", node.CapacityType))
+		}
+
+		return nil
+	}, instruments...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Export makes a snapshot the one the gauges are observed from and pushes them right away, rather than waiting for
+// the next export interval.
+func (e *OtlpExporter) Export(snapshot *Snapshot) error {
+	e.snapshot.Store(snapshot)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return e.provider.ForceFlush(ctx)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// TestOtlpExporter exports a snapshot to a manual reader, checking the resource, gauges, and data points.
+func TestOtlpExporter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	res := resource.NewSchemaless(attribute.String("service.name", "capacity"))
+
+	exporter, err := newOtlpExporterWithReader(reader, res)
+
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	snapshot := &Snapshot{
+		Time: time.Unix(1714564800, 0),
+		Nodes: []NodeJson{
+			{Name: "node-1", Pool: "gpu", Allocatable: ResourcesJson{Cpu: 7.5, Gpu: 4}, Free: ResourcesJson{Cpu: 1.5, Gpu: 2}},
+		},
+	}
+
+	if err := exporter.Export(snapshot); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	var collected metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &collected); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	if len(collected.ScopeMetrics) != 1 {
+		t.Fatalf(`len(ScopeMetrics) = %v, want match for %v`, len(collected.ScopeMetrics), 1)
+	}
+
+	// Finds the value of the data point of a gauge with the given attributes
+	value := func(name string, attributes ...attribute.KeyValue) (float64, bool) {
+		set := attribute.NewSet(attributes...)
+
+		for _, m := range collected.ScopeMetrics[0].Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			for _, point := range m.Data.(metricdata.Gauge[float64]).DataPoints {
+				if point.Attributes.Equals(&set) {
+					return point.Value, true
+				}
+			}
+		}
+
+		return 0, false
+	}
+
+	serviceName, _ := collected.Resource.Set().Value("service.name")
+	clusterFree, _ := value("kube_resources.cluster.free", attribute.String("resource", "cpu"))
+	nodeFree, ok := value("kube_resources.node.free",
+		attribute.String("node", "node-1"), attribute.String("pool", "gpu"), attribute.String("capacity_type", ""), attribute.String("resource", "gpu"))
+
+	switch {
+	case serviceName.AsString() != "capacity":
+		t.Fatalf(`service.name = %v, want match for %v`, serviceName.AsString(), "capacity")
+	case len(collected.ScopeMetrics[0].Metrics) != 6:
+		t.Fatalf(`len(metrics) = %v, want match for %v`, len(collected.ScopeMetrics[0].Metrics), 6)
+	case clusterFree != 1.5:
+		t.Fatalf(`kube_resources.cluster.free cpu = %v, want match for %v`, clusterFree, 1.5)
+	case !ok || nodeFree != 2:
+		t.Fatalf(`kube_resources.node.free gpu of node-1 = %v, want match for %v`, nodeFree, 2)
+	}
+}
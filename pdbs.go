@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PdbJson contains a PodDisruptionBudget and how many more of its pods can be disrupted right now
+type PdbJson struct {
+	Name               string `json:"name"`
+	Namespace          string `json:"namespace"`
+	Selector           string `json:"selector"`
+	MinAvailable       string `json:"minAvailable,omitempty"`
+	MaxUnavailable     string `json:"maxUnavailable,omitempty"`
+	ExpectedPods       int32  `json:"expectedPods"`
+	CurrentHealthy     int32  `json:"currentHealthy"`
+	DesiredHealthy     int32  `json:"desiredHealthy"`
+	DisruptionsAllowed int32  `json:"disruptionsAllowed"`
+}
+
+// PodDisruptionBudgets returns every PodDisruptionBudget in namespace, or in the whole cluster if namespace is
+// empty. If the service account can't read them, no PodDisruptionBudgets are returned rather than failing.
+func (c *Collector) PodDisruptionBudgets(namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	list, err := c.client.PolicyV1().PodDisruptionBudgets(namespace).List(context.Background(), metav1.ListOptions{})
+
+	if errors.IsForbidden(err) {
+		return []policyv1.PodDisruptionBudget{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// getPdbStructured takes a pointer to a PodDisruptionBudget and returns a PdbJson struct instance.
+func getPdbStructured(pdb *policyv1.PodDisruptionBudget) PdbJson {
+	pdbJson := PdbJson{
+		Name:               pdb.Name,
+		Namespace:          pdb.Namespace,
+		Selector:           metav1.FormatLabelSelector(pdb.Spec.Selector),
+		ExpectedPods:       pdb.Status.ExpectedPods,
+		CurrentHealthy:     pdb.Status.CurrentHealthy,
+		DesiredHealthy:     pdb.Status.DesiredHealthy,
+		DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+	}
+
+	if pdb.Spec.MinAvailable != nil {
+		pdbJson.MinAvailable = pdb.Spec.MinAvailable.String()
+	}
+
+	if pdb.Spec.MaxUnavailable != nil {
+		pdbJson.MaxUnavailable = pdb.Spec.MaxUnavailable.String()
+	}
+
+	return pdbJson
+}
+
+// getPdbsHandler returns a HandlerFunc to return every PodDisruptionBudget given a Collector. The namespace query
+// parameter limits them to one namespace, and blocking=true limits them to those allowing no disruptions.
+func getPdbsHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		pdbs, err := collector.PodDisruptionBudgets(c.Query("namespace"))
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving PodDisruptionBudget information")
+			return
+		}
+
+		blocking := c.Query("blocking") == "true"
+		pdbJsons := make([]PdbJson, 0, len(pdbs))
+
+		for i := range pdbs {
+			if blocking && pdbs[i].Status.DisruptionsAllowed > 0 {
+				continue
+			}
+
+			pdbJsons = append(pdbJsons, getPdbStructured(&pdbs[i]))
+		}
+
+		sort.Slice(pdbJsons, func(i, j int) bool {
+			if pdbJsons[i].Namespace != pdbJsons[j].Namespace {
+				return pdbJsons[i].Namespace < pdbJsons[j].Namespace
+			}
+			return pdbJsons[i].Name < pdbJsons[j].Name
+		})
+
+		c.IndentedJSON(http.StatusOK, pdbJsons)
+	}
+
+	return gin.HandlerFunc(handler)
+}
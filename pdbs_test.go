@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestCollectorPodDisruptionBudgets reads PodDisruptionBudgets from a fake cluster, checking the namespace filter
+// and that minAvailable and maxUnavailable are formatted as given.
+func TestCollectorPodDisruptionBudgets(t *testing.T) {
+	minAvailable := intstr.FromInt32(2)
+	maxUnavailable := intstr.FromString("25%")
+
+	client := fake.NewSimpleClientset(
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "storefront"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: 3, CurrentHealthy: 3, DesiredHealthy: 2, DisruptionsAllowed: 1},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "data"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable},
+		},
+	)
+
+	pdbs, err := newCollector(client).PodDisruptionBudgets("storefront")
+
+	if err != nil {
+		t.Fatalf(`PodDisruptionBudgets() err = %v, want match for %v`, err, nil)
+	}
+
+	if len(pdbs) != 1 {
+		t.Fatalf(`len(pdbs) = %v, want match for %v`, len(pdbs), 1)
+	}
+
+	want := PdbJson{
+		Name:               "web",
+		Namespace:          "storefront",
+		Selector:           "app=web",
+		MinAvailable:       "2",
+		ExpectedPods:       3,
+		CurrentHealthy:     3,
+		DesiredHealthy:     2,
+		DisruptionsAllowed: 1,
+	}
+
+	if got := getPdbStructured(&pdbs[0]); got != want {
+		t.Fatalf(`getPdbStructured() = %v, want match for %v`, got, want)
+	}
+
+	pdbs, _ = newCollector(client).PodDisruptionBudgets("data")
+
+	if got := getPdbStructured(&pdbs[0]); got.MaxUnavailable != "25%" || got.MinAvailable != "" {
+		t.Fatalf(`getPdbStructured() = %v, want maxUnavailable 25%%`, got)
+	}
+}
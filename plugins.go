@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// How long each plugin has to enrich a snapshot before it is skipped
+const collectorPluginTimeout = 10 * time.Second
+
+// CollectorPlugin adds site-specific information to the nodes of every snapshot, such as InfiniBand HCAs or
+// licensed software seats. Plugins should only add to the Extra field of each node, under their own name.
+type CollectorPlugin interface {
+	Name() string
+	Collect(ctx context.Context, snapshot *Snapshot) error
+}
+
+// CollectorPluginFactory creates a plugin that reads the cluster through collector
+type CollectorPluginFactory func(collector *Collector) (CollectorPlugin, error)
+
+// Factories of every plugin that can be enabled, keyed by name
+var collectorPluginFactories = make(map[string]CollectorPluginFactory)
+
+// registerCollectorPlugin makes a plugin available to enable by name. Plugins register themselves from an init
+// function in their own file, so adding one doesn't change any other code. It panics if the name is taken.
+func registerCollectorPlugin(name string, factory CollectorPluginFactory) {
+	if _, ok := collectorPluginFactories[name]; ok {
+		panic(fmt.Sprintf("collector plugin %q registered twice", name))
+	}
+
+	collectorPluginFactories[name] = factory
+}
+
+// newCollectorPlugins creates the named plugins for collector, in order. An error is returned if a name isn't
+// registered or a plugin can't be created.
+func newCollectorPlugins(collector *Collector, names []string) ([]CollectorPlugin, error) {
+	plugins := make([]CollectorPlugin, 0, len(names))
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		factory, ok := collectorPluginFactories[name]
+		if !ok {
+			registered := make([]string, 0, len(collectorPluginFactories))
+			for name := range collectorPluginFactories {
+				registered = append(registered, name)
+			}
+
+			sort.Strings(registered)
+			return nil, fmt.Errorf("unknown collector plugin %q: expected one of %v", name, strings.Join(registered, ", "))
+		}
+
+		plugin, err := factory(collector)
+
+		if err != nil {
+			return nil, fmt.Errorf("collector plugin %q: %v", name, err)
+		}
+
+		plugins = append(plugins, plugin)
+	}
+
+	return plugins, nil
+}
+
+// runCollectorPlugins passes a snapshot to each plugin in turn. A plugin that returns an error, such as when its
+// context times out, is logged and skipped, so one broken plugin doesn't stop snapshots from being taken.
+func runCollectorPlugins(plugins []CollectorPlugin, snapshot *Snapshot) {
+	for _, plugin := range plugins {
+		ctx, cancel := context.WithTimeout(context.Background(), collectorPluginTimeout)
+		err := plugin.Collect(ctx, snapshot)
+		cancel()
+
+		if err != nil {
+			fmt.Printf("collector plugin %v: %v\n", plugin.Name(), err)
+		}
+	}
+}
+
+// setNodeExtra sets the value a plugin adds to a node under its name.
+func setNodeExtra(node *NodeJson, name string, value interface{}) {
+	if node.Extra == nil {
+		node.Extra = make(map[string]interface{})
+	}
+
+	node.Extra[name] = value
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakePlugin adds a fixed value to every node, or fails
+type fakePlugin struct {
+	name string
+	err  error
+}
+
+func (p *fakePlugin) Name() string {
+	return p.name
+}
+
+func (p *fakePlugin) Collect(ctx context.Context, snapshot *Snapshot) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	for i := range snapshot.Nodes {
+		setNodeExtra(&snapshot.Nodes[i], p.name, "value")
+	}
+
+	return nil
+}
+
+// TestRunCollectorPlugins runs a plugin that fails before one that succeeds, checking that the failure doesn't
+// stop the second plugin from enriching the nodes.
+func TestRunCollectorPlugins(t *testing.T) {
+	registerCollectorPlugin("test", func(collector *Collector) (CollectorPlugin, error) {
+		return &fakePlugin{name: "test"}, nil
+	})
+	defer delete(collectorPluginFactories, "test")
+
+	if _, err := newCollectorPlugins(nil, []string{"missing"}); err == nil {
+		t.Fatalf(`err = %v, want match for %v`, err, "an error")
+	}
+
+	plugins, err := newCollectorPlugins(nil, []string{" test"})
+
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	snapshot := &Snapshot{Nodes: []NodeJson{{Name: "node-1"}}}
+	runCollectorPlugins(append([]CollectorPlugin{&fakePlugin{name: "broken", err: errors.New("broken")}}, plugins...), snapshot)
+
+	if len(snapshot.Nodes[0].Extra) != 1 || snapshot.Nodes[0].Extra["test"] != "value" {
+		t.Fatalf(`extra = %v, want match for %v`, snapshot.Nodes[0].Extra, map[string]interface{}{"test": "value"})
+	}
+}
+
+// TestGetExtendedResources checks that extended resources other than GPUs are counted on each node, less the
+// requests of its pods.
+func TestGetExtendedResources(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				"cpu":                 resource.MustParse("8"),
+				"nvidia.com/gpu":      resource.MustParse("4"),
+				"rdma/hca":            resource.MustParse("10"),
+				"example.com/license": resource.MustParse("2"),
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+			Status:     corev1.NodeStatus{Allocatable: corev1.ResourceList{"cpu": resource.MustParse("8")}},
+		},
+	}
+
+	pods := []corev1.Pod{
+		{
+			Spec: corev1.PodSpec{NodeName: "node-1", Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{"rdma/hca": resource.MustParse("3")}}},
+			}},
+		},
+	}
+
+	resources := getExtendedResources(nodes, pods)
+
+	switch {
+	case len(resources) != 1 || len(resources["node-1"]) != 2:
+		t.Fatalf(`resources = %v, want match for %v`, resources, "two resources on node-1")
+	case resources["node-1"]["rdma/hca"] != ExtendedResource{Allocatable: 10, Requested: 3, Free: 7}:
+		t.Fatalf(`rdma/hca = %v, want match for %v`, resources["node-1"]["rdma/hca"], ExtendedResource{Allocatable: 10, Requested: 3, Free: 7})
+	case resources["node-1"]["example.com/license"] != ExtendedResource{Allocatable: 2, Free: 2}:
+		t.Fatalf(`example.com/license = %v, want match for %v`, resources["node-1"]["example.com/license"], ExtendedResource{Allocatable: 2, Free: 2})
+	}
+}
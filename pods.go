@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Number of pods to list at a time, so that only one page of pods is held before its unused fields are stripped
+const podPageSize = 500
+
+// Pod information in JSON format to be returned by the API
+type PodJson struct {
+	Name            string             `json:"name"`
+	Namespace       string             `json:"namespace"`
+	Node            string             `json:"node"`
+	NominatedNode   string             `json:"nominatedNode,omitempty"`   // Node the pod is preempting other pods on, if it isn't scheduled yet
+	SchedulingGates []string           `json:"schedulingGates,omitempty"` // Scheduling gates holding the pod back from being scheduled
+	Labels          map[string]string  `json:"labels"`
+	Owner           *OwnerJson         `json:"owner,omitempty"`
+	Requests        ResourcesJson      `json:"requests"`
+	Limits          ResourcesJson      `json:"limits"`
+	Vpa             *VpaRecommendation `json:"vpa,omitempty"`
+	Containers      *PodContainers     `json:"containers,omitempty"` // Only with ?containers=true
+}
+
+// OwnerJson identifies the workload that controls a pod
+type OwnerJson struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// getPodStructured takes a pointer to a Pod and returns a PodJson struct instance with its
+// effective requests and limits converted to numbers
+func getPodStructured(pod *corev1.Pod) PodJson {
+	podReqs, podLimits := getPodRequestsAndLimits(pod)
+
+	podJson := PodJson{
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+		Node:       pod.Spec.NodeName,
+		Labels:     pod.Labels,
+		Owner:      getPodOwner(pod),
+		Requests:   getResourcesStructured(getResourcesFromList(podReqs)),
+		Limits:     getResourcesStructured(getResourcesFromList(podLimits)),
+		Containers: getPodContainers(pod),
+	}
+
+	if pod.Spec.NodeName == "" {
+		podJson.NominatedNode = pod.Status.NominatedNodeName
+	}
+
+	for _, gate := range pod.Spec.SchedulingGates {
+		podJson.SchedulingGates = append(podJson.SchedulingGates, gate.Name)
+	}
+
+	// Always return an object for labels, even if the pod has none
+	if podJson.Labels == nil {
+		podJson.Labels = make(map[string]string)
+	}
+
+	return podJson
+}
+
+// getPodOwner returns the workload controlling a pod, or nil if it doesn't have one. Pods owned by a ReplicaSet
+// are attributed to the Deployment that created it, which is found from the ReplicaSet's name.
+func getPodOwner(pod *corev1.Pod) *OwnerJson {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil
+	}
+
+	// Deployments name their ReplicaSets after themselves plus the pod template hash
+	if ref.Kind == "ReplicaSet" {
+		hash, ok := pod.Labels["pod-template-hash"]
+		if ok && strings.HasSuffix(ref.Name, "-"+hash) {
+			return &OwnerJson{Kind: "Deployment", Name: strings.TrimSuffix(ref.Name, "-"+hash)}
+		}
+	}
+
+	return &OwnerJson{Kind: ref.Kind, Name: ref.Name}
+}
+
+// getTopPods returns the limit pods with the highest requests of resource, largest first. If node or namespace
+// aren't empty, only pods on that node or in that namespace are included.
+func getTopPods(pods []PodJson, resource, node, namespace string, limit int) []PodJson {
+	top := filterPods(pods, node, namespace)
+
+	// Sort by requests, breaking ties by namespace and name so the order is stable
+	sort.Slice(top, func(i, j int) bool {
+		a, b := getResource(top[i].Requests, resource), getResource(top[j].Requests, resource)
+		if a != b {
+			return a > b
+		}
+		if top[i].Namespace != top[j].Namespace {
+			return top[i].Namespace < top[j].Namespace
+		}
+		return top[i].Name < top[j].Name
+	})
+
+	if len(top) > limit {
+		top = top[:limit]
+	}
+
+	return top
+}
+
+// filterPods returns the pods on node and in namespace, ignoring either filter if it is empty.
+func filterPods(pods []PodJson, node, namespace string) []PodJson {
+	filtered := make([]PodJson, 0)
+
+	for _, pod := range pods {
+		if (node != "" && pod.Node != node) || (namespace != "" && pod.Namespace != namespace) {
+			continue
+		}
+
+		filtered = append(filtered, pod)
+	}
+
+	return filtered
+}
+
+// Columns of the pods in CSV and table output when none are asked for
+const podColumns = "namespace,name,node,requests.cpu,requests.memory,requests.gpu,limits.cpu,limits.memory,limits.gpu"
+
+// getPodsHandler returns a HandlerFunc to return a list of pods given a Collector. The pods can be filtered with
+// the node and namespace query parameters. Pods whose workload has a VerticalPodAutoscaler include its recommendation.
+func getPodsHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		pods, err := collector.Pods()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		vpas, err := collector.VpaRecommendations()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving VerticalPodAutoscaler recommendations")
+			return
+		}
+
+		pods = filterPods(pods, c.Query("node"), c.Query("namespace"))
+		applyContainersParam(c.Query("containers"), pods)
+
+		for i := range pods {
+			if vpa, ok := vpas[getWorkloadKey(pods[i])]; ok {
+				pods[i].Vpa = &vpa
+			}
+		}
+
+		writeList(c, pods, podColumns)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getNodePodsHandler returns a HandlerFunc to return the pods scheduled on the node given by the name path
+// parameter given a Collector.
+func getNodePodsHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		pods, err := collector.NodePods(c.Param("name"))
+
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, "error: node not found")
+			return
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		applyContainersParam(c.Query("containers"), pods)
+		c.IndentedJSON(http.StatusOK, pods)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getTopPodsHandler returns a HandlerFunc to return the pods with the highest requests given a Collector.
+// The resource to sort by is given by the by query parameter (default cpu), the number of pods by the limit
+// parameter (default 10), and the pods can be filtered with the node and namespace parameters.
+func getTopPodsHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		by, limit, ok := getTopParams(c)
+		if !ok {
+			return
+		}
+
+		pods, err := collector.Pods()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		top := getTopPods(pods, by, c.Query("node"), c.Query("namespace"), limit)
+		applyContainersParam(c.Query("containers"), top)
+
+		c.IndentedJSON(http.StatusOK, top)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// listPods returns the pods matching fieldSelector that aren't terminated, with the default requests of
+// their namespace's LimitRanges applied and the requests of containers resized in place set to what is allocated.
+// Pods are listed podPageSize at a time and stripped as each page arrives, so the unused fields of every pod in the
+// cluster are never held at once.
+func (c *Collector) listPods(fieldSelector string) ([]corev1.Pod, error) {
+	pods := make([]corev1.Pod, 0)
+	options := metav1.ListOptions{FieldSelector: fieldSelector, Limit: podPageSize}
+
+	for {
+		podList, err := c.client.CoreV1().Pods("").List(context.Background(), options)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stripPodFields(podList.Items)
+		pods = append(pods, podList.Items...)
+
+		if podList.Continue == "" {
+			break
+		}
+
+		options.Continue = podList.Continue
+	}
+
+	return c.preparePods(pods)
+}
+
+// preparePods readies listed pods to be counted, stripping the fields nothing reads, applying the default requests of
+// their namespace's LimitRanges, and setting the requests of containers resized in place to what is allocated. Every
+// way pods are listed goes through it, so pods are counted the same however they were listed.
+func (c *Collector) preparePods(pods []corev1.Pod) ([]corev1.Pod, error) {
+	// LimitRanges are read once for every pod rather than once per query or page
+	limitRanges, err := c.LimitRanges("")
+
+	if err != nil {
+		return nil, err
+	}
+
+	stripPodFields(pods)
+	applyLimitRangeDefaults(pods, limitRanges)
+	applyResizedRequests(pods)
+
+	return pods, nil
+}
+
+// stripPodFields clears the fields of pods that nothing here reads, such as managedFields, container environments,
+// and volumes, so that the memory they take can be freed while the pods are used. Their requests, limits,
+// scheduling constraints, and statuses are kept.
+func stripPodFields(pods []corev1.Pod) {
+	for i := range pods {
+		pod := &pods[i]
+		pod.ManagedFields = nil
+		delete(pod.Annotations, corev1.LastAppliedConfigAnnotation)
+		pod.Spec.Volumes = nil
+
+		for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+			for j := range containers {
+				container := &containers[j]
+				container.Command = nil
+				container.Args = nil
+				container.Env = nil
+				container.EnvFrom = nil
+				container.VolumeMounts = nil
+				container.VolumeDevices = nil
+				container.LivenessProbe = nil
+				container.ReadinessProbe = nil
+				container.StartupProbe = nil
+				container.Lifecycle = nil
+			}
+		}
+
+		pod.Spec.EphemeralContainers = nil
+	}
+}
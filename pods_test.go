@@ -0,0 +1,212 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestGetPodStructured calls getPodStructured on a pod with two containers, checking that the requests and limits
+// are summed and that GPUs of any NVIDIA resource type are counted.
+func TestGetPodStructured(t *testing.T) {
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: "node-1",
+			Containers: []v1.Container{
+				{
+					Name: "a",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(500, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI),
+						},
+						Limits: v1.ResourceList{
+							v1.ResourceCPU: *resource.NewQuantity(1, resource.DecimalSI),
+						},
+					},
+				},
+				{
+					Name: "b",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:          *resource.NewMilliQuantity(1500, resource.DecimalSI),
+							"nvidia.com/mig-1g.5gb": *resource.NewQuantity(2, resource.DecimalSI),
+						},
+						Limits: v1.ResourceList{
+							"nvidia.com/mig-1g.5gb": *resource.NewQuantity(2, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	podJson := getPodStructured(&pod)
+
+	switch {
+	case podJson.Name != "pod-1" || podJson.Namespace != "default" || podJson.Node != "node-1":
+		t.Fatalf(`podJson = %v, want pod-1 in default on node-1`, podJson)
+	case podJson.Labels == nil:
+		t.Fatalf(`podJson.Labels = nil, want empty map`)
+	case podJson.Requests != (ResourcesJson{Cpu: 2, Memory: 1000, Gpu: 2}):
+		t.Fatalf(`podJson.Requests = %v, want match for %v`, podJson.Requests, ResourcesJson{Cpu: 2, Memory: 1000, Gpu: 2})
+	case podJson.Limits != (ResourcesJson{Cpu: 1, Gpu: 2}):
+		t.Fatalf(`podJson.Limits = %v, want match for %v`, podJson.Limits, ResourcesJson{Cpu: 1, Gpu: 2})
+	}
+}
+
+// TestGetTopPods checks that pods are filtered by node and namespace and sorted by the chosen resource.
+func TestGetTopPods(t *testing.T) {
+	pods := []PodJson{
+		{Name: "a", Namespace: "ml", Node: "node-7", Requests: ResourcesJson{Gpu: 1}},
+		{Name: "b", Namespace: "ml", Node: "node-7", Requests: ResourcesJson{Gpu: 4}},
+		{Name: "c", Namespace: "web", Node: "node-7", Requests: ResourcesJson{Gpu: 2}},
+		{Name: "d", Namespace: "ml", Node: "node-8", Requests: ResourcesJson{Gpu: 8}},
+	}
+
+	top := getTopPods(pods, "gpu", "node-7", "", 2)
+
+	if len(top) != 2 || top[0].Name != "b" || top[1].Name != "c" {
+		t.Fatalf(`top = %v, want pods b and c`, top)
+	}
+
+	top = getTopPods(pods, "gpu", "", "ml", 10)
+
+	if len(top) != 3 || top[0].Name != "d" || top[2].Name != "a" {
+		t.Fatalf(`top = %v, want pods d, b, and a`, top)
+	}
+}
+
+// TestGetPodOwner checks that pods owned by a Deployment's ReplicaSet are attributed to the Deployment, and that
+// other owners are returned as they are.
+func TestGetPodOwner(t *testing.T) {
+	controller := true
+
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "web-5d4f8b7c9-x2x4z",
+			Labels: map[string]string{"pod-template-hash": "5d4f8b7c9"},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: "web-5d4f8b7c9", Controller: &controller},
+			},
+		},
+	}
+
+	if owner := getPodOwner(&pod); owner == nil || *owner != (OwnerJson{Kind: "Deployment", Name: "web"}) {
+		t.Fatalf(`getPodOwner = %v, want match for %v`, owner, OwnerJson{Kind: "Deployment", Name: "web"})
+	}
+
+	pod.OwnerReferences[0] = metav1.OwnerReference{Kind: "StatefulSet", Name: "db", Controller: &controller}
+
+	if owner := getPodOwner(&pod); owner == nil || *owner != (OwnerJson{Kind: "StatefulSet", Name: "db"}) {
+		t.Fatalf(`getPodOwner = %v, want match for %v`, owner, OwnerJson{Kind: "StatefulSet", Name: "db"})
+	}
+
+	pod.OwnerReferences = nil
+
+	if owner := getPodOwner(&pod); owner != nil {
+		t.Fatalf(`getPodOwner = %v, want match for %v`, owner, nil)
+	}
+}
+
+// TestCollectorNodePods calls NodePods on a node that exists and one that doesn't, checking that a missing node
+// is reported as NotFound.
+func TestCollectorNodePods(t *testing.T) {
+	collector := newCollector(fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}))
+
+	if _, err := collector.NodePods("node-1"); err != nil {
+		t.Fatalf(`NodePods("node-1") returned error %v`, err)
+	}
+
+	if _, err := collector.NodePods("node-2"); !errors.IsNotFound(err) {
+		t.Fatalf(`NodePods("node-2") returned error %v, want NotFound`, err)
+	}
+}
+
+// TestStripPodFields checks that unused fields are cleared from pods while their requests and mirror pod
+// annotation are kept.
+func TestStripPodFields(t *testing.T) {
+	pods := []v1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          "pod",
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+			Annotations:   map[string]string{v1.LastAppliedConfigAnnotation: "{}", v1.MirrorPodAnnotationKey: "hash"},
+		},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{{Name: "data"}},
+			Containers: []v1.Container{{
+				Name:      "app",
+				Env:       []v1.EnvVar{{Name: "KEY", Value: "value"}},
+				Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+			}},
+		},
+	}}
+
+	stripPodFields(pods)
+	pod := pods[0]
+
+	switch {
+	case pod.ManagedFields != nil || pod.Spec.Volumes != nil || pod.Spec.Containers[0].Env != nil:
+		t.Fatalf(`pod = %v, want unused fields cleared`, pod)
+	case pod.Annotations[v1.LastAppliedConfigAnnotation] != "":
+		t.Fatalf(`annotations = %v, want match for %v`, pod.Annotations, "no last-applied-configuration")
+	case pod.Annotations[v1.MirrorPodAnnotationKey] != "hash":
+		t.Fatalf(`annotations = %v, want match for %v`, pod.Annotations, "the mirror pod annotation")
+	case pod.Spec.Containers[0].Resources.Requests.Cpu().String() != "1":
+		t.Fatalf(`requests = %v, want match for %v`, pod.Spec.Containers[0].Resources.Requests, "cpu: 1")
+	}
+}
+
+// TestPreparePods lists pods both across the cluster and node by node, checking that each way strips their unused
+// fields and applies LimitRange defaults.
+func TestPreparePods(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "vision"},
+			Spec: v1.LimitRangeSpec{
+				Limits: []v1.LimitRangeItem{{
+					Type:           v1.LimitTypeContainer,
+					DefaultRequest: v1.ResourceList{"cpu": resource.MustParse("500m")},
+				}},
+			},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "vision"},
+			Spec: v1.PodSpec{
+				NodeName:   "node-1",
+				Containers: []v1.Container{{Name: "besteffort", Env: []v1.EnvVar{{Name: "TOKEN", Value: "secret"}}}},
+			},
+		},
+	)
+	collector := newCollector(client)
+
+	listed, err := collector.listPods(nonTerminatedPodSelector)
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	nodesListed, err := collector.listNodesPods([]string{"node-1"})
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	for _, pods := range [][]v1.Pod{listed, nodesListed} {
+		if len(pods) != 1 {
+			t.Fatalf(`len(pods) = %v, want match for %v`, len(pods), 1)
+		}
+
+		container := pods[0].Spec.Containers[0]
+
+		switch {
+		case container.Env != nil:
+			t.Fatalf(`container.Env = %v, want match for %v`, container.Env, nil)
+		case container.Resources.Requests.Cpu().MilliValue() != 500:
+			t.Fatalf(`cpu request = %v, want match for %v`, container.Resources.Requests.Cpu(), "500m")
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// PrometheusClient runs queries against the HTTP API of a Prometheus-compatible server such as Thanos
+type PrometheusClient struct {
+	url    string
+	client *http.Client
+}
+
+// PromSeries is a single time series returned by a range query
+type PromSeries struct {
+	Metric map[string]string
+	Values []PromSample
+}
+
+// PromSample is a single value of a time series
+type PromSample struct {
+	Time  time.Time
+	Value float64
+}
+
+// promResponse is the body of a response from the Prometheus query and query_range APIs - instant queries
+// return a single value for each series, and range queries return a list of values
+type promResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+			Values [][]interface{}   `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// newPrometheusClient returns a PrometheusClient for the server at baseUrl.
+func newPrometheusClient(baseUrl string) *PrometheusClient {
+	return &PrometheusClient{
+		url:    baseUrl,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// QueryRange evaluates a PromQL query from from to to with a resolution of step, returning every resulting series.
+func (p *PrometheusClient) QueryRange(query string, from, to time.Time, step time.Duration) ([]PromSeries, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(from.Unix(), 10))
+	params.Set("end", strconv.FormatInt(to.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	return p.get("/api/v1/query_range", params)
+}
+
+// Query evaluates a PromQL query at a single time, returning every resulting series with one value each.
+func (p *PrometheusClient) Query(query string, at time.Time) ([]PromSeries, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(at.Unix(), 10))
+
+	return p.get("/api/v1/query", params)
+}
+
+// get sends a request to a Prometheus query API endpoint and parses the resulting series.
+func (p *PrometheusClient) get(endpoint string, params url.Values) ([]PromSeries, error) {
+	resp, err := p.client.Get(p.url + endpoint + "?" + params.Encode())
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var body promResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	if body.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %v", body.Error)
+	}
+
+	series := make([]PromSeries, 0, len(body.Data.Result))
+
+	for _, result := range body.Data.Result {
+		values := make([]PromSample, 0, len(result.Values))
+
+		// Instant queries have a single value instead of a list
+		pairs := result.Values
+		if result.Value != nil {
+			pairs = append(pairs, result.Value)
+		}
+
+		for _, pair := range pairs {
+			if sample, ok := parsePromSample(pair); ok {
+				values = append(values, sample)
+			}
+		}
+
+		series = append(series, PromSeries{Metric: result.Metric, Values: values})
+	}
+
+	return series, nil
+}
+
+// parsePromSample parses a pair of a Unix timestamp and a sample formatted as a string, as returned by the
+// Prometheus API. It returns false if the pair isn't in that format.
+func parsePromSample(pair []interface{}) (PromSample, bool) {
+	if len(pair) != 2 {
+		return PromSample{}, false
+	}
+
+	timestamp, ok := pair[0].(float64)
+	if !ok {
+		return PromSample{}, false
+	}
+
+	text, ok := pair[1].(string)
+	if !ok {
+		return PromSample{}, false
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+
+	if err != nil {
+		return PromSample{}, false
+	}
+
+	return PromSample{
+		Time:  time.Unix(0, int64(timestamp*float64(time.Second))).UTC(),
+		Value: value,
+	}, true
+}
+
+// PrometheusHistory serves node history from the kube-state-metrics series stored in Prometheus
+type PrometheusHistory struct {
+	client *PrometheusClient
+	step   time.Duration
+}
+
+// Resource label patterns used by kube-state-metrics for each of the resources we return
+var promResources = map[string]string{
+	"cpu":       "cpu",
+	"memory":    "memory",
+	"gpu":       "nvidia_com_.*",
+	"ephemeral": "ephemeral_storage",
+}
+
+// Query returns the history points of a node between from and to, one point per step.
+func (p *PrometheusHistory) Query(node string, from, to time.Time) ([]HistoryPoint, error) {
+	// Points keyed by Unix time so the results of the separate queries can be joined together
+	points := make(map[int64]*HistoryPoint)
+	order := make([]int64, 0)
+
+	for resource, pattern := range promResources {
+		selector := fmt.Sprintf(`{node=%q,resource=~%q}`, node, pattern)
+
+		queries := map[string]string{
+			"capacity":    "sum(kube_node_status_capacity" + selector + ")",
+			"allocatable": "sum(kube_node_status_allocatable" + selector + ")",
+			"free":        "sum(kube_node_status_allocatable" + selector + ") - (sum(kube_pod_container_resource_requests" + selector + ") or vector(0))",
+		}
+
+		for field, query := range queries {
+			series, err := p.client.QueryRange(query, from, to, p.step)
+
+			if err != nil {
+				return nil, err
+			}
+
+			for _, s := range series {
+				for _, sample := range s.Values {
+					key := sample.Time.Unix()
+
+					if _, ok := points[key]; !ok {
+						points[key] = &HistoryPoint{Time: sample.Time}
+						order = append(order, key)
+					}
+
+					var resources *ResourcesJson
+					switch field {
+					case "capacity":
+						resources = &points[key].Capacity
+					case "allocatable":
+						resources = &points[key].Allocatable
+					default:
+						resources = &points[key].Free
+					}
+
+					setResource(resources, resource, sample.Value)
+				}
+			}
+		}
+	}
+
+	slices.Sort(order)
+
+	result := make([]HistoryPoint, 0, len(order))
+	for _, key := range order {
+		result = append(result, *points[key])
+	}
+
+	return result, nil
+}
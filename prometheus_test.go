@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPrometheusHistory queries a fake Prometheus server through PrometheusHistory, checking that the separate
+// capacity, allocatable, and free series are joined into one point per timestamp.
+func TestPrometheusHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+
+		// Answer 8 for CPU capacity, 6 for allocatable CPU, 2 for free CPU, and nothing for the other resources
+		value := ""
+		switch {
+		case !strings.Contains(query, `resource=~"cpu"`):
+		case strings.HasPrefix(query, "sum(kube_node_status_capacity"):
+			value = "8"
+		case strings.Contains(query, "kube_pod_container_resource_requests"):
+			value = "2"
+		default:
+			value = "6"
+		}
+
+		if value == "" {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+			return
+		}
+
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1722470400,"` + value + `"],[1722470700,"` + value + `"]]}]}}`))
+	}))
+	defer server.Close()
+
+	history := PrometheusHistory{client: newPrometheusClient(server.URL), step: 5 * time.Minute}
+
+	start := time.Unix(1722470400, 0)
+	points, err := history.Query("node-1", start, start.Add(5*time.Minute))
+
+	if err != nil {
+		t.Fatalf(`Query returned error %v`, err)
+	}
+
+	if len(points) != 2 {
+		t.Fatalf(`len(points) = %v, want match for %v`, len(points), 2)
+	}
+
+	for i, point := range points {
+		switch {
+		case !point.Time.Equal(start.Add(time.Duration(i) * 5 * time.Minute)):
+			t.Fatalf(`points[%v].Time = %v, want match for %v`, i, point.Time, start.Add(time.Duration(i)*5*time.Minute))
+		case point.Capacity.Cpu != 8:
+			t.Fatalf(`points[%v].Capacity.Cpu = %v, want match for %v`, i, point.Capacity.Cpu, 8)
+		case point.Allocatable.Cpu != 6:
+			t.Fatalf(`points[%v].Allocatable.Cpu = %v, want match for %v`, i, point.Allocatable.Cpu, 6)
+		case point.Free.Cpu != 2:
+			t.Fatalf(`points[%v].Free.Cpu = %v, want match for %v`, i, point.Free.Cpu, 2)
+		}
+	}
+}
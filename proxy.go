@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trust forwarded headers from any address unless told otherwise, as gin does by default
+const defaultTrustedProxies = "0.0.0.0/0,::/0"
+
+// trustedProxies are the networks whose X-Forwarded-For and X-Forwarded-Proto headers are believed
+type trustedProxies []netip.Prefix
+
+// parseTrustedProxies parses a comma-separated list of IP addresses and CIDR ranges. An empty list trusts nothing.
+func parseTrustedProxies(value string) (trustedProxies, error) {
+	proxies := make(trustedProxies, 0)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %v", entry, err)
+			}
+
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", entry, err)
+		}
+
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+// Strings returns the networks in the form gin's SetTrustedProxies expects.
+func (p trustedProxies) Strings() []string {
+	values := make([]string, 0, len(p))
+	for _, prefix := range p {
+		values = append(values, prefix.String())
+	}
+
+	return values
+}
+
+// Trusts returns whether the request came directly from a trusted proxy.
+func (p trustedProxies) Trusts(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Scheme returns the scheme the client used to make a request - the first X-Forwarded-Proto value if the request
+// came from a trusted proxy, or else the scheme of the connection itself.
+func (p trustedProxies) Scheme(r *http.Request) string {
+	if p.Trusts(r) {
+		forwarded, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+		if forwarded = strings.ToLower(strings.TrimSpace(forwarded)); forwarded == "http" || forwarded == "https" {
+			return forwarded
+		}
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// getLogFormatter returns gin's default request log format with the scheme the client used added after its IP
+// address. The client IP already honors X-Forwarded-For from trusted proxies.
+func getLogFormatter(proxies trustedProxies) gin.LogFormatter {
+	return func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s | %-5s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			proxies.Scheme(param.Request),
+			methodColor, param.Method, resetColor,
+			param.Path,
+			param.ErrorMessage,
+		)
+	}
+}
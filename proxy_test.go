@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// TestTrustedProxies checks that X-Forwarded-Proto is only honored for requests from trusted networks, and that
+// invalid entries are rejected.
+func TestTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.0/8, 192.168.1.5")
+
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	for remote, want := range map[string]string{"10.1.2.3:5000": "https", "192.168.1.5:5000": "https", "192.168.1.6:5000": "http", "[::ffff:10.1.2.3]:5000": "https"} {
+		request := httptest.NewRequest("GET", "/nodes", nil)
+		request.RemoteAddr = remote
+		request.Header.Set("X-Forwarded-Proto", "https")
+
+		if got := proxies.Scheme(request); got != want {
+			t.Fatalf(`Scheme() from %v = %v, want match for %v`, remote, got, want)
+		}
+	}
+
+	if _, err := parseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Fatalf(`err = %v, want match for %v`, err, "an error")
+	}
+
+	if proxies, _ := parseTrustedProxies(""); len(proxies) != 0 {
+		t.Fatalf(`proxies = %v, want match for %v`, proxies, "no proxies")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Queues contains the queues of every batch scheduler installed in the cluster
+type Queues struct {
+	Kueue   []ClusterQueue `json:"kueue"`
+	Volcano []VolcanoQueue `json:"volcano"`
+}
+
+// Queues returns the queues of Kueue and Volcano, along with the free resources of the nodes they can use. Schedulers
+// that aren't installed have no queues.
+func (c *Collector) Queues() (*Queues, error) {
+	queues := &Queues{Kueue: []ClusterQueue{}, Volcano: []VolcanoQueue{}}
+
+	if c.dynamic == nil {
+		return queues, nil
+	}
+
+	snapshot, err := c.Snapshot()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if queues.Kueue, err = c.KueueQueues(snapshot.Nodes); err != nil {
+		return nil, err
+	}
+
+	if queues.Volcano, err = c.VolcanoQueues(snapshot.Nodes); err != nil {
+		return nil, err
+	}
+
+	return queues, nil
+}
+
+// getQueuesHandler returns a HandlerFunc to return the queues of every batch scheduler given a Collector.
+func getQueuesHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		queues, err := collector.Queues()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving queue information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, queues)
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QuotaUsage contains the hard limit and used amount of a single resource in a ResourceQuota
+type QuotaUsage struct {
+	Hard    float64 `json:"hard"`
+	Used    float64 `json:"used"`
+	Percent float64 `json:"percent"`
+}
+
+// QuotaJson contains the usage of every resource limited by a ResourceQuota, keyed by the resource name used
+// in the quota, e.g. requests.cpu or requests.nvidia.com/gpu
+type QuotaJson struct {
+	Name      string                `json:"name"`
+	Namespace string                `json:"namespace"`
+	Resources map[string]QuotaUsage `json:"resources"`
+}
+
+// Quotas returns every ResourceQuota in namespace, or in the whole cluster if namespace is empty.
+func (c *Collector) Quotas(namespace string) ([]QuotaJson, error) {
+	quotaList, err := c.client.CoreV1().ResourceQuotas(namespace).List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	quotas := make([]QuotaJson, 0, len(quotaList.Items))
+	for i := range quotaList.Items {
+		quotas = append(quotas, getQuotaStructured(&quotaList.Items[i]))
+	}
+
+	// Sort by namespace and then name so the order is stable
+	sort.Slice(quotas, func(i, j int) bool {
+		if quotas[i].Namespace != quotas[j].Namespace {
+			return quotas[i].Namespace < quotas[j].Namespace
+		}
+		return quotas[i].Name < quotas[j].Name
+	})
+
+	return quotas, nil
+}
+
+// getQuotaStructured takes a pointer to a ResourceQuota and returns a QuotaJson struct instance with the hard
+// limit, used amount, and percent used of each resource. Resources the quota controller hasn't counted yet
+// are reported as unused.
+func getQuotaStructured(quota *corev1.ResourceQuota) QuotaJson {
+	quotaJson := QuotaJson{
+		Name:      quota.Name,
+		Namespace: quota.Namespace,
+		Resources: make(map[string]QuotaUsage),
+	}
+
+	for name, hard := range quota.Status.Hard {
+		usage := QuotaUsage{Hard: hard.AsApproximateFloat64()}
+
+		if used, ok := quota.Status.Used[name]; ok {
+			usage.Used = used.AsApproximateFloat64()
+		}
+
+		if usage.Hard > 0 {
+			usage.Percent = usage.Used / usage.Hard * 100
+		}
+
+		quotaJson.Resources[string(name)] = usage
+	}
+
+	return quotaJson
+}
+
+// getQuotasHandler returns a HandlerFunc to return every ResourceQuota given a Collector. The quotas can be
+// filtered with the namespace query parameter.
+func getQuotasHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		quotas, err := collector.Quotas(c.Query("namespace"))
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving ResourceQuota information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, quotas)
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestGetQuotaStructured calls getQuotaStructured on a quota with a resource the controller hasn't counted yet
+// and a zero hard limit, checking the used amounts and percentages.
+func TestGetQuotaStructured(t *testing.T) {
+	quota := &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "vision"},
+		Status: v1.ResourceQuotaStatus{
+			Hard: v1.ResourceList{
+				"requests.cpu":            resource.MustParse("8"),
+				"requests.memory":         resource.MustParse("16Gi"),
+				"requests.nvidia.com/gpu": resource.MustParse("0"),
+			},
+			Used: v1.ResourceList{
+				"requests.cpu":            resource.MustParse("2500m"),
+				"requests.nvidia.com/gpu": resource.MustParse("0"),
+			},
+		},
+	}
+
+	want := map[string]QuotaUsage{
+		"requests.cpu":            {Hard: 8, Used: 2.5, Percent: 31.25},
+		"requests.memory":         {Hard: 16 << 30, Used: 0, Percent: 0},
+		"requests.nvidia.com/gpu": {Hard: 0, Used: 0, Percent: 0},
+	}
+
+	quotaJson := getQuotaStructured(quota)
+
+	if quotaJson.Name != "compute" || quotaJson.Namespace != "vision" || len(quotaJson.Resources) != len(want) {
+		t.Fatalf(`getQuotaStructured() = %v, want %v resources in vision/compute`, quotaJson, len(want))
+	}
+
+	for name, usage := range want {
+		if quotaJson.Resources[name] != usage {
+			t.Fatalf(`quotaJson.Resources[%q] = %v, want match for %v`, name, quotaJson.Resources[name], usage)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Period over which changes of each node's Ready condition are counted as flaps
+const readinessFlapWindow = time.Hour
+
+// NodeReadiness contains the state of a node's Ready condition and how often it has changed recently
+type NodeReadiness struct {
+	Ready              bool      `json:"ready"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+	Flaps              int       `json:"flaps"` // Changes of the Ready condition seen in the last readinessFlapWindow
+}
+
+// nodeReadinessHistory contains the Ready condition changes seen for one node
+type nodeReadinessHistory struct {
+	last        time.Time   // Last transition time of the condition in the previous snapshot
+	transitions []time.Time // Transition times seen since, oldest first
+}
+
+// ReadinessTracker remembers the Ready condition of every node between snapshots, counting how often it changes so
+// that unstable nodes can be told apart from ones that were briefly NotReady once. Only changes seen after the API
+// starts are counted, and a node that flaps more than once between two snapshots is only counted once.
+type ReadinessTracker struct {
+	mutex     sync.Mutex
+	histories map[string]*nodeReadinessHistory
+}
+
+// newReadinessTracker returns a ReadinessTracker that hasn't seen any nodes.
+func newReadinessTracker() *ReadinessTracker {
+	return &ReadinessTracker{histories: make(map[string]*nodeReadinessHistory)}
+}
+
+// getReadyCondition returns the Ready condition of a node, or nil if its kubelet hasn't reported one.
+func getReadyCondition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == corev1.NodeReady {
+			return node.Status.Conditions[i].DeepCopy()
+		}
+	}
+
+	return nil
+}
+
+// Update records the Ready condition of each node in a map of Node instances at now, forgetting nodes that no longer
+// exist, and returns the readiness of every node with a Ready condition, keyed by node name.
+func (t *ReadinessTracker) Update(nodes map[string]*Node, now time.Time) map[string]NodeReadiness {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	readiness := make(map[string]NodeReadiness, len(nodes))
+
+	for name := range t.histories {
+		if _, ok := nodes[name]; !ok {
+			delete(t.histories, name)
+		}
+	}
+
+	for name, node := range nodes {
+		if node.Ready == nil {
+			continue
+		}
+
+		transition := node.Ready.LastTransitionTime.Time
+		history, ok := t.histories[name]
+
+		// The first transition seen is where the history starts, since it may have happened long before
+		if !ok {
+			history = &nodeReadinessHistory{last: transition, transitions: make([]time.Time, 0)}
+			t.histories[name] = history
+		} else if transition.After(history.last) {
+			history.last = transition
+			history.transitions = append(history.transitions, transition)
+		}
+
+		// Forget transitions that have left the window
+		for len(history.transitions) > 0 && now.Sub(history.transitions[0]) > readinessFlapWindow {
+			history.transitions = history.transitions[1:]
+		}
+
+		readiness[name] = NodeReadiness{
+			Ready:              node.Ready.Status == corev1.ConditionTrue,
+			LastTransitionTime: transition,
+			Flaps:              len(history.transitions),
+		}
+	}
+
+	return readiness
+}
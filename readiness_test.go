@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestReadinessTracker updates a tracker with a node that becomes NotReady and Ready again, checking that only
+// transitions after the first snapshot count as flaps and that they stop counting once they leave the window.
+func TestReadinessTracker(t *testing.T) {
+	start := time.Now()
+	tracker := newReadinessTracker()
+
+	update := func(status corev1.ConditionStatus, transition, now time.Time) NodeReadiness {
+		ready := &corev1.NodeCondition{Type: corev1.NodeReady, Status: status, LastTransitionTime: metav1.NewTime(transition)}
+		nodes := map[string]*Node{"node-1": {Name: "node-1", Ready: ready}, "node-2": {Name: "node-2"}}
+
+		readiness := tracker.Update(nodes, now)
+
+		if _, ok := readiness["node-2"]; ok {
+			t.Fatalf(`Update() readiness of a node without a Ready condition = %v, want none`, readiness["node-2"])
+		}
+
+		return readiness["node-1"]
+	}
+
+	// The node became Ready a day ago, before the API started
+	if readiness := update(corev1.ConditionTrue, start.Add(-24*time.Hour), start); !readiness.Ready || readiness.Flaps != 0 {
+		t.Fatalf(`Update() = %v, want a Ready node with no flaps`, readiness)
+	}
+
+	notReady := start.Add(time.Minute)
+	if readiness := update(corev1.ConditionFalse, notReady, start.Add(2*time.Minute)); readiness.Ready || readiness.Flaps != 1 || !readiness.LastTransitionTime.Equal(notReady) {
+		t.Fatalf(`Update() = %v, want a NotReady node with 1 flap at %v`, readiness, notReady)
+	}
+
+	ready := start.Add(3 * time.Minute)
+	if readiness := update(corev1.ConditionTrue, ready, start.Add(4*time.Minute)); !readiness.Ready || readiness.Flaps != 2 {
+		t.Fatalf(`Update() = %v, want a Ready node with 2 flaps`, readiness)
+	}
+
+	// An unchanged condition isn't another flap
+	if readiness := update(corev1.ConditionTrue, ready, start.Add(5*time.Minute)); readiness.Flaps != 2 {
+		t.Fatalf(`Update() of an unchanged node = %v, want 2 flaps`, readiness)
+	}
+
+	if readiness := update(corev1.ConditionTrue, ready, start.Add(2*time.Hour)); readiness.Flaps != 0 {
+		t.Fatalf(`Update() after the window = %v, want no flaps`, readiness)
+	}
+}
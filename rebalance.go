@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Reasons a pod move is suggested
+const (
+	moveReasonOverpacked  = "overpacked"  // The pod's node has more of a resource requested than the high threshold
+	moveReasonConsolidate = "consolidate" // Every pod on the pod's node can move elsewhere, leaving it empty
+)
+
+// PodMove is a suggestion to move a pod from one node to another
+type PodMove struct {
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace"`
+	Owner     *OwnerJson    `json:"owner,omitempty"`
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Requests  ResourcesJson `json:"requests"`
+	Reason    string        `json:"reason"`
+}
+
+// RebalanceReport contains the pod moves that would relieve overpacked nodes and empty underused ones
+type RebalanceReport struct {
+	Moves        []PodMove `json:"moves"`
+	EmptiedNodes []string  `json:"emptiedNodes"`
+}
+
+// pdbBudget is the number of disruptions a PodDisruptionBudget has left to give
+type pdbBudget struct {
+	namespace string
+	selector  labels.Selector
+	remaining int32
+}
+
+// rebalancePod is an evictable pod and its requests
+type rebalancePod struct {
+	pod      *corev1.Pod
+	requests ResourcesJson
+	budgets  []int // Indices of the PodDisruptionBudgets selecting the pod
+}
+
+// getUtilization returns the largest fraction of a node's CPU, memory, or GPUs that is requested, ignoring
+// resources it doesn't have any of.
+func getUtilization(allocatable, free ResourcesJson) float64 {
+	utilization := 0.0
+
+	for _, resource := range []string{"cpu", "memory", "gpu"} {
+		total := getResource(allocatable, resource)
+		if total <= 0 {
+			continue
+		}
+
+		utilization = max(utilization, (total-getResource(free, resource))/total)
+	}
+
+	return utilization
+}
+
+// rebalancer tracks the free resources of the nodes and the disruptions left to PodDisruptionBudgets as pod
+// moves are planned
+type rebalancer struct {
+	nodes       []NodeJson // Sorted by name
+	allocatable map[string]ResourcesJson
+	free        map[string]ResourcesJson
+	budgets     []pdbBudget
+	high        float64
+}
+
+// utilization returns the utilization of the named node after the moves planned so far.
+func (r *rebalancer) utilization(name string) float64 {
+	return getUtilization(r.allocatable[name], r.free[name])
+}
+
+// findDestination returns the node other than from and those in exclude that a pod tolerates and matches, that
+// has room for it, and that stays at or under the high threshold with it. Of those, the least utilized node is
+// returned to spread load, or the most utilized if pack is set. If there is none, an empty string is returned.
+func (r *rebalancer) findDestination(entry rebalancePod, from string, exclude map[string]bool, pack bool) string {
+	destination := ""
+	best := 0.0
+
+	for _, node := range r.nodes {
+		if node.Name == from || exclude[node.Name] || !canSchedule(entry.pod, node) || !fitsIn(entry.requests, r.free[node.Name]) {
+			continue
+		}
+
+		if getUtilization(r.allocatable[node.Name], subtractResources(r.free[node.Name], entry.requests)) > r.high {
+			continue
+		}
+
+		current := r.utilization(node.Name)
+		if destination == "" || (pack && current > best) || (!pack && current < best) {
+			destination = node.Name
+			best = current
+		}
+	}
+
+	return destination
+}
+
+// allowed returns whether every PodDisruptionBudget selecting a pod has a disruption left.
+func (r *rebalancer) allowed(entry rebalancePod) bool {
+	for _, i := range entry.budgets {
+		if r.budgets[i].remaining <= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// move plans moving a pod from one node to another, using up a disruption of each PodDisruptionBudget selecting it.
+func (r *rebalancer) move(entry rebalancePod, from, to, reason string) PodMove {
+	r.free[from] = addResources(r.free[from], entry.requests)
+	r.free[to] = subtractResources(r.free[to], entry.requests)
+
+	for _, i := range entry.budgets {
+		r.budgets[i].remaining--
+	}
+
+	podJson := getPodStructured(entry.pod)
+
+	return PodMove{
+		Name:      podJson.Name,
+		Namespace: podJson.Namespace,
+		Owner:     podJson.Owner,
+		From:      from,
+		To:        to,
+		Requests:  entry.requests,
+		Reason:    reason,
+	}
+}
+
+// clone returns a copy of the rebalancer whose moves don't affect the original.
+func (r *rebalancer) clone() *rebalancer {
+	clone := *r
+	clone.free = maps.Clone(r.free)
+	clone.budgets = slices.Clone(r.budgets)
+	return &clone
+}
+
+// getRebalanceSuggestions suggests pod moves in two passes. First, pods are moved off nodes whose utilization is
+// above high, largest first, until they are back under it. Then, starting from the least utilized node under low,
+// each node whose pods can all move elsewhere is emptied. Pods only move to nodes they tolerate and match, that
+// have room for them, and that stay at or under high, and only while every PodDisruptionBudget selecting them has
+// disruptions left. Nodes that pods have already moved on or off of are never emptied, so no pod is moved twice.
+func getRebalanceSuggestions(nodes []NodeJson, pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget, high, low float64) RebalanceReport {
+	report := RebalanceReport{Moves: make([]PodMove, 0), EmptiedNodes: make([]string, 0)}
+
+	r := &rebalancer{
+		nodes:       append([]NodeJson(nil), nodes...),
+		allocatable: make(map[string]ResourcesJson),
+		free:        make(map[string]ResourcesJson),
+		budgets:     make([]pdbBudget, 0, len(pdbs)),
+		high:        high,
+	}
+
+	sort.Slice(r.nodes, func(i, j int) bool {
+		return r.nodes[i].Name < r.nodes[j].Name
+	})
+
+	for _, node := range r.nodes {
+		r.allocatable[node.Name] = node.Allocatable
+		r.free[node.Name] = node.Free
+	}
+
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+
+		r.budgets = append(r.budgets, pdbBudget{namespace: pdb.Namespace, selector: selector, remaining: pdb.Status.DisruptionsAllowed})
+	}
+
+	nodePods := make(map[string][]rebalancePod)
+
+	for i := range pods {
+		pod := &pods[i]
+		if _, ok := r.free[pod.Spec.NodeName]; !ok || !isEvictable(pod) {
+			continue
+		}
+
+		entry := rebalancePod{pod: pod, requests: getPodStructured(pod).Requests}
+		for j, budget := range r.budgets {
+			if budget.namespace == pod.Namespace && budget.selector.Matches(labels.Set(pod.Labels)) {
+				entry.budgets = append(entry.budgets, j)
+			}
+		}
+
+		nodePods[pod.Spec.NodeName] = append(nodePods[pod.Spec.NodeName], entry)
+	}
+
+	// Most utilized first, so the worst nodes get the emptiest destinations
+	overpacked := make([]string, 0)
+	for _, node := range r.nodes {
+		if r.utilization(node.Name) > high {
+			overpacked = append(overpacked, node.Name)
+		}
+	}
+
+	sort.SliceStable(overpacked, func(i, j int) bool {
+		return r.utilization(overpacked[i]) > r.utilization(overpacked[j])
+	})
+
+	touched := make(map[string]bool)
+
+	for _, name := range overpacked {
+		entries := nodePods[name]
+		sort.SliceStable(entries, func(i, j int) bool {
+			a := getUtilization(r.allocatable[name], subtractResources(r.allocatable[name], entries[i].requests))
+			b := getUtilization(r.allocatable[name], subtractResources(r.allocatable[name], entries[j].requests))
+			return a > b
+		})
+
+		for _, entry := range entries {
+			if r.utilization(name) <= high {
+				break
+			}
+
+			if !r.allowed(entry) {
+				continue
+			}
+
+			destination := r.findDestination(entry, name, nil, false)
+			if destination == "" {
+				continue
+			}
+
+			report.Moves = append(report.Moves, r.move(entry, name, destination, moveReasonOverpacked))
+			touched[name] = true
+			touched[destination] = true
+		}
+	}
+
+	// Least utilized first, since they have the fewest pods to move. Pods are packed onto the most utilized nodes
+	// that have room, leaving the rest free to be emptied too.
+	underused := make([]string, 0)
+	for _, node := range r.nodes {
+		if len(nodePods[node.Name]) > 0 && !touched[node.Name] && r.utilization(node.Name) < low {
+			underused = append(underused, node.Name)
+		}
+	}
+
+	sort.SliceStable(underused, func(i, j int) bool {
+		return r.utilization(underused[i]) < r.utilization(underused[j])
+	})
+
+	emptied := make(map[string]bool)
+
+	for _, name := range underused {
+		if touched[name] {
+			continue
+		}
+
+		// Try moving every pod off the node, only keeping the moves if they all succeed
+		tentative := r.clone()
+		moves := make([]PodMove, 0)
+
+		for _, entry := range nodePods[name] {
+			if !tentative.allowed(entry) {
+				break
+			}
+
+			destination := tentative.findDestination(entry, name, emptied, true)
+			if destination == "" {
+				break
+			}
+
+			moves = append(moves, tentative.move(entry, name, destination, moveReasonConsolidate))
+		}
+
+		if len(moves) != len(nodePods[name]) {
+			continue
+		}
+
+		r = tentative
+		emptied[name] = true
+
+		for _, podMove := range moves {
+			touched[podMove.To] = true
+		}
+
+		report.Moves = append(report.Moves, moves...)
+		report.EmptiedNodes = append(report.EmptiedNodes, name)
+	}
+
+	return report
+}
+
+// getRebalanceHandler returns a HandlerFunc to return suggested pod moves given a Collector. Nodes are overpacked
+// above the high query parameter and underused below the low query parameter, as fractions of their allocatable
+// resources.
+func getRebalanceHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		high, err := strconv.ParseFloat(c.DefaultQuery("high", "0.9"), 64)
+
+		if err != nil || high <= 0 || high > 1 {
+			c.JSON(http.StatusBadRequest, "error: high must be a number between 0 and 1")
+			return
+		}
+
+		low, err := strconv.ParseFloat(c.DefaultQuery("low", "0.3"), 64)
+
+		if err != nil || low < 0 || low >= high {
+			c.JSON(http.StatusBadRequest, "error: low must be a number between 0 and high")
+			return
+		}
+
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		pods, err := collector.listPods(nonTerminatedPodSelector)
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		pdbs, err := collector.PodDisruptionBudgets("")
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving PodDisruptionBudget information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getRebalanceSuggestions(snapshot.Nodes, pods, pdbs, high, low))
+	}
+
+	return gin.HandlerFunc(handler)
+}
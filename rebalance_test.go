@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestGetRebalanceSuggestions relieves an overpacked node whose largest pod is held by a PodDisruptionBudget, then
+// empties an underused node onto the most utilized node with room.
+func TestGetRebalanceSuggestions(t *testing.T) {
+	nodes := []NodeJson{
+		{Name: "hot", Allocatable: ResourcesJson{Cpu: 10}, Free: ResourcesJson{Cpu: 0.5}},
+		{Name: "warm", Allocatable: ResourcesJson{Cpu: 10}, Free: ResourcesJson{Cpu: 5}},
+		{Name: "idle", Allocatable: ResourcesJson{Cpu: 10}, Free: ResourcesJson{Cpu: 9}},
+		{Name: "spare", Allocatable: ResourcesJson{Cpu: 10}, Free: ResourcesJson{Cpu: 7}},
+	}
+
+	podOn := func(name, cpu, node string, labels map[string]string) corev1.Pod {
+		pod := newTestPod(name, cpu, labels)
+		pod.Spec.NodeName = node
+		return pod
+	}
+
+	controller := true
+	daemon := podOn("daemon", "1", "spare", nil)
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "daemon", Controller: &controller}}
+
+	pods := []corev1.Pod{
+		podOn("web", "4", "hot", map[string]string{"app": "web"}),
+		podOn("batch", "3", "hot", nil),
+		podOn("cache", "2.5", "hot", nil),
+		podOn("db", "5", "warm", nil),
+		podOn("cron", "1", "idle", nil),
+		podOn("report", "2", "spare", nil),
+		daemon,
+	}
+
+	pdbs := []policyv1.PodDisruptionBudget{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}}
+
+	result := getRebalanceSuggestions(nodes, pods, pdbs, 0.9, 0.35)
+
+	want := []PodMove{
+		{Name: "batch", From: "hot", To: "idle", Reason: moveReasonOverpacked},
+		{Name: "report", From: "spare", To: "hot", Reason: moveReasonConsolidate},
+	}
+
+	if len(result.Moves) != len(want) {
+		t.Fatalf(`result.Moves = %v, want match for %v`, result.Moves, want)
+	}
+
+	for i, move := range result.Moves {
+		if move.Name != want[i].Name || move.From != want[i].From || move.To != want[i].To || move.Reason != want[i].Reason {
+			t.Fatalf(`result.Moves[%v] = %v, want match for %v`, i, move, want[i])
+		}
+	}
+
+	if len(result.EmptiedNodes) != 1 || result.EmptiedNodes[0] != "spare" {
+		t.Fatalf(`result.EmptiedNodes = %v, want match for %v`, result.EmptiedNodes, []string{"spare"})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// RecordedFrame contains the raw nodes, pods, and LimitRanges of the cluster at a point in time - everything the
+// free resources of nodes are worked out from
+type RecordedFrame struct {
+	Time        time.Time           `json:"time"`
+	Nodes       []corev1.Node       `json:"nodes"`
+	Pods        []corev1.Pod        `json:"pods"`
+	LimitRanges []corev1.LimitRange `json:"limitRanges"`
+}
+
+// Recorder appends the state of the cluster to a file, one RecordedFrame per line, so that it can be replayed
+// somewhere without access to the cluster
+type Recorder struct {
+	client kubernetes.Interface
+	path   string
+}
+
+// newRecorder returns a Recorder that reads the cluster through client and appends to the file at path.
+func newRecorder(client kubernetes.Interface, path string) *Recorder {
+	return &Recorder{client: client, path: path}
+}
+
+// Record appends the current state of the cluster to the recording. Pods are stripped of the fields that aren't
+// used, which also keeps their environments, which can hold secrets, out of the recording.
+func (r *Recorder) Record() error {
+	nodes, err := r.client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return err
+	}
+
+	pods, err := r.client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{FieldSelector: nonTerminatedPodSelector})
+
+	if err != nil {
+		return err
+	}
+
+	limitRanges, err := newCollector(r.client).LimitRanges("")
+
+	if err != nil {
+		return err
+	}
+
+	// Node images and managedFields are large and never used
+	for i := range nodes.Items {
+		nodes.Items[i].ManagedFields = nil
+		nodes.Items[i].Status.Images = nil
+	}
+
+	stripPodFields(pods.Items)
+
+	data, err := json.Marshal(RecordedFrame{Time: time.Now(), Nodes: nodes.Items, Pods: pods.Items, LimitRanges: limitRanges})
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// readRecording reads every frame of the recording at path, in the order they were recorded.
+func readRecording(path string) ([]RecordedFrame, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	frames := make([]RecordedFrame, 0)
+	decoder := json.NewDecoder(file)
+
+	for {
+		var frame RecordedFrame
+
+		if err := decoder.Decode(&frame); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading frame %v of %v: %w", len(frames)+1, path, err)
+		}
+
+		frames = append(frames, frame)
+	}
+
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("recording %v has no frames", path)
+	}
+
+	return frames, nil
+}
+
+// Replay serves a recording of a cluster through a fake client, one frame at a time
+type Replay struct {
+	frames  []RecordedFrame
+	current atomic.Int64 // Index of the frame being served
+}
+
+// newReplayClient returns a client that serves the first frame of the recording at path, along with the Replay
+// to advance through the rest of the frames with.
+func newReplayClient(path string) (kubernetes.Interface, *Replay, error) {
+	frames, err := readRecording(path)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replay := &Replay{frames: frames}
+	client := fake.NewSimpleClientset()
+
+	// Objects are copied out of the frame, since the collector changes the pods it lists
+
+	client.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, (&corev1.NodeList{Items: replay.frame().Nodes}).DeepCopy(), nil
+	})
+
+	client.PrependReactor("get", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.GetAction).GetName()
+
+		for _, node := range replay.frame().Nodes {
+			if node.Name == name {
+				return true, node.DeepCopy(), nil
+			}
+		}
+
+		return true, nil, apierrors.NewNotFound(corev1.Resource("nodes"), name)
+	})
+
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pods := filterPodsByFields(replay.frame().Pods, action.GetNamespace(), action.(k8stesting.ListAction).GetListRestrictions().Fields)
+		return true, (&corev1.PodList{Items: pods}).DeepCopy(), nil
+	})
+
+	client.PrependReactor("list", "limitranges", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		list := &corev1.LimitRangeList{}
+
+		for _, limitRange := range replay.frame().LimitRanges {
+			if action.GetNamespace() == "" || limitRange.Namespace == action.GetNamespace() {
+				list.Items = append(list.Items, limitRange)
+			}
+		}
+
+		return true, list.DeepCopy(), nil
+	})
+
+	return client, replay, nil
+}
+
+// frame returns the frame being served.
+func (r *Replay) frame() *RecordedFrame {
+	return &r.frames[r.current.Load()]
+}
+
+// Run advances through the frames of the recording, keeping each for as long as it lasted when it was recorded, and
+// stops at the last frame. It blocks until then, so it should be run in its own goroutine.
+func (r *Replay) Run() {
+	for i := 1; i < len(r.frames); i++ {
+		time.Sleep(r.frames[i].Time.Sub(r.frames[i-1].Time))
+		r.current.Store(int64(i))
+
+		fmt.Printf("replaying frame %v of %v, recorded at %v\n", i+1, len(r.frames), r.frames[i].Time.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestRecordReplay records a generated cluster and replays it, checking that the replayed nodes are the same as the
+// recorded ones and that replaying can advance to the next frame.
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	client, _ := newDemoClients(6, 60, 1)
+	recorder := newRecorder(client, path)
+
+	for i := 0; i < 2; i++ {
+		if err := recorder.Record(); err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+	}
+
+	recorded, err := newCollector(client).takeSnapshot()
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	replayClient, replay, err := newReplayClient(path)
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	replay.Run()
+
+	replayed, err := newCollector(replayClient).takeSnapshot()
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	byName := func(a, b NodeJson) int { return strings.Compare(a.Name, b.Name) }
+	slices.SortFunc(recorded.Nodes, byName)
+	slices.SortFunc(replayed.Nodes, byName)
+
+	switch {
+	case len(replay.frames) != 2 || replay.current.Load() != 1:
+		t.Fatalf(`frame = %v of %v, want match for %v of %v`, replay.current.Load()+1, len(replay.frames), 2, 2)
+	case !reflect.DeepEqual(replayed.Nodes, recorded.Nodes):
+		t.Fatalf(`replayed.Nodes = %v, want match for %v`, replayed.Nodes, recorded.Nodes)
+	}
+}
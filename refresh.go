@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshResult describes the snapshot taken by POST /refresh
+type RefreshResult struct {
+	Version uint64    `json:"version"`
+	Time    time.Time `json:"time"`
+	Nodes   int       `json:"nodes"`
+	Pods    int       `json:"pods"`
+}
+
+// Refresh takes a new snapshot of the cluster right away, replacing the snapshot shared between replicas if there is
+// one, and makes every CachedSnapshot of the Collector take a new snapshot the next time it is used.
+func (c *Collector) Refresh() (*Snapshot, error) {
+	snapshot, err := c.freshSnapshot()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c.shared != nil {
+		if err := c.shared.Store(snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot.Version = c.tracker.Update(snapshot.Nodes)
+	c.refreshes.Add(1)
+
+	return snapshot, nil
+}
+
+// getRefreshHandler returns a HandlerFunc to take a new snapshot with a Collector, for operators who have just
+// changed the cluster and don't want to wait for cached snapshots to expire.
+func getRefreshHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		snapshot, err := collector.Refresh()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error refreshing node information")
+			return
+		}
+
+		c.Header(snapshotVersionHeader, strconv.FormatUint(snapshot.Version, 10))
+		c.IndentedJSON(http.StatusOK, RefreshResult{
+			Version: snapshot.Version,
+			Time:    snapshot.Time,
+			Nodes:   len(snapshot.Nodes),
+			Pods:    len(snapshot.Pods),
+		})
+	}
+
+	return gin.HandlerFunc(handler)
+}
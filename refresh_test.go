@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestRefresh adds a node after a snapshot has been cached, checking that the cached snapshot is only replaced once
+// the collector is refreshed through POST /refresh, and that the endpoint needs the admin token.
+func TestRefresh(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+	collector := newCollector(client)
+	cached := newCachedSnapshot(collector, "test", time.Hour)
+
+	if snapshot, err := cached.Get(); err != nil || len(snapshot.Nodes) != 1 {
+		t.Fatalf(`Get() = %v, %v, want 1 node`, snapshot, err)
+	}
+
+	client.CoreV1().Nodes().Create(context.Background(), &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}}, metav1.CreateOptions{})
+
+	if snapshot, _ := cached.Get(); len(snapshot.Nodes) != 1 {
+		t.Fatalf(`Get() before refreshing = %v nodes, want match for %v`, len(snapshot.Nodes), 1)
+	}
+
+	router := gin.New()
+	router.POST("/refresh", requireToken("secret"), getRefreshHandler(collector))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/refresh", nil))
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf(`POST /refresh without a token = %v, want match for %v`, recorder.Code, http.StatusUnauthorized)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var result RefreshResult
+	json.Unmarshal(recorder.Body.Bytes(), &result)
+
+	switch {
+	case recorder.Code != http.StatusOK:
+		t.Fatalf(`POST /refresh = %v, want match for %v`, recorder.Code, http.StatusOK)
+	case result.Version != 2 || result.Nodes != 2 || recorder.Header().Get(snapshotVersionHeader) != "2":
+		t.Fatalf(`POST /refresh = %v, want version 2 with 2 nodes`, result)
+	}
+
+	if snapshot, _ := cached.Get(); len(snapshot.Nodes) != 2 {
+		t.Fatalf(`Get() after refreshing = %v nodes, want match for %v`, len(snapshot.Nodes), 2)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Largest template that can be sent to /render
+const maxTemplateSize = 1 << 20
+
+// renderTemplate is a parsed text or HTML template
+type renderTemplate interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// storedTemplate is a template loaded at startup and the content type of its output
+type storedTemplate struct {
+	template    renderTemplate
+	contentType string
+}
+
+// RenderData is what templates are applied to, with the same field names as the JSON endpoints
+type RenderData struct {
+	Time    string         `json:"time"`
+	Nodes   []NodeJson     `json:"nodes"` // Sorted by name
+	Pods    []PodJson      `json:"pods"`
+	Pools   []NodePool     `json:"pools"`
+	Summary ClusterSummary `json:"summary"`
+}
+
+// Functions available to templates. Numbers may be float64 or json.Number, as they are in the data.
+var renderFuncs = map[string]interface{}{
+	// gib formats a number of bytes as GiB with one decimal place
+	"gib": func(value interface{}) (string, error) {
+		bytes, err := toFloat(value)
+		return strconv.FormatFloat(bytes/(1<<30), 'f', 1, 64), err
+	},
+	// percent formats part as a whole percentage of total, or 0 if total is 0
+	"percent": func(part, total interface{}) (string, error) {
+		a, err := toFloat(part)
+		if err != nil {
+			return "", err
+		}
+
+		b, err := toFloat(total)
+		if err != nil || b == 0 {
+			return "0", err
+		}
+
+		return strconv.FormatFloat(a/b*100, 'f', 0, 64), nil
+	},
+	// sub subtracts one number from another
+	"sub": func(a, b interface{}) (float64, error) {
+		x, err := toFloat(a)
+		if err != nil {
+			return 0, err
+		}
+
+		y, err := toFloat(b)
+		return x - y, err
+	},
+}
+
+// toFloat converts a number in template data to a float64.
+func toFloat(value interface{}) (float64, error) {
+	switch number := value.(type) {
+	case json.Number:
+		return number.Float64()
+	case float64:
+		return number, nil
+	case int:
+		return float64(number), nil
+	}
+
+	return 0, fmt.Errorf("expected a number, got %v", value)
+}
+
+// parseRenderTemplate parses a template, as HTML if html is set so that values are escaped, or else as text.
+func parseRenderTemplate(name, text string, html bool) (renderTemplate, error) {
+	if html {
+		return htmltemplate.New(name).Funcs(renderFuncs).Parse(text)
+	}
+
+	return texttemplate.New(name).Funcs(renderFuncs).Parse(text)
+}
+
+// loadStoredTemplates parses every template in dir, keyed by file name without its extension. Files ending in .html
+// are HTML templates and the rest are text templates, such as Markdown.
+func loadStoredTemplates(dir string) (map[string]storedTemplate, error) {
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]storedTemplate)
+
+	for _, entry := range entries {
+		// ConfigMap volumes contain hidden directories and symlinks to them, which are skipped
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		text, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		extension := filepath.Ext(entry.Name())
+		name := strings.TrimSuffix(entry.Name(), extension)
+		html := extension == ".html"
+
+		parsed, err := parseRenderTemplate(name, string(text), html)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contentType := "text/plain; charset=utf-8"
+		if html {
+			contentType = "text/html; charset=utf-8"
+		}
+
+		templates[name] = storedTemplate{template: parsed, contentType: contentType}
+	}
+
+	return templates, nil
+}
+
+// getRenderData returns the data templates are applied to for a snapshot, converted to plain maps and slices keyed
+// by JSON field names as kubectl's go-template output does. Numbers are kept as json.Number so they print exactly.
+func getRenderData(snapshot *Snapshot) (interface{}, error) {
+	nodes := append([]NodeJson(nil), snapshot.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	encoded, err := json.Marshal(RenderData{
+		Time:    snapshot.Time.Format(time.RFC3339),
+		Nodes:   nodes,
+		Pods:    snapshot.Pods,
+		Pools:   getNodePools(snapshot.Nodes),
+		Summary: getClusterSummary(snapshot.Nodes),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	var data interface{}
+	err = decoder.Decode(&data)
+
+	return data, err
+}
+
+// writeRendered applies a template to a new snapshot and writes the output, or a 400 error if the template fails.
+func writeRendered(c *gin.Context, collector *Collector, template renderTemplate, contentType string) {
+	snapshot, err := collector.Snapshot()
+
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, "error retrieving node information")
+		return
+	}
+
+	data, err := getRenderData(snapshot)
+
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, "error retrieving node information")
+		return
+	}
+
+	// Render into a buffer first so a failing template doesn't leave a partial response
+	var output bytes.Buffer
+
+	if err := template.Execute(&output, data); err != nil {
+		c.JSON(http.StatusBadRequest, "error: "+err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, output.Bytes())
+}
+
+// getRenderStoredHandler returns a HandlerFunc to apply the stored template named by the name path parameter to
+// the current snapshot given a Collector.
+func getRenderStoredHandler(collector *Collector, templates map[string]storedTemplate) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		stored, ok := templates[c.Param("name")]
+
+		if !ok {
+			c.JSON(http.StatusNotFound, "error: no template named "+c.Param("name"))
+			return
+		}
+
+		writeRendered(c, collector, stored.template, stored.contentType)
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getRenderHandler returns a HandlerFunc to apply the template in the request body to the current snapshot given a
+// Collector. The template is treated as HTML if the format query parameter is html, or else as text.
+func getRenderHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		text, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTemplateSize+1))
+
+		if err != nil || len(text) == 0 || len(text) > maxTemplateSize {
+			c.JSON(http.StatusBadRequest, "error: expected a template of at most 1 MiB in the request body")
+			return
+		}
+
+		html := c.Query("format") == "html"
+
+		template, err := parseRenderTemplate("request", string(text), html)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		contentType := "text/plain; charset=utf-8"
+		if html {
+			contentType = "text/html; charset=utf-8"
+		}
+
+		writeRendered(c, collector, template, contentType)
+	}
+
+	return gin.HandlerFunc(handler)
+}
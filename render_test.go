@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestRenderTemplate applies a Markdown and an HTML template to a snapshot, checking that fields are available by
+// their JSON names, that numbers print exactly, and that HTML output is escaped.
+func TestRenderTemplate(t *testing.T) {
+	snapshot := &Snapshot{
+		Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Nodes: []NodeJson{
+			{Name: "node-b", Labels: map[string]string{"note": "<b>"}, Allocatable: ResourcesJson{Cpu: 8, Memory: 34359738368}, Free: ResourcesJson{Cpu: 2, Memory: 17179869184}},
+			{Name: "node-a", Allocatable: ResourcesJson{Cpu: 4, Memory: 8589934592}, Free: ResourcesJson{Cpu: 4, Memory: 8589934592}},
+		},
+	}
+
+	data, err := getRenderData(snapshot)
+
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	tests := []struct {
+		text string
+		html bool
+		want string
+	}{
+		{
+			"{{.time}}\n{{range .nodes}}| {{.name}} | {{.free.cpu}} | {{gib .free.memory}} GiB | {{percent (sub .allocatable.cpu .free.cpu) .allocatable.cpu}}% |\n{{end}}",
+			false,
+			"2024-05-01T12:00:00Z\n| node-a | 4 | 8.0 GiB | 0% |\n| node-b | 2 | 16.0 GiB | 75% |\n",
+		},
+		{
+			`{{range .nodes}}{{with .labels}}<p>{{.note}}</p>{{end}}{{end}}{{.summary.nodes}}`,
+			true,
+			"<p>&lt;b&gt;</p>2",
+		},
+	}
+
+	for _, test := range tests {
+		template, err := parseRenderTemplate("test", test.text, test.html)
+
+		if err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+
+		var output bytes.Buffer
+
+		if err := template.Execute(&output, data); err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+
+		if output.String() != test.want {
+			t.Fatalf(`output = %q, want match for %q`, output.String(), test.want)
+		}
+	}
+
+	if _, err := parseRenderTemplate("test", "{{range .nodes}", false); err == nil {
+		t.Fatalf(`err = %v, want match for %v`, err, "an error")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Hard eviction signals that the kubelet subtracts from a node's capacity, and the resource each is counted against
+var evictionSignalResources = map[string]string{
+	"memory.available": "memory",
+	"nodefs.available": "ephemeral",
+}
+
+// NodeReserved breaks down the resources of a node held back from pods, the difference between its capacity and its
+// allocatable resources. The parts are only known if the kubelet's configuration was read.
+type NodeReserved struct {
+	Total             ResourcesJson  `json:"total"`                       // Capacity minus allocatable
+	KubeReserved      *ResourcesJson `json:"kubeReserved,omitempty"`      // For the kubelet and container runtime
+	SystemReserved    *ResourcesJson `json:"systemReserved,omitempty"`    // For the operating system's daemons
+	EvictionThreshold *ResourcesJson `json:"evictionThreshold,omitempty"` // Hard eviction thresholds the kubelet keeps free
+	Other             *ResourcesJson `json:"other,omitempty"`             // Not explained by the configuration, e.g. huge pages
+}
+
+// parseReserved reads kubeReserved or systemReserved from a kubelet's configuration, skipping invalid quantities and
+// resources the API doesn't report on, such as pids.
+func parseReserved(values map[string]string) ResourcesJson {
+	list := make(corev1.ResourceList)
+
+	for name, value := range values {
+		if quantity, err := resource.ParseQuantity(value); err == nil {
+			list[corev1.ResourceName(name)] = quantity
+		}
+	}
+
+	return getResourcesStructured(getResourcesFromList(list))
+}
+
+// parseEvictionThreshold reads the hard eviction thresholds of a kubelet's configuration that are subtracted from a
+// node's capacity. Thresholds are either quantities or percentages of the capacity.
+func parseEvictionThreshold(values map[string]string, capacity ResourcesJson) ResourcesJson {
+	var threshold ResourcesJson
+
+	for signal, name := range evictionSignalResources {
+		value, ok := values[signal]
+		if !ok {
+			continue
+		}
+
+		if percent, ok := strings.CutSuffix(value, "%"); ok {
+			if fraction, err := strconv.ParseFloat(percent, 64); err == nil {
+				setResource(&threshold, name, getResource(capacity, name)*fraction/100)
+			}
+		} else if quantity, err := resource.ParseQuantity(value); err == nil {
+			setResource(&threshold, name, float64(quantity.Value()))
+		}
+	}
+
+	return threshold
+}
+
+// setNodeReserved breaks down the reserved resources of each node whose kubelet configuration was read into what
+// the kubelet reserves for itself and the system and keeps free for eviction, with whatever is left over as other.
+// Nodes whose configuration wasn't read keep only their total.
+func setNodeReserved(nodes []NodeJson, configs map[string]*KubeletConfig) {
+	for i := range nodes {
+		config, ok := configs[nodes[i].Name]
+		if !ok {
+			continue
+		}
+
+		reserved := &nodes[i].Reserved
+		kubeReserved := parseReserved(config.KubeReserved)
+		systemReserved := parseReserved(config.SystemReserved)
+		evictionThreshold := parseEvictionThreshold(config.EvictionHard, nodes[i].Capacity)
+		other := subtractResources(reserved.Total, addResources(addResources(kubeReserved, systemReserved), evictionThreshold))
+
+		reserved.KubeReserved = &kubeReserved
+		reserved.SystemReserved = &systemReserved
+		reserved.EvictionThreshold = &evictionThreshold
+		reserved.Other = &other
+	}
+}
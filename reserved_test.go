@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestNodeReserved takes a snapshot of a node whose capacity is more than its allocatable resources, checking that
+// the total is known without the kubelet's configuration, and breaking it down once the configuration is read.
+func TestNodeReserved(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("16"),
+				corev1.ResourceMemory:           resource.MustParse("64Gi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("100Gi"),
+			},
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("15"),
+				corev1.ResourceMemory:           resource.MustParse("60Gi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("88Gi"),
+			},
+		},
+	})
+
+	snapshot, err := newCollector(client).Snapshot()
+
+	if err != nil {
+		t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+	}
+
+	reserved := snapshot.Nodes[0].Reserved
+	total := ResourcesJson{Cpu: 1, Memory: 4 << 30, Ephemeral: 12 << 30}
+
+	switch {
+	case reserved.Total != total:
+		t.Fatalf(`reserved total = %v, want match for %v`, reserved.Total, total)
+	case reserved.KubeReserved != nil || reserved.Other != nil:
+		t.Fatalf(`reserved without kubelet config = %v, %v, want match for nil, nil`, reserved.KubeReserved, reserved.Other)
+	}
+
+	configs := map[string]*KubeletConfig{
+		"node-1": {
+			KubeReserved:   map[string]string{"cpu": "500m", "memory": "1Gi", "pid": "1000"},
+			SystemReserved: map[string]string{"cpu": "250m", "memory": "1Gi", "ephemeral-storage": "2Gi"},
+			EvictionHard:   map[string]string{"memory.available": "1Gi", "nodefs.available": "10%", "imagefs.available": "15%"},
+		},
+	}
+
+	setNodeReserved(snapshot.Nodes, configs)
+	reserved = snapshot.Nodes[0].Reserved
+
+	switch {
+	case reserved.KubeReserved == nil || *reserved.KubeReserved != ResourcesJson{Cpu: 0.5, Memory: 1 << 30}:
+		t.Fatalf(`kubeReserved = %v, want match for 0.5 CPU and 1Gi`, reserved.KubeReserved)
+	case *reserved.SystemReserved != ResourcesJson{Cpu: 0.25, Memory: 1 << 30, Ephemeral: 2 << 30}:
+		t.Fatalf(`systemReserved = %v, want match for 0.25 CPU, 1Gi, and 2Gi`, *reserved.SystemReserved)
+	case *reserved.EvictionThreshold != ResourcesJson{Memory: 1 << 30, Ephemeral: 10 << 30}:
+		t.Fatalf(`evictionThreshold = %v, want match for 1Gi and 10Gi`, *reserved.EvictionThreshold)
+	case *reserved.Other != ResourcesJson{Cpu: 0.25, Memory: 1 << 30}:
+		t.Fatalf(`other = %v, want match for 0.25 CPU and 1Gi`, *reserved.Other)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// getAllocatedRequests returns the requests the kubelet has allocated to a container, from allocatedResources, or
+// from the resources it reports the container is running with if allocatedResources isn't set. Clusters without
+// in-place pod resizing set neither, and nil is returned.
+func getAllocatedRequests(status *corev1.ContainerStatus) corev1.ResourceList {
+	if len(status.AllocatedResources) > 0 {
+		return status.AllocatedResources
+	}
+
+	if status.Resources != nil && len(status.Resources.Requests) > 0 {
+		return status.Resources.Requests
+	}
+
+	return nil
+}
+
+// applyResizedRequests sets the requests of containers that have been resized in place to what their node has
+// allocated to them, as the scheduler does. While a resize is pending, each resource is counted at the larger of the
+// requested and allocated amounts, since the node has to hold room for both until the resize is done or undone. If
+// the resize is infeasible, the allocated amount is counted. Containers without allocated resources, such as on
+// clusters before 1.27, keep the requests in their spec. The allocated resources in the pod's status are set to the
+// same requests.
+func applyResizedRequests(pods []corev1.Pod) {
+	for i := range pods {
+		pod := &pods[i]
+
+		statuses := make(map[string]*corev1.ContainerStatus)
+		for _, list := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for j := range list {
+				statuses[list[j].Name] = &list[j]
+			}
+		}
+
+		for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+			for j := range containers {
+				status, ok := statuses[containers[j].Name]
+				if !ok {
+					continue
+				}
+
+				allocated := getAllocatedRequests(status)
+				if allocated == nil {
+					continue
+				}
+
+				// Resources that can't be resized, such as GPUs, aren't allocated and keep their requests
+				requests := make(corev1.ResourceList)
+				for name, quantity := range containers[j].Resources.Requests {
+					requests[name] = quantity.DeepCopy()
+				}
+
+				infeasible := pod.Status.Resize == corev1.PodResizeStatusInfeasible
+
+				for name, quantity := range allocated {
+					if current, ok := requests[name]; !ok || infeasible || quantity.Cmp(current) > 0 {
+						requests[name] = quantity.DeepCopy()
+					}
+				}
+
+				containers[j].Resources.Requests = requests
+
+				// resourcehelper also reads allocatedResources, and would drop the resources that aren't allocated
+				// from infeasible resizes, so it is set to the same requests
+				status.AllocatedResources = requests.DeepCopy()
+			}
+		}
+	}
+}
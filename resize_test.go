@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestApplyResizedRequests checks that containers resized in place are counted at what is allocated to them, at the
+// larger of the requested and allocated amounts while a resize is pending, and that pods without allocated resources
+// keep the requests in their spec.
+func TestApplyResizedRequests(t *testing.T) {
+	pod := func(name string, resize corev1.PodResizeStatus, status corev1.ContainerStatus) corev1.Pod {
+		status.Name = "app"
+
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+					"nvidia.com/gpu":      resource.MustParse("1"),
+				}}}},
+			},
+			Status: corev1.PodStatus{Resize: resize, ContainerStatuses: []corev1.ContainerStatus{status}},
+		}
+	}
+
+	allocated := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("512Mi")}
+
+	pods := []corev1.Pod{
+		pod("resized", "", corev1.ContainerStatus{Resources: &corev1.ResourceRequirements{Requests: allocated}}),
+		pod("in-progress", corev1.PodResizeStatusInProgress, corev1.ContainerStatus{AllocatedResources: allocated}),
+		pod("infeasible", corev1.PodResizeStatusInfeasible, corev1.ContainerStatus{AllocatedResources: allocated}),
+		pod("old-cluster", "", corev1.ContainerStatus{}),
+	}
+
+	applyResizedRequests(pods)
+
+	for i, want := range []ResourcesJson{
+		{Cpu: 4, Memory: 1 << 30, Gpu: 1},
+		{Cpu: 4, Memory: 1 << 30, Gpu: 1},
+		{Cpu: 4, Memory: 512 << 20, Gpu: 1},
+		{Cpu: 2, Memory: 1 << 30, Gpu: 1},
+	} {
+		if requests := getPodStructured(&pods[i]).Requests; requests != want {
+			t.Fatalf(`%v requests = %v, want match for %v`, pods[i].Name, requests, want)
+		}
+	}
+}
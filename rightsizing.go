@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// UsageSource returns how much CPU and memory pods are actually using
+type UsageSource interface {
+	// PodUsage returns the usage of every pod it has data for, keyed by namespace/name
+	PodUsage() (map[string]ResourcesJson, error)
+	// Name describes where the usage comes from, e.g. "prometheus-p95"
+	Name() string
+}
+
+// PrometheusUsage gets the 95th percentile usage of each pod over a time window from cAdvisor metrics in Prometheus
+type PrometheusUsage struct {
+	client *PrometheusClient
+	window time.Duration
+}
+
+// Name returns the name of the usage source.
+func (p *PrometheusUsage) Name() string {
+	return "prometheus-p95"
+}
+
+// PodUsage returns the 95th percentile CPU and memory usage of each pod over the window.
+func (p *PrometheusUsage) PodUsage() (map[string]ResourcesJson, error) {
+	window := strconv.FormatInt(int64(p.window.Seconds()), 10) + "s"
+
+	queries := map[string]string{
+		"cpu":    `quantile_over_time(0.95, sum by (namespace, pod) (rate(container_cpu_usage_seconds_total{container!=""}[5m]))[` + window + `:5m])`,
+		"memory": `quantile_over_time(0.95, sum by (namespace, pod) (container_memory_working_set_bytes{container!=""})[` + window + `:5m])`,
+	}
+
+	usage := make(map[string]ResourcesJson)
+
+	for resource, query := range queries {
+		series, err := p.client.Query(query, time.Now())
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range series {
+			if len(s.Values) == 0 {
+				continue
+			}
+
+			key := s.Metric["namespace"] + "/" + s.Metric["pod"]
+			resources := usage[key]
+			setResource(&resources, resource, s.Values[0].Value)
+			usage[key] = resources
+		}
+	}
+
+	return usage, nil
+}
+
+// MetricsServerUsage gets the current usage of each pod from metrics-server
+type MetricsServerUsage struct {
+	client metricsclient.Interface
+}
+
+// Name returns the name of the usage source.
+func (m *MetricsServerUsage) Name() string {
+	return "metrics-server"
+}
+
+// PodUsage returns the current CPU and memory usage of each pod, summed over its containers.
+func (m *MetricsServerUsage) PodUsage() (map[string]ResourcesJson, error) {
+	podMetrics, err := m.client.MetricsV1beta1().PodMetricses("").List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]ResourcesJson)
+
+	for _, pod := range podMetrics.Items {
+		var resources ResourcesJson
+
+		for _, container := range pod.Containers {
+			resources.Cpu += container.Usage.Cpu().AsApproximateFloat64()
+			resources.Memory += container.Usage.Memory().Value()
+		}
+
+		usage[pod.Namespace+"/"+pod.Name] = resources
+	}
+
+	return usage, nil
+}
+
+// CpuMemory contains an amount of CPU and memory
+type CpuMemory struct {
+	Cpu    float64 `json:"cpu"`
+	Memory int64   `json:"memory"`
+}
+
+// WorkloadRecommendation compares what a workload's pods request with what they use. Requests, usage, and
+// recommendations are averages per pod, and reclaimable is the total over every pod.
+type WorkloadRecommendation struct {
+	Namespace   string             `json:"namespace"`
+	Kind        string             `json:"kind"`
+	Name        string             `json:"name"`
+	Pods        int                `json:"pods"`
+	Requests    CpuMemory          `json:"requests"`
+	Usage       CpuMemory          `json:"usage"`
+	Recommended CpuMemory          `json:"recommended"`
+	Reclaimable CpuMemory          `json:"reclaimable"`
+	Source      string             `json:"source"`
+	Vpa         *VpaRecommendation `json:"vpa,omitempty"`
+}
+
+// getRightsizing groups pods by workload and recommends requests equal to their usage plus headroom
+// (e.g. 0.15 for 15%). Pods without usage data are skipped. Workloads with a VerticalPodAutoscaler include
+// its recommendation for comparison. Workloads are sorted by reclaimable CPU.
+func getRightsizing(pods []PodJson, usage map[string]ResourcesJson, vpas map[string]VpaRecommendation, headroom float64, source string) []WorkloadRecommendation {
+	workloads := make(map[string]*WorkloadRecommendation)
+	order := make([]string, 0)
+
+	for _, pod := range pods {
+		podUsage, ok := usage[pod.Namespace+"/"+pod.Name]
+		if !ok {
+			continue
+		}
+
+		// Pods without a controller are their own workload
+		owner := OwnerJson{Kind: "Pod", Name: pod.Name}
+		if pod.Owner != nil {
+			owner = *pod.Owner
+		}
+
+		key := getWorkloadKey(pod)
+		if _, ok := workloads[key]; !ok {
+			workloads[key] = &WorkloadRecommendation{Namespace: pod.Namespace, Kind: owner.Kind, Name: owner.Name, Source: source}
+			order = append(order, key)
+
+			if vpa, ok := vpas[key]; ok {
+				workloads[key].Vpa = &vpa
+			}
+		}
+
+		// Sum everything for now and divide by the number of pods at the end
+		workload := workloads[key]
+		workload.Pods++
+		workload.Requests.Cpu += pod.Requests.Cpu
+		workload.Requests.Memory += pod.Requests.Memory
+		workload.Usage.Cpu += podUsage.Cpu
+		workload.Usage.Memory += podUsage.Memory
+	}
+
+	result := make([]WorkloadRecommendation, 0, len(order))
+
+	for _, key := range order {
+		workload := *workloads[key]
+		pods := float64(workload.Pods)
+
+		workload.Requests = CpuMemory{Cpu: workload.Requests.Cpu / pods, Memory: workload.Requests.Memory / int64(pods)}
+		workload.Usage = CpuMemory{Cpu: workload.Usage.Cpu / pods, Memory: workload.Usage.Memory / int64(pods)}
+		workload.Recommended = CpuMemory{
+			Cpu:    workload.Usage.Cpu * (1 + headroom),
+			Memory: int64(float64(workload.Usage.Memory) * (1 + headroom)),
+		}
+		workload.Reclaimable = CpuMemory{
+			Cpu:    max(workload.Requests.Cpu-workload.Recommended.Cpu, 0) * pods,
+			Memory: max(workload.Requests.Memory-workload.Recommended.Memory, 0) * int64(pods),
+		}
+
+		result = append(result, workload)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Reclaimable.Cpu > result[j].Reclaimable.Cpu
+	})
+
+	return result
+}
+
+// getRightsizingHandler returns a HandlerFunc to return right-sizing recommendations for every workload given a
+// Collector and a source of pod usage.
+func getRightsizingHandler(collector *Collector, usageSource UsageSource, headroom float64) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		pods, err := collector.Pods()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		usage, err := usageSource.PodUsage()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod usage")
+			return
+		}
+
+		vpas, err := collector.VpaRecommendations()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving VerticalPodAutoscaler recommendations")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getRightsizing(pods, usage, vpas, headroom, usageSource.Name()))
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+// TestGetRightsizing groups pods by workload, checking that requests and usage are averaged per pod, that the
+// recommendation adds headroom to usage, and that reclaimable capacity is summed over every pod.
+func TestGetRightsizing(t *testing.T) {
+	pods := []PodJson{
+		{Name: "web-1", Namespace: "web", Owner: &OwnerJson{Kind: "Deployment", Name: "web"}, Requests: ResourcesJson{Cpu: 8, Memory: 4000}},
+		{Name: "web-2", Namespace: "web", Owner: &OwnerJson{Kind: "Deployment", Name: "web"}, Requests: ResourcesJson{Cpu: 8, Memory: 4000}},
+		{Name: "debug", Namespace: "web", Requests: ResourcesJson{Cpu: 1, Memory: 1000}},
+		{Name: "no-metrics", Namespace: "web", Requests: ResourcesJson{Cpu: 1}},
+	}
+
+	usage := map[string]ResourcesJson{
+		"web/web-1": {Cpu: 1, Memory: 1000},
+		"web/web-2": {Cpu: 3, Memory: 3000},
+		"web/debug": {Cpu: 2, Memory: 2000},
+	}
+
+	vpas := map[string]VpaRecommendation{
+		"web/Deployment/web": {Target: CpuMemory{Cpu: 2.5, Memory: 2500}},
+	}
+
+	recommendations := getRightsizing(pods, usage, vpas, 0.5, "test")
+
+	if len(recommendations) != 2 {
+		t.Fatalf(`len(recommendations) = %v, want match for %v`, len(recommendations), 2)
+	}
+
+	web := recommendations[0]
+
+	switch {
+	case web.Kind != "Deployment" || web.Name != "web" || web.Pods != 2:
+		t.Fatalf(`recommendations[0] = %v, want Deployment web with 2 pods`, web)
+	case web.Requests != (CpuMemory{Cpu: 8, Memory: 4000}):
+		t.Fatalf(`web.Requests = %v, want match for %v`, web.Requests, CpuMemory{Cpu: 8, Memory: 4000})
+	case web.Usage != (CpuMemory{Cpu: 2, Memory: 2000}):
+		t.Fatalf(`web.Usage = %v, want match for %v`, web.Usage, CpuMemory{Cpu: 2, Memory: 2000})
+	case web.Recommended != (CpuMemory{Cpu: 3, Memory: 3000}):
+		t.Fatalf(`web.Recommended = %v, want match for %v`, web.Recommended, CpuMemory{Cpu: 3, Memory: 3000})
+	case web.Reclaimable != (CpuMemory{Cpu: 10, Memory: 2000}):
+		t.Fatalf(`web.Reclaimable = %v, want match for %v`, web.Reclaimable, CpuMemory{Cpu: 10, Memory: 2000})
+	case web.Vpa == nil || web.Vpa.Target.Cpu != 2.5:
+		t.Fatalf(`web.Vpa = %v, want target of %v CPU`, web.Vpa, 2.5)
+	}
+
+	// The bare pod uses more than it requests, so nothing can be reclaimed
+	debug := recommendations[1]
+
+	if debug.Kind != "Pod" || debug.Name != "debug" || debug.Reclaimable != (CpuMemory{}) || debug.Vpa != nil {
+		t.Fatalf(`recommendations[1] = %v, want Pod debug with nothing reclaimable`, debug)
+	}
+}
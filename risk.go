@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Weights of each factor in the eviction-risk score, which add up to 1
+const (
+	riskWeightOvercommit = 0.4
+	riskWeightPressure   = 0.4
+	riskWeightBestEffort = 0.2
+)
+
+// Node conditions that mean the kubelet may start evicting pods
+var pressureConditions = []corev1.NodeConditionType{
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+}
+
+// EvictionRisk estimates how likely the kubelet is to start evicting pods from a node. The score is between 0 and
+// 1 and combines how far memory limits exceed allocatable memory, whether the node reports resource pressure, and
+// the share of pods that are BestEffort and so are evicted first.
+type EvictionRisk struct {
+	Score            float64  `json:"score"`
+	MemoryOvercommit float64  `json:"memoryOvercommit"`
+	Pressure         []string `json:"pressure"`
+	Pods             int      `json:"pods"`
+	BestEffortPods   int      `json:"bestEffortPods"`
+}
+
+// getPressureConditions returns the names of the pressure conditions that are true on a node.
+func getPressureConditions(node *corev1.Node) []string {
+	pressure := make([]string, 0)
+
+	for _, condition := range node.Status.Conditions {
+		for _, conditionType := range pressureConditions {
+			if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+				pressure = append(pressure, string(condition.Type))
+			}
+		}
+	}
+
+	return pressure
+}
+
+// getEvictionRisks calculates the eviction risk of every node in a map of Node instances from the pods scheduled
+// on them, keyed by node name.
+func getEvictionRisks(pods []corev1.Pod, nodes map[string]*Node) map[string]EvictionRisk {
+	memoryLimits := make(map[string]int64)
+	risks := make(map[string]EvictionRisk)
+
+	for name, node := range nodes {
+		// Always return a list for pressure, even if the node has none
+		pressure := node.Pressure
+		if pressure == nil {
+			pressure = make([]string, 0)
+		}
+
+		risks[name] = EvictionRisk{Pressure: pressure}
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+
+		risk, ok := risks[pod.Spec.NodeName]
+		if !ok {
+			continue
+		}
+
+		risk.Pods++
+		if isBestEffort(pod) {
+			risk.BestEffortPods++
+		}
+		risks[pod.Spec.NodeName] = risk
+
+		_, podLimits := getPodRequestsAndLimits(pod)
+		memoryLimits[pod.Spec.NodeName] += podLimits.Memory().Value()
+	}
+
+	for name, risk := range risks {
+		allocatable := nodes[name].Allocatable.Memory.Value()
+		if allocatable > 0 {
+			risk.MemoryOvercommit = float64(memoryLimits[name]) / float64(allocatable)
+		}
+
+		// Limits up to allocatable are safe, and limits of double allocatable or more are the worst case
+		overcommit := min(max(risk.MemoryOvercommit-1, 0), 1)
+
+		pressure := 0.0
+		if len(risk.Pressure) > 0 {
+			pressure = 1
+		}
+
+		bestEffort := 0.0
+		if risk.Pods > 0 {
+			bestEffort = float64(risk.BestEffortPods) / float64(risk.Pods)
+		}
+
+		risk.Score = riskWeightOvercommit*overcommit + riskWeightPressure*pressure + riskWeightBestEffort*bestEffort
+		risks[name] = risk
+	}
+
+	return risks
+}
+
+// isBestEffort returns whether a pod has the BestEffort QoS class. The class the API server assigned is used if
+// there is one, since default requests may have been applied to the pod since.
+func isBestEffort(pod *corev1.Pod) bool {
+	if pod.Status.QOSClass != "" {
+		return pod.Status.QOSClass == corev1.PodQOSBestEffort
+	}
+
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		if len(container.Resources.Requests) > 0 || len(container.Resources.Limits) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getAtRiskNodes returns the nodes with an eviction-risk score of at least threshold, riskiest first.
+func getAtRiskNodes(nodes []NodeJson, threshold float64) []NodeJson {
+	atRisk := make([]NodeJson, 0)
+
+	for _, node := range nodes {
+		if node.EvictionRisk.Score >= threshold {
+			atRisk = append(atRisk, node)
+		}
+	}
+
+	// Sort by score, breaking ties by name so the order is stable
+	sort.Slice(atRisk, func(i, j int) bool {
+		if atRisk[i].EvictionRisk.Score != atRisk[j].EvictionRisk.Score {
+			return atRisk[i].EvictionRisk.Score > atRisk[j].EvictionRisk.Score
+		}
+		return atRisk[i].Name < atRisk[j].Name
+	})
+
+	return atRisk
+}
+
+// getAtRiskNodesHandler returns a HandlerFunc to return the nodes most at risk of evictions given a Collector.
+// The minimum score is given by the threshold query parameter (default 0.5).
+func getAtRiskNodesHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		threshold, err := strconv.ParseFloat(c.DefaultQuery("threshold", "0.5"), 64)
+
+		if err != nil || threshold < 0 || threshold > 1 {
+			c.JSON(http.StatusBadRequest, "error: threshold must be a number between 0 and 1")
+			return
+		}
+
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getAtRiskNodes(snapshot.Nodes, threshold))
+	}
+
+	return gin.HandlerFunc(handler)
+}
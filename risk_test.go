@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestGetEvictionRisks calculates the eviction risk of a node with memory pressure, overcommitted memory limits,
+// and a BestEffort pod, and of a node with nothing on it.
+func TestGetEvictionRisks(t *testing.T) {
+	nodes := map[string]*Node{
+		"node-1": {Name: "node-1", Pressure: []string{"MemoryPressure"}, Allocatable: Resources{Memory: resource.MustParse("10Gi")}},
+		"node-2": {Name: "node-2", Allocatable: Resources{Memory: resource.MustParse("10Gi")}},
+	}
+
+	limited := v1.Container{Resources: v1.ResourceRequirements{Limits: v1.ResourceList{"memory": resource.MustParse("15Gi")}}}
+
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-1"}, Spec: v1.PodSpec{NodeName: "node-1", Containers: []v1.Container{limited}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-2"}, Spec: v1.PodSpec{NodeName: "node-1", Containers: []v1.Container{{}}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-3"}, Spec: v1.PodSpec{NodeName: "node-3", Containers: []v1.Container{limited}}},
+	}
+
+	risks := getEvictionRisks(pods, nodes)
+
+	risk := risks["node-1"]
+	want := 0.4*0.5 + 0.4*1 + 0.2*0.5
+
+	switch {
+	case risk.Pods != 2 || risk.BestEffortPods != 1:
+		t.Fatalf(`risk.Pods, risk.BestEffortPods = %v, %v, want match for %v, %v`, risk.Pods, risk.BestEffortPods, 2, 1)
+	case risk.MemoryOvercommit != 1.5:
+		t.Fatalf(`risk.MemoryOvercommit = %v, want match for %v`, risk.MemoryOvercommit, 1.5)
+	case math.Abs(risk.Score-want) > 1e-9:
+		t.Fatalf(`risk.Score = %v, want match for %v`, risk.Score, want)
+	}
+
+	if risks["node-2"].Score != 0 || risks["node-2"].Pressure == nil {
+		t.Fatalf(`risks["node-2"] = %v, want score 0 with empty pressure`, risks["node-2"])
+	}
+}
+
+// TestGetPressureConditions checks that only true pressure conditions are returned.
+func TestGetPressureConditions(t *testing.T) {
+	node := &v1.Node{
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionTrue},
+				{Type: v1.NodeMemoryPressure, Status: v1.ConditionTrue},
+				{Type: v1.NodeDiskPressure, Status: v1.ConditionFalse},
+			},
+		},
+	}
+
+	pressure := getPressureConditions(node)
+
+	if len(pressure) != 1 || pressure[0] != "MemoryPressure" {
+		t.Fatalf(`getPressureConditions() = %v, want match for %v`, pressure, []string{"MemoryPressure"})
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Reports that can be delivered on a schedule
+var scheduledReportNames = []string{"summary", "idle", "fragmentation"}
+
+// ReportSchedule describes a set of reports to render on a cron schedule and where to deliver them
+type ReportSchedule struct {
+	Name     string   `json:"name"`
+	Schedule string   `json:"schedule"` // Cron expression, e.g. "0 9 * * 1" for 9:00 every Monday
+	Reports  []string `json:"reports"`  // Any of summary, idle, or fragmentation
+	Webhook  string   `json:"webhook,omitempty"`
+	Email    []string `json:"email,omitempty"`
+
+	cron *CronSchedule
+}
+
+// ScheduledReport is a rendered set of reports, keyed by report name
+type ScheduledReport struct {
+	Name    string                 `json:"name"`
+	Time    time.Time              `json:"time"`
+	Reports map[string]interface{} `json:"reports"`
+}
+
+// SmtpConfig is the mail server scheduled reports are sent through
+type SmtpConfig struct {
+	Host     string
+	Port     string
+	Username string // Optional - no authentication is used if empty
+	Password string
+	From     string
+}
+
+// ReportScheduler renders reports from snapshots of the cluster and delivers them on their schedules
+type ReportScheduler struct {
+	collector *Collector
+	schedules []ReportSchedule
+	smtp      *SmtpConfig // Optional - needed if any schedule sends email
+	client    *http.Client
+}
+
+// loadReportSchedules reads a list of report schedules from the JSON file at path, checking that their cron
+// expressions and report names are valid and that each has somewhere to deliver to.
+func loadReportSchedules(path string) ([]ReportSchedule, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []ReportSchedule
+
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, err
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		schedule.cron, err = parseCron(schedule.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("report schedule %q: %v", schedule.Name, err)
+		}
+
+		if len(schedule.Reports) == 0 {
+			schedule.Reports = scheduledReportNames
+		}
+
+		for _, name := range schedule.Reports {
+			if !slices.Contains(scheduledReportNames, name) {
+				return nil, fmt.Errorf("report schedule %q: unknown report %q", schedule.Name, name)
+			}
+		}
+
+		if schedule.Webhook == "" && len(schedule.Email) == 0 {
+			return nil, fmt.Errorf("report schedule %q: expected a webhook or email recipients", schedule.Name)
+		}
+	}
+
+	return schedules, nil
+}
+
+// newReportScheduler returns a ReportScheduler that delivers schedules using snapshots from collector. An error is
+// returned if a schedule sends email but no mail server is given.
+func newReportScheduler(collector *Collector, schedules []ReportSchedule, smtpConfig *SmtpConfig) (*ReportScheduler, error) {
+	for _, schedule := range schedules {
+		if len(schedule.Email) > 0 && smtpConfig == nil {
+			return nil, fmt.Errorf("report schedule %q sends email, but no SMTP server is configured", schedule.Name)
+		}
+	}
+
+	return &ReportScheduler{
+		collector: collector,
+		schedules: schedules,
+		smtp:      smtpConfig,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Run checks the schedules at the start of every minute and delivers the reports of those that match. It blocks
+// forever, so it should be run in its own goroutine.
+func (s *ReportScheduler) Run() {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		time.Sleep(next.Sub(now))
+
+		// Only the leader delivers reports, so running several replicas doesn't send duplicates
+		if !isLeader() {
+			continue
+		}
+
+		for _, schedule := range s.schedules {
+			if !schedule.cron.Matches(next) {
+				continue
+			}
+
+			if err := s.Deliver(schedule, next); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+}
+
+// Deliver renders the reports of a schedule from a new snapshot and sends them to its webhook and email recipients.
+func (s *ReportScheduler) Deliver(schedule ReportSchedule, t time.Time) error {
+	snapshot, err := s.collector.Snapshot()
+
+	if err != nil {
+		return err
+	}
+
+	report := ScheduledReport{Name: schedule.Name, Time: t, Reports: renderReports(snapshot, schedule.Reports)}
+
+	if schedule.Webhook != "" {
+		body, err := json.Marshal(report)
+
+		if err != nil {
+			return err
+		}
+
+		if err := postJson(s.client, schedule.Webhook, body); err != nil {
+			return fmt.Errorf("report schedule %q: %v", schedule.Name, err)
+		}
+	}
+
+	if len(schedule.Email) > 0 {
+		message, err := getReportEmail(report, s.smtp.From, schedule.Email)
+
+		if err != nil {
+			return err
+		}
+
+		var auth smtp.Auth
+		if s.smtp.Username != "" {
+			auth = smtp.PlainAuth("", s.smtp.Username, s.smtp.Password, s.smtp.Host)
+		}
+
+		if err := smtp.SendMail(s.smtp.Host+":"+s.smtp.Port, auth, s.smtp.From, schedule.Email, message); err != nil {
+			return fmt.Errorf("report schedule %q: %v", schedule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// renderReports returns each of the named reports for a snapshot, keyed by name.
+func renderReports(snapshot *Snapshot, names []string) map[string]interface{} {
+	reports := make(map[string]interface{})
+
+	for _, name := range names {
+		switch name {
+		case "summary":
+			reports[name] = getClusterSummary(snapshot.Nodes)
+		case "idle":
+			reports[name] = getIdleReport(snapshot.Nodes)
+		case "fragmentation":
+			shapes := make([]WorkloadShape, 0, len(defaultShapes))
+			for _, text := range defaultShapes {
+				// The default shapes are always valid
+				shape, _ := parseWorkloadShape(text)
+				shapes = append(shapes, shape)
+			}
+
+			reports[name] = getFragmentation(snapshot.Nodes, shapes)
+		}
+	}
+
+	return reports
+}
+
+// getReportEmail formats a rendered report as a plain text email, with each report as indented JSON under a heading.
+func getReportEmail(report ScheduledReport, from string, to []string) ([]byte, error) {
+	var body bytes.Buffer
+
+	fmt.Fprintf(&body, "From: %v\r\n", from)
+	fmt.Fprintf(&body, "To: %v\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&body, "Subject: Capacity report: %v\r\n", report.Name)
+	fmt.Fprintf(&body, "Date: %v\r\n", report.Time.Format(time.RFC1123Z))
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	// Keep the reports in the same order in every email rather than map order
+	for _, name := range scheduledReportNames {
+		value, ok := report.Reports[name]
+		if !ok {
+			continue
+		}
+
+		text, err := json.MarshalIndent(value, "", "    ")
+
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&body, "%v\r\n\r\n%v\r\n\r\n", name, strings.ReplaceAll(string(text), "\n", "\r\n"))
+	}
+
+	return body.Bytes(), nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadReportSchedules loads a schedule with the default reports, and checks that unknown reports and schedules
+// with nowhere to deliver to are rejected.
+func TestLoadReportSchedules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+
+	write := func(text string) {
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[{"name": "weekly", "schedule": "0 9 * * 1", "webhook": "http://example.com"}]`)
+	schedules, err := loadReportSchedules(path)
+
+	switch {
+	case err != nil:
+		t.Fatalf(`loadReportSchedules() err = %v, want match for %v`, err, nil)
+	case len(schedules[0].Reports) != 3 || schedules[0].cron == nil:
+		t.Fatalf(`schedules[0] = %v, want every report and a parsed schedule`, schedules[0])
+	}
+
+	write(`[{"name": "weekly", "schedule": "0 9 * * 1", "reports": ["costs"], "webhook": "http://example.com"}]`)
+	if _, err := loadReportSchedules(path); err == nil {
+		t.Fatalf(`loadReportSchedules() err = %v, want an error for an unknown report`, err)
+	}
+
+	write(`[{"name": "weekly", "schedule": "0 9 * * 1"}]`)
+	if _, err := loadReportSchedules(path); err == nil {
+		t.Fatalf(`loadReportSchedules() err = %v, want an error for no webhook or email`, err)
+	}
+}
+
+// TestGetReportEmail renders reports from a snapshot into an email and checks the headers and report order.
+func TestGetReportEmail(t *testing.T) {
+	snapshot := &Snapshot{Nodes: []NodeJson{{Name: "node-1", Allocatable: ResourcesJson{Cpu: 4}, Free: ResourcesJson{Cpu: 2}}}}
+
+	report := ScheduledReport{
+		Name:    "weekly",
+		Time:    time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC),
+		Reports: renderReports(snapshot, []string{"idle", "summary"}),
+	}
+
+	message, err := getReportEmail(report, "api@example.edu", []string{"a@example.edu", "b@example.edu"})
+
+	if err != nil {
+		t.Fatalf(`getReportEmail() err = %v, want match for %v`, err, nil)
+	}
+
+	text := string(message)
+
+	switch {
+	case !strings.Contains(text, "To: a@example.edu, b@example.edu\r\n"):
+		t.Fatalf(`getReportEmail() = %q, want both recipients`, text)
+	case !strings.Contains(text, "Subject: Capacity report: weekly\r\n"):
+		t.Fatalf(`getReportEmail() = %q, want the schedule name in the subject`, text)
+	case strings.Index(text, "summary\r\n") > strings.Index(text, "idle\r\n"):
+		t.Fatalf(`getReportEmail() = %q, want summary before idle`, text)
+	case strings.Contains(text, "fragmentation"):
+		t.Fatalf(`getReportEmail() = %q, want no fragmentation report`, text)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Timeouts and limits of the API's HTTP servers when none are given. Gin's own server has no timeouts, so a client
+// could hold a connection open forever by sending its headers slowly. Writes are allowed long enough for long-polls.
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = maxLongPollWait + time.Minute
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultMaxHeaderBytes    = http.DefaultMaxHeaderBytes
+)
+
+// ServerLimits are the timeouts and limits of the API's HTTP servers, which keep slow or idle clients from tying up
+// connections when the API is exposed through a public ingress. A timeout of 0 means none.
+type ServerLimits struct {
+	ReadHeaderTimeout time.Duration // Longest a client can take to send a request's headers
+	ReadTimeout       time.Duration // Longest a client can take to send a whole request
+	WriteTimeout      time.Duration // Longest a response can take to send, from the end of the request's headers
+	IdleTimeout       time.Duration // Longest a keep-alive connection can wait for its next request
+	MaxHeaderBytes    int           // Largest a request's headers can be
+}
+
+// parseServerLimits reads the HTTP server limits from the HTTP_READ_HEADER_TIMEOUT, HTTP_READ_TIMEOUT,
+// HTTP_WRITE_TIMEOUT, HTTP_IDLE_TIMEOUT, and HTTP_MAX_HEADER_BYTES environment variables, using the defaults for
+// those that aren't set.
+func parseServerLimits() (ServerLimits, error) {
+	limits := ServerLimits{
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		ReadTimeout:       defaultReadTimeout,
+		WriteTimeout:      defaultWriteTimeout,
+		IdleTimeout:       defaultIdleTimeout,
+		MaxHeaderBytes:    defaultMaxHeaderBytes,
+	}
+
+	for name, timeout := range map[string]*time.Duration{
+		"HTTP_READ_HEADER_TIMEOUT": &limits.ReadHeaderTimeout,
+		"HTTP_READ_TIMEOUT":        &limits.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":       &limits.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        &limits.IdleTimeout,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration < 0 {
+			return limits, fmt.Errorf("invalid %v %q: must be a duration such as 30s, or 0 for none", name, value)
+		}
+
+		*timeout = duration
+	}
+
+	if value := os.Getenv("HTTP_MAX_HEADER_BYTES"); value != "" {
+		maxHeaderBytes, err := strconv.Atoi(value)
+		if err != nil || maxHeaderBytes <= 0 {
+			return limits, fmt.Errorf("invalid HTTP_MAX_HEADER_BYTES %q: must be a positive number of bytes", value)
+		}
+
+		limits.MaxHeaderBytes = maxHeaderBytes
+	}
+
+	return limits, nil
+}
+
+// newServer returns an HTTP server for handler on addr with the given limits.
+func newServer(addr string, handler http.Handler, limits ServerLimits) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: limits.ReadHeaderTimeout,
+		ReadTimeout:       limits.ReadTimeout,
+		WriteTimeout:      limits.WriteTimeout,
+		IdleTimeout:       limits.IdleTimeout,
+		MaxHeaderBytes:    limits.MaxHeaderBytes,
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestParseServerLimits checks the defaults, that set limits replace them, that 0 turns a timeout off, and that
+// invalid limits are rejected.
+func TestParseServerLimits(t *testing.T) {
+	limits, err := parseServerLimits()
+
+	switch {
+	case err != nil:
+		t.Fatalf(`parseServerLimits() = %v, want match for nil`, err)
+	case limits.ReadHeaderTimeout != defaultReadHeaderTimeout || limits.MaxHeaderBytes != http.DefaultMaxHeaderBytes:
+		t.Fatalf(`parseServerLimits() = %+v, want match for the defaults`, limits)
+	case limits.WriteTimeout <= maxLongPollWait:
+		t.Fatalf(`default write timeout = %v, want longer than a long-poll of %v`, limits.WriteTimeout, maxLongPollWait)
+	}
+
+	t.Setenv("HTTP_READ_HEADER_TIMEOUT", "5s")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "0")
+	t.Setenv("HTTP_MAX_HEADER_BYTES", "65536")
+
+	limits, err = parseServerLimits()
+
+	switch {
+	case err != nil:
+		t.Fatalf(`parseServerLimits() = %v, want match for nil`, err)
+	case limits.ReadHeaderTimeout != 5*time.Second || limits.WriteTimeout != 0 || limits.MaxHeaderBytes != 65536:
+		t.Fatalf(`parseServerLimits() = %+v, want match for 5s, no write timeout, and 65536 bytes`, limits)
+	case limits.IdleTimeout != defaultIdleTimeout:
+		t.Fatalf(`idle timeout = %v, want match for %v`, limits.IdleTimeout, defaultIdleTimeout)
+	}
+
+	server := newServer(":8080", http.NotFoundHandler(), limits)
+	if server.ReadHeaderTimeout != 5*time.Second || server.MaxHeaderBytes != 65536 {
+		t.Fatalf(`newServer() = %v, %v, want match for 5s, 65536`, server.ReadHeaderTimeout, server.MaxHeaderBytes)
+	}
+
+	for name, value := range map[string]string{
+		"HTTP_IDLE_TIMEOUT":     "forever",
+		"HTTP_READ_TIMEOUT":     "-1s",
+		"HTTP_MAX_HEADER_BYTES": "0",
+	} {
+		t.Setenv(name, value)
+
+		if _, err := parseServerLimits(); err == nil {
+			t.Fatalf(`parseServerLimits() with %v=%v = nil, want an error`, name, value)
+		}
+
+		t.Setenv(name, "")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ShardConfig splits the nodes of the cluster, and the pods on them, between replicas by a hash of the node name.
+// Each replica only collects its own shard, and snapshots are merged from every shard when they are taken.
+type ShardConfig struct {
+	index  int
+	count  int
+	peers  []string // Base URLs of every shard's replica, in order of index
+	token  string   // Bearer token sent to the other shards, if set
+	client *http.Client
+}
+
+// newShardConfig returns the ShardConfig for shard index of count, reaching the other shards at peers. peers must
+// have a URL for every shard, though the one for this shard isn't used.
+func newShardConfig(index, count int, peers []string, token string) (*ShardConfig, error) {
+	if count < 1 || index < 0 || index >= count {
+		return nil, fmt.Errorf("invalid shard %v of %v", index, count)
+	}
+
+	if len(peers) != count {
+		return nil, fmt.Errorf("expected a peer URL for each of %v shards, got %v", count, len(peers))
+	}
+
+	for i := range peers {
+		peers[i] = strings.TrimSuffix(strings.TrimSpace(peers[i]), "/")
+	}
+
+	return &ShardConfig{index: index, count: count, peers: peers, token: token, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// getShardIndex returns the ordinal at the end of a StatefulSet pod's hostname, such as 2 for resource-api-2.
+func getShardIndex(hostname string) (int, error) {
+	separator := strings.LastIndex(hostname, "-")
+
+	if separator >= 0 {
+		if index, err := strconv.Atoi(hostname[separator+1:]); err == nil && index >= 0 {
+			return index, nil
+		}
+	}
+
+	return 0, fmt.Errorf("can't get a shard index from hostname %q: set SHARD_INDEX or run as a StatefulSet", hostname)
+}
+
+// getNodeShard returns which of count shards the named node belongs to.
+func getNodeShard(name string, count int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+
+	return int(hash.Sum32() % uint32(count))
+}
+
+// ownsNode returns whether the named node belongs to this shard.
+func (s *ShardConfig) ownsNode(name string) bool {
+	return getNodeShard(name, s.count) == s.index
+}
+
+// ownsPod returns whether a pod belongs to this shard - pods belong to the shard of the node they use up the
+// resources of, and pods that don't use any node's resources belong to the first shard so that they're only counted
+// once.
+func (s *ShardConfig) ownsPod(pod *corev1.Pod) bool {
+	node := getAccountingNode(pod)
+
+	if node == "" {
+		return s.index == 0
+	}
+
+	return s.ownsNode(node)
+}
+
+// listShardPods returns the pods that aren't terminated, as listPods does, but only those in this shard if the
+// collection is sharded. Pods are listed a page at a time so the pods of other shards are never all held at once.
+func (c *Collector) listShardPods() ([]corev1.Pod, error) {
+	if c.shard == nil {
+		return c.listPods(nonTerminatedPodSelector)
+	}
+
+	pods := make([]corev1.Pod, 0)
+	options := metav1.ListOptions{FieldSelector: nonTerminatedPodSelector, Limit: podPageSize}
+
+	for {
+		podList, err := c.client.CoreV1().Pods("").List(context.Background(), options)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range podList.Items {
+			if c.shard.ownsPod(&podList.Items[i]) {
+				pods = append(pods, podList.Items[i])
+			}
+		}
+
+		if podList.Continue == "" {
+			break
+		}
+
+		options.Continue = podList.Continue
+	}
+
+	return c.preparePods(pods)
+}
+
+// fetchShard gets the snapshot of one of the other shards.
+func (s *ShardConfig) fetchShard(index int) (*Snapshot, error) {
+	req, err := http.NewRequest(http.MethodGet, s.peers[index]+"/internal/shard", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("shard %v returned status %v", index, resp.Status)
+	}
+
+	var snapshot Snapshot
+
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// mergedSnapshot takes a snapshot of this shard and merges it with snapshots fetched from every other shard at the
+// same time. The merged snapshot has the time of this shard's. If any shard can't be reached, an error is returned
+// rather than a snapshot missing some of the cluster.
+func (c *Collector) mergedSnapshot() (*Snapshot, error) {
+	snapshots := make([]*Snapshot, c.shard.count)
+	errs := make([]error, c.shard.count)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < c.shard.count; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if i == c.shard.index {
+				snapshots[i], errs[i] = c.takeSnapshot()
+			} else {
+				snapshots[i], errs[i] = c.shard.fetchShard(i)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	merged := &Snapshot{Time: snapshots[c.shard.index].Time, Nodes: make([]NodeJson, 0), Pods: make([]PodJson, 0)}
+
+	for _, snapshot := range snapshots {
+		merged.Nodes = append(merged.Nodes, snapshot.Nodes...)
+		merged.Pods = append(merged.Pods, snapshot.Pods...)
+	}
+
+	return merged, nil
+}
+
+// getShardHandler returns a HandlerFunc to return a snapshot of only this replica's shard given a Collector, for the
+// other shards to merge.
+func getShardHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		snapshot, err := collector.takeSnapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		c.JSON(http.StatusOK, snapshot)
+	}
+
+	return gin.HandlerFunc(handler)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestGetShardIndex reads the ordinal from StatefulSet hostnames and rejects hostnames without one.
+func TestGetShardIndex(t *testing.T) {
+	if index, err := getShardIndex("resource-api-2"); index != 2 || err != nil {
+		t.Fatalf(`getShardIndex() = %v, %v, want match for %v, %v`, index, err, 2, nil)
+	}
+
+	if _, err := getShardIndex("resource-api-7d9f8b6c4-x2x9z"); err == nil {
+		t.Fatalf(`err = %v, want error`, err)
+	}
+}
+
+// TestShardedSnapshot runs two shards against the same fake cluster, checking that each collects only its own nodes
+// and that a snapshot from either has every node.
+func TestShardedSnapshot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	objects := []runtime.Object{}
+	for _, name := range []string{"node-a", "node-b", "node-c", "node-d", "node-e", "node-f"} {
+		objects = append(objects, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	client := fake.NewSimpleClientset(objects...)
+	collectors := []*Collector{newCollector(client), newCollector(client)}
+	servers := make([]*httptest.Server, 2)
+
+	for i, collector := range collectors {
+		router := gin.New()
+		router.GET("/internal/shard", requireToken("secret"), getShardHandler(collector))
+		servers[i] = httptest.NewServer(router)
+		defer servers[i].Close()
+	}
+
+	for i, collector := range collectors {
+		shard, err := newShardConfig(i, 2, []string{servers[0].URL, servers[1].URL + "/"}, "secret")
+		if err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+
+		collector.shard = shard
+	}
+
+	local, err := collectors[0].takeSnapshot()
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	for _, node := range local.Nodes {
+		if getNodeShard(node.Name, 2) != 0 {
+			t.Fatalf(`node %v is in shard %v, want match for %v`, node.Name, getNodeShard(node.Name, 2), 0)
+		}
+	}
+
+	snapshot, err := collectors[1].Snapshot()
+
+	switch {
+	case err != nil:
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	case len(snapshot.Nodes) != 6:
+		t.Fatalf(`len(snapshot.Nodes) = %v, want match for %v`, len(snapshot.Nodes), 6)
+	case len(local.Nodes) == 0 || len(local.Nodes) == 6:
+		t.Fatalf(`len(local.Nodes) = %v, want some but not all nodes`, len(local.Nodes))
+	}
+}
+
+// TestNewShardConfig checks that a peer is required for every shard.
+func TestNewShardConfig(t *testing.T) {
+	if _, err := newShardConfig(0, 3, []string{"http://a", "http://b"}, ""); err == nil {
+		t.Fatalf(`err = %v, want error`, err)
+	}
+
+	if _, err := newShardConfig(2, 2, []string{"http://a", "http://b"}, ""); err == nil {
+		t.Fatalf(`err = %v, want error`, err)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Extends the refresh lock only if this replica still holds it, so an expired lock taken by another replica isn't
+// extended by mistake
+const redisExtendLockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+
+// How long each call to Redis can take before it's abandoned, so a stuck server can't hold up requests
+const redisTimeout = 5 * time.Second
+
+// redisClient is the part of a Redis client the shared snapshot cache uses
+type redisClient interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// SharedSnapshotCache stores the latest snapshot in Redis so every replica serves the same view of the cluster.
+// Replicas take turns holding a lock, and only the holder refreshes the snapshot.
+type SharedSnapshotCache struct {
+	redis    redisClient
+	key      string        // Key the snapshot is stored under
+	lockKey  string        // Key of the lock held by the replica that refreshes the snapshot
+	id       string        // Identifies this replica as the holder of the lock
+	interval time.Duration // Time between refreshes
+}
+
+// newRedisClient returns a Redis client for a URL such as redis://:password@redis:6379/0, or rediss:// to connect
+// over TLS. If addrs has more than one address, the client connects to a Redis Cluster through them, and if
+// masterName is set, to the master of that name found through the Sentinels at addrs. Connections are pooled, and
+// nothing is sent until the first command.
+func newRedisClient(rawUrl string, addrs []string, masterName string) (redis.UniversalClient, error) {
+	options, err := redis.ParseURL(rawUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		addrs = []string{options.Addr}
+	}
+
+	return redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:                 addrs,
+		MasterName:            masterName,
+		Username:              options.Username,
+		Password:              options.Password,
+		DB:                    options.DB,
+		TLSConfig:             options.TLSConfig,
+		DialTimeout:           redisTimeout,
+		ContextTimeoutEnabled: true,
+	}), nil
+}
+
+// newSharedSnapshotCache returns a SharedSnapshotCache that stores the snapshot under prefix, identifying this replica
+// by id and refreshing the snapshot every interval.
+func newSharedSnapshotCache(redis redisClient, prefix, id string, interval time.Duration) *SharedSnapshotCache {
+	return &SharedSnapshotCache{
+		redis:    redis,
+		key:      prefix + ":snapshot",
+		lockKey:  prefix + ":refresh-lock",
+		id:       id,
+		interval: interval,
+	}
+}
+
+// ttl returns how long snapshots and the lock last. A few refreshes can be missed before either expires, after which
+// replicas fall back to taking their own snapshots and another replica can take over refreshing.
+func (s *SharedSnapshotCache) ttl() time.Duration {
+	return 3 * s.interval
+}
+
+// Load returns the stored snapshot, or false if there is none.
+func (s *SharedSnapshotCache) Load() (*Snapshot, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := s.redis.Get(ctx, s.key).Bytes()
+
+	if err == redis.Nil {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	var snapshot Snapshot
+
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, false, err
+	}
+
+	return &snapshot, true, nil
+}
+
+// Store replaces the stored snapshot.
+func (s *SharedSnapshotCache) Store(snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.redis.Set(ctx, s.key, data, s.ttl()).Err()
+}
+
+// Acquire takes the refresh lock if no replica holds it, or extends it if this replica does, returning whether this
+// replica holds it.
+func (s *SharedSnapshotCache) Acquire() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	taken, err := s.redis.SetNX(ctx, s.lockKey, s.id, s.ttl()).Result()
+
+	if err != nil || taken {
+		return taken, err
+	}
+
+	extended, err := s.redis.Eval(ctx, redisExtendLockScript, []string{s.lockKey}, s.id, s.ttl().Milliseconds()).Int64()
+
+	if err != nil {
+		return false, err
+	}
+
+	return extended == 1, nil
+}
+
+// runSharedSnapshotLoop refreshes the shared snapshot every interval while this replica is the leader or, without
+// leader election, holds the refresh lock. It blocks forever, so it should be run in its own goroutine.
+func runSharedSnapshotLoop(collector *Collector, cache *SharedSnapshotCache) {
+	ticker := time.NewTicker(cache.interval)
+	defer ticker.Stop()
+
+	for {
+		// The leader refreshes the snapshot if leader election is enabled, or else whichever replica holds the lock
+		var held bool
+		var err error
+
+		if electionEnabled.Load() {
+			held = isLeader()
+		} else {
+			held, err = cache.Acquire()
+		}
+
+		if err != nil {
+			fmt.Println(err)
+		} else if held {
+			snapshot, err := collector.freshSnapshot()
+
+			if err != nil {
+				fmt.Println(err)
+			} else if err := cache.Store(snapshot); err != nil {
+				fmt.Println(err)
+			} else {
+				debugf("stored shared snapshot of %v nodes", len(snapshot.Nodes))
+			}
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis stores values in a map, shared by every cache using it. Expiry is ignored, and Eval is assumed to be the
+// lock extension script.
+type fakeRedis struct {
+	values map[string]string
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	if value, ok := f.values[key]; ok {
+		return redis.NewStringResult(value, nil)
+	}
+
+	return redis.NewStringResult("", redis.Nil)
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	f.values[key] = string(value.([]byte))
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if _, ok := f.values[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+
+	f.values[key] = value.(string)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (f *fakeRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	if f.values[keys[0]] == args[0] {
+		return redis.NewCmdResult(int64(1), nil)
+	}
+
+	return redis.NewCmdResult(int64(0), nil)
+}
+
+// TestSharedSnapshotCache checks that only one replica holds the refresh lock and that a stored snapshot can be
+// loaded by another replica.
+func TestSharedSnapshotCache(t *testing.T) {
+	client := &fakeRedis{values: make(map[string]string)}
+
+	leader := newSharedSnapshotCache(client, "test", "replica-1", time.Minute)
+	follower := newSharedSnapshotCache(client, "test", "replica-2", time.Minute)
+
+	if _, ok, err := follower.Load(); ok || err != nil {
+		t.Fatalf(`ok, err = %v, %v, want match for %v, %v`, ok, err, false, nil)
+	}
+
+	first, _ := leader.Acquire()
+	again, _ := leader.Acquire()
+	other, _ := follower.Acquire()
+
+	switch {
+	case !first || !again:
+		t.Fatalf(`first, again = %v, %v, want match for %v, %v`, first, again, true, true)
+	case other:
+		t.Fatalf(`other = %v, want match for %v`, other, false)
+	}
+
+	snapshot := &Snapshot{Time: time.Unix(1714564800, 0).UTC(), Nodes: []NodeJson{{Name: "node-1", Free: ResourcesJson{Gpu: 2}}}}
+	if err := leader.Store(snapshot); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	loaded, ok, err := follower.Load()
+
+	switch {
+	case err != nil || !ok:
+		t.Fatalf(`ok, err = %v, %v, want match for %v, %v`, ok, err, true, nil)
+	case !loaded.Time.Equal(snapshot.Time) || len(loaded.Nodes) != 1 || loaded.Nodes[0].Free.Gpu != 2:
+		t.Fatalf(`loaded = %v, want match for %v`, loaded, snapshot)
+	}
+}
+
+// TestNewRedisClient checks that redis:// and rediss:// URLs are accepted and other schemes are rejected.
+func TestNewRedisClient(t *testing.T) {
+	for _, url := range []string{"redis://:password@redis:6379/0", "rediss://redis:6380"} {
+		if _, err := newRedisClient(url, nil, ""); err != nil {
+			t.Fatalf(`err = %v, want match for %v`, err, nil)
+		}
+	}
+
+	if _, err := newRedisClient("http://redis:6379", nil, ""); err == nil {
+		t.Fatalf(`err = %v, want error`, err)
+	}
+}
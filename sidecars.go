@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	resourcehelper "k8s.io/kubectl/pkg/util/resource"
+)
+
+// Rules for summing the requests of a pod's containers, selected with --request-rules to match the cluster's scheduler
+const (
+	requestRulesSidecar = "post-1.28" // Restartable init containers (KEP-753 sidecars) run alongside the containers
+	requestRulesLegacy  = "pre-1.28"  // Every init container runs on its own before the containers
+)
+
+// Whether pod requests are summed with the rules of schedulers before 1.28, set from --request-rules
+var legacyRequestRules bool
+
+// parseRequestRules returns whether the named request rules are those of schedulers before 1.28.
+func parseRequestRules(rules string) (bool, error) {
+	switch rules {
+	case requestRulesSidecar:
+		return false, nil
+	case requestRulesLegacy:
+		return true, nil
+	}
+
+	return false, fmt.Errorf("invalid request rules %q: expected %v or %v", rules, requestRulesSidecar, requestRulesLegacy)
+}
+
+// getPodRequestsAndLimits returns the effective requests and limits of a pod, as the scheduler counts them. Since
+// 1.28, restartable init containers keep running alongside the containers, so their requests are added to the
+// containers' and to those of every init container after them. Before 1.28, there are no restartable init
+// containers, and a pod's requests are the larger of the sum of its containers' and those of each init container.
+// Which rules resourcehelper follows depends on the version of k8s.io/kubectl this is built with, so the legacy rules
+// are computed here rather than left to it.
+func getPodRequestsAndLimits(pod *corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	if !legacyRequestRules {
+		return resourcehelper.PodRequestsAndLimits(pod)
+	}
+
+	requests := getLegacyPodResources(pod, func(resources corev1.ResourceRequirements) corev1.ResourceList { return resources.Requests })
+	limits := getLegacyPodResources(pod, func(resources corev1.ResourceRequirements) corev1.ResourceList { return resources.Limits })
+
+	// Overhead is added to every request, but only to limits that are set, as resourcehelper does
+	if pod.Spec.Overhead != nil {
+		for name, quantity := range pod.Spec.Overhead {
+			if request, ok := requests[name]; ok {
+				request.Add(quantity)
+				requests[name] = request
+			} else {
+				requests[name] = quantity.DeepCopy()
+			}
+
+			if limit, ok := limits[name]; ok && !limit.IsZero() {
+				limit.Add(quantity)
+				limits[name] = limit
+			}
+		}
+	}
+
+	return requests, limits
+}
+
+// getLegacyPodResources sums the resources get picks out of each of a pod's containers, taking the larger of that
+// sum and the resources of each init container, as schedulers before 1.28 do.
+func getLegacyPodResources(pod *corev1.Pod, get func(corev1.ResourceRequirements) corev1.ResourceList) corev1.ResourceList {
+	total := make(corev1.ResourceList)
+
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range get(container.Resources) {
+			if sum, ok := total[name]; ok {
+				sum.Add(quantity)
+				total[name] = sum
+			} else {
+				total[name] = quantity.DeepCopy()
+			}
+		}
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range get(container.Resources) {
+			if current, ok := total[name]; !ok || quantity.Cmp(current) > 0 {
+				total[name] = quantity.DeepCopy()
+			}
+		}
+	}
+
+	return total
+}
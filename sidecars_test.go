@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// TestGetPodRequestsAndLimits sums the requests of pods with restartable init containers under both request rules,
+// checking that sidecars are added to the containers and to the init containers after them since 1.28, and are
+// treated as ordinary init containers before it.
+func TestGetPodRequestsAndLimits(t *testing.T) {
+	defer func() { legacyRequestRules = false }()
+
+	always := corev1.ContainerRestartPolicyAlways
+	container := func(cpu string, restartPolicy *corev1.ContainerRestartPolicy) corev1.Container {
+		return corev1.Container{
+			RestartPolicy: restartPolicy,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+			},
+		}
+	}
+
+	pods := []corev1.Pod{
+		{
+			// A sidecar before an init container, which runs alongside it
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{container("500m", &always), container("2", nil)},
+				Containers:     []corev1.Container{container("1", nil)},
+			},
+		},
+		{
+			// A sidecar larger than the containers, with overhead
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{container("3", &always)},
+				Containers:     []corev1.Container{container("1", nil)},
+				Overhead:       corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		rules string
+		want  []float64
+	}{
+		{requestRulesSidecar, []float64{2.5, 4.25}},
+		{requestRulesLegacy, []float64{2, 3.25}},
+	} {
+		var err error
+		legacyRequestRules, err = parseRequestRules(test.rules)
+
+		if err != nil {
+			t.Fatalf(`parseRequestRules(%v) returned error %v`, test.rules, err)
+		}
+
+		for i := range pods {
+			requests, limits := getPodRequestsAndLimits(&pods[i])
+
+			if cpu := requests.Cpu().AsApproximateFloat64(); cpu != test.want[i] {
+				t.Fatalf(`%v: pods[%v] CPU request = %v, want match for %v`, test.rules, i, cpu, test.want[i])
+			}
+
+			if cpu := limits.Cpu().AsApproximateFloat64(); cpu != test.want[i] {
+				t.Fatalf(`%v: pods[%v] CPU limit = %v, want match for %v`, test.rules, i, cpu, test.want[i])
+			}
+		}
+	}
+
+	if _, err := parseRequestRules("1.28"); err == nil {
+		t.Fatalf(`parseRequestRules(1.28) = nil, want an error`)
+	}
+}
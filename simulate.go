@@ -0,0 +1,591 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// Operators of node selector requirements and the label selector operators they are evaluated with
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// DrainRequest is the body of a drain simulation request
+type DrainRequest struct {
+	Node string `json:"node"`
+}
+
+// MovedPod is a pod that would have to be rescheduled, and the node it would fit on if there is one
+type MovedPod struct {
+	Name        string        `json:"name"`
+	Namespace   string        `json:"namespace"`
+	Owner       *OwnerJson    `json:"owner,omitempty"`
+	Requests    ResourcesJson `json:"requests"`
+	Fits        bool          `json:"fits"`
+	Destination string        `json:"destination,omitempty"`
+}
+
+// BlockingPdb is a PodDisruptionBudget that allows fewer disruptions than the number of its pods being evicted
+type BlockingPdb struct {
+	Name               string `json:"name"`
+	Namespace          string `json:"namespace"`
+	DisruptionsAllowed int32  `json:"disruptionsAllowed"`
+	Evicted            int    `json:"evicted"`
+}
+
+// DrainResult says which pods would be evicted by draining a node, whether the rest of the cluster has room for
+// them, and which PodDisruptionBudgets would stop the drain
+type DrainResult struct {
+	Node         string        `json:"node"`
+	Fits         bool          `json:"fits"`
+	Pods         []MovedPod    `json:"pods"`
+	BlockingPdbs []BlockingPdb `json:"blockingPdbs"`
+}
+
+// RemoveNodesRequest is the body of a node removal simulation request. Nodes are chosen by name, by label
+// selector, or both.
+type RemoveNodesRequest struct {
+	Nodes    []string `json:"nodes"`
+	Selector string   `json:"selector"`
+}
+
+// RemoveNodesResult contains the free resources of the cluster before and after removing a set of nodes and
+// rescheduling their pods, and the pods that would have nowhere to go
+type RemoveNodesResult struct {
+	Nodes         []string      `json:"nodes"`
+	Fits          bool          `json:"fits"`
+	Free          ResourcesJson `json:"free"`
+	ProjectedFree ResourcesJson `json:"projectedFree"`
+	Moved         int           `json:"moved"`
+	Unschedulable []MovedPod    `json:"unschedulable"`
+}
+
+// AddNodesRequest is the body of a node addition simulation request. The new nodes have the given allocatable
+// resources, as Kubernetes quantities keyed by cpu, memory, gpu, or ephemeral, along with any labels and taints
+// pending pods would need to match.
+type AddNodesRequest struct {
+	Allocatable map[string]string `json:"allocatable"`
+	Count       int               `json:"count"` // Defaults to 1
+	Labels      map[string]string `json:"labels,omitempty"`
+	Taints      []corev1.Taint    `json:"taints,omitempty"`
+}
+
+// AddNodesResult contains the free resources of the cluster before and after adding nodes, and how many of the
+// pods the scheduler couldn't place would fit on the new nodes
+type AddNodesResult struct {
+	Nodes             int           `json:"nodes"`
+	Free              ResourcesJson `json:"free"`
+	ProjectedHeadroom ResourcesJson `json:"projectedHeadroom"`
+	Pending           int           `json:"pending"`
+	Schedulable       int           `json:"schedulable"`
+	Unschedulable     []MovedPod    `json:"unschedulable"`
+}
+
+// SimulateDrain works out what would happen if the named node were drained. If the node doesn't exist, a NotFound
+// error is returned.
+func (c *Collector) SimulateDrain(name string) (*DrainResult, error) {
+	pods, err := c.NodeEvictablePods(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := c.Snapshot()
+
+	if err != nil {
+		return nil, err
+	}
+
+	pdbs, err := c.PodDisruptionBudgets("")
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := getDrainSimulation(name, snapshot.Nodes, pods, pdbs)
+	return &result, nil
+}
+
+// selectNodes returns the names of the nodes in request that are in nodes, along with every node matching its
+// selector. An error is returned if a named node doesn't exist, the selector is invalid, or no nodes are chosen.
+func selectNodes(nodes []NodeJson, request RemoveNodesRequest) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	names := make(map[string]bool)
+
+	for _, node := range nodes {
+		names[node.Name] = true
+	}
+
+	for _, name := range request.Nodes {
+		if !names[name] {
+			return nil, fmt.Errorf("node %q not found", name)
+		}
+
+		selected[name] = true
+	}
+
+	if request.Selector != "" {
+		selector, err := labels.Parse(request.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %v", err)
+		}
+
+		for _, node := range nodes {
+			if selector.Matches(labels.Set(node.Labels)) {
+				selected[node.Name] = true
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no nodes selected")
+	}
+
+	return selected, nil
+}
+
+// getRemoveNodesSimulation removes the nodes in removed and places the evictable pods that were on them on the
+// rest of the nodes. The projected free resources are those of the remaining nodes once the pods that fit are
+// placed.
+func getRemoveNodesSimulation(nodes []NodeJson, removed map[string]bool, pods []corev1.Pod) RemoveNodesResult {
+	result := RemoveNodesResult{Nodes: make([]string, 0, len(removed)), Unschedulable: make([]MovedPod, 0)}
+	remaining := make([]NodeJson, 0, len(nodes))
+
+	for _, node := range nodes {
+		free := maxResources(node.Free, ResourcesJson{})
+		result.Free = addResources(result.Free, free)
+
+		if removed[node.Name] {
+			result.Nodes = append(result.Nodes, node.Name)
+			continue
+		}
+
+		remaining = append(remaining, node)
+		result.ProjectedFree = addResources(result.ProjectedFree, free)
+	}
+
+	sort.Strings(result.Nodes)
+
+	evicted := make([]corev1.Pod, 0)
+	for _, pod := range pods {
+		if removed[pod.Spec.NodeName] && isEvictable(&pod) {
+			evicted = append(evicted, pod)
+		}
+	}
+
+	moved, fits := placePods(evicted, remaining)
+	result.Fits = fits
+
+	for _, pod := range moved {
+		if !pod.Fits {
+			result.Unschedulable = append(result.Unschedulable, pod)
+			continue
+		}
+
+		result.Moved++
+		result.ProjectedFree = subtractResources(result.ProjectedFree, pod.Requests)
+	}
+
+	return result
+}
+
+// getAddNodesSimulation adds count copies of node to the cluster and places the pending pods on them. Pending pods
+// are only placed on the new nodes since the scheduler has already found no room for them on the existing ones.
+// The projected headroom is the free resources of every node once the pods that fit are placed.
+func getAddNodesSimulation(nodes []NodeJson, node NodeJson, count int, pending []corev1.Pod) AddNodesResult {
+	result := AddNodesResult{Nodes: count, Pending: len(pending), Unschedulable: make([]MovedPod, 0)}
+
+	for _, existing := range nodes {
+		result.Free = addResources(result.Free, maxResources(existing.Free, ResourcesJson{}))
+	}
+
+	added := make([]NodeJson, count)
+	result.ProjectedHeadroom = result.Free
+
+	for i := range added {
+		added[i] = node
+		added[i].Name = fmt.Sprintf("new-%d", i+1)
+		added[i].Free = node.Allocatable
+		result.ProjectedHeadroom = addResources(result.ProjectedHeadroom, node.Allocatable)
+	}
+
+	moved, _ := placePods(pending, added)
+
+	for _, pod := range moved {
+		if !pod.Fits {
+			result.Unschedulable = append(result.Unschedulable, pod)
+			continue
+		}
+
+		result.Schedulable++
+		result.ProjectedHeadroom = subtractResources(result.ProjectedHeadroom, pod.Requests)
+	}
+
+	return result
+}
+
+// NodeEvictablePods returns the pods on the named node that a drain would evict. If the node doesn't exist, a
+// NotFound error is returned.
+func (c *Collector) NodeEvictablePods(name string) ([]corev1.Pod, error) {
+	_, err := c.getNode(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := c.listPods(nonTerminatedPodSelector + ",spec.nodeName=" + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	evictable := make([]corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if isEvictable(&pod) {
+			evictable = append(evictable, pod)
+		}
+	}
+
+	return evictable, nil
+}
+
+// isEvictable returns whether draining a node would evict a pod. As with kubectl drain, DaemonSet pods are left
+// alone since they would be recreated on the same node, and mirror pods can't be evicted through the API.
+func isEvictable(pod *corev1.Pod) bool {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+
+	ref := metav1.GetControllerOf(pod)
+	return ref == nil || ref.Kind != "DaemonSet"
+}
+
+// getDrainSimulation evicts pods from the named node and places them on the other nodes, and finds the
+// PodDisruptionBudgets that wouldn't allow every evicted pod they cover to be disrupted.
+func getDrainSimulation(name string, nodes []NodeJson, pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget) DrainResult {
+	remaining := make([]NodeJson, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Name != name {
+			remaining = append(remaining, node)
+		}
+	}
+
+	moved, fits := placePods(pods, remaining)
+
+	return DrainResult{
+		Node:         name,
+		Fits:         fits,
+		Pods:         moved,
+		BlockingPdbs: getBlockingPdbs(pods, pdbs),
+	}
+}
+
+// placePods places each pod on the first node, by name, that it tolerates, that matches its node selector and
+// required node affinity, and that has room for its requests, largest pods first. It returns where each pod went
+// in the order they were given, and whether every pod was placed.
+func placePods(pods []corev1.Pod, nodes []NodeJson) ([]MovedPod, bool) {
+	nodes = append([]NodeJson(nil), nodes...)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	free := make([]ResourcesJson, len(nodes))
+	for i, node := range nodes {
+		free[i] = maxResources(node.Free, ResourcesJson{})
+	}
+
+	moved := make([]MovedPod, len(pods))
+	order := make([]int, len(pods))
+
+	for i := range pods {
+		podJson := getPodStructured(&pods[i])
+		moved[i] = MovedPod{Name: podJson.Name, Namespace: podJson.Namespace, Owner: podJson.Owner, Requests: podJson.Requests}
+		order[i] = i
+	}
+
+	// Place GPUs first since they are scarcest, then CPU, then memory
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := moved[order[i]].Requests, moved[order[j]].Requests
+		if a.Gpu != b.Gpu {
+			return a.Gpu > b.Gpu
+		}
+		if a.Cpu != b.Cpu {
+			return a.Cpu > b.Cpu
+		}
+		return a.Memory > b.Memory
+	})
+
+	fits := true
+
+	for _, i := range order {
+		for j, node := range nodes {
+			if !canSchedule(&pods[i], node) || !fitsIn(moved[i].Requests, free[j]) {
+				continue
+			}
+
+			free[j] = subtractResources(free[j], moved[i].Requests)
+			moved[i].Fits = true
+			moved[i].Destination = node.Name
+			break
+		}
+
+		fits = fits && moved[i].Fits
+	}
+
+	return moved, fits
+}
+
+// canSchedule returns whether a pod tolerates every NoSchedule and NoExecute taint of a node and matches its
+// labels with its node selector and required node affinity. Node affinity terms on fields are not checked.
+func canSchedule(pod *corev1.Pod, node NodeJson) bool {
+	for _, taint := range node.Taints {
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		tolerated := false
+		for _, toleration := range pod.Spec.Tolerations {
+			if toleration.ToleratesTaint(&taint) {
+				tolerated = true
+				break
+			}
+		}
+
+		if !tolerated {
+			return false
+		}
+	}
+
+	if !matchesSelector(node, pod.Spec.NodeSelector) {
+		return false
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+
+	// The terms are ORed together, and the requirements of each term are ANDed
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if matchesNodeSelectorTerm(node, term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesNodeSelectorTerm returns whether a node's labels meet every expression of a node selector term. A term
+// with no expressions matches no nodes.
+func matchesNodeSelectorTerm(node NodeJson, term corev1.NodeSelectorTerm) bool {
+	if len(term.MatchExpressions) == 0 {
+		return false
+	}
+
+	selector := labels.NewSelector()
+
+	for _, expression := range term.MatchExpressions {
+		requirement, err := labels.NewRequirement(expression.Key, nodeSelectorOperators[expression.Operator], expression.Values)
+		if err != nil {
+			return false
+		}
+
+		selector = selector.Add(*requirement)
+	}
+
+	return selector.Matches(labels.Set(node.Labels))
+}
+
+// getBlockingPdbs returns the PodDisruptionBudgets that select more of the evicted pods than they allow to be
+// disrupted, sorted by namespace and name.
+func getBlockingPdbs(pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget) []BlockingPdb {
+	blocking := make([]BlockingPdb, 0)
+
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+
+		evicted := 0
+		for _, pod := range pods {
+			if pod.Namespace == pdb.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+				evicted++
+			}
+		}
+
+		if evicted > 0 && int32(evicted) > pdb.Status.DisruptionsAllowed {
+			blocking = append(blocking, BlockingPdb{
+				Name:               pdb.Name,
+				Namespace:          pdb.Namespace,
+				DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+				Evicted:            evicted,
+			})
+		}
+	}
+
+	sort.Slice(blocking, func(i, j int) bool {
+		if blocking[i].Namespace != blocking[j].Namespace {
+			return blocking[i].Namespace < blocking[j].Namespace
+		}
+		return blocking[i].Name < blocking[j].Name
+	})
+
+	return blocking
+}
+
+// getRemoveNodesHandler returns a HandlerFunc to simulate removing the nodes given in a RemoveNodesRequest in the
+// request body given a Collector.
+func getRemoveNodesHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var request RemoveNodesRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		if len(request.Nodes) == 0 && request.Selector == "" {
+			c.JSON(http.StatusBadRequest, "error: nodes or selector is required")
+			return
+		}
+
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		removed, err := selectNodes(snapshot.Nodes, request)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		// Only the pods on the removed nodes need to be placed, so only ask for theirs
+		names := make([]string, 0, len(removed))
+		for name := range removed {
+			names = append(names, name)
+		}
+
+		pods, err := collector.listNodesPods(names)
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getRemoveNodesSimulation(snapshot.Nodes, removed, pods))
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getAddNodesHandler returns a HandlerFunc to simulate adding the nodes described by an AddNodesRequest in the
+// request body given a Collector.
+func getAddNodesHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var request AddNodesRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		if request.Count == 0 {
+			request.Count = 1
+		}
+
+		if request.Count < 0 {
+			c.JSON(http.StatusBadRequest, "error: count must not be negative")
+			return
+		}
+
+		allocatable, err := parseQuantities(request.Allocatable)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "error: invalid allocatable: "+err.Error())
+			return
+		}
+
+		if allocatable == (ResourcesJson{}) {
+			c.JSON(http.StatusBadRequest, "error: allocatable is required")
+			return
+		}
+
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		pending, err := collector.UnschedulablePods()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving pod information")
+			return
+		}
+
+		node := NodeJson{Labels: request.Labels, Taints: request.Taints, Allocatable: allocatable}
+
+		c.IndentedJSON(http.StatusOK, getAddNodesSimulation(snapshot.Nodes, node, request.Count, pending))
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getDrainHandler returns a HandlerFunc to simulate draining the node given in a DrainRequest in the request body
+// given a Collector.
+func getDrainHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var request DrainRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		if request.Node == "" {
+			c.JSON(http.StatusBadRequest, "error: node is required")
+			return
+		}
+
+		result, err := collector.SimulateDrain(request.Node)
+
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, "error: node not found")
+			return
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error simulating drain")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(handler)
+}
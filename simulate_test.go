@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newTestPod returns a pod in namespace default with a single container requesting cpu.
+func newTestPod(name, cpu string, labels map[string]string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "main",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+				},
+			}},
+		},
+	}
+}
+
+// TestGetDrainSimulation drains a node whose pods have to respect a taint and a node selector, and checks that a
+// PodDisruptionBudget allowing no disruptions blocks the drain.
+func TestGetDrainSimulation(t *testing.T) {
+	nodes := []NodeJson{
+		{Name: "drained", Free: ResourcesJson{Cpu: 10}},
+		{Name: "a-tainted", Taints: []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}, Free: ResourcesJson{Cpu: 8}},
+		{Name: "b-ssd", Labels: map[string]string{"disk": "ssd"}, Free: ResourcesJson{Cpu: 2}},
+		{Name: "c-plain", Free: ResourcesJson{Cpu: 4}},
+	}
+
+	web := newTestPod("web", "1", map[string]string{"app": "web"})
+
+	db := newTestPod("db", "2", map[string]string{"app": "db"})
+	db.Spec.NodeSelector = map[string]string{"disk": "ssd"}
+
+	trainer := newTestPod("trainer", "6", nil)
+	trainer.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "gpu"}}
+
+	batch := newTestPod("batch", "4", nil)
+
+	pdbs := []policyv1.PodDisruptionBudget{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 0},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+		},
+	}
+
+	result := getDrainSimulation("drained", nodes, []corev1.Pod{web, db, trainer, batch}, pdbs)
+
+	// The trainer tolerates the taint and goes first, db needs the ssd node, batch fills c-plain, leaving no room
+	// for web anywhere it is allowed
+	want := []string{"", "b-ssd", "a-tainted", "c-plain"}
+
+	for i, pod := range result.Pods {
+		if pod.Destination != want[i] || pod.Fits != (want[i] != "") {
+			t.Fatalf(`result.Pods[%v] = %v, want destination %q`, i, pod, want[i])
+		}
+	}
+
+	switch {
+	case result.Fits:
+		t.Fatalf(`result.Fits = %v, want match for %v`, result.Fits, false)
+	case len(result.BlockingPdbs) != 1 || result.BlockingPdbs[0].Name != "web" || result.BlockingPdbs[0].Evicted != 1:
+		t.Fatalf(`result.BlockingPdbs = %v, want only web`, result.BlockingPdbs)
+	}
+}
+
+// TestCanScheduleNodeAffinity checks that required node affinity terms are ORed and their expressions ANDed.
+func TestCanScheduleNodeAffinity(t *testing.T) {
+	pod := newTestPod("pod", "1", nil)
+	pod.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+			{MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+				{Key: "spot", Operator: corev1.NodeSelectorOpDoesNotExist},
+			}},
+			{MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: "gpus", Operator: corev1.NodeSelectorOpGt, Values: []string{"4"}},
+			}},
+		}},
+	}}
+
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{"zone": "a"}, true},
+		{map[string]string{"zone": "a", "spot": "true"}, false},
+		{map[string]string{"zone": "c"}, false},
+		{map[string]string{"gpus": "8"}, true},
+		{map[string]string{"gpus": "2"}, false},
+	}
+
+	for _, test := range tests {
+		if got := canSchedule(&pod, NodeJson{Labels: test.labels}); got != test.want {
+			t.Fatalf(`canSchedule(%v) = %v, want match for %v`, test.labels, got, test.want)
+		}
+	}
+}
+
+// TestGetRemoveNodesSimulation removes the nodes matching a selector and checks which pods move, which are left
+// unschedulable, and the projected free resources.
+func TestGetRemoveNodesSimulation(t *testing.T) {
+	nodes := []NodeJson{
+		{Name: "spot-1", Labels: map[string]string{"capacity": "spot"}, Free: ResourcesJson{Cpu: 1}},
+		{Name: "spot-2", Labels: map[string]string{"capacity": "spot"}, Free: ResourcesJson{Cpu: -1}},
+		{Name: "on-demand", Labels: map[string]string{"capacity": "on-demand"}, Free: ResourcesJson{Cpu: 5}},
+	}
+
+	removed, err := selectNodes(nodes, RemoveNodesRequest{Selector: "capacity=spot"})
+	if err != nil {
+		t.Fatalf(`selectNodes() err = %v, want match for %v`, err, nil)
+	}
+
+	web := newTestPod("web", "2", nil)
+	web.Spec.NodeName = "spot-1"
+
+	batch := newTestPod("batch", "4", nil)
+	batch.Spec.NodeName = "spot-2"
+
+	daemon := newTestPod("daemon", "1", nil)
+	daemon.Spec.NodeName = "spot-2"
+	controller := true
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "daemon", Controller: &controller}}
+
+	stays := newTestPod("stays", "1", nil)
+	stays.Spec.NodeName = "on-demand"
+
+	result := getRemoveNodesSimulation(nodes, removed, []corev1.Pod{web, batch, daemon, stays})
+
+	switch {
+	case len(result.Nodes) != 2 || result.Nodes[0] != "spot-1" || result.Nodes[1] != "spot-2":
+		t.Fatalf(`result.Nodes = %v, want match for %v`, result.Nodes, []string{"spot-1", "spot-2"})
+	case result.Fits || result.Moved != 1:
+		t.Fatalf(`result.Fits, result.Moved = %v, %v, want match for %v, %v`, result.Fits, result.Moved, false, 1)
+	case len(result.Unschedulable) != 1 || result.Unschedulable[0].Name != "web":
+		t.Fatalf(`result.Unschedulable = %v, want only web`, result.Unschedulable)
+	case result.Free.Cpu != 6 || result.ProjectedFree.Cpu != 1:
+		t.Fatalf(`result.Free.Cpu, result.ProjectedFree.Cpu = %v, %v, want match for %v, %v`, result.Free.Cpu, result.ProjectedFree.Cpu, 6, 1)
+	}
+
+	if _, err := selectNodes(nodes, RemoveNodesRequest{Nodes: []string{"missing"}}); err == nil {
+		t.Fatalf(`selectNodes() err = %v, want an error for a missing node`, err)
+	}
+}
+
+// TestGetAddNodesSimulation adds two tainted GPU nodes and checks that only pending pods that tolerate the taint
+// and fit are counted as schedulable.
+func TestGetAddNodesSimulation(t *testing.T) {
+	nodes := []NodeJson{{Name: "existing", Free: ResourcesJson{Cpu: 2}}}
+
+	node := NodeJson{
+		Labels:      map[string]string{"pool": "gpu"},
+		Taints:      []corev1.Taint{{Key: "nvidia.com/gpu", Effect: corev1.TaintEffectNoSchedule}},
+		Allocatable: ResourcesJson{Cpu: 8},
+	}
+
+	toleration := []corev1.Toleration{{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists}}
+
+	first := newTestPod("first", "6", nil)
+	first.Spec.Tolerations = toleration
+
+	second := newTestPod("second", "6", nil)
+	second.Spec.Tolerations = toleration
+
+	third := newTestPod("third", "6", nil)
+	third.Spec.Tolerations = toleration
+
+	untolerated := newTestPod("untolerated", "1", nil)
+
+	result := getAddNodesSimulation(nodes, node, 2, []corev1.Pod{first, second, third, untolerated})
+
+	switch {
+	case result.Pending != 4 || result.Schedulable != 2:
+		t.Fatalf(`result.Pending, result.Schedulable = %v, %v, want match for %v, %v`, result.Pending, result.Schedulable, 4, 2)
+	case len(result.Unschedulable) != 2 || result.Unschedulable[0].Name != "third" || result.Unschedulable[1].Name != "untolerated":
+		t.Fatalf(`result.Unschedulable = %v, want third and untolerated`, result.Unschedulable)
+	case result.Free.Cpu != 2 || result.ProjectedHeadroom.Cpu != 6:
+		t.Fatalf(`result.Free.Cpu, result.ProjectedHeadroom.Cpu = %v, %v, want match for %v, %v`, result.Free.Cpu, result.ProjectedHeadroom.Cpu, 2, 6)
+	}
+}
+
+// TestListNodesPods checks that the pods of a few nodes are queried one node at a time, which the fake client
+// doesn't support by itself, so a reactor filters pods by the node in the field selector.
+func TestListNodesPods(t *testing.T) {
+	pods := []runtime.Object{}
+	for i, node := range []string{"node-1", "node-1", "node-2", "node-3"} {
+		pod := newTestPod(fmt.Sprintf("pod-%v", i), "1", nil)
+		pod.Spec.NodeName = node
+		pods = append(pods, &pod)
+	}
+
+	client := fake.NewSimpleClientset(pods...)
+
+	var queries atomic.Int32
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		queries.Add(1)
+
+		node, _ := action.(k8stesting.ListAction).GetListRestrictions().Fields.RequiresExactMatch("spec.nodeName")
+		list := &corev1.PodList{}
+		for _, object := range pods {
+			if pod := object.(*corev1.Pod); pod.Spec.NodeName == node {
+				list.Items = append(list.Items, *pod)
+			}
+		}
+
+		return true, list, nil
+	})
+
+	result, err := newCollector(client).listNodesPods([]string{"node-1", "node-3"})
+
+	switch {
+	case err != nil:
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	case queries.Load() != 2:
+		t.Fatalf(`queries = %v, want match for %v`, queries.Load(), 2)
+	case len(result) != 3 || result[0].Spec.NodeName != "node-1" || result[2].Spec.NodeName != "node-3":
+		t.Fatalf(`len(result) = %v, want match for %v`, len(result), 3)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SlackNotifier sends each alert to a Slack incoming webhook as a message block
+type SlackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// slackMessage is the body of a Slack incoming webhook request
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// slackBlock is a single section of a Slack message
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+// slackText is a piece of text in a Slack message block
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// newSlackNotifier returns a SlackNotifier that posts to the incoming webhook at url.
+func newSlackNotifier(url string) *SlackNotifier {
+	return &SlackNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify posts the alert to Slack.
+func (s *SlackNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(getSlackMessage(alert))
+
+	if err != nil {
+		return err
+	}
+
+	return postJson(s.client, s.url, body)
+}
+
+// getSlackMessage formats an alert as a Slack message with the node, resource, threshold, and current value.
+func getSlackMessage(alert Alert) slackMessage {
+	// Say where the alert is - cluster rules don't have a node
+	location := "the cluster"
+	if alert.Node != "" {
+		location = alert.Node
+	}
+
+	var summary string
+	if alert.Status == "resolved" {
+		summary = fmt.Sprintf(":white_check_mark: Resolved: %v on %v", alert.Rule, location)
+	} else {
+		summary = fmt.Sprintf(":rotating_light: Firing: %v on %v", alert.Rule, location)
+	}
+
+	return slackMessage{
+		// Fallback text for notifications that can't show blocks
+		Text: summary,
+		Blocks: []slackBlock{
+			{
+				Type: "section",
+				Text: &slackText{Type: "mrkdwn", Text: "*" + summary + "*"},
+			},
+			{
+				Type: "section",
+				Fields: []slackText{
+					{Type: "mrkdwn", Text: "*Node:*\n" + location},
+					{Type: "mrkdwn", Text: "*Resource:*\n" + alert.Resource},
+					{Type: "mrkdwn", Text: "*Threshold:*\nbelow " + formatAlertValue(alert.Threshold, alert.Resource, alert.Percent)},
+					{Type: "mrkdwn", Text: "*Current free:*\n" + formatAlertValue(alert.Value, alert.Resource, alert.Percent)},
+				},
+			},
+		},
+	}
+}
+
+// formatAlertValue formats a value of a resource for people to read - percentages get a percent sign,
+// and byte amounts are converted to the largest binary unit that keeps them above 1.
+func formatAlertValue(value float64, resource string, percent bool) string {
+	if percent {
+		return fmt.Sprintf("%.1f%%", value)
+	}
+
+	if resource != "memory" && resource != "ephemeral" {
+		return fmt.Sprintf("%g", value)
+	}
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+
+	return fmt.Sprintf("%.1f %v", value, units[i])
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGetSlackMessage formats firing and resolved alerts as Slack messages, checking that the node, resource,
+// threshold, and current value appear in the message fields.
+func TestGetSlackMessage(t *testing.T) {
+	alert := Alert{
+		Rule:      "node-low-memory",
+		Status:    "firing",
+		Node:      "node-1",
+		Resource:  "memory",
+		Value:     3 * 1024 * 1024 * 1024,
+		Threshold: 4 * 1024 * 1024 * 1024,
+	}
+
+	message := getSlackMessage(alert)
+
+	if !strings.Contains(message.Text, "Firing") || !strings.Contains(message.Text, "node-1") {
+		t.Fatalf(`message.Text = %v, want it to contain %v and %v`, message.Text, "Firing", "node-1")
+	}
+
+	if len(message.Blocks) != 2 || len(message.Blocks[1].Fields) != 4 {
+		t.Fatalf(`message.Blocks = %v, want 2 blocks with 4 fields in the second`, message.Blocks)
+	}
+
+	wantFields := []string{"node-1", "memory", "below 4.0 GiB", "3.0 GiB"}
+	for i, want := range wantFields {
+		if field := message.Blocks[1].Fields[i].Text; !strings.HasSuffix(field, want) {
+			t.Fatalf(`message.Blocks[1].Fields[%v].Text = %v, want suffix %v`, i, field, want)
+		}
+	}
+
+	// A resolved cluster-wide percentage alert
+	alert = Alert{Rule: "cluster-low-gpu", Status: "resolved", Resource: "gpu", Value: 25, Threshold: 10, Percent: true}
+	message = getSlackMessage(alert)
+
+	if !strings.Contains(message.Text, "Resolved") || !strings.Contains(message.Text, "the cluster") {
+		t.Fatalf(`message.Text = %v, want it to contain %v and %v`, message.Text, "Resolved", "the cluster")
+	}
+
+	if field := message.Blocks[1].Fields[3].Text; !strings.HasSuffix(field, "25.0%") {
+		t.Fatalf(`message.Blocks[1].Fields[3].Text = %v, want suffix %v`, field, "25.0%")
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Most queries for the pods of single nodes that run at once
+const podQueryWorkers = 8
+
+// Most nodes whose pods are queried one node at a time, rather than listing every pod in the cluster
+const maxNodePodQueries = 64
+
+// Snapshot contains the resources of every node in the cluster at a point in time
+type Snapshot struct {
+	Time    time.Time  `json:"time"`
+	Version uint64     `json:"version,omitempty"` // Version of the nodes given by the Collector's NodeTracker
+	Nodes   []NodeJson `json:"nodes"`
+	Pods    []PodJson  `json:"pods,omitempty"`
+}
+
+// Collector gets the state of every node in the cluster, along with anything that is derived from it
+type Collector struct {
+	client           kubernetes.Interface
+	dynamic          dynamic.Interface             // Optional - used to read custom resources such as VerticalPodAutoscalers
+	poolLabel        string                        // Label naming the pool of each node - well-known labels are checked if empty
+	costs            CostProvider                  // Optional - nodes have no cost if nil
+	autoscalerStatus string                        // Namespace and name of the cluster-autoscaler status ConfigMap
+	tracker          *NodeTracker                  // Versions the nodes of every snapshot
+	plugins          []CollectorPlugin             // Optional - add site-specific information to the nodes of every snapshot
+	shared           *SharedSnapshotCache          // Optional - serve the snapshot refreshed by one replica for all of them
+	shard            *ShardConfig                  // Optional - only collect some of the nodes, merging the rest from other replicas
+	imported         *Snapshot                     // Optional - serve this snapshot rather than reading the cluster
+	staleThreshold   time.Duration                 // Longest a node can go without a heartbeat before it is stale
+	readiness        *ReadinessTracker             // Counts changes of each node's Ready condition between snapshots
+	kubelet          *KubeletReader                // Optional - read each node's kubelet through the apiserver
+	kubeletConfig    bool                          // Add what is read from each kubelet's configuration to the nodes
+	kubeletStats     bool                          // Add what is read from each kubelet's stats summary to the nodes
+	nodeAddresses    bool                          // Add the addresses of each node to the nodes
+	releasePods      bool                          // Don't count pods past their grace period or on long dead nodes
+	refreshes        atomic.Int64                  // Number of forced refreshes, which cached snapshots are discarded after
+	lastSnapshot     atomic.Pointer[SnapshotStats] // Statistics of the last snapshot taken from the cluster
+	cachesMutex      sync.Mutex
+	caches           []*CachedSnapshot // Caches of the collector's snapshots, for reporting their age
+}
+
+// newCollector returns a Collector that reads the cluster through client.
+func newCollector(client kubernetes.Interface) *Collector {
+	return &Collector{
+		client:           client,
+		autoscalerStatus: defaultAutoscalerStatus,
+		tracker:          newNodeTracker(),
+		staleThreshold:   defaultStaleThreshold,
+		readiness:        newReadinessTracker(),
+	}
+}
+
+// Snapshot returns the snapshot shared between replicas if there is one, or else takes a new snapshot. Replicas
+// take their own snapshots if the shared one can't be read, so they keep serving while Redis is unavailable.
+func (c *Collector) Snapshot() (*Snapshot, error) {
+	if c.imported != nil {
+		return c.importedSnapshot(), nil
+	}
+
+	if c.shared != nil {
+		snapshot, ok, err := c.shared.Load()
+
+		if err != nil {
+			fmt.Println(err)
+		}
+
+		if ok {
+			snapshot.Version = c.tracker.Update(snapshot.Nodes)
+			return snapshot, nil
+		}
+	}
+
+	snapshot, err := c.freshSnapshot()
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.Version = c.tracker.Update(snapshot.Nodes)
+
+	return snapshot, nil
+}
+
+// freshSnapshot takes a new snapshot of the whole cluster, merging the snapshots of every shard if the collection is
+// sharded.
+func (c *Collector) freshSnapshot() (*Snapshot, error) {
+	start := time.Now()
+
+	var snapshot *Snapshot
+	var err error
+
+	if c.shard != nil {
+		snapshot, err = c.mergedSnapshot()
+	} else {
+		snapshot, err = c.takeSnapshot()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.lastSnapshot.Store(&SnapshotStats{Time: snapshot.Time, Duration: time.Since(start).Seconds(), Nodes: len(snapshot.Nodes), Pods: len(snapshot.Pods)})
+
+	return snapshot, nil
+}
+
+// takeSnapshot gets the capacity, allocatable, and free resources of every node in the cluster, or only those in
+// this replica's shard, along with the requests of every pod on them, and returns them as a Snapshot taken at the
+// current time.
+func (c *Collector) takeSnapshot() (*Snapshot, error) {
+	// Create a map of string to Node struct instances
+	nodes := make(map[string]*Node)
+
+	// Get the node capacity, allocatable resources, name, and taints
+	err := getNodeInfo(c.client, nodes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Only keep the nodes of this shard, if the collection is sharded
+	if c.shard != nil {
+		for name := range nodes {
+			if !c.shard.ownsNode(name) {
+				delete(nodes, name)
+			}
+		}
+	}
+
+	// Leave out the nodes configured to be excluded, such as control-plane nodes
+	excluded := make(map[string]bool)
+	for name, node := range nodes {
+		if isExcludedNode(name, node.Labels) {
+			excluded[name] = true
+			delete(nodes, name)
+		}
+	}
+
+	// Get every pod that could be using resources, with the requests LimitRanges would give it, other than those on
+	// excluded nodes
+	pods, err := c.listShardPods()
+
+	if err != nil {
+		return nil, err
+	}
+
+	pods = excludeNodePods(pods, excluded)
+	now := time.Now()
+
+	// Pods that are terminating past their grace period or stuck on dead nodes can be left out of the free resources
+	counted := pods
+	if c.releasePods {
+		counted = excludeReleasedPods(pods, nodes, now)
+	}
+
+	// Get the available resources of the nodes
+	subtractPodRequests(counted, nodes)
+	risks := getEvictionRisks(counted, nodes)
+	daemonSets := getDaemonSetRequests(counted, nodes)
+
+	readiness := c.readiness.Update(nodes, now)
+
+	snapshot := Snapshot{
+		Time:  now,
+		Nodes: make([]NodeJson, 0, len(nodes)),
+		Pods:  make([]PodJson, 0, len(pods)),
+	}
+
+	// Pods in excluded namespaces still use up their nodes' resources, but are left out of the list. Only pods listed
+	// for /pods are broken down by container, to keep snapshots small.
+	for i := range pods {
+		if !isExcludedNamespace(pods[i].Namespace) {
+			podJson := getPodStructured(&pods[i])
+			podJson.Containers = nil
+			snapshot.Pods = append(snapshot.Pods, podJson)
+		}
+	}
+
+	// The pool label in the config file takes precedence, since it can be changed without a restart
+	poolLabel := c.poolLabel
+	if label := currentConfig().PoolLabel; label != "" {
+		poolLabel = label
+	}
+
+	// Convert each node to JSON and add it to the snapshot
+	for _, value := range nodes {
+		nodeJson := getNodeStructured(value)
+		nodeJson.Pool = getNodePool(value.Labels, poolLabel)
+		nodeJson.EvictionRisk = risks[value.Name]
+		nodeJson.DaemonSetRequests = daemonSets[value.Name]
+
+		if c.nodeAddresses {
+			nodeJson.Addresses = value.Addresses
+		}
+
+		if ready, ok := readiness[value.Name]; ok {
+			nodeJson.Readiness = &ready
+		}
+
+		// Add the estimated cost of the node if we know it
+		if c.costs != nil {
+			if cost, ok := c.costs.HourlyCost(value); ok {
+				nodeJson.HourlyCost = &cost
+			}
+		}
+
+		snapshot.Nodes = append(snapshot.Nodes, nodeJson)
+	}
+
+	setNodeAges(snapshot.Nodes, snapshot.Time)
+	c.addNodeHeartbeats(&snapshot)
+	c.addKubeletInfo(&snapshot)
+	runCollectorPlugins(c.plugins, &snapshot)
+	setComputedFields(snapshot.Nodes, currentConfig().computed)
+
+	return &snapshot, nil
+}
+
+// Pods returns the requests and limits of every pod in the cluster that isn't terminated, other than those in
+// excluded namespaces.
+func (c *Collector) Pods() ([]PodJson, error) {
+	if c.imported != nil {
+		return c.importedSnapshot().Pods, nil
+	}
+
+	pods, err := c.listPods(nonTerminatedPodSelector)
+
+	if err != nil {
+		return nil, err
+	}
+
+	excluded, err := c.excludedNodeNames()
+
+	if err != nil {
+		return nil, err
+	}
+
+	pods = excludeNodePods(pods, excluded)
+
+	podJsons := make([]PodJson, 0, len(pods))
+	for i := range pods {
+		if !isExcludedNamespace(pods[i].Namespace) {
+			podJsons = append(podJsons, getPodStructured(&pods[i]))
+		}
+	}
+
+	return podJsons, nil
+}
+
+// NodePods returns the requests and limits of every pod scheduled on the named node that isn't terminated.
+// If the node doesn't exist, a NotFound error is returned.
+func (c *Collector) NodePods(name string) ([]PodJson, error) {
+	if c.imported != nil {
+		return c.importedNodePods(name)
+	}
+
+	_, err := c.getNode(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Only ask for the pods on this node rather than filtering every pod in the cluster
+	pods, err := c.listPods(nonTerminatedPodSelector + ",spec.nodeName=" + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	podJsons := make([]PodJson, 0, len(pods))
+	for i := range pods {
+		podJsons = append(podJsons, getPodStructured(&pods[i]))
+	}
+
+	return podJsons, nil
+}
+
+// listNodesPods returns the pods on the named nodes that aren't terminated, as listPods does. Each node's pods are
+// queried separately with a field selector, with at most podQueryWorkers queries at once. Past maxNodePodQueries
+// nodes, every pod in the cluster is listed in one query instead, which is cheaper than many small ones.
+func (c *Collector) listNodesPods(names []string) ([]corev1.Pod, error) {
+	if len(names) > maxNodePodQueries {
+		return c.listPods(nonTerminatedPodSelector)
+	}
+
+	results := make([][]corev1.Pod, len(names))
+	errs := make([]error, len(names))
+	workers := make(chan struct{}, podQueryWorkers)
+
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		wg.Add(1)
+		workers <- struct{}{}
+
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			podList, err := c.client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
+				FieldSelector: nonTerminatedPodSelector + ",spec.nodeName=" + name,
+			})
+
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			results[i] = podList.Items
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	pods := make([]corev1.Pod, 0)
+	for i := range names {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+
+		pods = append(pods, results[i]...)
+	}
+
+	return c.preparePods(pods)
+}
+
+// CachedSnapshot reuses a Collector's snapshots for a while, for callers that need one for every request but can
+// tolerate it being a little out of date
+type CachedSnapshot struct {
+	name      string // Identifies the cache in /debug/cache
+	collector *Collector
+	ttl       time.Duration
+	mutex     sync.Mutex
+	snapshot  *Snapshot
+	refreshes int64 // Forced refreshes of the collector when the snapshot was taken
+}
+
+// newCachedSnapshot returns a CachedSnapshot named name that takes a new snapshot with collector at most once every
+// ttl, and adds it to the collector's caches.
+func newCachedSnapshot(collector *Collector, name string, ttl time.Duration) *CachedSnapshot {
+	cache := &CachedSnapshot{name: name, collector: collector, ttl: ttl}
+
+	collector.cachesMutex.Lock()
+	collector.caches = append(collector.caches, cache)
+	collector.cachesMutex.Unlock()
+
+	return cache
+}
+
+// Get returns the latest snapshot, taking a new one if it is older than ttl or the collector has been refreshed since
+// it was taken. The snapshot is shared between callers, so it must not be changed.
+func (c *CachedSnapshot) Get() (*Snapshot, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	refreshes := c.collector.refreshes.Load()
+
+	if c.snapshot == nil || time.Since(c.snapshot.Time) > c.ttl || refreshes != c.refreshes {
+		snapshot, err := c.collector.Snapshot()
+
+		if err != nil {
+			return nil, err
+		}
+
+		c.snapshot = snapshot
+		c.refreshes = refreshes
+	}
+
+	return c.snapshot, nil
+}
+
+// runSnapshotLoop takes a snapshot of the cluster every interval and passes it to each of the handlers.
+// It blocks forever, so it should be run in its own goroutine.
+func runSnapshotLoop(collector *Collector, interval time.Duration, handlers []func(*Snapshot)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		snapshot, err := collector.Snapshot()
+
+		// Skip this interval if the cluster couldn't be reached - the next tick will try again
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			debugf("took snapshot of %v nodes and %v pods in %v", len(snapshot.Nodes), len(snapshot.Pods), time.Since(start))
+			for _, handler := range handlers {
+				handler(snapshot)
+			}
+		}
+
+		<-ticker.C
+	}
+}
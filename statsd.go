@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Largest datagram sent to the agent, which keeps packets within the MTU of most networks
+const maxStatsdPacketSize = 1432
+
+// Replaces characters that would end a DogStatsD tag early
+var statsdTagEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+// StatsdEmitter sends the free and allocatable resources of each node to a DogStatsD agent as gauges
+type StatsdEmitter struct {
+	conn      net.Conn
+	prefix    string
+	labelTags []string // Node labels added to each gauge as tags
+}
+
+// newStatsdEmitter returns a StatsdEmitter that sends gauges named under prefix to the agent at address, tagging
+// them with the values of the given node labels.
+func newStatsdEmitter(address, prefix string, labelTags []string) (*StatsdEmitter, error) {
+	// The agent listens on 8125 unless told otherwise
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "8125")
+	}
+
+	conn, err := net.Dial("udp", address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsdEmitter{conn: conn, prefix: prefix, labelTags: labelTags}, nil
+}
+
+// Emit sends the gauges for a snapshot, packing as many into each datagram as fit.
+func (e *StatsdEmitter) Emit(snapshot *Snapshot) error {
+	var packet []byte
+
+	for _, line := range encodeStatsdGauges(snapshot, e.prefix, e.labelTags) {
+		if len(packet) > 0 && len(packet)+1+len(line) > maxStatsdPacketSize {
+			if _, err := e.conn.Write(packet); err != nil {
+				return err
+			}
+
+			packet = packet[:0]
+		}
+
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+
+		packet = append(packet, line...)
+	}
+
+	if len(packet) > 0 {
+		if _, err := e.conn.Write(packet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeStatsdGauges formats a snapshot as DogStatsD gauges - <prefix>.free.<resource> and
+// <prefix>.allocatable.<resource> for each node, tagged with the node, pool, capacity type, and any of the given
+// labels the node has. Nodes are sorted by name.
+func encodeStatsdGauges(snapshot *Snapshot, prefix string, labelTags []string) []string {
+	nodes := append([]NodeJson(nil), snapshot.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	lines := make([]string, 0, len(nodes)*len(resourceNames)*2)
+
+	for _, node := range nodes {
+		tags := []string{"node:" + statsdTagEscaper.Replace(node.Name)}
+		if node.Pool != "" {
+			tags = append(tags, "pool:"+statsdTagEscaper.Replace(node.Pool))
+		}
+		if node.CapacityType != "" {
+			tags = append(tags, "capacity_type:"+statsdTagEscaper.Replace(node.CapacityType))
+		}
+
+		for _, label := range labelTags {
+			if value, ok := node.Labels[label]; ok {
+				tags = append(tags, statsdTagEscaper.Replace(label)+":"+statsdTagEscaper.Replace(value))
+			}
+		}
+
+		suffix := "|g|#" + strings.Join(tags, ",")
+
+		for _, resource := range resourceNames {
+			lines = append(lines,
+				fmt.Sprintf("%v.free.%v:%v%v", prefix, resource, strconv.FormatFloat(getResource(node.Free, resource), 'f', -1, 64), suffix),
+				fmt.Sprintf("%v.allocatable.%v:%v%v", prefix, resource, strconv.FormatFloat(getResource(node.Allocatable, resource), 'f', -1, 64), suffix),
+			)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStatsdEmitter sends a snapshot to a fake agent, checking the gauges and that label tags are included.
+func TestStatsdEmitter(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+	defer listener.Close()
+
+	snapshot := &Snapshot{
+		Time: time.Unix(1714564800, 0),
+		Nodes: []NodeJson{
+			{Name: "node-1", Pool: "gpu", Labels: map[string]string{"topology.kubernetes.io/zone": "us-west,1"}, Allocatable: ResourcesJson{Cpu: 7.5, Gpu: 4}, Free: ResourcesJson{Cpu: 1.5, Gpu: 2}},
+		},
+	}
+
+	emitter, err := newStatsdEmitter(listener.LocalAddr().String(), "kube_resources", []string{"topology.kubernetes.io/zone", "missing"})
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	if err := emitter.Emit(snapshot); err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	buf := make([]byte, maxStatsdPacketSize)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf(`err = %v, want match for %v`, err, nil)
+	}
+
+	lines := strings.Split(string(buf[:n]), "\n")
+	tags := "|g|#node:node-1,pool:gpu,topology.kubernetes.io/zone:us-west_1"
+
+	switch {
+	case len(lines) != 2*len(resourceNames):
+		t.Fatalf(`len(lines) = %v, want match for %v`, len(lines), 2*len(resourceNames))
+	case lines[0] != "kube_resources.free.cpu:1.5"+tags:
+		t.Fatalf(`lines[0] = %v, want match for %v`, lines[0], "kube_resources.free.cpu:1.5"+tags)
+	case lines[5] != "kube_resources.allocatable.gpu:4"+tags:
+		t.Fatalf(`lines[5] = %v, want match for %v`, lines[5], "kube_resources.allocatable.gpu:4"+tags)
+	}
+}
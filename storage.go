@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StorageTopology contains the capacity a CSI driver reports for a StorageClass in one topology segment, e.g. a
+// single node or zone
+type StorageTopology struct {
+	Topology          string `json:"topology"`
+	Capacity          int64  `json:"capacity"`
+	MaximumVolumeSize *int64 `json:"maximumVolumeSize,omitempty"`
+}
+
+// StorageClassJson contains the available capacity of a StorageClass and the volumes provisioned from it
+type StorageClassJson struct {
+	Name              string            `json:"name"`
+	Provisioner       string            `json:"provisioner"`
+	Capacity          int64             `json:"capacity"`
+	Topologies        []StorageTopology `json:"topologies"`
+	PersistentVolumes int               `json:"persistentVolumes"`
+	Provisioned       int64             `json:"provisioned"`
+	Claims            int               `json:"claims"`
+	Requested         int64             `json:"requested"`
+}
+
+// PersistentVolumeJson contains the size and status of a PersistentVolume
+type PersistentVolumeJson struct {
+	Name         string `json:"name"`
+	StorageClass string `json:"storageClass"`
+	Capacity     int64  `json:"capacity"`
+	Phase        string `json:"phase"`
+	Claim        string `json:"claim,omitempty"`
+}
+
+// ClaimJson contains the requested size of a PersistentVolumeClaim and the capacity of the volume bound to it
+type ClaimJson struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	StorageClass string `json:"storageClass"`
+	Phase        string `json:"phase"`
+	Volume       string `json:"volume,omitempty"`
+	Requested    int64  `json:"requested"`
+	Capacity     int64  `json:"capacity"`
+}
+
+// NamespaceClaims contains the summed sizes of the PersistentVolumeClaims in a namespace
+type NamespaceClaims struct {
+	Namespace string `json:"namespace"`
+	Claims    int    `json:"claims"`
+	Requested int64  `json:"requested"`
+	Capacity  int64  `json:"capacity"`
+}
+
+// ClaimsReport contains every PersistentVolumeClaim and the totals of each namespace
+type ClaimsReport struct {
+	Claims     []ClaimJson       `json:"claims"`
+	Namespaces []NamespaceClaims `json:"namespaces"`
+}
+
+// StorageReport contains the capacity of every StorageClass and every PersistentVolume in the cluster
+type StorageReport struct {
+	StorageClasses    []StorageClassJson     `json:"storageClasses"`
+	PersistentVolumes []PersistentVolumeJson `json:"persistentVolumes"`
+}
+
+// Storage gets the StorageClasses, CSIStorageCapacity objects, PersistentVolumes, and PersistentVolumeClaims in
+// the cluster and returns them as a StorageReport.
+func (c *Collector) Storage() (*StorageReport, error) {
+	ctx := context.Background()
+
+	classes, err := c.client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	capacities, err := c.client.StorageV1().CSIStorageCapacities("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := c.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := c.client.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	classJsons := make(map[string]*StorageClassJson)
+
+	// getClass returns the entry for a StorageClass, creating it if the class has been deleted but still has
+	// volumes or capacity
+	getClass := func(name string) *StorageClassJson {
+		if _, ok := classJsons[name]; !ok {
+			classJsons[name] = &StorageClassJson{Name: name, Topologies: make([]StorageTopology, 0)}
+		}
+		return classJsons[name]
+	}
+
+	for _, class := range classes.Items {
+		getClass(class.Name).Provisioner = class.Provisioner
+	}
+
+	for _, capacity := range capacities.Items {
+		topology := StorageTopology{Topology: metav1.FormatLabelSelector(capacity.NodeTopology)}
+
+		if capacity.Capacity != nil {
+			topology.Capacity = capacity.Capacity.Value()
+		}
+
+		if capacity.MaximumVolumeSize != nil {
+			size := capacity.MaximumVolumeSize.Value()
+			topology.MaximumVolumeSize = &size
+		}
+
+		class := getClass(capacity.StorageClassName)
+		class.Capacity += topology.Capacity
+		class.Topologies = append(class.Topologies, topology)
+	}
+
+	report := StorageReport{
+		StorageClasses:    make([]StorageClassJson, 0, len(classJsons)),
+		PersistentVolumes: make([]PersistentVolumeJson, 0, len(volumes.Items)),
+	}
+
+	for i := range volumes.Items {
+		volumeJson := getPersistentVolumeStructured(&volumes.Items[i])
+
+		class := getClass(volumeJson.StorageClass)
+		class.PersistentVolumes++
+		class.Provisioned += volumeJson.Capacity
+
+		report.PersistentVolumes = append(report.PersistentVolumes, volumeJson)
+	}
+
+	for _, claim := range claims.Items {
+		class := getClass(getClaimStorageClass(&claim))
+		class.Claims++
+		class.Requested += claim.Spec.Resources.Requests.Storage().Value()
+	}
+
+	for _, class := range classJsons {
+		sort.Slice(class.Topologies, func(i, j int) bool {
+			return class.Topologies[i].Topology < class.Topologies[j].Topology
+		})
+		report.StorageClasses = append(report.StorageClasses, *class)
+	}
+
+	sort.Slice(report.StorageClasses, func(i, j int) bool {
+		return report.StorageClasses[i].Name < report.StorageClasses[j].Name
+	})
+
+	sort.Slice(report.PersistentVolumes, func(i, j int) bool {
+		return report.PersistentVolumes[i].Name < report.PersistentVolumes[j].Name
+	})
+
+	return &report, nil
+}
+
+// getPersistentVolumeStructured takes a pointer to a PersistentVolume and returns a PersistentVolumeJson struct
+// instance with its capacity converted to bytes.
+func getPersistentVolumeStructured(volume *corev1.PersistentVolume) PersistentVolumeJson {
+	volumeJson := PersistentVolumeJson{
+		Name:         volume.Name,
+		StorageClass: volume.Spec.StorageClassName,
+		Capacity:     volume.Spec.Capacity.Storage().Value(),
+		Phase:        string(volume.Status.Phase),
+	}
+
+	if volume.Spec.ClaimRef != nil {
+		volumeJson.Claim = volume.Spec.ClaimRef.Namespace + "/" + volume.Spec.ClaimRef.Name
+	}
+
+	return volumeJson
+}
+
+// getClaimStorageClass returns the name of the StorageClass a PersistentVolumeClaim uses, which may still be
+// given by the deprecated beta annotation.
+func getClaimStorageClass(claim *corev1.PersistentVolumeClaim) string {
+	if claim.Spec.StorageClassName != nil {
+		return *claim.Spec.StorageClassName
+	}
+
+	return claim.Annotations[corev1.BetaStorageClassAnnotation]
+}
+
+// Claims returns every PersistentVolumeClaim in namespace, or in the whole cluster if namespace is empty.
+func (c *Collector) Claims(namespace string) ([]ClaimJson, error) {
+	claims, err := c.client.CoreV1().PersistentVolumeClaims(namespace).List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	claimJsons := make([]ClaimJson, 0, len(claims.Items))
+	for i := range claims.Items {
+		claimJsons = append(claimJsons, getClaimStructured(&claims.Items[i]))
+	}
+
+	return claimJsons, nil
+}
+
+// getClaimStructured takes a pointer to a PersistentVolumeClaim and returns a ClaimJson struct instance with its
+// sizes converted to bytes. The capacity is 0 until the claim is bound.
+func getClaimStructured(claim *corev1.PersistentVolumeClaim) ClaimJson {
+	return ClaimJson{
+		Name:         claim.Name,
+		Namespace:    claim.Namespace,
+		StorageClass: getClaimStorageClass(claim),
+		Phase:        string(claim.Status.Phase),
+		Volume:       claim.Spec.VolumeName,
+		Requested:    claim.Spec.Resources.Requests.Storage().Value(),
+		Capacity:     claim.Status.Capacity.Storage().Value(),
+	}
+}
+
+// getClaimsReport sorts a list of claims by namespace and name and sums them by namespace.
+func getClaimsReport(claims []ClaimJson) ClaimsReport {
+	totals := make(map[string]*NamespaceClaims)
+
+	for _, claim := range claims {
+		if _, ok := totals[claim.Namespace]; !ok {
+			totals[claim.Namespace] = &NamespaceClaims{Namespace: claim.Namespace}
+		}
+
+		totals[claim.Namespace].Claims++
+		totals[claim.Namespace].Requested += claim.Requested
+		totals[claim.Namespace].Capacity += claim.Capacity
+	}
+
+	report := ClaimsReport{
+		Claims:     claims,
+		Namespaces: make([]NamespaceClaims, 0, len(totals)),
+	}
+
+	for _, namespace := range totals {
+		report.Namespaces = append(report.Namespaces, *namespace)
+	}
+
+	sort.Slice(report.Claims, func(i, j int) bool {
+		if report.Claims[i].Namespace != report.Claims[j].Namespace {
+			return report.Claims[i].Namespace < report.Claims[j].Namespace
+		}
+		return report.Claims[i].Name < report.Claims[j].Name
+	})
+
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+
+	return report
+}
+
+// getClaimsHandler returns a HandlerFunc to return every PersistentVolumeClaim and the totals of each namespace
+// given a Collector. The claims can be filtered with the namespace query parameter.
+func getClaimsHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		claims, err := collector.Claims(c.Query("namespace"))
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving PersistentVolumeClaim information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getClaimsReport(claims))
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getStorageHandler returns a HandlerFunc to return the storage report given a Collector.
+func getStorageHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		report, err := collector.Storage()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving storage information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, report)
+	}
+
+	return gin.HandlerFunc(handler)
+}
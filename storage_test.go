@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestCollectorStorage calls Storage on a cluster with one StorageClass reporting capacity in two topologies, a
+// bound volume and claim, and a claim using the beta annotation for a class that doesn't exist.
+func TestCollectorStorage(t *testing.T) {
+	className := "local"
+	capacity := func(name, host, size string) *storagev1.CSIStorageCapacity {
+		quantity := resource.MustParse(size)
+		return &storagev1.CSIStorageCapacity{
+			ObjectMeta:       metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+			StorageClassName: className,
+			NodeTopology:     &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/hostname": host}},
+			Capacity:         &quantity,
+		}
+	}
+
+	client := fake.NewSimpleClientset(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: className}, Provisioner: "local.csi"},
+		capacity("capacity-2", "node-2", "200Gi"),
+		capacity("capacity-1", "node-1", "100Gi"),
+		&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: v1.PersistentVolumeSpec{
+				StorageClassName: className,
+				Capacity:         v1.ResourceList{"storage": resource.MustParse("10Gi")},
+				ClaimRef:         &v1.ObjectReference{Namespace: "vision", Name: "data"},
+			},
+			Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+		},
+		&v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "vision"},
+			Spec: v1.PersistentVolumeClaimSpec{
+				StorageClassName: &className,
+				Resources:        v1.VolumeResourceRequirements{Requests: v1.ResourceList{"storage": resource.MustParse("8Gi")}},
+			},
+		},
+		&v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "old", Namespace: "vision", Annotations: map[string]string{v1.BetaStorageClassAnnotation: "deleted"}},
+			Spec: v1.PersistentVolumeClaimSpec{
+				Resources: v1.VolumeResourceRequirements{Requests: v1.ResourceList{"storage": resource.MustParse("1Gi")}},
+			},
+		},
+	)
+
+	report, err := newCollector(client).Storage()
+
+	if err != nil {
+		t.Fatalf(`Storage() returned error %v`, err)
+	}
+
+	if len(report.StorageClasses) != 2 || report.StorageClasses[0].Name != "deleted" || report.StorageClasses[0].Claims != 1 {
+		t.Fatalf(`report.StorageClasses = %v, want deleted class with one claim first`, report.StorageClasses)
+	}
+
+	local := report.StorageClasses[1]
+
+	switch {
+	case local.Provisioner != "local.csi":
+		t.Fatalf(`local.Provisioner = %v, want match for %v`, local.Provisioner, "local.csi")
+	case local.Capacity != 300<<30:
+		t.Fatalf(`local.Capacity = %v, want match for %v`, local.Capacity, 300<<30)
+	case len(local.Topologies) != 2 || local.Topologies[0].Topology != "kubernetes.io/hostname=node-1":
+		t.Fatalf(`local.Topologies = %v, want node-1 and node-2`, local.Topologies)
+	case local.PersistentVolumes != 1 || local.Provisioned != 10<<30:
+		t.Fatalf(`local volumes = %v, %v, want match for %v, %v`, local.PersistentVolumes, local.Provisioned, 1, 10<<30)
+	case local.Claims != 1 || local.Requested != 8<<30:
+		t.Fatalf(`local claims = %v, %v, want match for %v, %v`, local.Claims, local.Requested, 1, 8<<30)
+	case len(report.PersistentVolumes) != 1 || report.PersistentVolumes[0].Claim != "vision/data":
+		t.Fatalf(`report.PersistentVolumes = %v, want pv-1 bound to vision/data`, report.PersistentVolumes)
+	}
+}
+
+// TestGetClaimsReport sums claims in two namespaces, checking the totals and that claims are sorted.
+func TestGetClaimsReport(t *testing.T) {
+	claims := []ClaimJson{
+		{Name: "b", Namespace: "vision", Requested: 10, Capacity: 16},
+		{Name: "a", Namespace: "vision", Requested: 5, Capacity: 0},
+		{Name: "c", Namespace: "audio", Requested: 1, Capacity: 1},
+	}
+
+	report := getClaimsReport(claims)
+
+	if report.Claims[0].Name != "c" || report.Claims[1].Name != "a" || report.Claims[2].Name != "b" {
+		t.Fatalf(`report.Claims = %v, want c, a, b`, report.Claims)
+	}
+
+	want := []NamespaceClaims{
+		{Namespace: "audio", Claims: 1, Requested: 1, Capacity: 1},
+		{Namespace: "vision", Claims: 2, Requested: 15, Capacity: 16},
+	}
+
+	if len(report.Namespaces) != len(want) || report.Namespaces[0] != want[0] || report.Namespaces[1] != want[1] {
+		t.Fatalf(`report.Namespaces = %v, want match for %v`, report.Namespaces, want)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClusterSummary contains the total resources of every node in the cluster
+type ClusterSummary struct {
+	Nodes          int                       `json:"nodes"`
+	Allocatable    ResourcesJson             `json:"allocatable"`
+	Capacity       ResourcesJson             `json:"capacity"`
+	Free           ResourcesJson             `json:"free"`
+	ByCapacityType map[string]ResourceTotals `json:"byCapacityType"`
+	ByTaintEffect  map[string]ResourceTotals `json:"byTaintEffect"` // Keyed by the strictest effect of each node's taints
+	ByOs           map[string]ResourceTotals `json:"byOs"`
+	HourlyCost     *float64                  `json:"hourlyCost,omitempty"`
+	IdleHourlyCost *float64                  `json:"idleHourlyCost,omitempty"`
+}
+
+// getSummaryHandler returns a HandlerFunc to return the total resources of the cluster given a Collector. The os and
+// minAge query parameters limit the totals to the nodes running one operating system or at least a given age.
+func getSummaryHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		nodes, ok := applyNodeFilters(c, snapshot.Nodes)
+		if !ok {
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, getClusterSummary(nodes))
+	}
+
+	return gin.HandlerFunc(handler)
+}
+
+// getClusterSummary sums the resources of a list of nodes. If any of the nodes have a cost, the total cost
+// and the cost of the capacity that isn't requested by any pod are included as well.
+func getClusterSummary(nodes []NodeJson) ClusterSummary {
+	summary := ClusterSummary{
+		Nodes:          len(nodes),
+		ByCapacityType: getTotalsByCapacityType(nodes),
+		ByTaintEffect:  getTotalsByTaintEffect(nodes),
+		ByOs:           getTotalsByOs(nodes),
+	}
+
+	for _, node := range nodes {
+		summary.Allocatable = addResources(summary.Allocatable, node.Allocatable)
+		summary.Capacity = addResources(summary.Capacity, node.Capacity)
+		summary.Free = addResources(summary.Free, node.Free)
+
+		if node.HourlyCost == nil {
+			continue
+		}
+
+		if summary.HourlyCost == nil {
+			summary.HourlyCost = new(float64)
+			summary.IdleHourlyCost = new(float64)
+		}
+
+		*summary.HourlyCost += *node.HourlyCost
+		*summary.IdleHourlyCost += *node.HourlyCost * getIdleFraction(node)
+	}
+
+	return summary
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TestGetClusterSummary sums a list of nodes, checking the resource totals and that the idle cost is the cost
+// of each node weighted by the average fraction of its resources that are free.
+func TestGetClusterSummary(t *testing.T) {
+	cost := 2.0
+
+	nodes := []NodeJson{
+		{
+			// Half the CPU and all the memory free, no GPUs - idle fraction 0.75
+			Name:        "node-1",
+			Allocatable: ResourcesJson{Cpu: 4, Memory: 1000},
+			Free:        ResourcesJson{Cpu: 2, Memory: 1000},
+			HourlyCost:  &cost,
+		},
+		{
+			// No cost, so it only counts towards the resource totals
+			Name:        "node-2",
+			Allocatable: ResourcesJson{Cpu: 4, Memory: 1000, Gpu: 2},
+			Free:        ResourcesJson{Cpu: 1, Memory: 0, Gpu: 1},
+		},
+	}
+
+	summary := getClusterSummary(nodes)
+
+	switch {
+	case summary.Nodes != 2:
+		t.Fatalf(`summary.Nodes = %v, want match for %v`, summary.Nodes, 2)
+	case summary.Allocatable != (ResourcesJson{Cpu: 8, Memory: 2000, Gpu: 2}):
+		t.Fatalf(`summary.Allocatable = %v, want match for %v`, summary.Allocatable, ResourcesJson{Cpu: 8, Memory: 2000, Gpu: 2})
+	case summary.Free != (ResourcesJson{Cpu: 3, Memory: 1000, Gpu: 1}):
+		t.Fatalf(`summary.Free = %v, want match for %v`, summary.Free, ResourcesJson{Cpu: 3, Memory: 1000, Gpu: 1})
+	case summary.HourlyCost == nil || *summary.HourlyCost != 2:
+		t.Fatalf(`summary.HourlyCost = %v, want match for %v`, summary.HourlyCost, 2)
+	case summary.IdleHourlyCost == nil || *summary.IdleHourlyCost != 1.5:
+		t.Fatalf(`summary.IdleHourlyCost = %v, want match for %v`, summary.IdleHourlyCost, 1.5)
+	}
+
+	// Without any costs, the cost fields should be left out
+	summary = getClusterSummary(nodes[1:])
+
+	if summary.HourlyCost != nil || summary.IdleHourlyCost != nil {
+		t.Fatalf(`summary.HourlyCost = %v, want match for %v`, summary.HourlyCost, nil)
+	}
+}
+
+// TestGetTotalsByTaintEffect sums nodes under the strictest effect of their taints, with nodes without taints under
+// untaintedEffect.
+func TestGetTotalsByTaintEffect(t *testing.T) {
+	nodes := []NodeJson{
+		{Name: "node-1", Free: ResourcesJson{Cpu: 1}, Taints: []corev1.Taint{}},
+		{Name: "node-2", Free: ResourcesJson{Cpu: 2}, Taints: []corev1.Taint{
+			{Key: "gpu", Effect: corev1.TaintEffectPreferNoSchedule},
+		}},
+		{Name: "node-3", Free: ResourcesJson{Cpu: 4}, Taints: []corev1.Taint{
+			{Key: "gpu", Effect: corev1.TaintEffectPreferNoSchedule},
+			{Key: "node.kubernetes.io/unschedulable", Effect: corev1.TaintEffectNoSchedule},
+		}},
+		{Name: "node-4", Free: ResourcesJson{Cpu: 8}, Taints: []corev1.Taint{
+			{Key: "node.kubernetes.io/unschedulable", Effect: corev1.TaintEffectNoSchedule},
+		}},
+		{Name: "node-5", Free: ResourcesJson{Cpu: 16}, Taints: []corev1.Taint{
+			{Key: "node.kubernetes.io/unschedulable", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "node.kubernetes.io/unreachable", Effect: corev1.TaintEffectNoExecute},
+		}},
+	}
+
+	totals := getTotalsByTaintEffect(nodes)
+
+	switch {
+	case len(totals) != 4:
+		t.Fatalf(`len(totals) = %v, want match for %v`, len(totals), 4)
+	case totals[untaintedEffect].Nodes != 1 || totals[untaintedEffect].Free.Cpu != 1:
+		t.Fatalf(`totals[%v] = %v, want match for %v`, untaintedEffect, totals[untaintedEffect], "1 node with 1 free CPU")
+	case totals["PreferNoSchedule"].Nodes != 1 || totals["PreferNoSchedule"].Free.Cpu != 2:
+		t.Fatalf(`totals["PreferNoSchedule"] = %v, want match for %v`, totals["PreferNoSchedule"], "1 node with 2 free CPUs")
+	case totals["NoSchedule"].Nodes != 2 || totals["NoSchedule"].Free.Cpu != 12:
+		t.Fatalf(`totals["NoSchedule"] = %v, want match for %v`, totals["NoSchedule"], "2 nodes with 12 free CPUs")
+	case totals["NoExecute"].Nodes != 1 || totals["NoExecute"].Free.Cpu != 16:
+		t.Fatalf(`totals["NoExecute"] = %v, want match for %v`, totals["NoExecute"], "1 node with 16 free CPUs")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Swap behaviors of the kubelet's memorySwap configuration. Kubelets since 1.30 use NoSwap if none is set.
+const (
+	swapBehaviorNone      = "NoSwap"
+	swapBehaviorLimited   = "LimitedSwap"
+	swapBehaviorUnlimited = "UnlimitedSwap" // Removed in 1.30
+)
+
+// Group, version, and resource of Node objects, read through the dynamic client for status fields newer than the
+// client this is built with
+var nodeResource = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "nodes",
+}
+
+// NodeSwap contains the swap of a node and whether its pods can use it
+type NodeSwap struct {
+	Capacity   *int64 `json:"capacity,omitempty"`   // Bytes of swap, if the node reports it in its status
+	Behavior   string `json:"behavior,omitempty"`   // Swap behavior of the kubelet, if its configuration was read
+	FailSwapOn *bool  `json:"failSwapOn,omitempty"` // Whether the kubelet refuses to start with swap on
+	Usable     bool   `json:"usable"`               // True if the node has swap and its kubelet lets pods use it
+}
+
+// getNodeSwapCapacities returns the swap capacity each node reports in status.nodeInfo.swap, keyed by node name.
+// Nodes only report it on clusters with NodeSwap enabled since 1.32, and the client this is built with doesn't have
+// the field, so nodes are read through the dynamic client. Without one, no capacities are returned.
+func (c *Collector) getNodeSwapCapacities() (map[string]int64, error) {
+	capacities := make(map[string]int64)
+
+	if c.dynamic == nil {
+		return capacities, nil
+	}
+
+	list, err := c.dynamic.Resource(nodeResource).List(context.Background(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range list.Items {
+		if capacity, ok, _ := unstructured.NestedInt64(item.Object, "status", "nodeInfo", "swap", "capacity"); ok {
+			capacities[item.GetName()] = capacity
+		}
+	}
+
+	return capacities, nil
+}
+
+// getNodeSwap returns the swap of a node from the capacity in its status and its kubelet's configuration, either of
+// which may be unknown. Pods can only use swap on nodes with some and a kubelet with a swap behavior other than
+// NoSwap. If neither is known, nil is returned.
+func getNodeSwap(capacity *int64, config *KubeletConfig) *NodeSwap {
+	if capacity == nil && config == nil {
+		return nil
+	}
+
+	swap := &NodeSwap{Capacity: capacity}
+
+	if config != nil {
+		failSwapOn := config.FailSwapOn
+		swap.FailSwapOn = &failSwapOn
+		swap.Behavior = config.MemorySwap.SwapBehavior
+
+		if swap.Behavior == "" {
+			swap.Behavior = swapBehaviorNone
+		}
+	}
+
+	swap.Usable = capacity != nil && *capacity > 0 &&
+		(swap.Behavior == swapBehaviorLimited || swap.Behavior == swapBehaviorUnlimited)
+
+	return swap
+}
+
+// addNodeSwap adds the swap of every node to a snapshot from the configuration of its kubelet and the capacity in
+// its status. Failing to read nodes' swap capacity is logged and leaves it unknown rather than failing the snapshot.
+func (c *Collector) addNodeSwap(snapshot *Snapshot, configs map[string]*KubeletConfig) {
+	capacities, err := c.getNodeSwapCapacities()
+
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	for i := range snapshot.Nodes {
+		// Swap for pods is only supported on Linux, so Windows kubelets' swap settings don't mean anything
+		if snapshot.Nodes[i].Os == windowsOs {
+			continue
+		}
+
+		var capacity *int64
+		if value, ok := capacities[snapshot.Nodes[i].Name]; ok {
+			capacity = &value
+		}
+
+		snapshot.Nodes[i].Swap = getNodeSwap(capacity, configs[snapshot.Nodes[i].Name])
+	}
+}
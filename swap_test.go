@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestGetNodeSwap checks which combinations of swap capacity and kubelet configuration let pods use swap.
+func TestGetNodeSwap(t *testing.T) {
+	capacity := int64(8 << 30)
+	none := int64(0)
+	limited := &KubeletConfig{}
+	limited.MemorySwap.SwapBehavior = swapBehaviorLimited
+
+	tests := []struct {
+		name     string
+		capacity *int64
+		config   *KubeletConfig
+		behavior string
+		usable   bool
+	}{
+		{"limited swap", &capacity, limited, swapBehaviorLimited, true},
+		{"default behavior", &capacity, &KubeletConfig{}, swapBehaviorNone, false},
+		{"no swap", &none, limited, swapBehaviorLimited, false},
+		{"unknown config", &capacity, nil, "", false},
+		{"unknown capacity", nil, limited, swapBehaviorLimited, false},
+	}
+
+	for _, test := range tests {
+		swap := getNodeSwap(test.capacity, test.config)
+
+		switch {
+		case swap == nil:
+			t.Fatalf(`%v: getNodeSwap() = nil, want swap`, test.name)
+		case swap.Behavior != test.behavior || swap.Usable != test.usable:
+			t.Fatalf(`%v: getNodeSwap() = %v, %v, want match for %v, %v`, test.name, swap.Behavior, swap.Usable, test.behavior, test.usable)
+		}
+	}
+
+	if swap := getNodeSwap(nil, nil); swap != nil {
+		t.Fatalf(`getNodeSwap(nil, nil) = %v, want match for nil`, swap)
+	}
+}
+
+// TestAddNodeSwap takes a snapshot of a node reporting swap in its status with a kubelet allowing pods to use it,
+// checking that both are added to the node.
+func TestAddNodeSwap(t *testing.T) {
+	collector := newCollector(fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "swap-1"}}))
+	collector.dynamic = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{nodeResource: "NodeList"},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Node",
+			"metadata":   map[string]interface{}{"name": "swap-1"},
+			"status":     map[string]interface{}{"nodeInfo": map[string]interface{}{"swap": map[string]interface{}{"capacity": int64(4 << 30)}}},
+		}},
+	)
+	collector.kubelet = &KubeletReader{
+		fetch: func(node, path string) ([]byte, error) {
+			return []byte(`{"kubeletconfig": {"failSwapOn": false, "memorySwap": {"swapBehavior": "LimitedSwap"}}}`), nil
+		},
+		ttl:     time.Minute,
+		configs: make(map[string]cachedKubeletConfig),
+	}
+	collector.kubeletConfig = true
+
+	snapshot, err := collector.Snapshot()
+
+	if err != nil {
+		t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+	}
+
+	swap := snapshot.Nodes[0].Swap
+
+	switch {
+	case swap == nil || swap.Capacity == nil:
+		t.Fatalf(`swap = %v, want a capacity`, swap)
+	case *swap.Capacity != 4<<30 || swap.Behavior != swapBehaviorLimited || !swap.Usable:
+		t.Fatalf(`swap = %v, %v, %v, want match for %v, LimitedSwap, true`, *swap.Capacity, swap.Behavior, swap.Usable, 4<<30)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// How long pods stay on a node that isn't ready before they are evicted, if they don't tolerate it for a different
+// time, matching the tolerations the DefaultTolerationSeconds admission plugin adds
+const defaultNotReadyEvictionTimeout = 300 * time.Second
+
+// getNotReadyEvictionTimeout returns how long a pod stays on a node whose Ready condition has status before it is
+// evicted, from the pod's toleration of the taint the node controller puts on such nodes. Pods that tolerate it with
+// no time limit, such as DaemonSet pods, are never evicted, and false is returned for them.
+func getNotReadyEvictionTimeout(pod *corev1.Pod, status corev1.ConditionStatus) (time.Duration, bool) {
+	key := corev1.TaintNodeNotReady
+	if status == corev1.ConditionUnknown {
+		key = corev1.TaintNodeUnreachable
+	}
+
+	for _, toleration := range pod.Spec.Tolerations {
+		if toleration.Effect != "" && toleration.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+
+		if toleration.Key != key && !(toleration.Key == "" && toleration.Operator == corev1.TolerationOpExists) {
+			continue
+		}
+
+		if toleration.TolerationSeconds == nil {
+			return 0, false
+		}
+
+		return time.Duration(*toleration.TolerationSeconds) * time.Second, true
+	}
+
+	return defaultNotReadyEvictionTimeout, true
+}
+
+// isReleasedPod returns whether a pod has stopped holding its node's resources at now, although it hasn't been
+// removed yet: either it is being deleted and its grace period is over, since deletionTimestamp is set to when the
+// grace period ends, or its node has not been ready for longer than the pod tolerates.
+func isReleasedPod(pod *corev1.Pod, nodes map[string]*Node, now time.Time) bool {
+	if pod.DeletionTimestamp != nil && now.After(pod.DeletionTimestamp.Time) {
+		return true
+	}
+
+	node, ok := nodes[pod.Spec.NodeName]
+	if !ok || node.Ready == nil || node.Ready.Status == corev1.ConditionTrue {
+		return false
+	}
+
+	timeout, ok := getNotReadyEvictionTimeout(pod, node.Ready.Status)
+
+	return ok && now.Sub(node.Ready.LastTransitionTime.Time) > timeout
+}
+
+// excludeReleasedPods returns the pods that still hold their node's resources at now, leaving out those that are
+// terminating past their grace period or on nodes that have been not ready for longer than they tolerate. During
+// large rollouts, many pods can be stuck terminating on nodes long after the scheduler has stopped counting them.
+func excludeReleasedPods(pods []corev1.Pod, nodes map[string]*Node, now time.Time) []corev1.Pod {
+	counted := make([]corev1.Pod, 0, len(pods))
+
+	for i := range pods {
+		if !isReleasedPod(&pods[i], nodes, now) {
+			counted = append(counted, pods[i])
+		}
+	}
+
+	return counted
+}
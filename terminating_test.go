@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestExcludeReleasedPods checks which pods still hold their node's resources: pods within their grace period and on
+// ready nodes do, while pods past their grace period and on nodes not ready for longer than they tolerate don't.
+func TestExcludeReleasedPods(t *testing.T) {
+	now := time.Now()
+	forever := corev1.Toleration{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute}
+	seconds := int64(1800)
+	patient := corev1.Toleration{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &seconds}
+
+	pod := func(name, node string, deleted *time.Time, tolerations ...corev1.Toleration) corev1.Pod {
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PodSpec{NodeName: node, Tolerations: tolerations},
+		}
+		if deleted != nil {
+			deletion := metav1.NewTime(*deleted)
+			pod.DeletionTimestamp = &deletion
+		}
+		return pod
+	}
+
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	nodes := map[string]*Node{
+		"ready": {Name: "ready", Ready: &corev1.NodeCondition{Status: corev1.ConditionTrue}},
+		"unreachable": {Name: "unreachable", Ready: &corev1.NodeCondition{
+			Status:             corev1.ConditionUnknown,
+			LastTransitionTime: metav1.NewTime(now.Add(-10 * time.Minute)),
+		}},
+	}
+
+	pods := []corev1.Pod{
+		pod("running", "ready", nil),
+		pod("terminating", "ready", &future),
+		pod("past-grace-period", "ready", &past),
+		pod("evicted", "unreachable", nil),
+		pod("daemon", "unreachable", nil, forever),
+		pod("patient", "unreachable", nil, patient),
+	}
+
+	counted := excludeReleasedPods(pods, nodes, now)
+	want := []string{"running", "terminating", "daemon", "patient"}
+
+	if len(counted) != len(want) {
+		t.Fatalf(`excludeReleasedPods() = %v pods, want match for %v`, len(counted), want)
+	}
+
+	for i := range want {
+		if counted[i].Name != want[i] {
+			t.Fatalf(`excludeReleasedPods()[%v] = %v, want match for %v`, i, counted[i].Name, want[i])
+		}
+	}
+}
+
+// TestReleasePods takes snapshots of a node with a pod past its grace period with and without releasing such pods,
+// checking that only the first counts its requests against the node.
+func TestReleasePods(t *testing.T) {
+	deleted := metav1.NewTime(time.Now().Add(-time.Minute))
+	client := fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "stuck", Namespace: "default", DeletionTimestamp: &deleted},
+			Spec: corev1.PodSpec{
+				NodeName: "node-1",
+				Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				}}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	)
+
+	for _, release := range []bool{false, true} {
+		collector := newCollector(client)
+		collector.releasePods = release
+		snapshot, err := collector.Snapshot()
+
+		if err != nil {
+			t.Fatalf(`Snapshot() = %v, want match for nil`, err)
+		}
+
+		want := 3.0
+		if release {
+			want = 4
+		}
+
+		if free := snapshot.Nodes[0].Free.Cpu; free != want {
+			t.Fatalf(`free CPU releasing pods %v = %v, want match for %v`, release, free, want)
+		}
+
+		if len(snapshot.Pods) != 1 {
+			t.Fatalf(`snapshot pods releasing pods %v = %v, want the stuck pod listed`, release, snapshot.Pods)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header containing the snapshot version of a /nodes response
+const snapshotVersionHeader = "X-Snapshot-Version"
+
+// How often a long-polling request checks the cluster for changes, and the longest it can wait
+const (
+	longPollInterval = 5 * time.Second
+	maxLongPollWait  = 5 * time.Minute
+)
+
+// NodeTracker assigns a version to the state of the nodes in the cluster, which increases every time any node
+// changes, and remembers the version at which each node last changed so clients can fetch only what is new.
+// Versions start from 1 whenever the API starts and are not shared between replicas.
+type NodeTracker struct {
+	mu      sync.Mutex
+	version uint64
+	nodes   map[string]NodeJson
+	changed map[string]uint64 // Version at which each current node last changed
+	removed map[string]uint64 // Version at which each node that no longer exists was removed
+	notify  chan struct{}     // Closed and replaced whenever the version increases
+}
+
+// NodeDiff contains the nodes that changed after a version
+type NodeDiff struct {
+	Version uint64     `json:"version"`
+	Full    bool       `json:"full"` // True if every node is included because the version is unknown
+	Nodes   []NodeJson `json:"nodes"`
+	Removed []string   `json:"removed"`
+}
+
+// newNodeTracker returns a NodeTracker with no nodes.
+func newNodeTracker() *NodeTracker {
+	return &NodeTracker{
+		nodes:   make(map[string]NodeJson),
+		changed: make(map[string]uint64),
+		removed: make(map[string]uint64),
+		notify:  make(chan struct{}),
+	}
+}
+
+// Update compares nodes with the last nodes it was given, increasing the version if any were added, changed, or
+// removed, and returns the current version.
+func (t *NodeTracker) Update(nodes []NodeJson) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next := t.version + 1
+	changed := false
+	seen := make(map[string]bool, len(nodes))
+
+	for _, node := range nodes {
+		seen[node.Name] = true
+
+		// Ages, heartbeat ages, and disk usage change with every snapshot, so only keep the latest rather than counting
+		// them as changes
+		if previous, ok := t.nodes[node.Name]; ok && reflect.DeepEqual(withoutMeasurements(previous), withoutMeasurements(node)) {
+			t.nodes[node.Name] = node
+			continue
+		}
+
+		t.nodes[node.Name] = node
+		t.changed[node.Name] = next
+		delete(t.removed, node.Name)
+		changed = true
+	}
+
+	for name := range t.nodes {
+		if seen[name] {
+			continue
+		}
+
+		delete(t.nodes, name)
+		delete(t.changed, name)
+		t.removed[name] = next
+		changed = true
+	}
+
+	if changed {
+		t.version = next
+
+		// Wake up everything waiting for a change
+		close(t.notify)
+		t.notify = make(chan struct{})
+	}
+
+	return t.version
+}
+
+// withoutMeasurements returns a node without its age, heartbeat age, and disk usage, for comparing nodes by everything
+// else.
+func withoutMeasurements(node NodeJson) NodeJson {
+	node.Age = 0
+	node.HeartbeatAge = nil
+	node.DiskUsage = nil
+	node.Images.UsedPercent = nil
+	return node
+}
+
+// Changed returns the current version and a channel that is closed when the version next increases.
+func (t *NodeTracker) Changed() (uint64, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.version, t.notify
+}
+
+// Diff returns the nodes that changed or were removed after since. If since is newer than the current version,
+// which happens when the API restarts or a client talks to a different replica, every node is returned.
+func (t *NodeTracker) Diff(since uint64) NodeDiff {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	diff := NodeDiff{
+		Version: t.version,
+		Full:    since > t.version,
+		Nodes:   make([]NodeJson, 0),
+		Removed: make([]string, 0),
+	}
+
+	for name, node := range t.nodes {
+		if diff.Full || t.changed[name] > since {
+			diff.Nodes = append(diff.Nodes, node)
+		}
+	}
+
+	if !diff.Full {
+		for name, version := range t.removed {
+			if version > since {
+				diff.Removed = append(diff.Removed, name)
+			}
+		}
+	}
+
+	sort.Slice(diff.Nodes, func(i, j int) bool {
+		return diff.Nodes[i].Name < diff.Nodes[j].Name
+	})
+	sort.Strings(diff.Removed)
+
+	return diff
+}
+
+// Nodes returns the current version and the nodes as of that version, sorted by name.
+func (t *NodeTracker) Nodes() (uint64, []NodeJson) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	nodes := make([]NodeJson, 0, len(t.nodes))
+	for _, node := range t.nodes {
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	return t.version, nodes
+}
+
+// waitForNodes waits until the version of the nodes is different from since, taking a snapshot every longPollInterval
+// to find out. Snapshots taken by anything else, such as other requests, also end the wait. It returns the newer
+// version and nodes, or ok is false if timeout elapses or ctx is cancelled first.
+func waitForNodes(ctx context.Context, collector *Collector, since uint64, timeout time.Duration) (version uint64, nodes []NodeJson, ok bool, err error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(longPollInterval)
+	defer ticker.Stop()
+
+	for {
+		current, changed := collector.tracker.Changed()
+
+		if current != since {
+			version, nodes = collector.tracker.Nodes()
+			return version, nodes, true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, false, nil
+		case <-deadline.C:
+			return 0, nil, false, nil
+		case <-changed:
+		case <-ticker.C:
+			// Updates the tracker, which is checked at the top of the loop
+			if _, err := collector.Snapshot(); err != nil {
+				return 0, nil, false, err
+			}
+		}
+	}
+}
+
+// getLongPollParams parses the wait and since query parameters of a long-polling request. wait is capped at
+// maxLongPollWait. If either is invalid, an error is sent as the response and ok is false.
+func getLongPollParams(c *gin.Context) (wait time.Duration, since uint64, ok bool) {
+	wait, err := time.ParseDuration(c.Query("wait"))
+
+	if err != nil || wait <= 0 {
+		c.JSON(http.StatusBadRequest, "error: wait must be a positive duration such as 30s")
+		return wait, since, false
+	}
+
+	since, err = strconv.ParseUint(c.Query("since"), 10, 64)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "error: since must be a snapshot version")
+		return wait, since, false
+	}
+
+	return min(wait, maxLongPollWait), since, true
+}
+
+// getNodesDiffHandler returns a HandlerFunc to return the nodes that changed after the version given by the since
+// query parameter given a Collector.
+func getNodesDiffHandler(collector *Collector) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		since, err := strconv.ParseUint(c.Query("since"), 10, 64)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "error: since must be a snapshot version")
+			return
+		}
+
+		// Take a new snapshot so the diff is up to date
+		if _, err := collector.Snapshot(); err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, "error retrieving node information")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, collector.tracker.Diff(since))
+	}
+
+	return gin.HandlerFunc(handler)
+}
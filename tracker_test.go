@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestNodeTracker updates a tracker with changed, unchanged, added, and removed nodes, checking the version and
+// the diffs since each version.
+func TestNodeTracker(t *testing.T) {
+	tracker := newNodeTracker()
+
+	node1 := NodeJson{Name: "node-1", Free: ResourcesJson{Cpu: 4}}
+	node2 := NodeJson{Name: "node-2", Free: ResourcesJson{Cpu: 8}}
+
+	if version := tracker.Update([]NodeJson{node1, node2}); version != 1 {
+		t.Fatalf(`Update() = %v, want match for %v`, version, 1)
+	}
+
+	// Nothing changed, so the version stays the same
+	if version := tracker.Update([]NodeJson{node1, node2}); version != 1 {
+		t.Fatalf(`Update() of the same nodes = %v, want match for %v`, version, 1)
+	}
+
+	node1.Free.Cpu = 2
+	node3 := NodeJson{Name: "node-3"}
+
+	if version := tracker.Update([]NodeJson{node1, node3}); version != 2 {
+		t.Fatalf(`Update() = %v, want match for %v`, version, 2)
+	}
+
+	diff := tracker.Diff(1)
+
+	switch {
+	case diff.Version != 2 || diff.Full:
+		t.Fatalf(`Diff(1) = %v, want version 2 that isn't full`, diff)
+	case len(diff.Nodes) != 2 || diff.Nodes[0].Name != "node-1" || diff.Nodes[0].Free.Cpu != 2 || diff.Nodes[1].Name != "node-3":
+		t.Fatalf(`Diff(1).Nodes = %v, want node-1 and node-3`, diff.Nodes)
+	case len(diff.Removed) != 1 || diff.Removed[0] != "node-2":
+		t.Fatalf(`Diff(1).Removed = %v, want match for %v`, diff.Removed, []string{"node-2"})
+	}
+
+	if diff := tracker.Diff(2); len(diff.Nodes) != 0 || len(diff.Removed) != 0 {
+		t.Fatalf(`Diff(2) = %v, want no changes`, diff)
+	}
+
+	// A version from before a restart gets every node
+	if diff := tracker.Diff(100); !diff.Full || len(diff.Nodes) != 2 || len(diff.Removed) != 0 {
+		t.Fatalf(`Diff(100) = %v, want every node`, diff)
+	}
+}
+
+// TestWaitForNodes checks that a long-polling wait ends as soon as the nodes change, and that it gives up once its
+// timeout elapses if they don't.
+func TestWaitForNodes(t *testing.T) {
+	collector := newCollector(fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}))
+
+	snapshot, err := collector.Snapshot()
+	if err != nil {
+		t.Fatalf(`Snapshot() returned error %v`, err)
+	}
+
+	if _, _, ok, err := waitForNodes(context.Background(), collector, snapshot.Version, 50*time.Millisecond); ok || err != nil {
+		t.Fatalf(`waitForNodes() of unchanged nodes = %v, %v, want match for false, nil`, ok, err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		collector.tracker.Update([]NodeJson{{Name: "node-2"}})
+	}()
+
+	version, nodes, ok, err := waitForNodes(context.Background(), collector, snapshot.Version, 5*time.Second)
+
+	if !ok || err != nil || version != snapshot.Version+1 || len(nodes) != 1 || nodes[0].Name != "node-2" {
+		t.Fatalf(`waitForNodes() = %v, %v, %v, %v, want node-2 at version %v`, version, nodes, ok, err, snapshot.Version+1)
+	}
+}
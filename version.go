@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/client-go/discovery"
+)
+
+// Build information, set at build time with -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=..."
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// BuildInfo describes the build of this API
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// ClusterVersion describes the Kubernetes API server the API is connected to
+type ClusterVersion struct {
+	GitVersion string `json:"gitVersion"`
+	Major      string `json:"major"`
+	Minor      string `json:"minor"`
+	Platform   string `json:"platform"`
+}
+
+// VersionInfo contains the versions of this API and of the cluster it reads from
+type VersionInfo struct {
+	BuildInfo
+	Cluster *ClusterVersion `json:"cluster"`
+}
+
+// getBuildInfo returns the build information set at build time. If the commit wasn't set, the VCS information
+// Go embeds in binaries built from a repository is used instead.
+func getBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if info.Commit == "" {
+		if buildInfo, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range buildInfo.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.Commit = setting.Value
+				case "vcs.time":
+					if info.BuildDate == "" {
+						info.BuildDate = setting.Value
+					}
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// getVersionHandler returns a HandlerFunc to return the version of the API and of the cluster given a discovery
+// client. If the cluster can't be reached, the API version is still returned and the cluster is null.
+func getVersionHandler(client discovery.DiscoveryInterface) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		info := VersionInfo{BuildInfo: getBuildInfo()}
+
+		serverVersion, err := client.ServerVersion()
+
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			info.Cluster = &ClusterVersion{
+				GitVersion: serverVersion.GitVersion,
+				Major:      serverVersion.Major,
+				Minor:      serverVersion.Minor,
+				Platform:   serverVersion.Platform,
+			}
+		}
+
+		c.IndentedJSON(http.StatusOK, info)
+	}
+
+	return gin.HandlerFunc(handler)
+}
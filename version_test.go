@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apiversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestGetVersionHandler calls the version handler with a fake cluster, checking that the build and cluster
+// versions are both returned.
+func TestGetVersionHandler(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &apiversion.Info{
+		GitVersion: "v1.30.4",
+		Major:      "1",
+		Minor:      "30",
+		Platform:   "linux/amd64",
+	}
+
+	router := gin.New()
+	router.GET("/version", getVersionHandler(client.Discovery()))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	var info VersionInfo
+	if err := json.Unmarshal(recorder.Body.Bytes(), &info); err != nil {
+		t.Fatalf(`json.Unmarshal() returned error %v`, err)
+	}
+
+	if info.Version != "dev" || info.GoVersion == "" {
+		t.Fatalf(`info.BuildInfo = %v, want dev version with Go version`, info.BuildInfo)
+	}
+
+	if info.Cluster == nil || info.Cluster.GitVersion != "v1.30.4" || info.Cluster.Platform != "linux/amd64" {
+		t.Fatalf(`info.Cluster = %v, want v1.30.4 on linux/amd64`, info.Cluster)
+	}
+}
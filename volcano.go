@@ -0,0 +1,118 @@
+package main
+
+import (
+	"slices"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Group, version, and resource of Volcano Queue objects
+var volcanoQueueResource = schema.GroupVersionResource{
+	Group:    "scheduling.volcano.sh",
+	Version:  "v1beta1",
+	Resource: "queues",
+}
+
+// VolcanoQueue compares the resources allocated to a Volcano Queue's jobs with its capability, keyed by the name this
+// API uses for each resource. CPU is given in cores and memory in bytes. Resources missing from capability aren't
+// capped by the queue.
+type VolcanoQueue struct {
+	Name       string             `json:"name"`
+	State      string             `json:"state"`
+	Weight     int64              `json:"weight"`
+	Capability map[string]float64 `json:"capability"`
+	Deserved   map[string]float64 `json:"deserved,omitempty"`
+	Guarantee  map[string]float64 `json:"guarantee,omitempty"`
+	Allocated  map[string]float64 `json:"allocated"`
+	Headroom   map[string]float64 `json:"headroom"`  // Capability left, for each capped resource
+	Available  map[string]float64 `json:"available"` // Headroom that is also free on the cluster's nodes
+	Running    int64              `json:"running"`   // PodGroups running
+	Pending    int64              `json:"pending"`   // PodGroups waiting for resources
+	Inqueue    int64              `json:"inqueue"`   // PodGroups admitted to the queue but not running yet
+}
+
+// VolcanoQueues gets every Volcano Queue in the cluster and returns the capability and allocated resources of each,
+// along with how much of its headroom is free on nodes. If the Volcano CRDs aren't installed, no queues are returned.
+func (c *Collector) VolcanoQueues(nodes []NodeJson) ([]VolcanoQueue, error) {
+	queues, err := c.listOptionalResources(volcanoQueueResource)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseVolcanoQueues(queues, nodes), nil
+}
+
+// parseQueueResources reads an unstructured resource list into amounts keyed by the name this API uses for each
+// resource, skipping invalid quantities.
+func parseQueueResources(list map[string]interface{}) map[string]float64 {
+	result := make(map[string]float64)
+
+	for name, value := range list {
+		if amount, ok := parseQueueQuantity(value); ok {
+			result[getQueueResourceName(name)] += amount
+		}
+	}
+
+	return result
+}
+
+// parseVolcanoQueues reads the capability, allocated resources, and PodGroup counts of each Queue object. Volcano
+// queues aren't tied to nodes, so the available resources of each are its headroom limited by the free resources of
+// every node. Queues are sorted by name.
+func parseVolcanoQueues(items []unstructured.Unstructured, nodes []NodeJson) []VolcanoQueue {
+	_, free := getFlavorFree(nil, nodes)
+	queues := make([]VolcanoQueue, 0, len(items))
+
+	for _, item := range items {
+		capability, _, _ := unstructured.NestedMap(item.Object, "spec", "capability")
+		deserved, _, _ := unstructured.NestedMap(item.Object, "spec", "deserved")
+		guarantee, _, _ := unstructured.NestedMap(item.Object, "spec", "guarantee", "resource")
+		allocated, _, _ := unstructured.NestedMap(item.Object, "status", "allocated")
+		weight, _, _ := unstructured.NestedInt64(item.Object, "spec", "weight")
+		state, _, _ := unstructured.NestedString(item.Object, "status", "state")
+		running, _, _ := unstructured.NestedInt64(item.Object, "status", "running")
+		pending, _, _ := unstructured.NestedInt64(item.Object, "status", "pending")
+		inqueue, _, _ := unstructured.NestedInt64(item.Object, "status", "inqueue")
+
+		queue := VolcanoQueue{
+			Name:       item.GetName(),
+			State:      state,
+			Weight:     weight,
+			Capability: parseQueueResources(capability),
+			Allocated:  parseQueueResources(allocated),
+			Headroom:   make(map[string]float64),
+			Available:  make(map[string]float64),
+			Running:    running,
+			Pending:    pending,
+			Inqueue:    inqueue,
+		}
+
+		if len(deserved) > 0 {
+			queue.Deserved = parseQueueResources(deserved)
+		}
+
+		if len(guarantee) > 0 {
+			queue.Guarantee = parseQueueResources(guarantee)
+		}
+
+		for name, limit := range queue.Capability {
+			queue.Headroom[name] = limit - queue.Allocated[name]
+			queue.Available[name] = queue.Headroom[name]
+
+			if slices.Contains(resourceNames, name) {
+				queue.Available[name] = max(min(queue.Headroom[name], getResource(free, name)), 0)
+			}
+		}
+
+		queues = append(queues, queue)
+	}
+
+	sort.Slice(queues, func(i, j int) bool {
+		return queues[i].Name < queues[j].Name
+	})
+
+	return queues
+}
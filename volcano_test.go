@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// TestParseVolcanoQueues parses a Volcano Queue capped on CPU and GPUs, checking the headroom, that the available
+// resources are limited by what is free on the nodes, and that resources the API doesn't report on keep their headroom.
+func TestParseVolcanoQueues(t *testing.T) {
+	items := []unstructured.Unstructured{
+		{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "batch"},
+				"spec": map[string]interface{}{
+					"weight":     int64(2),
+					"capability": map[string]interface{}{"cpu": "100", "nvidia.com/gpu": int64(8), "pods": "50"},
+					"guarantee":  map[string]interface{}{"resource": map[string]interface{}{"cpu": "10"}},
+				},
+				"status": map[string]interface{}{
+					"state":     "Open",
+					"allocated": map[string]interface{}{"cpu": "40", "memory": "64Gi", "nvidia.com/gpu": "6", "pods": "20"},
+					"running":   int64(3),
+					"pending":   int64(1),
+				},
+			},
+		},
+	}
+
+	nodes := []NodeJson{
+		{Name: "gpu-1", Free: ResourcesJson{Cpu: 10, Gpu: 1}},
+		{Name: "cpu-1", Free: ResourcesJson{Cpu: 20}},
+	}
+
+	result := parseVolcanoQueues(items, nodes)
+
+	if len(result) != 1 {
+		t.Fatalf(`parseVolcanoQueues() = %v, want 1 queue`, result)
+	}
+
+	queue := result[0]
+
+	switch {
+	case queue.State != "Open" || queue.Weight != 2 || queue.Running != 3 || queue.Pending != 1:
+		t.Fatalf(`queue = %v, want an open queue of weight 2 with 3 running and 1 pending`, queue)
+	case queue.Deserved != nil || queue.Guarantee["cpu"] != 10:
+		t.Fatalf(`queue.Guarantee = %v, want match for %v`, queue.Guarantee, map[string]float64{"cpu": 10})
+	case queue.Allocated["memory"] != 64<<30:
+		t.Fatalf(`queue.Allocated = %v, want 64Gi of memory`, queue.Allocated)
+	case len(queue.Headroom) != 3 || queue.Headroom["cpu"] != 60 || queue.Headroom["gpu"] != 2 || queue.Headroom["pods"] != 30:
+		t.Fatalf(`queue.Headroom = %v, want match for %v`, queue.Headroom, map[string]float64{"cpu": 60, "gpu": 2, "pods": 30})
+	case queue.Available["cpu"] != 30 || queue.Available["gpu"] != 1 || queue.Available["pods"] != 30:
+		t.Fatalf(`queue.Available = %v, want match for %v`, queue.Available, map[string]float64{"cpu": 30, "gpu": 1, "pods": 30})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Group, version, and resource of VerticalPodAutoscaler objects
+var vpaResource = schema.GroupVersionResource{
+	Group:    "autoscaling.k8s.io",
+	Version:  "v1",
+	Resource: "verticalpodautoscalers",
+}
+
+// VpaRecommendation contains the requests a VerticalPodAutoscaler recommends for each pod of a workload,
+// summed over its containers
+type VpaRecommendation struct {
+	Target     CpuMemory `json:"target"`
+	LowerBound CpuMemory `json:"lowerBound"`
+	UpperBound CpuMemory `json:"upperBound"`
+}
+
+// VpaRecommendations gets the recommendation of every VerticalPodAutoscaler in the cluster, keyed by the
+// namespace, kind, and name of the workload it targets. If the VPA CRD isn't installed, no recommendations
+// are returned.
+func (c *Collector) VpaRecommendations() (map[string]VpaRecommendation, error) {
+	if c.dynamic == nil {
+		return map[string]VpaRecommendation{}, nil
+	}
+
+	list, err := c.dynamic.Resource(vpaResource).List(context.Background(), metav1.ListOptions{})
+
+	// The CRD not existing just means there are no VPAs, and not being allowed to read them shouldn't
+	// stop the rest of the response
+	if errors.IsNotFound(err) || errors.IsForbidden(err) {
+		return map[string]VpaRecommendation{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseVpaRecommendations(list.Items), nil
+}
+
+// parseVpaRecommendations reads the target and recommendation of each VerticalPodAutoscaler object. VPAs that
+// haven't made a recommendation yet are skipped.
+func parseVpaRecommendations(items []unstructured.Unstructured) map[string]VpaRecommendation {
+	recommendations := make(map[string]VpaRecommendation)
+
+	for _, item := range items {
+		kind, _, _ := unstructured.NestedString(item.Object, "spec", "targetRef", "kind")
+		name, _, _ := unstructured.NestedString(item.Object, "spec", "targetRef", "name")
+		containers, found, _ := unstructured.NestedSlice(item.Object, "status", "recommendation", "containerRecommendations")
+
+		if kind == "" || name == "" || !found {
+			continue
+		}
+
+		var recommendation VpaRecommendation
+
+		for _, container := range containers {
+			fields, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			recommendation.Target = addCpuMemory(recommendation.Target, parseCpuMemory(fields["target"]))
+			recommendation.LowerBound = addCpuMemory(recommendation.LowerBound, parseCpuMemory(fields["lowerBound"]))
+			recommendation.UpperBound = addCpuMemory(recommendation.UpperBound, parseCpuMemory(fields["upperBound"]))
+		}
+
+		recommendations[item.GetNamespace()+"/"+kind+"/"+name] = recommendation
+	}
+
+	return recommendations
+}
+
+// parseCpuMemory reads the cpu and memory quantities from an unstructured resource list.
+func parseCpuMemory(value interface{}) CpuMemory {
+	var result CpuMemory
+
+	list, ok := value.(map[string]interface{})
+	if !ok {
+		return result
+	}
+
+	if text, ok := list["cpu"].(string); ok {
+		if quantity, err := resource.ParseQuantity(text); err == nil {
+			result.Cpu = quantity.AsApproximateFloat64()
+		}
+	}
+
+	if text, ok := list["memory"].(string); ok {
+		if quantity, err := resource.ParseQuantity(text); err == nil {
+			result.Memory = quantity.Value()
+		}
+	}
+
+	return result
+}
+
+// addCpuMemory returns the sum of each field of a and b.
+func addCpuMemory(a, b CpuMemory) CpuMemory {
+	return CpuMemory{Cpu: a.Cpu + b.Cpu, Memory: a.Memory + b.Memory}
+}
+
+// getWorkloadKey returns the key of the workload controlling a pod in a map of VPA recommendations.
+func getWorkloadKey(pod PodJson) string {
+	if pod.Owner == nil {
+		return pod.Namespace + "/Pod/" + pod.Name
+	}
+
+	return pod.Namespace + "/" + pod.Owner.Kind + "/" + pod.Owner.Name
+}
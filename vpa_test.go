@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// TestParseVpaRecommendations parses two VerticalPodAutoscaler objects, checking that container recommendations
+// are summed and keyed by the target workload, and that VPAs without a recommendation are skipped.
+func TestParseVpaRecommendations(t *testing.T) {
+	items := []unstructured.Unstructured{
+		{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "web-vpa", "namespace": "web"},
+				"spec": map[string]interface{}{
+					"targetRef": map[string]interface{}{"kind": "Deployment", "name": "web"},
+				},
+				"status": map[string]interface{}{
+					"recommendation": map[string]interface{}{
+						"containerRecommendations": []interface{}{
+							map[string]interface{}{
+								"containerName": "app",
+								"target":        map[string]interface{}{"cpu": "500m", "memory": "1Gi"},
+								"upperBound":    map[string]interface{}{"cpu": "1", "memory": "2Gi"},
+							},
+							map[string]interface{}{
+								"containerName": "sidecar",
+								"target":        map[string]interface{}{"cpu": "100m", "memory": "128Mi"},
+								"upperBound":    map[string]interface{}{"cpu": "200m", "memory": "256Mi"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "new-vpa", "namespace": "web"},
+				"spec": map[string]interface{}{
+					"targetRef": map[string]interface{}{"kind": "Deployment", "name": "new"},
+				},
+			},
+		},
+	}
+
+	recommendations := parseVpaRecommendations(items)
+
+	if len(recommendations) != 1 {
+		t.Fatalf(`len(recommendations) = %v, want match for %v`, len(recommendations), 1)
+	}
+
+	web, ok := recommendations["web/Deployment/web"]
+
+	switch {
+	case !ok:
+		t.Fatalf(`recommendations does not contain key %v`, "web/Deployment/web")
+	case web.Target != (CpuMemory{Cpu: 0.6, Memory: (1 << 30) + (128 << 20)}):
+		t.Fatalf(`web.Target = %v, want match for %v`, web.Target, CpuMemory{Cpu: 0.6, Memory: (1 << 30) + (128 << 20)})
+	case web.UpperBound != (CpuMemory{Cpu: 1.2, Memory: (2 << 30) + (256 << 20)}):
+		t.Fatalf(`web.UpperBound = %v, want match for %v`, web.UpperBound, CpuMemory{Cpu: 1.2, Memory: (2 << 30) + (256 << 20)})
+	}
+}
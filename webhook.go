@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Label namespaces opt in to capacity checks with, set to the policy to apply to their pods
+const webhookPolicyLabel = "resource-api.nrp-nautilus.io/capacity-check"
+
+// Policies for pods that don't fit in the free resources of any node
+const (
+	webhookPolicyWarn   = "warn"   // Admit the pod with a warning shown by kubectl
+	webhookPolicyReject = "reject" // Deny the pod
+)
+
+// CapacityWebhook checks pods being created against the free resources of the cluster's nodes
+type CapacityWebhook struct {
+	collector *Collector
+	snapshots *CachedSnapshot // Snapshots are reused, since taking one for every pod would list every pod each time
+}
+
+// newCapacityWebhook returns a CapacityWebhook that reads the cluster through collector, taking a new snapshot at
+// most once every ttl.
+func newCapacityWebhook(collector *Collector, ttl time.Duration) *CapacityWebhook {
+	return &CapacityWebhook{collector: collector, snapshots: newCachedSnapshot(collector, "webhook", ttl)}
+}
+
+// Review decides whether to admit a pod. Pods in namespaces without the policy label are always admitted, as are
+// pods that fit on a node they can be scheduled on. Errors reading the cluster admit the pod, so the API being
+// unavailable never stops pods from being created.
+func (w *CapacityWebhook) Review(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+
+	if request.Operation != admissionv1.Create || request.Kind.Kind != "Pod" {
+		return response
+	}
+
+	namespace, err := w.collector.client.CoreV1().Namespaces().Get(context.Background(), request.Namespace, metav1.GetOptions{})
+
+	if err != nil {
+		fmt.Println(err)
+		return response
+	}
+
+	policy := namespace.Labels[webhookPolicyLabel]
+
+	if policy != webhookPolicyWarn && policy != webhookPolicyReject {
+		return response
+	}
+
+	var pod corev1.Pod
+
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		fmt.Println(err)
+		return response
+	}
+
+	snapshot, err := w.snapshots.Get()
+
+	if err != nil {
+		fmt.Println(err)
+		return response
+	}
+
+	moved, fits := placePods([]corev1.Pod{pod}, snapshot.Nodes)
+
+	if fits {
+		return response
+	}
+
+	requests := moved[0].Requests
+	message := fmt.Sprintf("requests of cpu %v, memory %v, and gpu %v don't fit in the free resources of any node the pod can be scheduled on",
+		requests.Cpu, requests.Memory, requests.Gpu)
+
+	debugf("%v pod %v in namespace %v: %v", policy, getAdmittedPodName(&pod), request.Namespace, message)
+
+	if policy == webhookPolicyWarn {
+		response.Warnings = []string{message}
+		return response
+	}
+
+	response.Allowed = false
+	response.Result = &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden, Message: message}
+
+	return response
+}
+
+// getAdmittedPodName returns the name of a pod being created, which may only have a prefix to generate its name from.
+func getAdmittedPodName(pod *corev1.Pod) string {
+	if pod.Name == "" {
+		return pod.GenerateName + "*"
+	}
+
+	return pod.Name
+}
+
+// getWebhookHandler returns a HandlerFunc to review the AdmissionReview in the request body with a CapacityWebhook.
+func getWebhookHandler(webhook *CapacityWebhook) gin.HandlerFunc {
+	handler := func(c *gin.Context) {
+		var review admissionv1.AdmissionReview
+
+		if err := c.ShouldBindJSON(&review); err != nil {
+			c.JSON(http.StatusBadRequest, "error: "+err.Error())
+			return
+		}
+
+		if review.Request == nil {
+			c.JSON(http.StatusBadRequest, "error: expected an AdmissionReview with a request")
+			return
+		}
+
+		review.Response = webhook.Review(review.Request)
+		review.Request = nil
+
+		c.JSON(http.StatusOK, review)
+	}
+
+	return gin.HandlerFunc(handler)
+}
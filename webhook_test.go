@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestCapacityWebhook reviews pods too big for the only node in namespaces with each policy, and a pod that fits.
+func TestCapacityWebhook(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status: corev1.NodeStatus{
+			Capacity:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("16Gi")},
+			Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("16Gi")},
+		},
+	}
+
+	client := fake.NewSimpleClientset(
+		node,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "warned", Labels: map[string]string{webhookPolicyLabel: webhookPolicyWarn}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "rejected", Labels: map[string]string{webhookPolicyLabel: webhookPolicyReject}}},
+	)
+
+	webhook := newCapacityWebhook(newCollector(client), time.Minute)
+
+	review := func(namespace, cpu string) *admissionv1.AdmissionResponse {
+		pod := newTestPod("pod", cpu, nil)
+		pod.Namespace = namespace
+		raw, _ := json.Marshal(&pod)
+
+		return webhook.Review(&admissionv1.AdmissionRequest{
+			UID:       "uid",
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace: namespace,
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		})
+	}
+
+	unlabeled := review("default", "8")
+	warned := review("warned", "8")
+	rejected := review("rejected", "8")
+	fits := review("rejected", "2")
+
+	switch {
+	case !unlabeled.Allowed || len(unlabeled.Warnings) != 0:
+		t.Fatalf(`unlabeled = %v, want match for %v`, unlabeled, "allowed without warnings")
+	case !warned.Allowed || len(warned.Warnings) != 1:
+		t.Fatalf(`warned = %v, want match for %v`, warned, "allowed with a warning")
+	case rejected.Allowed || rejected.Result == nil || rejected.Result.Code != 403 || rejected.UID != "uid":
+		t.Fatalf(`rejected = %v, want match for %v`, rejected, "denied with code 403")
+	case !fits.Allowed:
+		t.Fatalf(`fits = %v, want match for %v`, fits, "allowed")
+	}
+}